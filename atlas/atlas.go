@@ -0,0 +1,375 @@
+// Package atlas writes schemas in the HCL format of Atlas
+// (https://atlasgo.io), so that teams moving to declarative schema
+// management can start from their existing schema files
+package atlas
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/internal/option"
+	"github.com/schemalex/schemalex/model"
+)
+
+type Option = schemalex.Option
+
+const optkeySchema = "schema"
+
+// WithSchema specifies the name of the schema, that is the database,
+// that the tables belong to. By default, the name of the database
+// created by the statements is used, or "main" if there is none.
+func WithSchema(name string) Option {
+	return option.New(optkeySchema, name)
+}
+
+// Stmts writes the statements to dst as Atlas HCL. Triggers and the
+// partitioning of tables are left out, as they are not supported by the
+// open source version of Atlas.
+func Stmts(dst io.Writer, stmts model.Stmts, options ...Option) error {
+	var schema string
+	for _, o := range options {
+		switch o.Name() {
+		case optkeySchema:
+			schema = o.Value().(string)
+		}
+	}
+	if schema == "" {
+		schema = "main"
+		for _, stmt := range stmts {
+			if db, ok := stmt.(model.Database); ok {
+				schema = db.Name()
+				break
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("schema ")
+	buf.WriteString(hclString(schema))
+	buf.WriteString(" {\n}\n")
+
+	for _, stmt := range stmts {
+		var err error
+		switch stmt := stmt.(type) {
+		case model.Database, model.Trigger:
+		case model.Table:
+			err = writeTable(&buf, schema, stmt)
+		case model.View:
+			writeView(&buf, schema, stmt)
+		default:
+			err = errors.Errorf(`unsupported statement %s`, stmt.ID())
+		}
+		if err != nil {
+			return errors.Wrapf(err, `failed to write statement %s`, stmt.ID())
+		}
+	}
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write schema`)
+	}
+	return nil
+}
+
+// Source writes the schema read from src as Atlas HCL (see Stmts)
+func Source(dst io.Writer, src schemalex.SchemaSource, options ...Option) error {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+	return Stmts(dst, stmts, options...)
+}
+
+// hclString quotes the string for HCL, where "${" and "%{" start
+// template sequences
+func hclString(s string) string {
+	s = strconv.Quote(s)
+	s = strings.Replace(s, "${", "$${", -1)
+	return strings.Replace(s, "%{", "%%{", -1)
+}
+
+var identifierRx = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reference returns a reference to a block, such as column.id, or
+// column["user-id"] if the name is not a valid HCL identifier
+func reference(kind, name string) string {
+	if identifierRx.MatchString(name) {
+		return kind + "." + name
+	}
+	return kind + "[" + hclString(name) + "]"
+}
+
+type block struct {
+	buf    *bytes.Buffer
+	indent string
+}
+
+func (b block) attr(key, value string) {
+	b.buf.WriteString(b.indent)
+	b.buf.WriteString(key)
+	b.buf.WriteString(" = ")
+	b.buf.WriteString(value)
+	b.buf.WriteByte('\n')
+}
+
+func (b block) open(kind, name string) block {
+	b.buf.WriteString(b.indent)
+	b.buf.WriteString(kind)
+	if name != "" {
+		b.buf.WriteByte(' ')
+		b.buf.WriteString(hclString(name))
+	}
+	b.buf.WriteString(" {\n")
+	return block{buf: b.buf, indent: b.indent + "  "}
+}
+
+func (b block) close() {
+	b.buf.WriteString(b.indent[2:])
+	b.buf.WriteString("}\n")
+}
+
+func writeTable(buf *bytes.Buffer, schema string, table model.Table) error {
+	table, _ = table.Normalize()
+	if table.HasLikeTable() {
+		return errors.New(`CREATE TABLE ... LIKE is not supported`)
+	}
+
+	buf.WriteByte('\n')
+	t := block{buf: buf}.open("table", table.Name())
+	t.attr("schema", reference("schema", schema))
+	for col := range table.Columns() {
+		writeColumn(t, col)
+	}
+
+	for idx := range table.Indexes() {
+		switch {
+		case idx.IsPrimaryKey():
+			pk := t.open("primary_key", "")
+			writeIndexColumns(pk, idx)
+			pk.close()
+		case idx.IsForeignKey():
+			writeForeignKey(t, idx)
+		default:
+			name := idx.Symbol()
+			if idx.HasName() {
+				name = idx.Name()
+			}
+			i := t.open("index", name)
+			switch {
+			case idx.IsUnique():
+				i.attr("unique", "true")
+			case idx.IsFullText():
+				i.attr("type", "FULLTEXT")
+			case idx.IsSpatial():
+				i.attr("type", "SPATIAL")
+			case idx.IsHash():
+				i.attr("type", "HASH")
+			}
+			writeIndexColumns(i, idx)
+			i.close()
+		}
+	}
+
+	for check := range table.Checks() {
+		c := t.open("check", check.Name())
+		c.attr("expr", hclString(check.Expression()))
+		if !check.IsEnforced() {
+			c.attr("enforced", "false")
+		}
+		c.close()
+	}
+
+	for opt := range table.Options() {
+		switch strings.ToUpper(opt.Key()) {
+		case "DEFAULT CHARACTER SET", "CHARACTER SET":
+			t.attr("charset", hclString(opt.Value()))
+		case "DEFAULT COLLATE", "COLLATE":
+			t.attr("collate", hclString(opt.Value()))
+		case "COMMENT":
+			t.attr("comment", hclString(opt.Value()))
+		case "AUTO_INCREMENT":
+			t.attr("auto_increment", opt.Value())
+		}
+	}
+	t.close()
+	return nil
+}
+
+func writeColumn(t block, col model.TableColumn) {
+	c := t.open("column", col.Name())
+	c.attr("null", strconv.FormatBool(col.NullState() != model.NullStateNotNull))
+	c.attr("type", columnType(col))
+	if col.IsUnsigned() {
+		c.attr("unsigned", "true")
+	}
+	if col.HasDefault() {
+		if def, ok := defaultValue(col); ok {
+			c.attr("default", def)
+		}
+	}
+	if col.HasAutoUpdate() {
+		c.attr("on_update", "sql("+hclString(col.AutoUpdate())+")")
+	}
+	if col.IsAutoIncrement() {
+		c.attr("auto_increment", "true")
+	}
+	if col.HasGeneratedExpr() {
+		g := c.open("as", "")
+		g.attr("expr", hclString(col.GeneratedExpr()))
+		if col.StoreOption() == model.StoreOptionStored {
+			g.attr("type", "STORED")
+		} else {
+			g.attr("type", "VIRTUAL")
+		}
+		g.close()
+	}
+	if col.HasCharacterSet() {
+		c.attr("charset", hclString(col.CharacterSet()))
+	}
+	if col.HasCollation() {
+		c.attr("collate", hclString(col.Collation()))
+	}
+	if col.HasComment() {
+		c.attr("comment", hclString(col.Comment()))
+	}
+	c.close()
+}
+
+// isBoolean tells if the column is TINYINT(1), which is what BOOLEAN
+// is in MySQL
+func isBoolean(col model.TableColumn) bool {
+	return col.Type() == model.ColumnTypeTinyInt && col.HasLength() && col.Length().Length() == "1" && !col.IsUnsigned()
+}
+
+// columnType returns the type of the column as Atlas writes it. Display
+// widths of integers are left out, as Atlas does.
+func columnType(col model.TableColumn) string {
+	if isBoolean(col) || col.Type() == model.ColumnTypeBool || col.Type() == model.ColumnTypeBoolean {
+		return "bool"
+	}
+
+	typ := col.Type().SynonymType()
+	name := strings.ToLower(typ.String())
+	switch typ {
+	case model.ColumnTypeTinyInt, model.ColumnTypeSmallInt, model.ColumnTypeMediumInt,
+		model.ColumnTypeInt, model.ColumnTypeBigInt, model.ColumnTypeYear:
+		return name
+	case model.ColumnTypeEnum:
+		return name + "(" + quoteValues(col.EnumValues()) + ")"
+	case model.ColumnTypeSet:
+		return name + "(" + quoteValues(col.SetValues()) + ")"
+	}
+	if col.HasLength() {
+		l := col.Length()
+		if l.HasDecimal() {
+			return name + "(" + l.Length() + "," + l.Decimal() + ")"
+		}
+		return name + "(" + l.Length() + ")"
+	}
+	return name
+}
+
+func quoteValues(ch chan string) string {
+	var values []string
+	for v := range ch {
+		values = append(values, hclString(v))
+	}
+	return strings.Join(values, ",")
+}
+
+var numberRx = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// defaultValue returns the default value of the column, if it has one
+// other than NULL
+func defaultValue(col model.TableColumn) (string, bool) {
+	def := col.Default()
+	switch {
+	case col.IsQuotedDefault():
+		return hclString(def), true
+	case strings.EqualFold(def, "NULL"):
+		return "", false
+	case isBoolean(col) && (def == "0" || def == "1"):
+		return strconv.FormatBool(def == "1"), true
+	case numberRx.MatchString(def):
+		return def, true
+	}
+	// CURRENT_TIMESTAMP and other expressions
+	return "sql(" + hclString(def) + ")", true
+}
+
+func writeIndexColumns(b block, c model.ColumnContainer) {
+	var simple []string
+	var parts bool
+	for col := range c.Columns() {
+		simple = append(simple, reference("column", col.Name()))
+		if col.HasLength() || (col.HasSortDirection() && col.IsDescending()) {
+			parts = true
+		}
+	}
+	if !parts {
+		b.attr("columns", "["+strings.Join(simple, ", ")+"]")
+		return
+	}
+
+	// prefix lengths and sort directions need a part of their own
+	for col := range c.Columns() {
+		on := b.open("on", "")
+		on.attr("column", reference("column", col.Name()))
+		if col.HasLength() {
+			on.attr("prefix", col.Length())
+		}
+		if col.HasSortDirection() && col.IsDescending() {
+			on.attr("desc", "true")
+		}
+		on.close()
+	}
+}
+
+var referenceOptions = map[model.ReferenceOption]string{
+	model.ReferenceOptionRestrict: "RESTRICT",
+	model.ReferenceOptionCascade:  "CASCADE",
+	model.ReferenceOptionSetNull:  "SET_NULL",
+	model.ReferenceOptionNoAction: "NO_ACTION",
+}
+
+func writeForeignKey(t block, idx model.Index) {
+	fk := t.open("foreign_key", idx.Symbol())
+	var cols []string
+	for col := range idx.Columns() {
+		cols = append(cols, reference("column", col.Name()))
+	}
+	fk.attr("columns", "["+strings.Join(cols, ", ")+"]")
+
+	if r := idx.Reference(); r != nil {
+		table := reference("table", r.TableName())
+		var refs []string
+		for col := range r.Columns() {
+			refs = append(refs, table+"."+reference("column", col.Name()))
+		}
+		fk.attr("ref_columns", "["+strings.Join(refs, ", ")+"]")
+		if action, ok := referenceOptions[r.OnUpdate()]; ok {
+			fk.attr("on_update", action)
+		}
+		if action, ok := referenceOptions[r.OnDelete()]; ok {
+			fk.attr("on_delete", action)
+		}
+	}
+	fk.close()
+}
+
+func writeView(buf *bytes.Buffer, schema string, view model.View) {
+	buf.WriteByte('\n')
+	v := block{buf: buf}.open("view", view.Name())
+	v.attr("schema", reference("schema", schema))
+	def := strings.NewReplacer("${", "$${", "%{", "%%{", "\n", "\n    ").Replace(view.Definition())
+	v.attr("as", "<<-SQL\n    "+def+"\n  SQL")
+	v.close()
+}
@@ -0,0 +1,95 @@
+package atlas_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/atlas"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmts(t *testing.T) {
+	src := "CREATE TABLE `users` ( `id` INTEGER UNSIGNED NOT NULL AUTO_INCREMENT, `active` BOOL NOT NULL DEFAULT TRUE, `name` VARCHAR (20) NOT NULL DEFAULT '${x}' COMMENT 'the name', `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (`id`), KEY `idx_name` (`name`(5), `created` DESC) ) COMMENT 'users';\n" +
+		"CREATE TABLE `user-posts` ( `id` BIGINT NOT NULL, `user_id` INTEGER UNSIGNED NOT NULL, UNIQUE KEY `uniq_user` (`user_id`), CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE, CONSTRAINT `chk_id` CHECK (`id` > 0) NOT ENFORCED );"
+	expect := `schema "app" {
+}
+
+table "users" {
+  schema = schema.app
+  column "id" {
+    null = false
+    type = int
+    unsigned = true
+    auto_increment = true
+  }
+  column "active" {
+    null = false
+    type = bool
+    default = true
+  }
+  column "name" {
+    null = false
+    type = varchar(20)
+    default = "$${x}"
+    comment = "the name"
+  }
+  column "created" {
+    null = false
+    type = datetime
+    default = sql("CURRENT_TIMESTAMP")
+  }
+  primary_key {
+    columns = [column.id]
+  }
+  index "idx_name" {
+    on {
+      column = column.name
+      prefix = 5
+    }
+    on {
+      column = column.created
+      desc = true
+    }
+  }
+  comment = "users"
+}
+
+table "user-posts" {
+  schema = schema.app
+  column "id" {
+    null = false
+    type = bigint
+  }
+  column "user_id" {
+    null = false
+    type = int
+    unsigned = true
+  }
+  index "uniq_user" {
+    unique = true
+    columns = [column.user_id]
+  }
+  foreign_key "fk_user" {
+    columns = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update = RESTRICT
+    on_delete = CASCADE
+  }
+  check "chk_id" {
+    expr = "` + "`id` > 0" + `"
+    enforced = false
+  }
+}
+`
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parsing should succeed") {
+		return
+	}
+	var buf bytes.Buffer
+	if !assert.NoError(t, atlas.Stmts(&buf, stmts, atlas.WithSchema("app")), "atlas.Stmts should succeed") {
+		return
+	}
+	assert.Equal(t, expect, buf.String(), "HCL should match")
+}
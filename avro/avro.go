@@ -0,0 +1,212 @@
+// Package avro generates Avro record schemas from tables, so that
+// Kafka Connect and other CDC pipelines stay aligned with the schema
+// of the database they capture
+package avro
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/internal/option"
+	"github.com/schemalex/schemalex/model"
+)
+
+type Option = schemalex.Option
+
+const optkeyNamespace = "namespace"
+
+// WithNamespace specifies the namespace of the records, which is left
+// out by default
+func WithNamespace(ns string) Option {
+	return option.New(optkeyNamespace, ns)
+}
+
+// record is an Avro record schema
+type record struct {
+	Type      string  `json:"type"`
+	Name      string  `json:"name"`
+	Namespace string  `json:"namespace,omitempty"`
+	Doc       string  `json:"doc,omitempty"`
+	Fields    []field `json:"fields"`
+}
+
+type field struct {
+	Name string      `json:"name"`
+	Type interface{} `json:"type"`
+	Doc  string      `json:"doc,omitempty"`
+	// Default is only set for nullable fields, whose default is null
+	Default json.Marshaler `json:"default,omitempty"`
+}
+
+// null is a default value of null, which cannot be told from no default
+// value otherwise
+type null struct{}
+
+func (null) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// Stmts writes the record schemas of the tables among the statements
+// to dst, as a JSON array with a record for each table. Nullable
+// columns are unions with null, which is their default. DECIMAL, DATE,
+// TIME, DATETIME and TIMESTAMP columns are of logical types, DATETIME
+// being local-timestamp as it has no time zone.
+func Stmts(dst io.Writer, stmts model.Stmts, options ...Option) error {
+	var namespace string
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyNamespace:
+			namespace = o.Value().(string)
+		}
+	}
+
+	records := []record{}
+	for _, stmt := range stmts {
+		table, ok := stmt.(model.Table)
+		if !ok {
+			continue
+		}
+		table, _ = table.Normalize()
+		records = append(records, tableRecord(table, namespace))
+	}
+
+	buf, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, `failed to encode schemas`)
+	}
+	buf = append(buf, '\n')
+	if _, err := dst.Write(buf); err != nil {
+		return errors.Wrap(err, `failed to write schemas`)
+	}
+	return nil
+}
+
+// Source writes the record schemas of the schema read from src (see
+// Stmts)
+func Source(dst io.Writer, src schemalex.SchemaSource, options ...Option) error {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+	return Stmts(dst, stmts, options...)
+}
+
+var invalidRx = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// name returns the name in the form Avro allows for records and fields
+func name(s string) string {
+	s = invalidRx.ReplaceAllString(s, "_")
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		s = "_" + s
+	}
+	return s
+}
+
+func tableRecord(table model.Table, namespace string) record {
+	r := record{
+		Type:      "record",
+		Name:      name(table.Name()),
+		Namespace: namespace,
+		Fields:    []field{},
+	}
+	for opt := range table.Options() {
+		if strings.EqualFold(opt.Key(), "COMMENT") {
+			r.Doc = opt.Value()
+		}
+	}
+	for col := range table.Columns() {
+		f := field{
+			Name: name(col.Name()),
+			Type: columnType(col),
+			Doc:  col.Comment(),
+		}
+		if col.NullState() != model.NullStateNotNull {
+			// null has to come first in the union to be the default
+			f.Type = []interface{}{"null", f.Type}
+			f.Default = null{}
+		}
+		r.Fields = append(r.Fields, f)
+	}
+	return r
+}
+
+// logical is an Avro type annotated with a logical type
+type logical struct {
+	Type        string `json:"type"`
+	LogicalType string `json:"logicalType"`
+	Precision   int    `json:"precision,omitempty"`
+	Scale       int    `json:"scale,omitempty"`
+}
+
+func columnType(col model.TableColumn) interface{} {
+	switch col.Type().SynonymType() {
+	case model.ColumnTypeTinyInt:
+		if col.Type() != model.ColumnTypeTinyInt || col.HasLength() && col.Length().Length() == "1" && !col.IsUnsigned() {
+			return "boolean"
+		}
+		return "int"
+	case model.ColumnTypeSmallInt, model.ColumnTypeMediumInt, model.ColumnTypeYear:
+		return "int"
+	case model.ColumnTypeInt:
+		if col.IsUnsigned() {
+			return "long"
+		}
+		return "int"
+	case model.ColumnTypeBigInt, model.ColumnTypeBit:
+		return "long"
+	case model.ColumnTypeFloat:
+		return "float"
+	case model.ColumnTypeDouble:
+		return "double"
+	case model.ColumnTypeDecimal:
+		// DECIMAL is DECIMAL(10, 0) by default
+		precision, scale := 10, 0
+		if col.HasLength() {
+			l := col.Length()
+			if n, err := strconv.Atoi(l.Length()); err == nil {
+				precision = n
+			}
+			if l.HasDecimal() {
+				if n, err := strconv.Atoi(l.Decimal()); err == nil {
+					scale = n
+				}
+			}
+		}
+		return logical{Type: "bytes", LogicalType: "decimal", Precision: precision, Scale: scale}
+	case model.ColumnTypeDate:
+		return logical{Type: "int", LogicalType: "date"}
+	case model.ColumnTypeTime:
+		return logical{Type: "long", LogicalType: "time-micros"}
+	case model.ColumnTypeDateTime:
+		return logical{Type: "long", LogicalType: "local-timestamp-" + precision(col)}
+	case model.ColumnTypeTimestamp:
+		return logical{Type: "long", LogicalType: "timestamp-" + precision(col)}
+	case model.ColumnTypeBinary, model.ColumnTypeVarBinary,
+		model.ColumnTypeTinyBlob, model.ColumnTypeBlob, model.ColumnTypeMediumBlob, model.ColumnTypeLongBlob:
+		return "bytes"
+	default:
+		// texts, ENUM, SET and JSON
+		return "string"
+	}
+}
+
+// precision returns the precision of the logical type for the fractional
+// seconds of the column, millis unless it has more than 3 digits
+func precision(col model.TableColumn) string {
+	if col.HasLength() {
+		if n, err := strconv.Atoi(col.Length().Length()); err == nil && n > 3 {
+			return "micros"
+		}
+	}
+	return "millis"
+}
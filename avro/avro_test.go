@@ -0,0 +1,85 @@
+package avro_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/avro"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmts(t *testing.T) {
+	const input = "CREATE TABLE `orders` ( `id` BIGINT NOT NULL, `paid` BOOLEAN NOT NULL, `total` DECIMAL (10, 2) NOT NULL COMMENT 'tax included', `placed_on` DATE, `created_at` DATETIME (6) NOT NULL, `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, `note` TEXT, PRIMARY KEY (`id`) ) COMMENT 'orders placed';"
+	const expect = `[
+  {
+    "type": "record",
+    "name": "orders",
+    "namespace": "com.example",
+    "doc": "orders placed",
+    "fields": [
+      {
+        "name": "id",
+        "type": "long"
+      },
+      {
+        "name": "paid",
+        "type": "boolean"
+      },
+      {
+        "name": "total",
+        "type": {
+          "type": "bytes",
+          "logicalType": "decimal",
+          "precision": 10,
+          "scale": 2
+        },
+        "doc": "tax included"
+      },
+      {
+        "name": "placed_on",
+        "type": [
+          "null",
+          {
+            "type": "int",
+            "logicalType": "date"
+          }
+        ],
+        "default": null
+      },
+      {
+        "name": "created_at",
+        "type": {
+          "type": "long",
+          "logicalType": "local-timestamp-micros"
+        }
+      },
+      {
+        "name": "updated_at",
+        "type": {
+          "type": "long",
+          "logicalType": "timestamp-millis"
+        }
+      },
+      {
+        "name": "note",
+        "type": [
+          "null",
+          "string"
+        ],
+        "default": null
+      }
+    ]
+  }
+]
+`
+	stmts, err := schemalex.New().ParseString(input)
+	if !assert.NoError(t, err, "Parse should succeed") {
+		return
+	}
+	var buf bytes.Buffer
+	if !assert.NoError(t, avro.Stmts(&buf, stmts, avro.WithNamespace("com.example")), "avro.Stmts should succeed") {
+		return
+	}
+	assert.Equal(t, expect, buf.String(), "schemas should match")
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/diff"
+	"github.com/schemalex/schemalex/internal/atomicfile"
+	"github.com/schemalex/schemalex/model"
+)
+
+// pair is a pair of schemas compared in batch mode
+type pair struct {
+	Name   string `yaml:"name"`
+	Before string `yaml:"before"`
+	After  string `yaml:"after"`
+	// Output is the file to write the statements to. If it is empty,
+	// they are written along with those of the other pairs.
+	Output string `yaml:"output"`
+}
+
+// readPairs reads the pairs listed in the file, one per line as
+// "before after [output]". Blank lines and lines starting with "#" are
+// ignored.
+func readPairs(path string) ([]pair, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to read pairs from %s`, path)
+	}
+
+	var pairs []pair
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, errors.Errorf(`%s:%d: expected "before after [output]"`, path, i+1)
+		}
+		pr := pair{Before: fields[0], After: fields[1]}
+		if len(fields) == 3 {
+			pr.Output = fields[2]
+		}
+		pairs = append(pairs, pr)
+	}
+	return pairs, nil
+}
+
+// batchSources compares each pair of schemas, writing the statements
+// for each of them to its output file, or to dst after a comment
+// naming the pair, and writes a summary of all of them to summary. A
+// pair that fails does not stop the others from being compared.
+// Returns whether any of the pairs differ.
+func batchSources(dst, summary io.Writer, pairs []pair, cfg *config, p *schemalex.Parser, sourceOptions []schemalex.Option, options []diff.Option) (bool, error) {
+	var changed, unchanged, failed int
+	for i, pr := range pairs {
+		pr.Before = cfg.resolve(pr.Before)
+		pr.After = cfg.resolve(pr.After)
+		if len(pr.Name) == 0 {
+			pr.Name = fmt.Sprintf("#%d", i+1)
+		}
+
+		s, err := comparePair(dst, pr, p, sourceOptions, options)
+		if err != nil {
+			failed++
+			fmt.Fprintf(summary, "%s: failed: %s\n", pr.Name, err)
+			continue
+		}
+		if len(s.Tables) == 0 {
+			unchanged++
+		} else {
+			changed++
+		}
+		fmt.Fprintf(summary, "%s: %s\n", pr.Name, s.ShortStat())
+	}
+	fmt.Fprintf(summary, "%d pairs: %d changed, %d unchanged, %d failed\n", len(pairs), changed, unchanged, failed)
+
+	if failed > 0 {
+		return changed > 0, errors.Errorf(`%d of %d pairs failed`, failed, len(pairs))
+	}
+	return changed > 0, nil
+}
+
+func comparePair(dst io.Writer, pr pair, p *schemalex.Parser, sourceOptions []schemalex.Option, options []diff.Option) (diff.Summary, error) {
+	from, err := readStmts(p, pr.Before, sourceOptions)
+	if err != nil {
+		return diff.Summary{}, err
+	}
+	to, err := readStmts(p, pr.After, sourceOptions)
+	if err != nil {
+		return diff.Summary{}, err
+	}
+
+	changes, err := diff.Compute(from, to, options...)
+	if err != nil {
+		return diff.Summary{}, err
+	}
+
+	if len(pr.Output) == 0 {
+		fmt.Fprintf(dst, "-- %s: %s -> %s\n", pr.Name, pr.Before, pr.After)
+		if err := diff.Statements(dst, from, to, options...); err != nil {
+			return diff.Summary{}, err
+		}
+		fmt.Fprint(dst, "\n\n")
+		return diff.Summarize(changes), nil
+	}
+
+	f, err := atomicfile.Create(pr.Output, 0644, strings.HasSuffix(pr.Output, ".gz"))
+	if err != nil {
+		return diff.Summary{}, err
+	}
+	defer f.Close()
+	// files are never colored
+	options = append(options[:len(options):len(options)], diff.WithColor(false))
+	if err := diff.Statements(f, from, to, options...); err != nil {
+		return diff.Summary{}, err
+	}
+	return diff.Summarize(changes), f.Commit()
+}
+
+func readStmts(p *schemalex.Parser, uri string, sourceOptions []schemalex.Option) (model.Stmts, error) {
+	if uri == "-" {
+		return nil, errors.New(`stdin cannot be compared in batch mode`)
+	}
+	src, err := schemalex.NewSchemaSource(uri, sourceOptions...)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to create schema source for %s`, uri)
+	}
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return nil, errors.Wrapf(err, `failed to retrieve schema from source %s`, uri)
+	}
+	stmts, err := p.Parse(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to parse source %s`, uri)
+	}
+	return stmts, nil
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// completionFlag is a flag completed by the shell
+type completionFlag struct {
+	name string
+	// arg tells if the flag takes a value, and values lists the values
+	// it can take, if they are a fixed set. file tells if the value is
+	// a file. Other values are not completed.
+	arg    bool
+	values []string
+	file   bool
+}
+
+// completionCommand is a subcommand completed by the shell. The one
+// without a name is schemalex itself, which comes last.
+type completionCommand struct {
+	name  string
+	flags []completionFlag
+	// args lists the arguments that the command takes, which are
+	// sources if it is empty
+	args []string
+}
+
+// sourcePrefixes are completed along with files wherever a source is
+// expected
+var sourcePrefixes = []string{
+	"file://",
+	"mysql://",
+	"git://",
+	"git+ssh://",
+	"git+https://",
+	"local-git://",
+	"manifest://",
+	"https://",
+	"s3://",
+}
+
+// completionCommands must be kept in line with the flags that each
+// command defines
+func completionCommands() []completionCommand {
+	exportFormats := []string{
+		"atlas", "postgres", "sqlite", "markdown", "json", "csv", "tsv",
+		"jsonschema", "openapi", "graphql", "avro", "proto", "go",
+		"go-structs", "dbml", "dot", "mermaid", "plantuml",
+	}
+	for _, dialect := range format.Dialects() {
+		if !contains(exportFormats, dialect) {
+			exportFormats = append(exportFormats, dialect)
+		}
+	}
+	return []completionCommand{
+		{
+			name: "fmt",
+			flags: []completionFlag{
+				{name: "w"},
+				{name: "l"},
+				{name: "i", arg: true},
+				{name: "quote", arg: true, values: []string{"always", "needed", "never"}},
+				{name: "no-int-width"},
+				{name: "align"},
+				{name: "canonical"},
+				{name: "sort-columns"},
+			},
+		},
+		{
+			name: "export",
+			flags: []completionFlag{
+				{name: "format", arg: true, values: exportFormats},
+				{name: "to", arg: true, values: exportFormats},
+				{name: "schema", arg: true},
+				{name: "identity"},
+				{name: "title", arg: true},
+				{name: "package", arg: true},
+				{name: "pointers"},
+				{name: "namespace", arg: true},
+			},
+		},
+		{
+			name: "graph",
+			flags: []completionFlag{
+				{name: "format", arg: true, values: []string{"dot", "mermaid", "plantuml"}},
+			},
+		},
+		{
+			name: "lint",
+			flags: []completionFlag{
+				{name: "rules", arg: true},
+				{name: "list"},
+			},
+		},
+		{
+			name: "apply",
+			flags: []completionFlag{
+				{name: "dry-run"},
+				{name: "auto-approve"},
+				{name: "allow-destructive"},
+				{name: "timeout", arg: true},
+				{name: "before-apply", arg: true},
+				{name: "after-apply", arg: true},
+				{name: "hook-plugin", arg: true, file: true},
+			},
+		},
+		{
+			name: "normalize",
+			flags: []completionFlag{
+				{name: "strictness", arg: true, values: []string{"default", "strict", "lenient"}},
+				{name: "ignore-auto-increment"},
+				{name: "ignore-comments"},
+				{name: "ignore-table-options", arg: true},
+				{name: "include", arg: true},
+				{name: "exclude", arg: true},
+			},
+		},
+		{
+			name: "validate",
+		},
+		{
+			name: "completion",
+			args: []string{"bash", "zsh", "fish"},
+		},
+		// last, as it matches whatever the subcommands do not
+		{
+			flags: []completionFlag{
+				{name: "v"},
+				{name: "t"},
+				{name: "o", arg: true, file: true},
+				{name: "output", arg: true, file: true},
+				{name: "gzip"},
+				{name: "fingerprint"},
+				{name: "mysql-tls", arg: true, values: []string{"true", "false", "skip-verify", "preferred"}},
+				{name: "mysql-timeout", arg: true},
+				{name: "mysql-tables", arg: true},
+			},
+		},
+	}
+}
+
+// completionMain implements `schemalex completion`, which writes the
+// script completing schemalex for a shell
+func completionMain(args []string) error {
+	fs := flag.NewFlagSet("completion", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf(`schemalex completion bash|zsh|fish
+
+Writes the script completing the subcommands, options and source URI
+prefixes of schemalex for the shell. For example:
+
+  # bash, in ~/.bashrc
+  source <(schemalex completion bash)
+  # zsh, in a directory of $fpath
+  schemalex completion zsh > ~/.zsh/completion/_schemalex
+  # fish
+  schemalex completion fish > ~/.config/fish/completions/schemalex.fish
+`)
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("wrong number of arguments")
+	}
+
+	commands := completionCommands()
+	switch fs.Arg(0) {
+	case "bash":
+		return writeBashCompletion(os.Stdout, commands)
+	case "zsh":
+		return writeZshCompletion(os.Stdout, commands)
+	case "fish":
+		return writeFishCompletion(os.Stdout, commands)
+	}
+	fs.Usage()
+	return errors.Errorf(`invalid shell %q`, fs.Arg(0))
+}
+
+func subcommandNames(commands []completionCommand) []string {
+	var names []string
+	for _, cmd := range commands {
+		if len(cmd.name) > 0 {
+			names = append(names, cmd.name)
+		}
+	}
+	return names
+}
+
+func writeBashCompletion(dst io.Writer, commands []completionCommand) error {
+	names := subcommandNames(commands)
+
+	var buf strings.Builder
+	buf.WriteString("# bash completion for schemalex\n\n")
+	buf.WriteString("_schemalex() {\n")
+	buf.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\" cmd=\n")
+	fmt.Fprintf(&buf, "    case \"${COMP_WORDS[1]}\" in\n        %s) [[ ${COMP_CWORD} -gt 1 ]] && cmd=\"${COMP_WORDS[1]}\" ;;\n    esac\n\n", strings.Join(names, "|"))
+
+	buf.WriteString("    case \"${cmd}${prev}\" in\n")
+	for _, cmd := range commands {
+		for _, f := range cmd.flags {
+			if !f.arg {
+				continue
+			}
+			fmt.Fprintf(&buf, "        %s-%s)\n", cmd.name, f.name)
+			switch {
+			case len(f.values) > 0:
+				fmt.Fprintf(&buf, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(f.values, " "))
+			case f.file:
+				buf.WriteString("            COMPREPLY=($(compgen -f -- \"$cur\"))\n")
+			default:
+				buf.WriteString("            COMPREPLY=()\n")
+			}
+			buf.WriteString("            return ;;\n")
+		}
+	}
+	buf.WriteString("    esac\n\n")
+
+	buf.WriteString("    local flags args\n    case \"$cmd\" in\n")
+	for _, cmd := range commands {
+		label := cmd.name
+		if len(label) == 0 {
+			label = "*"
+		}
+		fmt.Fprintf(&buf, "        %s)\n", label)
+		fmt.Fprintf(&buf, "            flags=%q\n", strings.Join(flagNames(cmd.flags), " "))
+		if len(cmd.args) > 0 {
+			fmt.Fprintf(&buf, "            args=%q\n", strings.Join(cmd.args, " "))
+		}
+		buf.WriteString("            ;;\n")
+	}
+	buf.WriteString("    esac\n\n")
+
+	buf.WriteString("    if [[ \"$cur\" == -* ]]; then\n")
+	buf.WriteString("        COMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	buf.WriteString("    elif [[ -n \"$args\" ]]; then\n")
+	buf.WriteString("        COMPREPLY=($(compgen -W \"$args\" -- \"$cur\"))\n")
+	buf.WriteString("    else\n")
+	buf.WriteString("        [[ -z \"$cmd\" && ${COMP_CWORD} -eq 1 ]] && COMPREPLY=($(compgen -W " + fmt.Sprintf("%q", strings.Join(names, " ")) + " -- \"$cur\"))\n")
+	fmt.Fprintf(&buf, "        COMPREPLY+=($(compgen -W %q -- \"$cur\") $(compgen -f -- \"$cur\"))\n", strings.Join(sourcePrefixes, " "))
+	buf.WriteString("        [[ ${#COMPREPLY[@]} -eq 1 && \"${COMPREPLY[0]}\" == *:// ]] && compopt -o nospace\n")
+	buf.WriteString("    fi\n")
+	buf.WriteString("}\n\n")
+	buf.WriteString("complete -F _schemalex schemalex\n")
+
+	_, err := io.WriteString(dst, buf.String())
+	return err
+}
+
+func writeZshCompletion(dst io.Writer, commands []completionCommand) error {
+	var buf strings.Builder
+	buf.WriteString("#compdef schemalex\n\n")
+	buf.WriteString("_schemalex_sources() {\n")
+	fmt.Fprintf(&buf, "    compadd -S '' -- %s\n", strings.Join(sourcePrefixes, " "))
+	buf.WriteString("    _files\n")
+	buf.WriteString("}\n\n")
+	buf.WriteString("_schemalex_first() {\n")
+	fmt.Fprintf(&buf, "    compadd -- %s\n", strings.Join(subcommandNames(commands), " "))
+	buf.WriteString("    _schemalex_sources\n")
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("_schemalex() {\n")
+	buf.WriteString("    local cmd\n")
+	buf.WriteString("    (( CURRENT > 2 )) && cmd=$words[2]\n")
+	buf.WriteString("    case $cmd in\n")
+	for _, cmd := range commands {
+		var specs []string
+		for _, f := range cmd.flags {
+			spec := "-" + f.name
+			switch {
+			case len(f.values) > 0:
+				spec += ":" + f.name + ":(" + strings.Join(f.values, " ") + ")"
+			case f.file:
+				spec += ":" + f.name + ":_files"
+			case f.arg:
+				spec += ":" + f.name + ": "
+			}
+			specs = append(specs, "'"+spec+"'")
+		}
+
+		if len(cmd.name) == 0 {
+			specs = append(specs, "'1:command or source:_schemalex_first'", "'*:source:_schemalex_sources'")
+			buf.WriteString("        *)\n")
+		} else {
+			if len(cmd.args) > 0 {
+				specs = append(specs, "'1:"+cmd.name+":("+strings.Join(cmd.args, " ")+")'")
+			} else {
+				specs = append(specs, "'*:source:_schemalex_sources'")
+			}
+			fmt.Fprintf(&buf, "        %s)\n", cmd.name)
+			buf.WriteString("            shift words\n")
+			buf.WriteString("            (( CURRENT-- ))\n")
+		}
+		fmt.Fprintf(&buf, "            _arguments %s\n", strings.Join(specs, " "))
+		buf.WriteString("            ;;\n")
+	}
+	buf.WriteString("    esac\n")
+	buf.WriteString("}\n\n")
+	buf.WriteString("_schemalex \"$@\"\n")
+
+	_, err := io.WriteString(dst, buf.String())
+	return err
+}
+
+func writeFishCompletion(dst io.Writer, commands []completionCommand) error {
+	names := strings.Join(subcommandNames(commands), " ")
+
+	var buf strings.Builder
+	buf.WriteString("# fish completion for schemalex\n\n")
+	fmt.Fprintf(&buf, "complete -c schemalex -n '__fish_use_subcommand' -a '%s'\n", names)
+	for _, cmd := range commands {
+		cond := "not __fish_seen_subcommand_from " + names
+		if len(cmd.name) > 0 {
+			cond = "__fish_seen_subcommand_from " + cmd.name
+		}
+		for _, f := range cmd.flags {
+			line := fmt.Sprintf("complete -c schemalex -n '%s' -o %s", cond, f.name)
+			switch {
+			case len(f.values) > 0:
+				line += " -x -a '" + strings.Join(f.values, " ") + "'"
+			case f.file:
+				line += " -r"
+			case f.arg:
+				line += " -x"
+			}
+			buf.WriteString(line + "\n")
+		}
+		if len(cmd.args) > 0 {
+			fmt.Fprintf(&buf, "complete -c schemalex -n '%s' -x -a '%s'\n", cond, strings.Join(cmd.args, " "))
+		} else {
+			fmt.Fprintf(&buf, "complete -c schemalex -n '%s' -a '%s'\n", cond, strings.Join(sourcePrefixes, " "))
+		}
+	}
+
+	_, err := io.WriteString(dst, buf.String())
+	return err
+}
+
+func flagNames(flags []completionFlag) []string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = "-" + f.name
+	}
+	return names
+}
+
+func contains(l []string, s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
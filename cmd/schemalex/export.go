@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/atlas"
+	"github.com/schemalex/schemalex/avro"
+	"github.com/schemalex/schemalex/codegen"
+	"github.com/schemalex/schemalex/dbml"
+	"github.com/schemalex/schemalex/docs"
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/graph"
+	"github.com/schemalex/schemalex/graphql"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/jsonschema"
+	"github.com/schemalex/schemalex/postgres"
+	"github.com/schemalex/schemalex/protobuf"
+	"github.com/schemalex/schemalex/sqlite"
+)
+
+// exportMain implements `schemalex export`, which writes a schema in
+// the format of another database or tool
+func exportMain(args []string) error {
+	var to string
+	var schema string
+	var identity bool
+	var title string
+	var pkg string
+	var pointers bool
+	var namespace string
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf(`schemalex export -format format [options...] source
+
+-format format, -to format
+              Write the schema as "atlas" HCL, as "postgres" or
+              "sqlite" DDL, as a "markdown" or "json" data dictionary
+              or a flat "csv" or "tsv" one, as a "jsonschema" document
+              describing the rows of each table, as "openapi" component
+              schemas, as "avro" records, as "proto" messages, as "go"
+              (or "go-structs") structs, as "graphql" types, as "dbml"
+              for dbdiagram.io, or as a "dot", "mermaid" or "plantuml"
+              ER diagram, as drawn by "schemalex graph"
+-schema name  Name of the schema that the tables belong to, for atlas
+              (default: main)
+-identity     Use identity columns instead of SERIAL for AUTO_INCREMENT
+              columns, for postgres
+-title text   Heading of the data dictionary, for markdown, or title
+              of the API, for openapi (default: Schema)
+-package name Package of the messages, for proto, or of the structs,
+              for go (default: models)
+-pointers     Use pointers for nullable columns instead of the types
+              of database/sql, for go
+-namespace ns Namespace of the records, for avro
+
+The format may also be any dialect registered with the format package,
+such as "mysql".
+
+Constructs that PostgreSQL cannot express are reported on stderr.
+
+"source" may be a file path, or a URI, as for comparing schemas.
+`)
+	}
+	fs.StringVar(&to, "format", "", "")
+	fs.StringVar(&to, "to", "", "")
+	fs.StringVar(&schema, "schema", "", "")
+	fs.BoolVar(&identity, "identity", false, "")
+	fs.StringVar(&title, "title", "Schema", "")
+	fs.StringVar(&pkg, "package", "", "")
+	fs.BoolVar(&pointers, "pointers", false, "")
+	fs.StringVar(&namespace, "namespace", "", "")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("wrong number of arguments")
+	}
+
+	src, err := schemalex.NewSchemaSource(fs.Arg(0))
+	if err != nil {
+		return errors.Wrap(err, `failed to create schema source`)
+	}
+
+	switch to {
+	case "atlas":
+		return atlas.Source(os.Stdout, src, atlas.WithSchema(schema))
+	case "postgres":
+		issues, err := postgres.Source(os.Stdout, src, postgres.WithIdentity(identity))
+		if err != nil {
+			return err
+		}
+		for _, issue := range issues {
+			fmt.Fprintln(os.Stderr, issue)
+		}
+		return nil
+	case "sqlite":
+		return sqlite.Source(os.Stdout, src)
+	case "markdown":
+		return docs.Source(os.Stdout, src, docs.WithTitle(title))
+	case "json":
+		return docs.JSONSource(os.Stdout, src)
+	case "csv":
+		return docs.CSVSource(os.Stdout, src)
+	case "tsv":
+		return docs.CSVSource(os.Stdout, src, docs.WithComma('\t'))
+	case "dbml":
+		return dbml.Source(os.Stdout, src)
+	case "jsonschema":
+		return jsonschema.Source(os.Stdout, src)
+	case "openapi":
+		return jsonschema.OpenAPISource(os.Stdout, src, jsonschema.WithTitle(title))
+	case "graphql":
+		return graphql.Source(os.Stdout, src)
+	case "avro":
+		return avro.Source(os.Stdout, src, avro.WithNamespace(namespace))
+	case "proto":
+		return protobuf.Source(os.Stdout, src, protobuf.WithPackage(pkg))
+	case "go", "go-structs":
+		options := []codegen.Option{codegen.WithPointers(pointers)}
+		if pkg != "" {
+			options = append(options, codegen.WithPackage(pkg))
+		}
+		return codegen.Source(os.Stdout, src, options...)
+	}
+
+	// diagrams and other registered dialects of SQL
+	_, isDialect := format.Lookup(to)
+	switch to {
+	case "dot", "mermaid", "plantuml":
+	default:
+		if !isDialect {
+			fs.Usage()
+			return errors.Errorf(`invalid format %q`, to)
+		}
+	}
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+	switch to {
+	case "dot":
+		return graph.DOT(os.Stdout, stmts)
+	case "mermaid":
+		return graph.Mermaid(os.Stdout, stmts)
+	case "plantuml":
+		return graph.PlantUML(os.Stdout, stmts)
+	}
+	return format.SQL(os.Stdout, stmts, format.WithDialect(to))
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/lint"
+)
+
+// fmtMain implements `schemalex fmt`, which rewrites schema files in
+// canonical form, in the manner of gofmt
+func fmtMain(args []string) error {
+	var write bool
+	var list bool
+	var indentNum int
+	var quoting string
+	var noIntWidth bool
+	var align bool
+	var canonical bool
+	var sortColumns bool
+
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf(`schemalex fmt [options...] [file...]
+
+-w            Write the result to the files instead of stdout
+-l            List the files whose formatting differs, and exit with
+              a non-zero status if there are any
+-i number     Number of spaces to insert as indent (default: 2)
+-quote policy Quote identifiers "always" (default), only when "needed",
+              or "never"
+-no-int-width Leave out display widths of integer types, as MySQL 8.0.19
+              and later do
+-align        Line up the names, types and attributes of columns
+-canonical    Write indexes, constraints and table options in a fixed
+              order, whatever order they are declared in
+-sort-columns Write columns sorted by name
+
+Statements are rewritten in canonical form: keywords are upper case,
+implicit defaults are spelled out, and tables are sorted by name,
+followed by views and triggers. Comments before tables and columns,
+and after columns on the same line, are kept. Other comments are not.
+If no file is given, the schema is read from stdin.
+`)
+	}
+	fs.BoolVar(&write, "w", false, "")
+	fs.BoolVar(&list, "l", false, "")
+	fs.IntVar(&indentNum, "i", 2, "")
+	fs.StringVar(&quoting, "quote", "always", "")
+	fs.BoolVar(&noIntWidth, "no-int-width", false, "")
+	fs.BoolVar(&align, "align", false, "")
+	fs.BoolVar(&canonical, "canonical", false, "")
+	fs.BoolVar(&sortColumns, "sort-columns", false, "")
+	fs.Parse(args)
+
+	options := []lint.Option{lint.WithIndent(" ", indentNum), lint.WithSort(true), lint.WithIntDisplayWidth(!noIntWidth), lint.WithComments(true), lint.WithAlign(align), lint.WithCanonical(canonical)}
+	if sortColumns {
+		options = append(options, lint.WithColumnOrder(format.ColumnOrderAlphabetical))
+	}
+	switch quoting {
+	case "always":
+	case "needed":
+		options = append(options, lint.WithQuoting(format.QuoteWhenNeeded))
+	case "never":
+		options = append(options, lint.WithQuoting(format.QuoteNever))
+	default:
+		return errors.Errorf(`invalid quoting policy %s`, quoting)
+	}
+
+	if fs.NArg() == 0 {
+		if write || list {
+			return errors.New(`-w and -l require files`)
+		}
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return errors.Wrap(err, `failed to read from stdin`)
+		}
+		out, err := formatSchema(src, options)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+
+	var unformatted int
+	for _, file := range fs.Args() {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.Wrapf(err, `failed to read file %s`, file)
+		}
+		out, err := formatSchema(src, options)
+		if err != nil {
+			return errors.Wrapf(err, `failed to format file %s`, file)
+		}
+
+		if bytes.Equal(src, out) {
+			continue
+		}
+		if list {
+			fmt.Println(file)
+			unformatted++
+		}
+		if write {
+			fi, err := os.Stat(file)
+			if err != nil {
+				return errors.Wrapf(err, `failed to stat file %s`, file)
+			}
+			if err := ioutil.WriteFile(file, out, fi.Mode().Perm()); err != nil {
+				return errors.Wrapf(err, `failed to write file %s`, file)
+			}
+		}
+		if !list && !write {
+			if _, err := os.Stdout.Write(out); err != nil {
+				return err
+			}
+		}
+	}
+	if list && unformatted > 0 && !write {
+		return errors.Errorf(`%d file(s) not formatted`, unformatted)
+	}
+	return nil
+}
+
+func formatSchema(src []byte, options []lint.Option) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := lint.New().Run(context.Background(), schemalex.NewReaderSource(bytes.NewReader(src)), &buf, options...); err != nil {
+		return nil, err
+	}
+	if buf.Len() > 0 {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
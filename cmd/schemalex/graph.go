@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/graph"
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// graphMain implements `schemalex graph`, which draws a schema as an
+// ER diagram
+func graphMain(args []string) error {
+	var format string
+
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf(`schemalex graph [options...] source
+
+-format name  Write the diagram as a Graphviz "dot" graph (default), as
+              a "mermaid" erDiagram, or as a "plantuml" entity diagram
+
+Tables are drawn with their columns, and foreign keys as relationships
+between them. For example, to draw an SVG image:
+
+  schemalex graph schema.sql | dot -Tsvg > schema.svg
+
+"source" may be a file path, or a URI, as for comparing schemas.
+`)
+	}
+	fs.StringVar(&format, "format", "dot", "")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("wrong number of arguments")
+	}
+
+	src, err := schemalex.NewSchemaSource(fs.Arg(0))
+	if err != nil {
+		return errors.Wrap(err, `failed to create schema source`)
+	}
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+
+	switch format {
+	case "dot":
+		return graph.DOT(os.Stdout, stmts)
+	case "mermaid":
+		return graph.Mermaid(os.Stdout, stmts)
+	case "plantuml":
+		return graph.PlantUML(os.Stdout, stmts)
+	default:
+		fs.Usage()
+		return errors.Errorf(`invalid format %q`, format)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/lint"
+)
+
+// lintMain implements `schemalex lint`, which checks a schema against
+// a set of rules
+func lintMain(args []string) error {
+	var rules string
+	var list bool
+
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf(`schemalex lint [options...] source
+
+-rules name=severity,...
+              Comma separated list of rules to configure, with the
+              severity of their findings: "error", "warning" or "off"
+              to disable the rule (default: none)
+-list         List the rules and their default severity, and exit
+
+Findings are written as "source:line: severity: message (rule)". Fails
+if any of them is an error.
+
+"source" may be a file path, or a URI, as for comparing schemas.
+`)
+	}
+	fs.StringVar(&rules, "rules", "", "")
+	fs.BoolVar(&list, "list", false, "")
+	fs.Parse(args)
+
+	if list {
+		for _, rule := range lint.Rules() {
+			fmt.Printf("%-20s %-8s %s\n", rule.Name, rule.Severity, rule.Description)
+		}
+		return nil
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("wrong number of arguments")
+	}
+
+	var options []lint.Option
+	if len(rules) > 0 {
+		for _, rule := range strings.Split(rules, ",") {
+			i := strings.IndexByte(rule, '=')
+			if i < 0 {
+				return errors.Errorf(`invalid rule configuration %s`, rule)
+			}
+			severity, err := lint.ParseSeverity(rule[i+1:])
+			if err != nil {
+				return err
+			}
+			options = append(options, lint.WithSeverity(rule[:i], severity))
+		}
+	}
+
+	src, err := schemalex.NewSchemaSource(fs.Arg(0))
+	if err != nil {
+		return errors.Wrap(err, `failed to create schema source`)
+	}
+	findings, err := lint.CheckSource(src, options...)
+	if err != nil {
+		return err
+	}
+
+	var errs int
+	for _, f := range findings {
+		fmt.Fprintf(os.Stdout, "%s:%s\n", fs.Arg(0), f)
+		if f.Severity == lint.SeverityError {
+			errs++
+		}
+	}
+	if errs > 0 {
+		return errors.Errorf(`%d errors found`, errs)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/diff"
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// normalizeMain implements `schemalex normalize`, which writes a schema
+// the way it is compared
+func normalizeMain(args []string) error {
+	var strictness string
+	var ignoreAutoIncrement bool
+	var ignoreComments bool
+	var ignoreTableOptions string
+	var include string
+	var exclude string
+
+	fs := flag.NewFlagSet("normalize", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf(`schemalex normalize [options...] source
+
+-strictness level
+              Which differences count, as for comparing schemas:
+              "default", "strict" or "lenient" (default: default)
+-ignore-auto-increment
+              Leave out AUTO_INCREMENT table options (default: false)
+-ignore-comments
+              Leave out table and column comments (default: false)
+-ignore-table-options keys
+              Comma separated list of table options to leave out, such
+              as ROW_FORMAT, which may contain glob patterns
+-include patterns
+              Comma separated list of tables to keep, which may contain
+              glob patterns such as 'app_*' (default: all)
+-exclude patterns
+              Comma separated list of tables to leave out
+
+Writes the schema as it is compared: implicit defaults are spelled
+out, and default values, and integer display widths and character set
+aliases where they do not count, are written in a single way. Tables
+are sorted by name, followed by views and triggers. Two schemas only
+differ if their normalized forms do, renames aside, when compared with
+the same options.
+
+"source" may be a file path, or a URI, as for comparing schemas.
+`)
+	}
+	fs.StringVar(&strictness, "strictness", "default", "")
+	fs.BoolVar(&ignoreAutoIncrement, "ignore-auto-increment", false, "")
+	fs.BoolVar(&ignoreComments, "ignore-comments", false, "")
+	fs.StringVar(&ignoreTableOptions, "ignore-table-options", "", "")
+	fs.StringVar(&include, "include", "", "")
+	fs.StringVar(&exclude, "exclude", "", "")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("wrong number of arguments")
+	}
+
+	options := []diff.Option{
+		diff.WithIgnoreAutoIncrement(ignoreAutoIncrement),
+		diff.WithIgnoreComments(ignoreComments),
+	}
+	switch strictness {
+	case "default":
+	case "strict":
+		options = append(options, diff.WithStrictness(diff.StrictnessStrict))
+	case "lenient":
+		options = append(options, diff.WithStrictness(diff.StrictnessLenient))
+	default:
+		return errors.Errorf(`invalid strictness %s`, strictness)
+	}
+	if len(ignoreTableOptions) > 0 {
+		options = append(options, diff.WithIgnoreTableOptions(strings.Split(ignoreTableOptions, ",")...))
+	}
+	if len(include) > 0 {
+		options = append(options, diff.WithIncludeTables(strings.Split(include, ",")...))
+	}
+	if len(exclude) > 0 {
+		options = append(options, diff.WithExcludeTables(strings.Split(exclude, ",")...))
+	}
+
+	src, err := schemalex.NewSchemaSource(fs.Arg(0))
+	if err != nil {
+		return errors.Wrap(err, `failed to create schema source`)
+	}
+	return diff.NormalizeSource(os.Stdout, src, options...)
+}
@@ -7,9 +7,13 @@ import (
 	"log"
 	"os"
 	"runtime"
+	"sort"
+	"time"
 
 	"github.com/schemalex/schemalex"
 	"github.com/schemalex/schemalex/diff"
+	"github.com/schemalex/schemalex/fingerprint"
+	"github.com/schemalex/schemalex/internal/atomicfile"
 	"github.com/schemalex/schemalex/internal/errors"
 )
 
@@ -19,24 +23,103 @@ func main() {
 	}
 }
 
-func _main() error {
+func _main() (err error) {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			return fmtMain(os.Args[2:])
+		case "export":
+			return exportMain(os.Args[2:])
+		case "graph":
+			return graphMain(os.Args[2:])
+		case "lint":
+			return lintMain(os.Args[2:])
+		case "apply":
+			return applyMain(os.Args[2:])
+		case "normalize":
+			return normalizeMain(os.Args[2:])
+		case "validate":
+			return validateMain(os.Args[2:])
+		case "completion":
+			return completionMain(os.Args[2:])
+		}
+	}
+
 	var txn bool
 	var version bool
 	var outfile string
+	var gzipOutput bool
+	var fingerprintOnly bool
+	var mysqlTLS string
+	var mysqlTimeout time.Duration
+	var mysqlTables string
 
 	flag.Usage = func() {
 		fmt.Printf(`schemalex version %s
 
 schemalex -version
 schemalex [options...] before after
+schemalex -fingerprint source
+schemalex fmt [options...] [file...]
+schemalex export -format format [options...] source
+schemalex graph [options...] source
+schemalex lint [options...] source
+schemalex apply [options...] source dsn
+schemalex normalize [options...] source
+schemalex validate source...
+schemalex completion bash|zsh|fish
 
 -v            Print out the version and exit
--o file	      Output the result to the specified file (default: stdout)
+-o file, -output file
+              Output the result to the specified file, which is only
+              replaced once the whole result is written (default: stdout)
+-gzip         Compress the file given by -o with gzip (default: false)
 -t[=true]     Enable/Disable transaction in the output (default: true)
-
-"before" and "after" may be a file path, or a URI.
-Special URI schemes "mysql" and "local-git" are supported on top of
-"file". If the special path "-" is used, it is treated as stdin
+-fingerprint  Print the hash of the schema and of each of its tables,
+              instead of comparing two schemas
+-mysql-tls mode
+              TLS mode of "mysql" sources, such as "true" or
+              "skip-verify" (default: false)
+-mysql-timeout duration
+              Timeout for connecting to and reading from "mysql"
+              sources, such as 10s (default: none)
+-mysql-tables patterns
+              Comma separated list of tables to read from "mysql"
+              sources, which may contain glob patterns such as 'app_*'
+              (default: all)
+
+"schemalex fmt" rewrites schema files in canonical form. Run
+"schemalex fmt -h" for its options.
+
+"schemalex export" writes a schema as Atlas HCL, as PostgreSQL or
+SQLite DDL, as a Markdown or JSON data dictionary, as an ER diagram,
+or as code such as Go structs or protobuf messages. Run
+"schemalex export -h" for its options.
+
+"schemalex graph" draws a schema as an ER diagram. Run
+"schemalex graph -h" for its options.
+
+"schemalex lint" checks a schema against rules such as "every table
+needs a primary key". Run "schemalex lint -h" for its options.
+
+"schemalex apply" executes the statements that bring a live database
+in line with a schema. Run "schemalex apply -h" for its options.
+
+"schemalex normalize" writes a schema the way it is compared, to see
+why schemas differ or not. Run "schemalex normalize -h" for its
+options.
+
+"schemalex validate" checks that schemas parse, and problems such as
+tables without primary keys or foreign keys to missing tables. Run
+"schemalex validate -h" for details.
+
+"schemalex completion" writes the script completing schemalex for
+bash, zsh or fish. Run "schemalex completion -h" for how to load it.
+
+"before" and "after" may be a file path, a directory of *.sql files,
+or a URI. Special URI schemes "mysql", "git", "local-git",
+"manifest", "http", "https" and "s3" are supported on top of "file".
+If the special path "-" is used, it is treated as stdin
 
 Examples:
 
@@ -58,6 +141,12 @@ Examples:
 	flag.BoolVar(&version, "v", false, "")
 	flag.BoolVar(&txn, "t", true, "")
 	flag.StringVar(&outfile, "o", "", "")
+	flag.StringVar(&outfile, "output", "", "")
+	flag.BoolVar(&gzipOutput, "gzip", false, "")
+	flag.BoolVar(&fingerprintOnly, "fingerprint", false, "")
+	flag.StringVar(&mysqlTLS, "mysql-tls", "", "")
+	flag.DurationVar(&mysqlTimeout, "mysql-timeout", 0, "")
+	flag.StringVar(&mysqlTables, "mysql-tables", "", "")
 	flag.Parse()
 
 	if version {
@@ -71,27 +160,59 @@ Examples:
 		return nil
 	}
 
-	if flag.NArg() != 2 {
+	if fingerprintOnly && flag.NArg() != 1 || !fingerprintOnly && flag.NArg() != 2 {
 		flag.Usage()
 		return errors.New("wrong number of arguments")
 	}
+	if err := schemalex.CheckStdin(flag.Args()...); err != nil {
+		return err
+	}
 
+	if gzipOutput && len(outfile) == 0 {
+		return errors.New(`-gzip requires -o`)
+	}
 	var dst io.Writer = os.Stdout
 	if len(outfile) > 0 {
-		f, err := os.OpenFile(outfile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-		if err != nil {
-			return errors.Wrapf(err, `failed to open file %s for writing`, outfile)
+		f, ferr := atomicfile.Create(outfile, 0644, gzipOutput)
+		if ferr != nil {
+			return ferr
 		}
-		dst = f
 		defer f.Close()
+		dst = f
+		// the file is only replaced once the whole result is written
+		defer func() {
+			if err == nil {
+				if cerr := f.Commit(); cerr != nil {
+					err = cerr
+				}
+			}
+		}()
+	}
+
+	var sourceOptions []schemalex.Option
+	if len(mysqlTLS) > 0 {
+		sourceOptions = append(sourceOptions, schemalex.WithMySQLParam("tls", mysqlTLS))
+	}
+	if mysqlTimeout > 0 {
+		sourceOptions = append(sourceOptions,
+			schemalex.WithMySQLParam("timeout", mysqlTimeout.String()),
+			schemalex.WithMySQLParam("readTimeout", mysqlTimeout.String()),
+		)
+	}
+	if len(mysqlTables) > 0 {
+		sourceOptions = append(sourceOptions, schemalex.WithMySQLParam("tables", mysqlTables))
 	}
 
-	fromSource, err := schemalex.NewSchemaSource(flag.Arg(0))
+	if fingerprintOnly {
+		return writeFingerprint(dst, flag.Arg(0), sourceOptions)
+	}
+
+	fromSource, err := schemalex.NewSchemaSource(flag.Arg(0), sourceOptions...)
 	if err != nil {
 		return errors.Wrap(err, `failed to create schema source for "from"`)
 	}
 
-	toSource, err := schemalex.NewSchemaSource(flag.Arg(1))
+	toSource, err := schemalex.NewSchemaSource(flag.Arg(1), sourceOptions...)
 	if err != nil {
 		return errors.Wrap(err, `failed to create schema source for "to"`)
 	}
@@ -104,3 +225,28 @@ Examples:
 		diff.WithTransaction(txn), diff.WithParser(p),
 	)
 }
+
+// writeFingerprint writes the hash of the schema, followed by the hash
+// of each table, in the format of sha256sum
+func writeFingerprint(dst io.Writer, uri string, sourceOptions []schemalex.Option) error {
+	src, err := schemalex.NewSchemaSource(uri, sourceOptions...)
+	if err != nil {
+		return errors.Wrap(err, `failed to create schema source`)
+	}
+	f, err := fingerprint.Source(src)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(f.Tables))
+	for name := range f.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(dst, "%s  %s\n", f.Sum, uri)
+	for _, name := range names {
+		fmt.Fprintf(dst, "%s  %s\n", f.Tables[name], name)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/lint"
+)
+
+// validateMain implements `schemalex validate`, which checks that
+// schemas parse and make sense, such as for a pre-commit hook
+func validateMain(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf(`schemalex validate source...
+
+Checks that each schema parses, that every table has a primary key,
+that foreign keys refer to tables and columns that exist, and that no
+table has two indexes on the same columns or by the same name.
+
+Problems are written as "source:line: error: message (check)", or as
+"source:line:column: error: message (syntax)" for syntax errors. Fails
+if there are any.
+
+"source" may be a file path, or a URI, as for comparing schemas.
+`)
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("wrong number of arguments")
+	}
+	if err := schemalex.CheckStdin(fs.Args()...); err != nil {
+		return err
+	}
+
+	var problems int
+	for _, uri := range fs.Args() {
+		src, err := schemalex.NewSchemaSource(uri)
+		if err != nil {
+			return errors.Wrapf(err, `failed to create schema source for %s`, uri)
+		}
+		findings, err := lint.ValidateSource(src)
+		if err != nil {
+			pe, ok := err.(schemalex.ParseError)
+			if !ok {
+				return errors.Wrapf(err, `failed to validate %s`, uri)
+			}
+			problems++
+			fmt.Fprintf(os.Stdout, "%s:%d:%d: error: %s (syntax)\n", uri, pe.Line(), pe.Col(), pe.Message())
+			continue
+		}
+		for _, f := range findings {
+			problems++
+			fmt.Fprintf(os.Stdout, "%s:%s\n", uri, f)
+		}
+	}
+	if problems > 0 {
+		return errors.Errorf(`%d problems found`, problems)
+	}
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/format"
 	"github.com/schemalex/schemalex/lint"
 )
 
@@ -27,6 +28,15 @@ func _main() error {
 	var showVersion bool
 	var outfile string
 	var indentNum int
+	var lowercase bool
+	var leadingCommas bool
+	var compact bool
+	var align bool
+	var canonical bool
+	var sortColumns bool
+	var quoting string
+	var noIntWidth bool
+	var target string
 
 	flag.Usage = func() {
 		fmt.Printf(`schemalint version %s
@@ -37,10 +47,26 @@ schemalint [options...] source
 -v            Print out the version and exit
 -o file	      Output the result to the specified file (default: stdout)
 -i number     Number of spaces to insert as indent (default: 2)
-
-"source" may be a file path, or a URI.
-Special URI schemes "mysql" and "local-git" are supported on top of
-"file". If the special path "-" is used, it is treated as stdin.
+-lowercase    Write keywords and data types in lower case
+-leading-commas
+              Put commas at the beginning of lines instead of the end
+-compact      Write the fields of each table on a single line
+-align        Line up the names, types and attributes of columns
+-canonical    Write indexes, constraints and table options in a fixed
+              order, whatever order they are declared in
+-sort-columns Write columns sorted by name
+-quote policy Quote identifiers "always" (default), only when "needed",
+              or "never"
+-no-int-width Leave out display widths of integer types, as MySQL 8.0.19
+              and later do
+-target version
+              Write statements for the given version of MySQL, such
+              as 5.7 or 8.0.21
+
+"source" may be a file path, a directory of *.sql files,
+or a URI. Special URI schemes "mysql", "git", "local-git",
+"manifest", "http", "https" and "s3" are supported on top of "file".
+If the special path "-" is used, it is treated as stdin.
 
 Examples:
 
@@ -62,6 +88,15 @@ Examples:
 	flag.BoolVar(&showVersion, "v", false, "")
 	flag.StringVar(&outfile, "o", "", "")
 	flag.IntVar(&indentNum, "i", 2, "")
+	flag.BoolVar(&lowercase, "lowercase", false, "")
+	flag.BoolVar(&leadingCommas, "leading-commas", false, "")
+	flag.BoolVar(&compact, "compact", false, "")
+	flag.BoolVar(&align, "align", false, "")
+	flag.BoolVar(&canonical, "canonical", false, "")
+	flag.BoolVar(&sortColumns, "sort-columns", false, "")
+	flag.StringVar(&quoting, "quote", "always", "")
+	flag.BoolVar(&noIntWidth, "no-int-width", false, "")
+	flag.StringVar(&target, "target", "", "")
 	flag.Parse()
 
 	if showVersion {
@@ -84,6 +119,29 @@ Examples:
 		return errors.New("wrong number of arguments")
 	}
 
+	options := []lint.Option{lint.WithIndent(" ", indentNum), lint.WithCompact(compact), lint.WithAlign(align), lint.WithCanonical(canonical), lint.WithIntDisplayWidth(!noIntWidth)}
+	if lowercase {
+		options = append(options, lint.WithKeywordCase(format.KeywordCaseLower))
+	}
+	if sortColumns {
+		options = append(options, lint.WithColumnOrder(format.ColumnOrderAlphabetical))
+	}
+	if leadingCommas {
+		options = append(options, lint.WithCommaStyle(format.CommaLeading))
+	}
+	if target != "" {
+		options = append(options, lint.WithTargetVersion(target))
+	}
+	switch quoting {
+	case "always":
+	case "needed":
+		options = append(options, lint.WithQuoting(format.QuoteWhenNeeded))
+	case "never":
+		options = append(options, lint.WithQuoting(format.QuoteNever))
+	default:
+		return errors.Errorf(`invalid quoting policy %s`, quoting)
+	}
+
 	var dst io.Writer = os.Stdout
 	if len(outfile) > 0 {
 		f, err := os.OpenFile(outfile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
@@ -104,7 +162,7 @@ Examples:
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	if err := linter.Run(ctx, src, dst, lint.WithIndent(" ", indentNum)); err != nil {
+	if err := linter.Run(ctx, src, dst, options...); err != nil {
 		return errors.Wrap(err, `failed to lint source`)
 	}
 
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+
+	"github.com/pkg/errors"
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/merge"
+	"github.com/schemalex/schemalex/model"
+)
+
+func main() {
+	if err := _main(); err != nil {
+		log.Printf("%s", err)
+		os.Exit(1)
+	}
+}
+
+func _main() error {
+	var version bool
+	var outfile string
+
+	flag.Usage = func() {
+		fmt.Printf(`schemamerge version %s
+
+schemamerge -version
+schemamerge [options...] base ours theirs
+
+-v            Print out the version and exit
+-o file	      Output the result to the specified file (default: stdout)
+
+Merges the changes made from "base" to "ours" and from "base" to
+"theirs", and outputs the merged schema. Objects changed differently
+on both sides are reported as conflicts, and the version in "ours"
+is used for them.
+
+"base", "ours" and "theirs" may be a file path, a directory of *.sql files,
+or a URI. Special URI schemes "mysql", "git", "local-git",
+"manifest", "http", "https" and "s3" are supported on top of "file".
+If the special path "-" is used, it is treated as stdin
+
+Examples:
+
+* Merge two branches in a local git repository
+  schemamerge "local-git:///path/to/repo?file=foo.sql&commitish=master" \
+    "local-git:///path/to/repo?file=foo.sql&commitish=feature-a" \
+    "local-git:///path/to/repo?file=foo.sql&commitish=feature-b"
+
+`, schemalex.Version)
+	}
+	flag.BoolVar(&version, "v", false, "")
+	flag.StringVar(&outfile, "o", "", "")
+	flag.Parse()
+
+	if version {
+		fmt.Printf(
+			"schemamerge version %s, built with go %s for %s/%s\n",
+			schemalex.Version,
+			runtime.Version(),
+			runtime.GOOS,
+			runtime.GOARCH,
+		)
+		return nil
+	}
+
+	if flag.NArg() != 3 {
+		flag.Usage()
+		return errors.New("wrong number of arguments")
+	}
+	if err := schemalex.CheckStdin(flag.Args()...); err != nil {
+		return err
+	}
+
+	p := schemalex.New()
+	var schemas []model.Stmts
+	for _, name := range []string{"base", "ours", "theirs"} {
+		src, err := schemalex.NewSchemaSource(flag.Arg(len(schemas)))
+		if err != nil {
+			return errors.Wrapf(err, `failed to create schema source for "%s"`, name)
+		}
+		var buf bytes.Buffer
+		if err := src.WriteSchema(&buf); err != nil {
+			return errors.Wrapf(err, `failed to retrieve schema from "%s"`, name)
+		}
+		stmts, err := p.Parse(buf.Bytes())
+		if err != nil {
+			return errors.Wrapf(err, `failed to parse "%s"`, name)
+		}
+		schemas = append(schemas, stmts)
+	}
+
+	merged, conflicts, err := merge.Stmts(schemas[0], schemas[1], schemas[2])
+	if err != nil {
+		return errors.Wrap(err, `failed to merge schemas`)
+	}
+
+	var dst io.Writer = os.Stdout
+	if len(outfile) > 0 {
+		f, err := os.OpenFile(outfile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return errors.Wrapf(err, `failed to open file %s for writing`, outfile)
+		}
+		dst = f
+		defer f.Close()
+	}
+
+	for i, stmt := range merged {
+		if i != 0 {
+			dst.Write([]byte{'\n', '\n'})
+		}
+		if err := format.SQL(dst, stmt); err != nil {
+			return errors.Wrap(err, `failed to format merged schema`)
+		}
+		dst.Write([]byte{';'})
+	}
+	dst.Write([]byte{'\n'})
+
+	for _, c := range conflicts {
+		fmt.Fprintf(os.Stderr, "CONFLICT: %s\n", c)
+	}
+	if len(conflicts) > 0 {
+		return errors.Errorf(`%d conflict(s) found`, len(conflicts))
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+// Package codegen generates Go code from schemas: a struct for each
+// table, with a field for each column, tagged with the name of the
+// column for database/sql mappers such as sqlx and for encoding/json
+package codegen
+
+import (
+	"bytes"
+	"go/format"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/internal/option"
+	"github.com/schemalex/schemalex/model"
+)
+
+type Option = schemalex.Option
+
+const (
+	optkeyPackage  = "package"
+	optkeyPointers = "pointers"
+	optkeyNaming   = "naming"
+)
+
+// WithPackage specifies the package of the generated code, which is
+// "models" by default
+func WithPackage(name string) Option {
+	return option.New(optkeyPackage, name)
+}
+
+// WithPointers specifies whether nullable columns are pointers, such as
+// *string, instead of the types of database/sql, such as sql.NullString
+func WithPointers(b bool) Option {
+	return option.New(optkeyPointers, b)
+}
+
+// WithNaming specifies the function that turns the names of tables and
+// columns into the names of structs and fields, which is CamelCase by
+// default
+func WithNaming(f func(string) string) Option {
+	return option.New(optkeyNaming, f)
+}
+
+type genCtx struct {
+	pointers bool
+	naming   func(string) string
+	imports  map[string]struct{}
+}
+
+// Stmts writes a Go file to dst with a struct for each of the tables
+// among the statements. Comments on tables and columns are carried over
+// to the structs and fields.
+func Stmts(dst io.Writer, stmts model.Stmts, options ...Option) error {
+	pkg := "models"
+	ctx := genCtx{
+		naming:  CamelCase,
+		imports: make(map[string]struct{}),
+	}
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyPackage:
+			pkg = o.Value().(string)
+		case optkeyPointers:
+			ctx.pointers = o.Value().(bool)
+		case optkeyNaming:
+			ctx.naming = o.Value().(func(string) string)
+		}
+	}
+
+	var body bytes.Buffer
+	for _, stmt := range stmts {
+		if table, ok := stmt.(model.Table); ok {
+			table, _ = table.Normalize()
+			writeStruct(&ctx, &body, table)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by schemalex. DO NOT EDIT.\n\npackage ")
+	buf.WriteString(pkg)
+	buf.WriteString("\n")
+	if len(ctx.imports) > 0 {
+		var imports []string
+		for path := range ctx.imports {
+			imports = append(imports, `"`+path+`"`)
+		}
+		sort.Strings(imports)
+		buf.WriteString("\nimport (\n")
+		buf.WriteString(strings.Join(imports, "\n"))
+		buf.WriteString("\n)\n")
+	}
+	body.WriteTo(&buf)
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return errors.Wrap(err, `failed to format generated code`)
+	}
+	if _, err := dst.Write(src); err != nil {
+		return errors.Wrap(err, `failed to write generated code`)
+	}
+	return nil
+}
+
+// Source writes Go structs for the schema read from src (see Stmts)
+func Source(dst io.Writer, src schemalex.SchemaSource, options ...Option) error {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+	return Stmts(dst, stmts, options...)
+}
+
+var wordRx = regexp.MustCompile(`[A-Za-z0-9]+`)
+
+// initialisms are the words that are written in upper case in Go names
+var initialisms = map[string]bool{
+	"API": true, "CSS": true, "DNS": true, "HTML": true, "HTTP": true,
+	"ID": true, "IP": true, "JSON": true, "SQL": true, "SSH": true,
+	"UID": true, "URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// CamelCase returns the name in camel case, such as UserID for user_id,
+// as Go names are written
+func CamelCase(name string) string {
+	var buf bytes.Buffer
+	for _, word := range wordRx.FindAllString(name, -1) {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			buf.WriteString(upper)
+			continue
+		}
+		buf.WriteString(strings.ToUpper(word[:1]))
+		buf.WriteString(word[1:])
+	}
+	s := buf.String()
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		s = "X" + s
+	}
+	return s
+}
+
+func writeStruct(ctx *genCtx, buf *bytes.Buffer, table model.Table) {
+	name := ctx.naming(table.Name())
+	buf.WriteString("\n// ")
+	buf.WriteString(name)
+	buf.WriteString(" is a row of the ")
+	buf.WriteString(table.Name())
+	buf.WriteString(" table")
+	for opt := range table.Options() {
+		if strings.EqualFold(opt.Key(), "COMMENT") {
+			buf.WriteString("\n//\n")
+			writeComment(buf, opt.Value())
+		}
+	}
+	buf.WriteString("\ntype ")
+	buf.WriteString(name)
+	buf.WriteString(" struct {\n")
+	for col := range table.Columns() {
+		buf.WriteString(ctx.naming(col.Name()))
+		buf.WriteByte(' ')
+		buf.WriteString(ctx.fieldType(col))
+		buf.WriteString(" `db:\"")
+		buf.WriteString(col.Name())
+		buf.WriteString("\" json:\"")
+		buf.WriteString(col.Name())
+		buf.WriteString("\"`")
+		if col.HasComment() {
+			buf.WriteString(" // ")
+			buf.WriteString(strings.Replace(col.Comment(), "\n", " ", -1))
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("}\n")
+}
+
+func writeComment(buf *bytes.Buffer, comment string) {
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		buf.WriteString("// ")
+		buf.WriteString(strings.TrimRight(line, "\r"))
+		if i < len(lines)-1 {
+			buf.WriteByte('\n')
+		}
+	}
+}
+
+// goType is how a column is represented in Go: its type, and the type
+// from database/sql that is used instead if it is nullable
+type goType struct {
+	name     string
+	nullable string
+	pkg      string
+}
+
+func (ctx *genCtx) fieldType(col model.TableColumn) string {
+	typ := columnType(col)
+	switch {
+	case col.NullState() == model.NullStateNotNull,
+		typ.name == "[]byte", typ.name == "json.RawMessage":
+		// slices are nil for NULL
+	case ctx.pointers:
+		typ.name = "*" + typ.name
+	default:
+		typ.name = typ.nullable
+		typ.pkg = "database/sql"
+	}
+	if typ.pkg != "" {
+		ctx.imports[typ.pkg] = struct{}{}
+	}
+	return typ.name
+}
+
+func columnType(col model.TableColumn) goType {
+	switch col.Type().SynonymType() {
+	case model.ColumnTypeTinyInt:
+		if col.Type() != model.ColumnTypeTinyInt || col.HasLength() && col.Length().Length() == "1" && !col.IsUnsigned() {
+			return goType{name: "bool", nullable: "sql.NullBool"}
+		}
+		if col.IsUnsigned() {
+			return goType{name: "uint8", nullable: "sql.NullInt32"}
+		}
+		return goType{name: "int8", nullable: "sql.NullInt32"}
+	case model.ColumnTypeSmallInt:
+		if col.IsUnsigned() {
+			return goType{name: "uint16", nullable: "sql.NullInt32"}
+		}
+		return goType{name: "int16", nullable: "sql.NullInt32"}
+	case model.ColumnTypeMediumInt, model.ColumnTypeInt:
+		if col.IsUnsigned() {
+			return goType{name: "uint32", nullable: "sql.NullInt64"}
+		}
+		return goType{name: "int32", nullable: "sql.NullInt32"}
+	case model.ColumnTypeBigInt:
+		if col.IsUnsigned() {
+			return goType{name: "uint64", nullable: "sql.NullInt64"}
+		}
+		return goType{name: "int64", nullable: "sql.NullInt64"}
+	case model.ColumnTypeYear:
+		return goType{name: "int16", nullable: "sql.NullInt32"}
+	case model.ColumnTypeBit:
+		return goType{name: "uint64", nullable: "sql.NullInt64"}
+	case model.ColumnTypeFloat:
+		return goType{name: "float32", nullable: "sql.NullFloat64"}
+	case model.ColumnTypeDouble:
+		return goType{name: "float64", nullable: "sql.NullFloat64"}
+	case model.ColumnTypeDate, model.ColumnTypeDateTime, model.ColumnTypeTimestamp:
+		return goType{name: "time.Time", nullable: "sql.NullTime", pkg: "time"}
+	case model.ColumnTypeBinary, model.ColumnTypeVarBinary,
+		model.ColumnTypeTinyBlob, model.ColumnTypeBlob, model.ColumnTypeMediumBlob, model.ColumnTypeLongBlob:
+		return goType{name: "[]byte"}
+	case model.ColumnTypeJSON:
+		return goType{name: "json.RawMessage", pkg: "encoding/json"}
+	default:
+		// DECIMAL, TIME, texts, ENUM and SET
+		return goType{name: "string", nullable: "sql.NullString"}
+	}
+}
@@ -0,0 +1,98 @@
+package codegen_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/codegen"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmts(t *testing.T) {
+	type Spec struct {
+		Name    string
+		Input   string
+		Options []codegen.Option
+		Expect  string
+	}
+
+	specs := []Spec{
+		{
+			Name:  "types",
+			Input: "CREATE TABLE `user_profiles` ( `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT, `user_id` INTEGER NOT NULL, `active` BOOLEAN NOT NULL, `homepage_url` VARCHAR (255) COMMENT 'shown on the profile', `score` INTEGER, `settings` JSON, `created_at` DATETIME NOT NULL, `deleted_at` DATETIME, PRIMARY KEY (`id`) ) COMMENT 'profiles of users';",
+			Expect: `// Code generated by schemalex. DO NOT EDIT.
+
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// UserProfiles is a row of the user_profiles table
+//
+// profiles of users
+type UserProfiles struct {
+	ID          uint64          ` + "`db:\"id\" json:\"id\"`" + `
+	UserID      int32           ` + "`db:\"user_id\" json:\"user_id\"`" + `
+	Active      bool            ` + "`db:\"active\" json:\"active\"`" + `
+	HomepageURL sql.NullString  ` + "`db:\"homepage_url\" json:\"homepage_url\"`" + ` // shown on the profile
+	Score       sql.NullInt32   ` + "`db:\"score\" json:\"score\"`" + `
+	Settings    json.RawMessage ` + "`db:\"settings\" json:\"settings\"`" + `
+	CreatedAt   time.Time       ` + "`db:\"created_at\" json:\"created_at\"`" + `
+	DeletedAt   sql.NullTime    ` + "`db:\"deleted_at\" json:\"deleted_at\"`" + `
+}
+`,
+		},
+		{
+			Name:  "pointers and naming",
+			Input: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `name` VARCHAR (20), `avatar` BLOB );",
+			Options: []codegen.Option{
+				codegen.WithPackage("db"),
+				codegen.WithPointers(true),
+				codegen.WithNaming(func(s string) string { return strings.ToUpper(s) }),
+			},
+			Expect: `// Code generated by schemalex. DO NOT EDIT.
+
+package db
+
+// HOGE is a row of the hoge table
+type HOGE struct {
+	ID     int32   ` + "`db:\"id\" json:\"id\"`" + `
+	NAME   *string ` + "`db:\"name\" json:\"name\"`" + `
+	AVATAR []byte  ` + "`db:\"avatar\" json:\"avatar\"`" + `
+}
+`,
+		},
+	}
+
+	for _, spec := range specs {
+		t.Run(spec.Name, func(t *testing.T) {
+			stmts, err := schemalex.New().ParseString(spec.Input)
+			if !assert.NoError(t, err, "Parse should succeed") {
+				return
+			}
+			var buf bytes.Buffer
+			if !assert.NoError(t, codegen.Stmts(&buf, stmts, spec.Options...), "codegen.Stmts should succeed") {
+				return
+			}
+			assert.Equal(t, spec.Expect, buf.String(), "code should match")
+		})
+	}
+}
+
+func TestCamelCase(t *testing.T) {
+	for input, expect := range map[string]string{
+		"users":        "Users",
+		"user_id":      "UserID",
+		"api-key":      "APIKey",
+		"2fa_secret":   "X2faSecret",
+		"createdAt":    "CreatedAt",
+		"homepage_url": "HomepageURL",
+	} {
+		assert.Equal(t, expect, codegen.CamelCase(input), "CamelCase(%q)", input)
+	}
+}
@@ -0,0 +1,324 @@
+// Package dbml writes schemas in DBML, the Database Markup Language of
+// dbdiagram.io (https://dbml.dbdiagram.io), so that they can be drawn
+// and shared with the tools of that ecosystem
+package dbml
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// Stmts writes the tables among the statements to dst as DBML. Each
+// table is written with its columns, indexes and comment, enums as
+// Enum definitions of their own, and foreign keys as refs after all of
+// the tables. Views and triggers are left out, as DBML cannot express
+// them.
+func Stmts(dst io.Writer, stmts model.Stmts) error {
+	var buf bytes.Buffer
+	var refs bytes.Buffer
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case model.Database:
+			buf.WriteString("Project ")
+			buf.WriteString(name(stmt.Name()))
+			buf.WriteString(" {\n  database_type: 'MySQL'\n}\n\n")
+		case model.Table:
+			table, _ := stmt.Normalize()
+			if table.HasLikeTable() {
+				return errors.Errorf(`failed to write statement %s: CREATE TABLE ... LIKE is not supported`, stmt.ID())
+			}
+			writeTable(&buf, table)
+			writeRefs(&refs, table)
+		}
+	}
+	refs.WriteTo(&buf)
+
+	// blocks are followed by a blank line, except for the last one
+	out := append(bytes.TrimRight(buf.Bytes(), "\n"), '\n')
+	if _, err := dst.Write(out); err != nil {
+		return errors.Wrap(err, `failed to write schema`)
+	}
+	return nil
+}
+
+// Source writes the schema read from src as DBML (see Stmts)
+func Source(dst io.Writer, src schemalex.SchemaSource) error {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+	return Stmts(dst, stmts)
+}
+
+var identifierRx = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// name returns the name as is if it is a valid identifier, or double
+// quoted otherwise
+func name(s string) string {
+	if identifierRx.MatchString(s) {
+		return s
+	}
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
+
+var stringReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\r", `\r`, "\n", `\n`)
+
+// str returns the text as a single quoted string
+func str(s string) string {
+	return "'" + stringReplacer.Replace(s) + "'"
+}
+
+func writeTable(buf *bytes.Buffer, table model.Table) {
+	var primary []string
+	var indexes []model.Index
+	for idx := range table.Indexes() {
+		switch {
+		case idx.IsPrimaryKey():
+			primary = columnNames(idx)
+		case idx.IsForeignKey():
+		default:
+			indexes = append(indexes, idx)
+		}
+	}
+
+	// enums come before the table that uses them
+	for col := range table.Columns() {
+		if col.Type() != model.ColumnTypeEnum {
+			continue
+		}
+		buf.WriteString("Enum ")
+		buf.WriteString(name(enumName(table, col)))
+		buf.WriteString(" {\n")
+		for v := range col.EnumValues() {
+			buf.WriteString("  ")
+			buf.WriteString(`"` + strings.Replace(v, `"`, `\"`, -1) + `"`)
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("}\n\n")
+	}
+
+	buf.WriteString("Table ")
+	buf.WriteString(name(table.Name()))
+	buf.WriteString(" {\n")
+	for col := range table.Columns() {
+		writeColumn(buf, table, col, len(primary) == 1 && primary[0] == col.Name())
+	}
+
+	if len(primary) > 1 || len(indexes) > 0 {
+		buf.WriteString("\n  indexes {\n")
+		if len(primary) > 1 {
+			buf.WriteString("    ")
+			buf.WriteString(indexColumns(primary))
+			buf.WriteString(" [pk]\n")
+		}
+		for _, idx := range indexes {
+			var settings []string
+			if idx.IsUnique() {
+				settings = append(settings, "unique")
+			}
+			if idx.IsHash() {
+				settings = append(settings, "type: hash")
+			}
+			if idx.HasName() {
+				settings = append(settings, "name: "+str(idx.Name()))
+			}
+			buf.WriteString("    ")
+			buf.WriteString(indexColumns(columnNames(idx)))
+			if len(settings) > 0 {
+				buf.WriteString(" [")
+				buf.WriteString(strings.Join(settings, ", "))
+				buf.WriteString("]")
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("  }\n")
+	}
+
+	for opt := range table.Options() {
+		if strings.EqualFold(opt.Key(), "COMMENT") {
+			buf.WriteString("\n  Note: ")
+			buf.WriteString(str(opt.Value()))
+			buf.WriteByte('\n')
+		}
+	}
+	buf.WriteString("}\n\n")
+}
+
+func writeColumn(buf *bytes.Buffer, table model.Table, col model.TableColumn, primary bool) {
+	buf.WriteString("  ")
+	buf.WriteString(name(col.Name()))
+	buf.WriteByte(' ')
+	if col.Type() == model.ColumnTypeEnum {
+		buf.WriteString(name(enumName(table, col)))
+	} else {
+		buf.WriteString(columnType(col))
+	}
+
+	var settings []string
+	if primary {
+		settings = append(settings, "pk")
+	}
+	if col.IsAutoIncrement() {
+		settings = append(settings, "increment")
+	}
+	if col.NullState() == model.NullStateNotNull {
+		settings = append(settings, "not null")
+	}
+	if col.IsUnique() {
+		settings = append(settings, "unique")
+	}
+	if col.HasDefault() {
+		if def, ok := defaultValue(col); ok {
+			settings = append(settings, "default: "+def)
+		}
+	}
+	if col.HasComment() {
+		settings = append(settings, "note: "+str(col.Comment()))
+	}
+	if len(settings) > 0 {
+		buf.WriteString(" [")
+		buf.WriteString(strings.Join(settings, ", "))
+		buf.WriteString("]")
+	}
+	buf.WriteByte('\n')
+}
+
+// enumName returns the name of the Enum definition for the values of
+// an ENUM column, such as users_status
+func enumName(table model.Table, col model.TableColumn) string {
+	return table.Name() + "_" + col.Name()
+}
+
+// columnType returns the type of the column in lower case, such as
+// varchar(255), double quoted if it is not a plain word such as
+// "int unsigned". Display widths of integers are left out.
+func columnType(col model.TableColumn) string {
+	var buf bytes.Buffer
+	buf.WriteString(strings.ToLower(col.Type().String()))
+	switch col.Type().SynonymType() {
+	case model.ColumnTypeTinyInt, model.ColumnTypeSmallInt, model.ColumnTypeMediumInt,
+		model.ColumnTypeInt, model.ColumnTypeBigInt, model.ColumnTypeYear:
+	case model.ColumnTypeSet:
+		var values []string
+		for v := range col.SetValues() {
+			values = append(values, "'"+v+"'")
+		}
+		buf.WriteString("(" + strings.Join(values, ",") + ")")
+	default:
+		if col.HasLength() {
+			l := col.Length()
+			buf.WriteString("(" + l.Length())
+			if l.HasDecimal() {
+				buf.WriteString("," + l.Decimal())
+			}
+			buf.WriteString(")")
+		}
+	}
+	if col.IsUnsigned() {
+		buf.WriteString(" unsigned")
+	}
+	if col.IsZeroFill() {
+		buf.WriteString(" zerofill")
+	}
+
+	typ := buf.String()
+	if strings.ContainsAny(typ, ` '"`) {
+		return `"` + strings.Replace(typ, `"`, `\"`, -1) + `"`
+	}
+	return typ
+}
+
+var numberRx = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// defaultValue returns the default value of the column, if it has one
+// other than NULL. Expressions such as CURRENT_TIMESTAMP are enclosed
+// in backticks.
+func defaultValue(col model.TableColumn) (string, bool) {
+	def := col.Default()
+	switch {
+	case col.IsQuotedDefault():
+		return str(def), true
+	case strings.EqualFold(def, "NULL"):
+		return "", false
+	case strings.EqualFold(def, "TRUE"), strings.EqualFold(def, "FALSE"):
+		return strings.ToLower(def), true
+	case numberRx.MatchString(def):
+		return def, true
+	}
+	return "`" + def + "`", true
+}
+
+func columnNames(c model.ColumnContainer) []string {
+	var cols []string
+	for col := range c.Columns() {
+		cols = append(cols, col.Name())
+	}
+	return cols
+}
+
+// indexColumns returns the columns of an index, as a single name or a
+// parenthesized list
+func indexColumns(cols []string) string {
+	if len(cols) == 1 {
+		return name(cols[0])
+	}
+	var names []string
+	for _, col := range cols {
+		names = append(names, name(col))
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+var referenceOptions = map[model.ReferenceOption]string{
+	model.ReferenceOptionCascade:  "cascade",
+	model.ReferenceOptionSetNull:  "set null",
+	model.ReferenceOptionNoAction: "no action",
+}
+
+// writeRefs writes the foreign keys of the table as many-to-one refs.
+// RESTRICT, which is what MySQL does by default, is left out.
+func writeRefs(buf *bytes.Buffer, table model.Table) {
+	for idx := range table.Indexes() {
+		r := idx.Reference()
+		if !idx.IsForeignKey() || r == nil {
+			continue
+		}
+		buf.WriteString("Ref")
+		if identifierRx.MatchString(idx.Symbol()) {
+			buf.WriteByte(' ')
+			buf.WriteString(idx.Symbol())
+		}
+		buf.WriteString(": ")
+		buf.WriteString(name(table.Name()))
+		buf.WriteByte('.')
+		buf.WriteString(indexColumns(columnNames(idx)))
+		buf.WriteString(" > ")
+		buf.WriteString(name(r.TableName()))
+		buf.WriteByte('.')
+		buf.WriteString(indexColumns(columnNames(r)))
+
+		var settings []string
+		if action, ok := referenceOptions[r.OnDelete()]; ok {
+			settings = append(settings, "delete: "+action)
+		}
+		if action, ok := referenceOptions[r.OnUpdate()]; ok {
+			settings = append(settings, "update: "+action)
+		}
+		if len(settings) > 0 {
+			buf.WriteString(" [")
+			buf.WriteString(strings.Join(settings, ", "))
+			buf.WriteString("]")
+		}
+		buf.WriteByte('\n')
+	}
+}
@@ -0,0 +1,56 @@
+package dbml_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/dbml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmts(t *testing.T) {
+	src := "CREATE TABLE `users` ( `id` INTEGER UNSIGNED NOT NULL AUTO_INCREMENT, `email` VARCHAR (255) NOT NULL, `status` ENUM('active','banned') NOT NULL DEFAULT 'active', `name` VARCHAR (20) DEFAULT NULL COMMENT 'the user''s name', `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (`id`), UNIQUE KEY `uniq_email` (`email`), KEY `idx_name` (`name`, `created`) ) COMMENT 'users';\n" +
+		"CREATE TABLE `user-posts` ( `user_id` INTEGER UNSIGNED NOT NULL, `seq` INT NOT NULL, `score` DECIMAL (5,2) NOT NULL DEFAULT 0.00, PRIMARY KEY (`user_id`, `seq`), CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE );"
+	expect := `Enum users_status {
+  "active"
+  "banned"
+}
+
+Table users {
+  id "int unsigned" [pk, increment, not null]
+  email varchar(255) [not null]
+  status users_status [not null, default: 'active']
+  name varchar(20) [note: 'the user\'s name']
+  created datetime [not null, default: ` + "`CURRENT_TIMESTAMP`" + `]
+
+  indexes {
+    email [unique, name: 'uniq_email']
+    (name, created) [name: 'idx_name']
+  }
+
+  Note: 'users'
+}
+
+Table "user-posts" {
+  user_id "int unsigned" [not null]
+  seq int [not null]
+  score decimal(5,2) [not null, default: 0.00]
+
+  indexes {
+    (user_id, seq) [pk]
+  }
+}
+
+Ref fk_user: "user-posts".user_id > users.id [delete: cascade]
+`
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parsing should succeed") {
+		return
+	}
+	var buf bytes.Buffer
+	if assert.NoError(t, dbml.Stmts(&buf, stmts), "dbml.Stmts should succeed") {
+		assert.Equal(t, expect, buf.String(), "DBML should match")
+	}
+}
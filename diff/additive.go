@@ -0,0 +1,61 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+)
+
+// isDropKind reports whether the change drops something that
+// WithAdditiveOnly keeps in place
+func isDropKind(kind ChangeKind) bool {
+	switch kind {
+	case DropTable, DropColumn, DropIndex, DropPrimaryKey, DropForeignKey, DropPartition:
+		return true
+	}
+	return false
+}
+
+// suppressDrops marks the changes that drop tables, columns, indexes
+// or partitions as suppressed, and removes them unless they are to be
+// written as comments. Indexes that are dropped to be recreated under
+// the same name, because their definition has changed, are still
+// dropped, as adding them would fail otherwise.
+func suppressDrops(ctx *diffCtx, changes []Change) []Change {
+	added := make(map[string]struct{})
+	for _, change := range changes {
+		switch change.Kind {
+		case AddIndex, AddFulltextIndex, AddSpatialIndex, AddPrimaryKey, AddForeignKey:
+			added[change.Table+"."+change.Name] = struct{}{}
+		}
+	}
+
+	result := changes[:0]
+	for _, change := range changes {
+		if isDropKind(change.Kind) {
+			_, recreated := added[change.Table+"."+change.Name]
+			if recreated && change.Kind != DropTable && change.Kind != DropColumn && change.Kind != DropPartition {
+				result = append(result, change)
+				continue
+			}
+			if !ctx.dropComments {
+				continue
+			}
+			change.suppressed = true
+		}
+		result = append(result, change)
+	}
+	return result
+}
+
+// writeSuppressedChange writes a change suppressed by WithAdditiveOnly
+// as a comment
+func writeSuppressedChange(ctx *diffCtx, buf *bytes.Buffer, change Change) {
+	// shell commands need shell comments
+	prefix := "-- "
+	if change.batch != 0 && ctx.alterMode != AlterModeSQL {
+		prefix = "# "
+	}
+	buf.WriteString(prefix)
+	buf.WriteString("skipped (additive only): ")
+	buf.WriteString(strings.Replace(change.SQL, "\n", "\n"+prefix, -1))
+}
@@ -0,0 +1,179 @@
+package diff
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// Execer executes statements, such as *sql.DB, *sql.Conn or *sql.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Decision is what an approval callback decides to do with a change
+// about to be applied (see WithApproval)
+type Decision int
+
+const (
+	// DecisionApprove applies the change
+	DecisionApprove Decision = iota
+	// DecisionSkip leaves the change out, and goes on with the rest
+	DecisionSkip
+	// DecisionAbort stops applying changes
+	DecisionAbort
+)
+
+// ErrAborted is the cause of the error returned by Apply when an
+// approval callback aborts
+var ErrAborted = errors.New(`aborted by approval callback`)
+
+// ApplyError describes a change that failed to be applied
+type ApplyError struct {
+	// Change is the change that failed
+	Change Change
+	// Statement is the statement of the change that failed
+	Statement string
+	// Err is the error returned by the database
+	Err error
+}
+
+func (e *ApplyError) Error() string {
+	return "failed to apply " + string(e.Change.Kind) + " (" + e.Statement + "): " + e.Err.Error()
+}
+
+// Cause returns the error returned by the database
+func (e *ApplyError) Cause() error {
+	return e.Err
+}
+
+// ApplyErrors is returned by Apply when changes fail to be applied
+// and WithContinueOnError is enabled
+type ApplyErrors []*ApplyError
+
+func (l ApplyErrors) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Apply executes the statements of the changes, in order, against db.
+// The changes must have been computed with the default AlterModeSQL.
+// By default Apply stops at the first change that fails, and returns
+// an *ApplyError describing it. With WithContinueOnError, the rest of
+// the changes are applied anyway, and all the failures are returned as
+// ApplyErrors. WithStatementTimeout limits how long each statement may
+// take.
+//
+// With WithApproval, each change is submitted to a callback before
+// being applied, which may approve it, skip it, or abort, in which case
+// an *ApplyError caused by ErrAborted is returned. The hooks given by
+// WithHook for BeforeApply and AfterApply run before the first change
+// and after the last one, and are given ctx.
+//
+// Note that MySQL commits DDL statements implicitly, so the changes
+// applied before a failure are not rolled back.
+func Apply(ctx context.Context, db Execer, changes []Change, options ...Option) error {
+	hooks := newHookRunner(ctx, options)
+	if err := hooks.before(BeforeApply, changes); err != nil {
+		return err
+	}
+	return hooks.after(AfterApply, changes, applyChanges(ctx, db, changes, options))
+}
+
+func applyChanges(ctx context.Context, db Execer, changes []Change, options []Option) error {
+	var continueOnError bool
+	var timeout time.Duration
+	var approve func(Change) Decision
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyApproval:
+			approve = o.Value().(func(Change) Decision)
+		case optkeyContinueOnError:
+			continueOnError = o.Value().(bool)
+		case optkeyStatementTimeout:
+			timeout = o.Value().(time.Duration)
+		}
+	}
+
+	var errs ApplyErrors
+	for _, change := range changes {
+		if change.suppressed || change.Kind == NormalizeCharset {
+			continue
+		}
+		if approve != nil {
+			switch approve(change) {
+			case DecisionSkip:
+				continue
+			case DecisionAbort:
+				aerr := &ApplyError{Change: change, Statement: change.SQL, Err: ErrAborted}
+				if len(errs) > 0 {
+					return append(errs, aerr)
+				}
+				return aerr
+			}
+		}
+		for _, stmt := range splitStatements(change.SQL) {
+			if err := execStatement(ctx, db, stmt, timeout); err != nil {
+				aerr := &ApplyError{Change: change, Statement: stmt, Err: err}
+				if !continueOnError {
+					return aerr
+				}
+				errs = append(errs, aerr)
+				// the rest of the change depends on this statement
+				break
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, `failed to apply changes`)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func execStatement(ctx context.Context, db Execer, stmt string, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	_, err := db.ExecContext(ctx, stmt)
+	return err
+}
+
+// splitStatements splits the SQL of a change into statements that can
+// be executed one by one, removing the DELIMITER commands meant for the
+// mysql client, as well as the delimiters themselves
+func splitStatements(s string) []string {
+	var stmts []string
+	var buf []string
+	delimiter := ";"
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(strings.ToUpper(trimmed), "DELIMITER ") {
+			delimiter = strings.TrimSpace(trimmed[len("DELIMITER "):])
+			continue
+		}
+		if !strings.HasSuffix(trimmed, delimiter) {
+			buf = append(buf, line)
+			continue
+		}
+		buf = append(buf, strings.TrimSuffix(strings.TrimRight(line, " \t"), delimiter))
+		if stmt := strings.TrimSpace(strings.Join(buf, "\n")); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+		buf = buf[:0]
+	}
+	if stmt := strings.TrimSpace(strings.Join(buf, "\n")); stmt != "" {
+		stmts = append(stmts, stmt)
+	}
+	return stmts
+}
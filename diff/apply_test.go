@@ -0,0 +1,178 @@
+package diff_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/schemalex/schemalex/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+type execRecorder struct {
+	stmts []string
+	fail  map[string]error
+}
+
+func (r *execRecorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.stmts = append(r.stmts, query)
+	return nil, r.fail[query]
+}
+
+func TestApply(t *testing.T) {
+	changes := []diff.Change{
+		{Kind: diff.AddColumn, Table: "hoge", Name: "a", SQL: "ALTER TABLE `hoge` ADD COLUMN `a` INT (11) NOT NULL;"},
+		{Kind: diff.CreateTrigger, Name: "t", SQL: "DELIMITER ;;\nCREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW BEGIN SET NEW.id = 1; END;;\nDELIMITER ;"},
+		{Kind: diff.DropColumn, Table: "hoge", Name: "b", SQL: "ALTER TABLE `hoge` DROP COLUMN `b`;"},
+	}
+	errFailed := errors.New("failed")
+
+	t.Run("all statements", func(t *testing.T) {
+		var r execRecorder
+		if !assert.NoError(t, diff.Apply(context.Background(), &r, changes), "diff.Apply should succeed") {
+			return
+		}
+		assert.Equal(t, []string{
+			"ALTER TABLE `hoge` ADD COLUMN `a` INT (11) NOT NULL",
+			"CREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW BEGIN SET NEW.id = 1; END",
+			"ALTER TABLE `hoge` DROP COLUMN `b`",
+		}, r.stmts)
+	})
+
+	t.Run("stop on error", func(t *testing.T) {
+		r := execRecorder{fail: map[string]error{"ALTER TABLE `hoge` ADD COLUMN `a` INT (11) NOT NULL": errFailed}}
+		err := diff.Apply(context.Background(), &r, changes)
+		aerr, ok := err.(*diff.ApplyError)
+		if !assert.True(t, ok, "error should be *diff.ApplyError") {
+			return
+		}
+		assert.Equal(t, diff.AddColumn, aerr.Change.Kind)
+		assert.Equal(t, errFailed, aerr.Err)
+		assert.Len(t, r.stmts, 1)
+	})
+
+	t.Run("continue on error", func(t *testing.T) {
+		r := execRecorder{fail: map[string]error{"ALTER TABLE `hoge` ADD COLUMN `a` INT (11) NOT NULL": errFailed}}
+		err := diff.Apply(context.Background(), &r, changes, diff.WithContinueOnError(true))
+		errs, ok := err.(diff.ApplyErrors)
+		if !assert.True(t, ok, "error should be diff.ApplyErrors") {
+			return
+		}
+		assert.Len(t, errs, 1)
+		assert.Len(t, r.stmts, 3)
+	})
+	t.Run("approval", func(t *testing.T) {
+		var r execRecorder
+		var approved []diff.ChangeKind
+		err := diff.Apply(context.Background(), &r, changes, diff.WithApproval(func(change diff.Change) diff.Decision {
+			approved = append(approved, change.Kind)
+			if change.Kind == diff.CreateTrigger {
+				return diff.DecisionSkip
+			}
+			return diff.DecisionApprove
+		}))
+		if !assert.NoError(t, err, "diff.Apply should succeed") {
+			return
+		}
+		assert.Equal(t, []diff.ChangeKind{diff.AddColumn, diff.CreateTrigger, diff.DropColumn}, approved)
+		assert.Equal(t, []string{
+			"ALTER TABLE `hoge` ADD COLUMN `a` INT (11) NOT NULL",
+			"ALTER TABLE `hoge` DROP COLUMN `b`",
+		}, r.stmts)
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		var r execRecorder
+		err := diff.Apply(context.Background(), &r, changes, diff.WithApproval(func(change diff.Change) diff.Decision {
+			if change.Kind == diff.DropColumn {
+				return diff.DecisionAbort
+			}
+			return diff.DecisionApprove
+		}))
+		aerr, ok := err.(*diff.ApplyError)
+		if !assert.True(t, ok, "error should be *diff.ApplyError") {
+			return
+		}
+		assert.Equal(t, diff.ErrAborted, aerr.Err)
+		assert.Equal(t, diff.DropColumn, aerr.Change.Kind)
+		assert.Len(t, r.stmts, 2)
+	})
+}
+
+func TestHooks(t *testing.T) {
+	changes := []diff.Change{
+		{Kind: diff.AddColumn, Table: "hoge", Name: "a", SQL: "ALTER TABLE `hoge` ADD COLUMN `a` INT (11) NOT NULL;"},
+	}
+	errFailed := errors.New("failed")
+
+	record := func(events *[]diff.HookEvent) diff.Hook {
+		return func(ctx context.Context, ev diff.HookEvent) error {
+			*events = append(*events, ev)
+			return nil
+		}
+	}
+
+	t.Run("diff", func(t *testing.T) {
+		var events []diff.HookEvent
+		var buf bytes.Buffer
+		err := diff.Strings(&buf, "CREATE TABLE hoge (id INT);", "CREATE TABLE hoge (id INT, a INT);",
+			diff.WithHook(diff.BeforeDiff, record(&events)),
+			diff.WithHook(diff.AfterDiff, record(&events)),
+			diff.WithHook(diff.BeforeApply, record(&events)),
+		)
+		if !assert.NoError(t, err, "diff.Strings should succeed") {
+			return
+		}
+		if !assert.Len(t, events, 2) {
+			return
+		}
+		assert.Equal(t, diff.BeforeDiff, events[0].Point)
+		assert.Empty(t, events[0].Changes)
+		assert.Equal(t, diff.AfterDiff, events[1].Point)
+		if assert.Len(t, events[1].Changes, 1) {
+			assert.Equal(t, diff.AddColumn, events[1].Changes[0].Kind)
+		}
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		var events []diff.HookEvent
+		r := execRecorder{fail: map[string]error{"ALTER TABLE `hoge` ADD COLUMN `a` INT (11) NOT NULL": errFailed}}
+		err := diff.Apply(context.Background(), &r, changes,
+			diff.WithHook(diff.BeforeApply, record(&events)),
+			diff.WithHook(diff.AfterApply, record(&events)),
+		)
+		if !assert.Error(t, err, "diff.Apply should fail") {
+			return
+		}
+		if !assert.Len(t, events, 2) {
+			return
+		}
+		assert.Equal(t, diff.BeforeApply, events[0].Point)
+		assert.NoError(t, events[0].Err)
+		assert.Equal(t, diff.AfterApply, events[1].Point)
+		assert.Equal(t, err, events[1].Err)
+	})
+
+	t.Run("failing hook", func(t *testing.T) {
+		var r execRecorder
+		err := diff.Apply(context.Background(), &r, changes, diff.WithHook(diff.BeforeApply, func(ctx context.Context, ev diff.HookEvent) error {
+			return errFailed
+		}))
+		assert.Error(t, err, "diff.Apply should fail")
+		assert.Empty(t, r.stmts, "no statements should be executed")
+	})
+
+	t.Run("command", func(t *testing.T) {
+		var out bytes.Buffer
+		var r execRecorder
+		err := diff.Apply(context.Background(), &r, changes,
+			diff.WithHook(diff.AfterApply, diff.CommandHook(`echo "$SCHEMALEX_HOOK $SCHEMALEX_CHANGES"; grep -c '"kind"'`, &out)),
+		)
+		if !assert.NoError(t, err, "diff.Apply should succeed") {
+			return
+		}
+		assert.Equal(t, "after-apply 1\n1\n", out.String())
+	})
+}
@@ -0,0 +1,296 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/model"
+)
+
+// ChangeKind describes the kind of a change made by a generated statement
+type ChangeKind string
+
+// List of possible ChangeKind values
+const (
+	CreateTable         ChangeKind = "create-table"
+	DropTable           ChangeKind = "drop-table"
+	RenameTable         ChangeKind = "rename-table"
+	AddColumn           ChangeKind = "add-column"
+	DropColumn          ChangeKind = "drop-column"
+	ChangeColumn        ChangeKind = "change-column"
+	RenameColumn        ChangeKind = "rename-column"
+	MoveColumn          ChangeKind = "move-column"
+	ChangeColumnDefault ChangeKind = "change-column-default"
+	AddIndex            ChangeKind = "add-index"
+	AddFulltextIndex    ChangeKind = "add-fulltext-index"
+	AddSpatialIndex     ChangeKind = "add-spatial-index"
+	DropIndex           ChangeKind = "drop-index"
+	RenameIndex         ChangeKind = "rename-index"
+	AddPrimaryKey       ChangeKind = "add-primary-key"
+	DropPrimaryKey      ChangeKind = "drop-primary-key"
+	AddForeignKey       ChangeKind = "add-foreign-key"
+	DropForeignKey      ChangeKind = "drop-foreign-key"
+	AddCheck            ChangeKind = "add-check"
+	DropCheck           ChangeKind = "drop-check"
+	AlterCheck          ChangeKind = "alter-check"
+	ChangeEngine        ChangeKind = "change-engine"
+	ChangeTableOption   ChangeKind = "change-table-option"
+	ConvertCharset      ChangeKind = "convert-charset"
+	NormalizeCharset    ChangeKind = "normalize-charset"
+	AddPartition        ChangeKind = "add-partition"
+	DropPartition       ChangeKind = "drop-partition"
+	ReorganizePartition ChangeKind = "reorganize-partition"
+	CoalescePartition   ChangeKind = "coalesce-partition"
+	Repartition         ChangeKind = "repartition"
+	RemovePartitioning  ChangeKind = "remove-partitioning"
+	CreateView          ChangeKind = "create-view"
+	ReplaceView         ChangeKind = "replace-view"
+	DropView            ChangeKind = "drop-view"
+	CreateTrigger       ChangeKind = "create-trigger"
+	DropTrigger         ChangeKind = "drop-trigger"
+)
+
+// Change describes a single change needed to migrate from the old
+// schema to the new one
+type Change struct {
+	// Kind is the kind of the change
+	Kind ChangeKind `json:"kind"`
+	// Table is the name of the table being changed. For triggers,
+	// it is the table that the trigger is defined on, and for views
+	// it is empty.
+	Table string `json:"table,omitempty"`
+	// Name is the name of the object being changed, such as a column,
+	// an index, a table option, a view or a trigger. It is empty for
+	// changes to a table as a whole.
+	Name string `json:"name,omitempty"`
+	// OldName is the name of a renamed table or column before the
+	// rename. It is empty for other changes.
+	OldName string `json:"old_name,omitempty"`
+	// Before and After are the definitions of the object being
+	// changed, such as a column or an index, in the old and the new
+	// schema. They are empty if the object does not exist in the
+	// respective schema, or has no definition of its own.
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	// SQL is the statement that applies this change on its own
+	SQL string `json:"sql"`
+	// Safety tells how risky it is to apply this change
+	Safety Safety `json:"safety"`
+	// Warning tells why this change may lose existing data, if it
+	// does so unexpectedly. Changes that obviously lose data, such as
+	// dropping a table, have no warning.
+	Warning string `json:"warning,omitempty"`
+	// Comment is written as a comment before the statement. It is
+	// only ever set by a Rewriter.
+	Comment string `json:"comment,omitempty"`
+
+	phase  int         // index of the proc that produced the change
+	batch  int         // changes to be combined when coalescing share the same batch
+	clause alterClause // the change within ALTER TABLE, if batch is not 0
+
+	suppressed bool // written as a comment only (see WithAdditiveOnly)
+}
+
+// alterClause is a single change within an ALTER TABLE statement,
+// such as "DROP COLUMN `foo`"
+type alterClause struct {
+	kind    ChangeKind
+	name    string
+	oldName string
+	before  string
+	after   string
+	sql     string
+	warning string
+	// appended is true for columns added after all existing ones
+	appended bool
+	// detail describes the change to a column for intent comments,
+	// such as "nullable, no default"
+	detail string
+}
+
+// indexName returns the name MySQL knows the index by
+func indexName(idx model.Index) string {
+	switch {
+	case idx.IsPrimaryKey():
+		return "PRIMARY"
+	case idx.IsForeignKey() && idx.HasSymbol():
+		return idx.Symbol()
+	case idx.HasName():
+		return idx.Name()
+	default:
+		return idx.Symbol()
+	}
+}
+
+// definition returns the SQL definition of a statement, or a part of
+// one such as a column or an index, for Change.Before and Change.After
+func definition(v interface{}) string {
+	var buf bytes.Buffer
+	if err := format.SQL(&buf, v); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// alterTableChanges returns a change for each of the clauses to be
+// applied to the table. The changes belong to the same batch, so that
+// they can be rendered as a single statement when coalescing.
+func alterTableChanges(ctx *diffCtx, table string, clauses []alterClause) []Change {
+	if len(clauses) == 0 {
+		return nil
+	}
+
+	ctx.batches++
+	changes := make([]Change, len(clauses))
+	for i, clause := range clauses {
+		var buf bytes.Buffer
+		writeAlterTable(ctx, &buf, table, []alterClause{clause})
+		changes[i] = Change{
+			Kind:    clause.kind,
+			Table:   table,
+			Name:    clause.name,
+			OldName: clause.oldName,
+			Before:  clause.before,
+			After:   clause.after,
+			SQL:     buf.String(),
+			Warning: clause.warning,
+			batch:   ctx.batches,
+			clause:  clause,
+		}
+	}
+	return changes
+}
+
+// scriptWrapping describes the statements written around the changes,
+// so that the script applies cleanly on a real server
+type scriptWrapping struct {
+	txn                     bool
+	disableForeignKeyChecks bool
+	sqlMode                 string
+	setSQLMode              bool
+}
+
+// writeStatements renders the changes as SQL, wrapped in the
+// statements described by w
+func writeStatements(ctx *diffCtx, buf *bytes.Buffer, changes []Change, w scriptWrapping) {
+	var header, footer []string
+	if w.txn {
+		header = append(header, "BEGIN;")
+		footer = append(footer, "COMMIT;")
+	}
+	if w.setSQLMode {
+		header = append(header, "SET @OLD_SQL_MODE = @@SESSION.sql_mode;\nSET SESSION sql_mode = "+sqlString(w.sqlMode)+";")
+		footer = append(footer, "SET SESSION sql_mode = @OLD_SQL_MODE;")
+	}
+	if w.disableForeignKeyChecks {
+		header = append(header, "SET FOREIGN_KEY_CHECKS = 0;")
+		footer = append(footer, "SET FOREIGN_KEY_CHECKS = 1;")
+	}
+
+	if len(header) > 0 {
+		buf.WriteByte('\n')
+		buf.WriteString(strings.Join(header, "\n\n"))
+		if len(changes) > 0 {
+			buf.WriteString("\n\n")
+		}
+	}
+	writeChanges(ctx, buf, changes)
+	for i := len(footer) - 1; i >= 0; i-- {
+		buf.WriteString("\n\n")
+		buf.WriteString(footer[i])
+	}
+}
+
+// writeChanges renders the changes as a series of statements. Changes
+// produced by different procs are separated by a blank line.
+func writeChanges(ctx *diffCtx, buf *bytes.Buffer, changes []Change) {
+	if ctx.idempotent && ctx.alterMode == AlterModeSQL {
+		writeIdempotentChanges(ctx, buf, changes)
+		return
+	}
+
+	for i := 0; i < len(changes); {
+		change := changes[i]
+		if i > 0 {
+			if change.phase != changes[i-1].phase {
+				buf.WriteString("\n\n")
+			} else {
+				buf.WriteByte('\n')
+			}
+		}
+
+		if change.batch == 0 {
+			if change.suppressed {
+				writeSuppressedChange(ctx, buf, change)
+				i++
+				continue
+			}
+			writeAnnotations(ctx, buf, "-- ", change)
+			writeColored(ctx, buf, changeDirection(change.Kind), change.SQL)
+			i++
+			continue
+		}
+
+		var batch, suppressed []Change
+		for ; i < len(changes) && changes[i].batch == change.batch; i++ {
+			if changes[i].suppressed {
+				suppressed = append(suppressed, changes[i])
+				continue
+			}
+			batch = append(batch, changes[i])
+		}
+		for j, change := range suppressed {
+			if j > 0 {
+				buf.WriteByte('\n')
+			}
+			writeSuppressedChange(ctx, buf, change)
+		}
+		if len(batch) > 0 {
+			if len(suppressed) > 0 {
+				buf.WriteByte('\n')
+			}
+			writeAlterTableChanges(ctx, buf, batch)
+		}
+	}
+}
+
+// writeAlterTableChanges writes the changes belonging to the same batch
+func writeAlterTableChanges(ctx *diffCtx, buf *bytes.Buffer, changes []Change) {
+	clauses := make([]alterClause, len(changes))
+	for i, change := range changes {
+		clauses[i] = change.clause
+	}
+
+	// shell commands need shell comments
+	prefix := "-- "
+	if ctx.alterMode != AlterModeSQL {
+		prefix = "# "
+	}
+	if ctx.coalesce || ctx.alterMode != AlterModeSQL {
+		for _, change := range changes {
+			writeComment(buf, prefix, change.Comment)
+			if ctx.intentComments {
+				writeIntentComment(ctx, buf, prefix, change)
+			}
+		}
+		if ctx.safetyComments && !ctx.intentComments {
+			writeSafetyComment(buf, prefix, worstSafety(changes))
+		}
+		if !ctx.color {
+			writeAlterTable(ctx, buf, changes[0].Table, clauses)
+			return
+		}
+		var stmt bytes.Buffer
+		writeAlterTable(ctx, &stmt, changes[0].Table, clauses)
+		writeColored(ctx, buf, batchDirection(changes), stmt.String())
+		return
+	}
+	for i, change := range changes {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		writeAnnotations(ctx, buf, prefix, change)
+		writeColored(ctx, buf, changeDirection(change.Kind), change.SQL)
+	}
+}
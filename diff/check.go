@@ -0,0 +1,131 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// checkKey identifies a CHECK constraint within a table. Named
+// constraints are matched by name, which MySQL compares case
+// insensitively, and the rest by their condition.
+func checkKey(c model.Check) string {
+	if c.HasName() {
+		return "name#" + strings.ToLower(c.Name())
+	}
+	return "expr#" + checkExpression(c)
+}
+
+// checkExpression returns the condition of the constraint, normalized
+// so that MySQL's rendition of it, such as ((`price` > 0)), compares
+// equal to what was written in the schema
+func checkExpression(c model.Check) string {
+	s := normalizeDefinition(c.Expression())
+	for len(s) > 1 && s[0] == '(' && closingParen(s) == len(s)-1 {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
+
+// closingParen returns the position of the parenthesis that closes the
+// one at the beginning of s, or -1 if there is none. Parentheses in
+// quoted strings are not counted.
+func closingParen(s string) int {
+	var depth int
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func checksByKey(table model.Table) map[string]model.Check {
+	checks := make(map[string]model.Check)
+	for c := range table.Checks() {
+		checks[checkKey(c)] = c
+	}
+	return checks
+}
+
+// dropTableChecks drops the constraints that are gone or whose
+// condition has changed. They are dropped before the columns, as MySQL
+// refuses to drop a column that a constraint refers to.
+func dropTableChecks(ctx *alterCtx) ([]alterClause, error) {
+	to := checksByKey(ctx.to)
+
+	var clauses []alterClause
+	for c := range ctx.from.Checks() {
+		if other, ok := to[checkKey(c)]; ok && checkExpression(other) == checkExpression(c) {
+			continue
+		}
+		if !c.HasName() {
+			return nil, errors.Errorf("can not drop check constraint without name: %s", c.ID())
+		}
+		clauses = append(clauses, alterClause{kind: DropCheck, name: c.Name(), before: definition(c), sql: "DROP CHECK `" + c.Name() + "`"})
+	}
+	return clauses, nil
+}
+
+// addTableChecks adds the new constraints, and the ones whose condition
+// has changed, after the columns they may refer to are in place
+func addTableChecks(ctx *alterCtx) ([]alterClause, error) {
+	from := checksByKey(ctx.from)
+
+	var clauses []alterClause
+	for c := range ctx.to.Checks() {
+		if other, ok := from[checkKey(c)]; ok && checkExpression(other) == checkExpression(c) {
+			continue
+		}
+
+		var buf bytes.Buffer
+		buf.WriteString("ADD ")
+		if err := format.SQL(&buf, c); err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, alterClause{kind: AddCheck, name: c.Name(), after: definition(c), sql: buf.String()})
+	}
+	return clauses, nil
+}
+
+// alterTableChecks enforces, or stops enforcing, the constraints that
+// are otherwise unchanged
+func alterTableChecks(ctx *alterCtx) ([]alterClause, error) {
+	from := checksByKey(ctx.from)
+
+	var clauses []alterClause
+	for c := range ctx.to.Checks() {
+		other, ok := from[checkKey(c)]
+		if !ok || checkExpression(other) != checkExpression(c) || other.IsEnforced() == c.IsEnforced() {
+			continue
+		}
+		if !c.HasName() {
+			return nil, errors.Errorf("can not alter check constraint without name: %s", c.ID())
+		}
+
+		sql := "ALTER CHECK `" + c.Name() + "` ENFORCED"
+		if !c.IsEnforced() {
+			sql = "ALTER CHECK `" + c.Name() + "` NOT ENFORCED"
+		}
+		clauses = append(clauses, alterClause{kind: AlterCheck, name: c.Name(), before: definition(other), after: definition(c), sql: sql})
+	}
+	return clauses, nil
+}
@@ -0,0 +1,215 @@
+package diff
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/schemalex/schemalex/model"
+)
+
+// columnsEqual reports whether two columns have the same definition,
+// taking the comparison options in ctx into account
+func columnsEqual(ctx *alterCtx, a, b model.TableColumn) bool {
+	if ctx.ignoreComments {
+		a = withoutComment(a)
+		b = withoutComment(b)
+	}
+	if ctx.convertCharset && charsetsEqual(ctx, a.CharacterSet(), ctx.fromCharset) && charsetsEqual(ctx, b.CharacterSet(), ctx.toCharset) &&
+		(ctx.toCollation == "" || charsetsEqual(ctx, b.Collation(), ctx.toCollation)) {
+		// the column is converted along with the table
+		a = a.Clone().SetCharacterSet(b.CharacterSet())
+		if b.HasCollation() {
+			a.SetCollation(b.Collation())
+		}
+	}
+	if defaultsEqual(a, b) {
+		a, b = withSameDefault(a, b)
+	}
+	a, b = withoutStrictnessDifferences(ctx, a, b)
+	if a.TableID() != b.TableID() {
+		// the table is renamed
+		a = a.Clone().SetTableID(b.TableID())
+	}
+	if a.IsImpliedCollation() != b.IsImpliedCollation() {
+		// only the formatter cares whether the collation was written
+		a = a.Clone().SetImpliedCollation(b.IsImpliedCollation())
+	}
+	return reflect.DeepEqual(withoutSourceComments(a), withoutSourceComments(b))
+}
+
+// defaultsEqual reports whether the columns have the same default
+// value as far as MySQL is concerned, even if they are written
+// differently, such as DEFAULT 0 and DEFAULT '0.0' on numeric
+// columns, or no default and DEFAULT NULL on nullable columns
+func defaultsEqual(a, b model.TableColumn) bool {
+	switch {
+	case !a.HasDefault() && !b.HasDefault():
+		return true
+	case !a.HasDefault():
+		return isImplicitDefault(b)
+	case !b.HasDefault():
+		return isImplicitDefault(a)
+	}
+
+	if a.IsQuotedDefault() == b.IsQuotedDefault() && a.Default() == b.Default() {
+		return true
+	}
+	if !a.IsQuotedDefault() && !b.IsQuotedDefault() {
+		// keywords such as CURRENT_TIMESTAMP
+		if canonicalKeyword(a.Default()) == canonicalKeyword(b.Default()) {
+			return true
+		}
+	}
+
+	// literals mean the same whether they are quoted or not, but
+	// keywords and expressions do not
+	x, xerr := strconv.ParseFloat(a.Default(), 64)
+	y, yerr := strconv.ParseFloat(b.Default(), 64)
+	if xerr != nil || yerr != nil {
+		return a.IsQuotedDefault() && b.IsQuotedDefault() && a.Default() == b.Default()
+	}
+	if isNumericType(a.Type()) && isNumericType(b.Type()) {
+		return x == y
+	}
+	return a.Default() == b.Default()
+}
+
+// canonicalKeyword returns the keyword used as a default value in
+// upper case, with synonyms of CURRENT_TIMESTAMP replaced by it
+func canonicalKeyword(s string) string {
+	s = strings.ToUpper(s)
+	switch strings.TrimSuffix(s, "()") {
+	case "CURRENT_TIMESTAMP", "NOW", "LOCALTIME", "LOCALTIMESTAMP":
+		return "CURRENT_TIMESTAMP"
+	}
+	return s
+}
+
+// isImplicitDefault reports whether the default value of the column
+// is the one it would have without a DEFAULT clause: NULL if the
+// column is nullable, or an empty string for NOT NULL string columns
+func isImplicitDefault(col model.TableColumn) bool {
+	if col.NullState() != model.NullStateNotNull {
+		return !col.IsQuotedDefault() && strings.EqualFold(col.Default(), "NULL")
+	}
+	return isTextType(col.Type()) && col.IsQuotedDefault() && col.Default() == ""
+}
+
+// withSameDefault returns the columns with the default value of one
+// given to the other, for columns whose defaults are equal
+func withSameDefault(a, b model.TableColumn) (model.TableColumn, model.TableColumn) {
+	switch {
+	case !a.HasDefault() && !b.HasDefault():
+		return a, b
+	case b.HasDefault():
+		return a.Clone().SetDefault(b.Default(), b.IsQuotedDefault()), b
+	default:
+		return a, b.Clone().SetDefault(a.Default(), a.IsQuotedDefault())
+	}
+}
+
+// defaultChangedOnly reports whether the columns only differ in their
+// default values
+func defaultChangedOnly(ctx *alterCtx, a, b model.TableColumn) bool {
+	if defaultsEqual(a, b) {
+		return false
+	}
+	// a default cannot be removed from a column, so the one without
+	// it takes the other's instead
+	if b.HasDefault() {
+		a = a.Clone().SetDefault(b.Default(), b.IsQuotedDefault())
+	} else {
+		b = b.Clone().SetDefault(a.Default(), a.IsQuotedDefault())
+	}
+	return columnsEqual(ctx, a, b)
+}
+
+// tableOptionsEqual reports whether two table options have the same
+// value. Values that are not quoted, such as ENGINE, are compared
+// case insensitively
+func tableOptionsEqual(a, b model.TableOption) bool {
+	if a.NeedQuotes() || b.NeedQuotes() {
+		return a.Value() == b.Value()
+	}
+	return strings.EqualFold(a.Value(), b.Value())
+}
+
+// isCharsetOption reports whether the table option is the default
+// character set or collation
+func isCharsetOption(opt model.TableOption) bool {
+	switch strings.ToUpper(opt.Key()) {
+	case "DEFAULT CHARACTER SET", "DEFAULT COLLATE":
+		return true
+	}
+	return false
+}
+
+// normalizeDefinition normalizes a fragment of SQL, such as the
+// definition of a view, so that fragments that only differ in
+// formatting compare equal. Keywords and identifiers are lower cased,
+// backquotes are removed, and whitespaces are collapsed into a single
+// space, or removed altogether around punctuations.
+// Quoted strings are left untouched.
+func normalizeDefinition(s string) string {
+	var buf bytes.Buffer
+	var quote rune
+	var escape bool
+	var space bool
+	for _, r := range s {
+		if quote != 0 {
+			switch {
+			case escape:
+				escape = false
+				buf.WriteRune(r)
+			case r == '\\' && quote != '`':
+				escape = true
+				buf.WriteRune(r)
+			case r == quote:
+				quote = 0
+				if r != '`' {
+					buf.WriteRune(r)
+				}
+			case quote == '`':
+				buf.WriteRune(unicode.ToLower(r))
+			default:
+				buf.WriteRune(r)
+			}
+			continue
+		}
+
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			if buf.Len() > 0 && !isPunctuation(r) && !isPunctuation(lastRune(&buf)) {
+				buf.WriteByte(' ')
+			}
+			space = false
+		}
+
+		switch r {
+		case '`':
+			quote = r
+		case '\'', '"':
+			quote = r
+			buf.WriteRune(r)
+		default:
+			buf.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return buf.String()
+}
+
+func isPunctuation(r rune) bool {
+	return strings.ContainsRune("(),.;=<>!+-*/%", r)
+}
+
+func lastRune(buf *bytes.Buffer) rune {
+	r, _ := utf8.DecodeLastRune(buf.Bytes())
+	return r
+}
@@ -4,14 +4,19 @@ package diff
 
 import (
 	"bytes"
+	"context"
 	"io"
-	"reflect"
+	"path"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/deckarep/golang-set"
 	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/fingerprint"
 	"github.com/schemalex/schemalex/format"
 	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/internal/version"
 	"github.com/schemalex/schemalex/model"
 )
 
@@ -20,6 +25,41 @@ type diffCtx struct {
 	toSet   mapset.Set
 	from    model.Stmts
 	to      model.Stmts
+
+	coalesce              bool
+	idempotent            bool
+	alterMode             AlterMode
+	databaseName          string
+	toolArgs              []string
+	onlineDDL             bool
+	onlineDDLOverrides    map[ChangeKind]OnlineDDL
+	mysqlVersion          version.MySQL
+	safetyComments        bool
+	intentComments        bool
+	ignoreComments        bool
+	ignoreTableOptions    []string
+	detectColumnRename    bool
+	columnRenames         map[string]map[string]string // new table name -> old column name -> new column name
+	columnRenameThreshold float64
+	reorderColumns        bool
+	ignoreColumnOrder     bool
+	perColumnCharset      bool
+	additiveOnly          bool
+	dropComments          bool
+	rewriters             []Rewriter
+	matchIndexesByColumns bool
+	renameIndexes         bool
+	ignoreIntDisplayWidth bool
+	ignoreCharsetAliases  bool
+	ignoreDefaultQuoting  bool
+	compareIndexOrder     bool
+	charsetAliasNotes     bool
+	progress              tableProgress
+	trace                 tracer
+	color                 bool
+	renamedTables         map[string]string // old table ID -> new table ID
+	droppedForeignKeys    mapset.Set        // index IDs dropped before dropping tables
+	batches               int               // number of ALTER TABLE batches so far
 }
 
 func newDiffCtx(from, to model.Stmts) *diffCtx {
@@ -37,57 +77,458 @@ func newDiffCtx(from, to model.Stmts) *diffCtx {
 	}
 
 	return &diffCtx{
-		fromSet: fromSet,
-		toSet:   toSet,
-		from:    from,
-		to:      to,
+		fromSet:            fromSet,
+		toSet:              toSet,
+		from:               from,
+		to:                 to,
+		renamedTables:      make(map[string]string),
+		droppedForeignKeys: mapset.NewSet(),
 	}
 }
 
 // Statements compares two model.Stmts and generates a series
 // of statements to migrate from the old one to the new one,
-// writing the result to `dst`
+// writing the result to `dst`. The hooks given by WithHook for
+// BeforeDiff and AfterDiff run before and after the comparison.
 func Statements(dst io.Writer, from, to model.Stmts, options ...Option) error {
+	hooks := newHookRunner(nil, options)
+	if err := hooks.before(BeforeDiff, nil); err != nil {
+		return err
+	}
+	changes, err := writeDiff(dst, from, to, options)
+	return hooks.after(AfterDiff, changes, err)
+}
+
+// writeDiff writes the statements to migrate from the old statements
+// to the new ones, and returns the changes they are made of
+func writeDiff(dst io.Writer, from, to model.Stmts, options []Option) ([]Change, error) {
 	var txn bool
+	var foreignKeyChecks *bool
+	var sqlMode *string
+	terminators := scriptTerminators{delimiter: ";"}
+	var safe bool
+	var failOnDestructive bool
+	var outputFormat OutputFormat
+	var color bool
+	var reverse io.Writer
+	var warnings io.Writer
 	for _, o := range options {
 		switch o.Name() {
+		case optkeyReverse:
+			reverse = o.Value().(io.Writer)
 		case optkeyTransaction:
 			txn = o.Value().(bool)
+		case optkeyForeignKeyChecks:
+			v := o.Value().(bool)
+			foreignKeyChecks = &v
+		case optkeySQLMode:
+			v := o.Value().(string)
+			sqlMode = &v
+		case optkeyDelimiter:
+			terminators.delimiter = o.Value().(string)
+		case optkeyBatchSeparator:
+			terminators.batchSeparator = o.Value().(string)
+		case optkeyBatchSize:
+			terminators.batchSize = o.Value().(int)
+		case optkeyTrailingNewline:
+			terminators.trailingNewline = o.Value().(bool)
+		case optkeyWarnings:
+			warnings = o.Value().(io.Writer)
+		case optkeySafe:
+			safe = o.Value().(bool)
+		case optkeyFailOnDestructive:
+			failOnDestructive = o.Value().(bool)
+		case optkeyOutputFormat:
+			outputFormat = o.Value().(OutputFormat)
+		case optkeyColor:
+			color = o.Value().(bool)
+		}
+	}
+
+	if terminators.delimiter == "" {
+		return nil, errors.New(`statement delimiter must not be empty`)
+	}
+	if terminators.batchSize < 0 {
+		return nil, errors.Errorf(`invalid batch size %d`, terminators.batchSize)
+	}
+
+	ctx, err := prepareDiff(from, to, options...)
+	if err != nil {
+		return nil, err
+	}
+	ctx.color = color
+	changes, err := computeChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		if change.Warning == "" {
+			continue
+		}
+		if safe {
+			return nil, errors.Errorf(`refusing to produce diff that may lose data: %s`, change.Warning)
+		}
+		if warnings != nil {
+			if _, err := io.WriteString(warnings, change.Warning+"\n"); err != nil {
+				return nil, errors.Wrap(err, `failed to write warning`)
+			}
+		}
+	}
+
+	if failOnDestructive {
+		var destructive []Change
+		for _, change := range changes {
+			if change.Safety == Destructive && !change.suppressed {
+				destructive = append(destructive, change)
+			}
+		}
+		if len(destructive) > 0 {
+			return nil, &DestructiveChangesError{Changes: destructive}
+		}
+	}
+
+	var buf bytes.Buffer
+	switch outputFormat {
+	case OutputFormatJSON:
+		if err := writeJSONChanges(&buf, changes); err != nil {
+			return nil, err
+		}
+	case OutputFormatStat:
+		if err := Summarize(changes).WriteStat(&buf); err != nil {
+			return nil, err
+		}
+	case OutputFormatShortStat:
+		buf.WriteString(Summarize(changes).ShortStat() + "\n")
+	case OutputFormatPlan:
+		writePlan(&buf, changes, color)
+	default:
+		// foreign key checks are disabled along with the transaction,
+		// unless told otherwise
+		w := scriptWrapping{txn: txn, disableForeignKeyChecks: txn}
+		if foreignKeyChecks != nil {
+			w.disableForeignKeyChecks = !*foreignKeyChecks
+		}
+		if sqlMode != nil {
+			w.sqlMode = *sqlMode
+			w.setSQLMode = true
+		}
+		writeStatements(ctx, &buf, changes, w)
+		if !terminators.isDefault() {
+			s := terminateStatements(buf.String(), terminators)
+			buf.Reset()
+			buf.WriteString(s)
+		}
+	}
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return nil, errors.Wrap(err, `failed to write diff`)
+	}
+
+	if reverse != nil {
+		reverseOptions := make([]Option, 0, len(options))
+		for _, o := range options {
+			if o.Name() == optkeyReverse {
+				continue
+			}
+			reverseOptions = append(reverseOptions, o)
+		}
+		if _, err := writeDiff(reverse, to, from, reverseOptions); err != nil {
+			return nil, errors.Wrap(err, `failed to produce reverse diff`)
+		}
+	}
+	return changes, nil
+}
+
+// Compute compares two model.Stmts and returns the changes needed
+// to migrate from the old one to the new one, in the order they
+// should be applied. Options that only affect how the statements are
+// written out as a whole, such as WithTransaction and WithReverse,
+// are ignored.
+//
+// The order of the changes only depends on the schemas: tables are
+// renamed first, then triggers, views and tables are dropped, tables
+// are created, altered and repartitioned, and finally views and
+// triggers are created. Tables are created after the ones they refer
+// to and dropped before them, and sorted by name otherwise, and tables
+// are altered in the order of their names. Views and triggers keep the
+// order they appear in, as views may refer to each other, and triggers
+// run in the order they are created.
+func Compute(from, to model.Stmts, options ...Option) ([]Change, error) {
+	ctx, err := prepareDiff(from, to, options...)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := computeChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// suppressed changes are only meant to be written as comments
+	result := changes[:0]
+	for _, change := range changes {
+		if !change.suppressed {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
+// prepareDiff applies the options to the statements being compared,
+// and returns the context to compute the changes with
+func prepareDiff(from, to model.Stmts, options ...Option) (*diffCtx, error) {
+	var coalesce bool
+	var ignoreComments bool
+	var ignoreAutoIncrement bool
+	var ignoreTableOptions []string
+	var detectTableRename bool
+	var detectColumnRename bool
+	tableRenames := make(map[string]string)
+	renamedColumns := make(map[string]map[string]string)
+	var columnRenameThreshold float64
+	var reorderColumns bool
+	var ignoreColumnOrder bool
+	var perColumnCharset bool
+	var additiveOnly bool
+	var dropComments bool
+	var rewriters []Rewriter
+	var matchIndexesByColumns bool
+	var renameIndexes bool
+	var strictness Strictness
+	var charsetAliasNotes bool
+	var knobs strictnessKnobs
+	var include, exclude []string
+	var alterMode AlterMode
+	var databaseName string
+	var toolArgs []string
+	var onlineDDL bool
+	var onlineDDLOverrides = make(map[ChangeKind]OnlineDDL)
+	var mysqlVersion = defaultMySQLVersion
+	var safetyComments bool
+	var intentComments bool
+	var idempotent bool
+	var progress tableProgress
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyCoalesce:
+			coalesce = o.Value().(bool)
+		case optkeyAlterMode:
+			alterMode = o.Value().(AlterMode)
+		case optkeyDatabaseName:
+			databaseName = o.Value().(string)
+		case optkeyToolArgs:
+			toolArgs = append(toolArgs, o.Value().([]string)...)
+		case optkeyOnlineDDL:
+			onlineDDL = o.Value().(bool)
+		case optkeyOnlineDDLOverride:
+			override := o.Value().(*onlineDDLOverride)
+			onlineDDLOverrides[override.kind] = override.hint
+		case optkeyMySQLVersion:
+			mysqlVersion = o.Value().(string)
+		case optkeyIdempotent:
+			idempotent = o.Value().(bool)
+		case optkeySafetyComments:
+			safetyComments = o.Value().(bool)
+		case optkeyIntentComments:
+			intentComments = o.Value().(bool)
+		case optkeyIgnoreComments:
+			ignoreComments = o.Value().(bool)
+		case optkeyIgnoreTableOptions:
+			ignoreTableOptions = append(ignoreTableOptions, o.Value().([]string)...)
+		case optkeyIgnoreAutoIncrement:
+			ignoreAutoIncrement = o.Value().(bool)
+		case optkeyIncludeTables:
+			include = append(include, o.Value().([]string)...)
+		case optkeyExcludeTables:
+			exclude = append(exclude, o.Value().([]string)...)
+		case optkeyDetectTableRename:
+			detectTableRename = o.Value().(bool)
+		case optkeyDetectColumnRename:
+			detectColumnRename = o.Value().(bool)
+		case optkeyTableRenames:
+			for oldName, newName := range o.Value().(map[string]string) {
+				tableRenames[oldName] = newName
+			}
+		case optkeyColumnRenames:
+			v := o.Value().(*columnRenames)
+			if renamedColumns[v.table] == nil {
+				renamedColumns[v.table] = make(map[string]string)
+			}
+			for oldName, newName := range v.renames {
+				renamedColumns[v.table][oldName] = newName
+			}
+		case optkeyColumnRenameThreshold:
+			columnRenameThreshold = o.Value().(float64)
+		case optkeyReorderColumns:
+			reorderColumns = o.Value().(bool)
+		case optkeyIgnoreColumnOrder:
+			ignoreColumnOrder = o.Value().(bool)
+		case optkeyPerColumnCharset:
+			perColumnCharset = o.Value().(bool)
+		case optkeyAdditiveOnly:
+			additiveOnly = o.Value().(bool)
+		case optkeyDropComments:
+			dropComments = o.Value().(bool)
+		case optkeyRewriter:
+			rewriters = append(rewriters, o.Value().(Rewriter))
+		case optkeyMatchIndexesByColumns:
+			matchIndexesByColumns = o.Value().(bool)
+		case optkeyRenameIndexes:
+			renameIndexes = o.Value().(bool)
+		case optkeyStrictness:
+			strictness = o.Value().(Strictness)
+		case optkeyCharsetAliasNotes:
+			charsetAliasNotes = o.Value().(bool)
+		case optkeyIgnoreIntDisplayWidth:
+			v := o.Value().(bool)
+			knobs.ignoreIntDisplayWidth = &v
+		case optkeyIgnoreCharsetAliases:
+			v := o.Value().(bool)
+			knobs.ignoreCharsetAliases = &v
+		case optkeyIgnoreDefaultQuoting:
+			v := o.Value().(bool)
+			knobs.ignoreDefaultQuoting = &v
+		case optkeyCompareIndexOrder:
+			v := o.Value().(bool)
+			knobs.compareIndexOrder = &v
+		case optkeyContext:
+			progress.context = o.Value().(context.Context)
+		case optkeyProgress:
+			progress.callback = o.Value().(func(Progress))
+		}
+	}
+
+	mv, err := version.ParseMySQL(mysqlVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse MySQL version`)
+	}
+
+	trace := newTracer(options)
+
+	if len(include) > 0 || len(exclude) > 0 {
+		f, err := newTableNameFilter(include, exclude)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to parse table filters`)
+		}
+		if trace.debug {
+			for _, table := range f.leftOut(from, to) {
+				trace.debugf(`table %s: left out by the table filters`, table)
+			}
 		}
+		from = f.apply(from)
+		to = f.apply(to)
+	}
+
+	for _, pattern := range ignoreTableOptions {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, `invalid table option pattern %s`, pattern)
+		}
+	}
+
+	if ignoreAutoIncrement {
+		trace.debugf(`ignoring AUTO_INCREMENT table options`)
+		from = withoutTableOptions(from, "AUTO_INCREMENT")
+		to = withoutTableOptions(to, "AUTO_INCREMENT")
+	}
+	if len(ignoreTableOptions) > 0 {
+		trace.debugf(`ignoring table options %s`, strings.Join(ignoreTableOptions, ", "))
+	}
+	if ignoreComments {
+		trace.debugf(`ignoring comments`)
 	}
 
 	ctx := newDiffCtx(from, to)
+	ctx.coalesce = coalesce
+	ctx.idempotent = idempotent
+	ctx.alterMode = alterMode
+	ctx.databaseName = databaseName
+	ctx.toolArgs = toolArgs
+	ctx.onlineDDL = onlineDDL
+	ctx.onlineDDLOverrides = onlineDDLOverrides
+	ctx.mysqlVersion = mv
+	ctx.safetyComments = safetyComments
+	ctx.intentComments = intentComments
+	ctx.ignoreComments = ignoreComments
+	ctx.ignoreTableOptions = ignoreTableOptions
+	ctx.detectColumnRename = detectColumnRename
+	ctx.columnRenames = renamedColumns
+	ctx.columnRenameThreshold = columnRenameThreshold
+	ctx.reorderColumns = reorderColumns && !ignoreColumnOrder
+	ctx.ignoreColumnOrder = ignoreColumnOrder
+	ctx.perColumnCharset = perColumnCharset
+	ctx.additiveOnly = additiveOnly
+	ctx.dropComments = dropComments
+	ctx.rewriters = rewriters
+	ctx.matchIndexesByColumns = matchIndexesByColumns
+	// RENAME INDEX is only available since MySQL 5.7
+	ctx.renameIndexes = renameIndexes && mv.AtLeast(5, 7, 0)
+	knobs.apply(ctx, strictness)
+	ctx.charsetAliasNotes = charsetAliasNotes
+	ctx.progress = progress
+	ctx.trace = trace
+	trace.debugf(`comparing for MySQL %s, ignoring integer display widths: %t, character set aliases: %t, quoting of defaults: %t, order of indexes: %t`,
+		mysqlVersion, ctx.ignoreIntDisplayWidth, ctx.ignoreCharsetAliases, ctx.ignoreDefaultQuoting, !ctx.compareIndexOrder)
+
+	if err := applyTableRenames(ctx, tableRenames); err != nil {
+		return nil, errors.Wrap(err, `failed to apply table renames`)
+	}
+	if err := checkColumnRenames(ctx); err != nil {
+		return nil, errors.Wrap(err, `failed to apply column renames`)
+	}
+	if detectTableRename {
+		if err := detectTableRenames(ctx); err != nil {
+			return nil, errors.Wrap(err, `failed to detect table renames`)
+		}
+	}
+	if trace.debug {
+		for oldID, newID := range ctx.renamedTables {
+			trace.debugf(`table %s: renamed to %s`, oldID, newID)
+		}
+	}
+	return ctx, nil
+}
 
-	var procs = []func(*diffCtx, io.Writer) (int64, error){
+func computeChanges(ctx *diffCtx) ([]Change, error) {
+	var procs = []func(*diffCtx) ([]Change, error){
+		renameTables,
+		dropTriggers,
+		dropViews,
 		dropTables,
 		createTables,
 		alterTables,
+		alterPartitions,
+		charsetAliasNotes,
+		createViews,
+		createTriggers,
 	}
 
-	var buf bytes.Buffer
-	if txn {
-		buf.WriteString("\nBEGIN;\n\nSET FOREIGN_KEY_CHECKS = 0;")
-	}
+	startProgress(ctx)
+	start := time.Now()
 
-	for _, p := range procs {
-		var pbuf bytes.Buffer
-		n, err := p(ctx, &pbuf)
+	var changes []Change
+	for i, p := range procs {
+		if err := checkCancelled(ctx); err != nil {
+			return nil, err
+		}
+		c, err := p(ctx)
 		if err != nil {
-			return errors.Wrap(err, `failed to produce diff`)
+			return nil, errors.Wrap(err, `failed to produce diff`)
 		}
-		if txn && n > 0 || !txn && buf.Len() > 0 && n > 0 {
-			buf.WriteString("\n\n")
+		for j := range c {
+			c[j].phase = i
+			c[j].Safety = classifySafety(ctx, c[j])
 		}
-		pbuf.WriteTo(&buf)
-	}
-	if txn {
-		buf.WriteString("\n\nSET FOREIGN_KEY_CHECKS = 1;\n\nCOMMIT;")
+		changes = append(changes, c...)
 	}
 
-	if _, err := buf.WriteTo(dst); err != nil {
-		return errors.Wrap(err, `failed to write diff`)
+	if ctx.additiveOnly {
+		changes = suppressDrops(ctx, changes)
+	}
+	if len(ctx.rewriters) > 0 {
+		changes = rewriteChanges(ctx, changes)
 	}
-	return nil
+	ctx.trace.logf(`compared %d tables in %s: %d changes`, ctx.progress.total, since(start), len(changes))
+	return changes, nil
 }
 
 // Strings compares two strings and generates a series
@@ -104,16 +545,21 @@ func Strings(dst io.Writer, from, to string, options ...Option) error {
 	if p == nil {
 		p = schemalex.New()
 	}
+	trace := newTracer(options)
 
+	start := time.Now()
 	stmts1, err := p.ParseString(from)
 	if err != nil {
 		return errors.Wrapf(err, `failed to parse "from" %s`, from)
 	}
+	trace.logf(`parsed "from" schema in %s: %d statements`, since(start), len(stmts1))
 
+	start = time.Now()
 	stmts2, err := p.ParseString(to)
 	if err != nil {
 		return errors.Wrapf(err, `failed to parse "to" %s`, to)
 	}
+	trace.logf(`parsed "to" schema in %s: %d statements`, since(start), len(stmts2))
 
 	return Statements(dst, stmts1, stmts2, options...)
 }
@@ -129,77 +575,140 @@ func Files(dst io.Writer, from, to string, options ...Option) error {
 // of statements to migrate from the old one to the new one,
 // writing the result to `dst`
 func Sources(dst io.Writer, from, to schemalex.SchemaSource, options ...Option) error {
+	trace := newTracer(options)
+
 	var buf bytes.Buffer
+	start := time.Now()
 	if err := from.WriteSchema(&buf); err != nil {
 		return errors.Wrapf(err, `failed to retrieve schema from "from" source %s`, from)
 	}
 	fromStr := buf.String()
 	buf.Reset()
+	trace.logf(`read "from" schema from %T in %s: %d bytes`, from, since(start), len(fromStr))
 
+	start = time.Now()
 	if err := to.WriteSchema(&buf); err != nil {
 		return errors.Wrapf(err, `failed to retrieve schema from "to" source %s`, to)
 	}
+	trace.logf(`read "to" schema from %T in %s: %d bytes`, to, since(start), buf.Len())
 	return Strings(dst, fromStr, buf.String(), options...)
 }
 
-func dropTables(ctx *diffCtx, dst io.Writer) (int64, error) {
-	var buf bytes.Buffer
-	ids := ctx.fromSet.Difference(ctx.toSet)
-	for i, id := range ids.ToSlice() {
-		if i > 0 {
-			buf.WriteByte('\n')
-		}
+func dropTables(ctx *diffCtx) ([]Change, error) {
+	// tables that refer to other tables must be dropped first
+	tables, err := sortTablesByDependents(ctx.from, ctx.fromSet.Difference(ctx.toSet))
+	if err != nil {
+		return nil, err
+	}
 
-		stmt, ok := ctx.from.Lookup(id.(string))
-		if !ok {
-			return 0, errors.Errorf(`failed to lookup table %s`, id)
-		}
+	changes, err := dropReferencingForeignKeys(ctx, tables)
+	if err != nil {
+		return nil, err
+	}
 
-		table, ok := stmt.(model.Table)
-		if !ok {
-			return 0, errors.Errorf(`lookup failed: %s is not a model.Table`, id)
+	for _, table := range tables {
+		ctx.trace.logf(`table %s: dropped`, table.Name())
+		if err := tableCompared(ctx, table.Name()); err != nil {
+			return nil, err
 		}
-		buf.WriteString("DROP TABLE `")
-		buf.WriteString(table.Name())
-		buf.WriteString("`;")
+		changes = append(changes, Change{
+			Kind:   DropTable,
+			Table:  table.Name(),
+			Before: definition(table),
+			SQL:    "DROP TABLE " + ifExists(ctx) + "`" + table.Name() + "`;",
+		})
 	}
-
-	return buf.WriteTo(dst)
+	return changes, nil
 }
 
-func createTables(ctx *diffCtx, dst io.Writer) (int64, error) {
-	var buf bytes.Buffer
+func createTables(ctx *diffCtx) ([]Change, error) {
+	// tables that are referred to by other tables must be created first
+	tables, err := sortTablesByDependency(ctx.to, ctx.toSet.Difference(ctx.fromSet))
+	if err != nil {
+		return nil, err
+	}
 
-	ids := ctx.toSet.Difference(ctx.fromSet)
-	for _, id := range ids.ToSlice() {
-		// Lookup the corresponding statement, and add its SQL
-		stmt, ok := ctx.to.Lookup(id.(string))
-		if !ok {
-			return 0, errors.Errorf(`failed to lookup table %s`, id)
+	// foreign keys that refer to tables that are not created yet
+	// (which only happens if the references form a cycle) are
+	// added after all of the tables have been created
+	pending := ctx.toSet.Difference(ctx.fromSet)
+	deferred := make(map[string][]model.Index)
+
+	var changes []Change
+	for _, table := range tables {
+		ctx.trace.logf(`table %s: created`, table.Name())
+		if err := tableCompared(ctx, table.Name()); err != nil {
+			return nil, err
 		}
+		pending.Remove(table.ID())
 
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
+		exclude := mapset.NewSet()
+		for idx := range table.Indexes() {
+			if id, ok := foreignKeyTableID(table, idx); ok && pending.Contains(id) {
+				exclude.Add(idx.ID())
+				deferred[table.ID()] = append(deferred[table.ID()], idx)
+			}
 		}
 
+		var stmt model.Stmt = table
+		if exclude.Cardinality() > 0 {
+			stmt = tableWithoutIndexes(table, exclude)
+		}
+		if ctx.idempotent {
+			t := tableFilter{}.apply(stmt.(model.Table))
+			t.SetIfNotExists(true)
+			stmt = t
+		}
+
+		var buf bytes.Buffer
 		if err := format.SQL(&buf, stmt); err != nil {
-			return 0, err
+			return nil, err
 		}
 		buf.WriteByte(';')
+		changes = append(changes, Change{Kind: CreateTable, Table: table.Name(), After: definition(table), SQL: buf.String()})
 	}
-	return buf.WriteTo(dst)
+
+	c, err := addDeferredForeignKeys(ctx, tables, deferred)
+	if err != nil {
+		return nil, err
+	}
+	return append(changes, c...), nil
 }
 
 type alterCtx struct {
-	fromColumns mapset.Set
-	toColumns   mapset.Set
-	fromIndexes mapset.Set
-	toIndexes   mapset.Set
-	from        model.Table
-	to          model.Table
+	fromColumns      mapset.Set
+	toColumns        mapset.Set
+	fromIndexes      mapset.Set
+	toIndexes        mapset.Set
+	from             model.Table
+	to               model.Table
+	renamedColumns   map[string]string // old column ID -> new column ID
+	movedColumns     mapset.Set        // new column IDs to be moved
+	replacedIndexes  []model.Index     // indexes to be dropped after adding new ones
+	matchedIndexes   map[string]string // old index ID -> new index ID, matched by columns
+	reorderedIndexes mapset.Set        // index IDs to be dropped and added again to keep them in order
+	ignoreComments   bool
+	ignoreOptions    []string // patterns of table option keys
+	ignoreOrder      bool
+	renameIndexes    bool
+	renameColumns    bool // use RENAME COLUMN for columns that are only renamed
+
+	// comparison knobs (see WithStrictness)
+	ignoreIntDisplayWidth bool
+	ignoreCharsetAliases  bool
+	ignoreDefaultQuoting  bool
+
+	// convertCharset is true if the default character set or collation
+	// of the table is changed, in which case the table is converted as a
+	// whole, unless the columns are to be converted one by one
+	convertCharset bool
+	fromCharset    string
+	toCharset      string
+	fromCollation  string
+	toCollation    string
 }
 
-func newAlterCtx(from, to model.Table) *alterCtx {
+func newAlterCtx(ctx *diffCtx, from, to model.Table) (*alterCtx, error) {
 	fromColumns := mapset.NewSet()
 	for col := range from.Columns() {
 		fromColumns.Add(col.ID())
@@ -212,6 +721,10 @@ func newAlterCtx(from, to model.Table) *alterCtx {
 
 	fromIndexes := mapset.NewSet()
 	for idx := range from.Indexes() {
+		// foreign keys referring to dropped tables are already gone
+		if ctx.droppedForeignKeys.Contains(idx.ID()) {
+			continue
+		}
 		fromIndexes.Add(idx.ID())
 	}
 
@@ -220,100 +733,289 @@ func newAlterCtx(from, to model.Table) *alterCtx {
 		toIndexes.Add(idx.ID())
 	}
 
-	return &alterCtx{
-		fromColumns: fromColumns,
-		toColumns:   toColumns,
-		fromIndexes: fromIndexes,
-		toIndexes:   toIndexes,
-		from:        from,
-		to:          to,
+	actx := &alterCtx{
+		fromColumns:      fromColumns,
+		toColumns:        toColumns,
+		fromIndexes:      fromIndexes,
+		toIndexes:        toIndexes,
+		from:             from,
+		to:               to,
+		renamedColumns:   make(map[string]string),
+		movedColumns:     mapset.NewSet(),
+		matchedIndexes:   make(map[string]string),
+		reorderedIndexes: mapset.NewSet(),
+		ignoreComments:   ctx.ignoreComments,
+		ignoreOptions:    ctx.ignoreTableOptions,
+		ignoreOrder:      ctx.ignoreColumnOrder,
+		renameIndexes:    ctx.renameIndexes,
+		// RENAME COLUMN is only available since MySQL 8.0
+		renameColumns: ctx.mysqlVersion.AtLeast(8, 0, 0),
+
+		ignoreIntDisplayWidth: ctx.ignoreIntDisplayWidth,
+		ignoreCharsetAliases:  ctx.ignoreCharsetAliases,
+		ignoreDefaultQuoting:  ctx.ignoreDefaultQuoting,
+	}
+
+	if opt, ok := lookupTableOption(to, "DEFAULT CHARACTER SET"); ok && !ctx.perColumnCharset {
+		actx.toCharset = opt.Value()
+		if opt, ok := lookupTableOption(from, "DEFAULT CHARACTER SET"); ok {
+			actx.fromCharset = opt.Value()
+		}
+		if opt, ok := lookupTableOption(to, "DEFAULT COLLATE"); ok {
+			actx.toCollation = opt.Value()
+		}
+		if opt, ok := lookupTableOption(from, "DEFAULT COLLATE"); ok {
+			actx.fromCollation = opt.Value()
+		}
+		// a change of collation alone is only noticed if both tables
+		// specify it, as the default collation of the character set is
+		// not known here
+		actx.convertCharset = !charsetsEqual(actx, actx.fromCharset, actx.toCharset) ||
+			(actx.fromCollation != "" && actx.toCollation != "" && !charsetsEqual(actx, actx.fromCollation, actx.toCollation))
+	}
+
+	if ctx.matchIndexesByColumns {
+		detectIndexRenames(actx)
+	}
+	if ctx.compareIndexOrder {
+		detectIndexReorders(actx)
+	}
+	if renames, ok := ctx.columnRenames[to.Name()]; ok {
+		if err := applyColumnRenames(actx, renames); err != nil {
+			return nil, errors.Wrap(err, `failed to apply column renames`)
+		}
+	}
+	if ctx.detectColumnRename {
+		if err := detectColumnRenames(actx, ctx.columnRenameThreshold); err != nil {
+			return nil, errors.Wrap(err, `failed to detect column renames`)
+		}
+	}
+	if len(actx.renamedColumns) > 0 {
+		if err := matchRenamedColumnIndexes(actx); err != nil {
+			return nil, errors.Wrap(err, `failed to match indexes on renamed columns`)
+		}
+	}
+	if ctx.reorderColumns {
+		detectColumnMoves(actx)
+	}
+	return actx, nil
+}
+
+// tablePair identifies a table that exists in both schemas
+type tablePair struct {
+	oldID string
+	newID string
+}
+
+// alteredTables returns the tables that exist in both schemas, renamed
+// ones included, sorted by their names in the new schema
+func alteredTables(ctx *diffCtx) []tablePair {
+	var pairs []tablePair
+	for _, id := range ctx.toSet.Intersect(ctx.fromSet).ToSlice() {
+		pairs = append(pairs, tablePair{oldID: id.(string), newID: id.(string)})
+	}
+	for oldID, newID := range ctx.renamedTables {
+		pairs = append(pairs, tablePair{oldID: oldID, newID: newID})
+	}
+	// table IDs are made of their names, so this sorts them by name
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].newID < pairs[j].newID
+	})
+	return pairs
+}
+
+// identicalTables reports whether the tables have the same fingerprint,
+// in which case there is nothing to alter. Foreign keys of the old
+// table may have been dropped along with the tables they refer to,
+// and need to be added again even if the tables are the same.
+func identicalTables(ctx *diffCtx, from, to model.Table) (bool, error) {
+	// columns to be renamed must exist, which is checked later on
+	if _, ok := ctx.columnRenames[to.Name()]; ok {
+		return false, nil
+	}
+	for idx := range from.Indexes() {
+		if ctx.droppedForeignKeys.Contains(idx.ID()) {
+			return false, nil
+		}
+	}
+	fromSum, err := fingerprint.Stmt(from)
+	if err != nil {
+		return false, err
+	}
+	toSum, err := fingerprint.Stmt(to)
+	if err != nil {
+		return false, err
 	}
+	return fromSum == toSum, nil
 }
 
-func alterTables(ctx *diffCtx, dst io.Writer) (int64, error) {
-	procs := []func(*alterCtx, io.Writer) (int64, error){
+func alterTables(ctx *diffCtx) ([]Change, error) {
+	// Each of these procs generates a list of clauses to be used
+	// in ALTER TABLE statements, e.g. "DROP COLUMN `foo`"
+	procs := []func(*alterCtx) ([]alterClause, error){
+		alterTableOptions,
+		dropTableForeignKeys,
+		dropTableChecks,
 		dropTableIndexes,
+		renameTableIndexes,
 		dropTableColumns,
+		renameTableColumns,
+		reorderTableColumns,
 		addTableColumns,
 		alterTableColumns,
 		addTableIndexes,
+		dropReplacedIndexes,
+		addTableForeignKeys,
+		alterTableChecks,
+		addTableChecks,
 	}
 
-	ids := ctx.toSet.Intersect(ctx.fromSet)
-	var buf bytes.Buffer
-	for _, id := range ids.ToSlice() {
+	var changes []Change
+	for _, pair := range alteredTables(ctx) {
 		var stmt model.Stmt
 		var ok bool
 
-		stmt, ok = ctx.from.Lookup(id.(string))
+		stmt, ok = ctx.from.Lookup(pair.oldID)
 		if !ok {
-			return 0, errors.Errorf(`table '%s' not found in old schema (alter table)`, id)
+			return nil, errors.Errorf(`table '%s' not found in old schema (alter table)`, pair.oldID)
 		}
 		beforeStmt := stmt.(model.Table)
 
-		stmt, ok = ctx.to.Lookup(id.(string))
+		stmt, ok = ctx.to.Lookup(pair.newID)
 		if !ok {
-			return 0, errors.Errorf(`table '%s' not found in new schema (alter table)`, id)
+			return nil, errors.Errorf(`table '%s' not found in new schema (alter table)`, pair.newID)
 		}
 		afterStmt := stmt.(model.Table)
+		if pair.oldID != pair.newID {
+			beforeStmt = renamedTable(ctx, beforeStmt, afterStmt.Name())
+		}
+
+		same, err := identicalTables(ctx, beforeStmt, afterStmt)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to generate alter table`)
+		}
+		if same {
+			ctx.trace.debugf(`table %s: unchanged`, afterStmt.Name())
+			if err := tableCompared(ctx, afterStmt.Name()); err != nil {
+				return nil, err
+			}
+			continue
+		}
 
-		var pbuf bytes.Buffer
-		alterCtx := newAlterCtx(beforeStmt, afterStmt)
+		alterCtx, err := newAlterCtx(ctx, beforeStmt, afterStmt)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to generate alter table`)
+		}
+
+		var clauses []alterClause
 		for _, p := range procs {
-			n, err := p(alterCtx, &pbuf)
+			c, err := p(alterCtx)
 			if err != nil {
-				return 0, errors.Wrap(err, `failed to generate alter table`)
+				return nil, errors.Wrap(err, `failed to generate alter table`)
 			}
+			clauses = append(clauses, c...)
+		}
 
-			if buf.Len() > 0 && n > 0 {
-				buf.WriteByte('\n')
+		if len(clauses) == 0 {
+			ctx.trace.debugf(`table %s: definitions differ, but only in ways that are ignored or normalized away`, afterStmt.Name())
+		} else {
+			ctx.trace.logf(`table %s: altered with %d clauses`, afterStmt.Name(), len(clauses))
+		}
+
+		// renamed tables are renamed before they are altered
+		changes = append(changes, alterTableChanges(ctx, afterStmt.Name(), clauses)...)
+		if err := tableCompared(ctx, afterStmt.Name()); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// writeAlterTable writes ALTER TABLE statements for the given clauses.
+// If coalescing is enabled, all clauses are combined into a single
+// statement. Otherwise each clause is written as a separate statement.
+// If an online schema change tool is used, its command line is
+// written instead.
+func writeAlterTable(ctx *diffCtx, buf *bytes.Buffer, table string, clauses []alterClause) {
+	switch ctx.alterMode {
+	case AlterModeGhost:
+		// these tools always apply all changes to a table at once
+		writeGhostCommand(ctx, buf, table, clauses)
+		return
+	case AlterModePTOSC:
+		writePTOSCCommand(ctx, buf, table, clauses)
+		return
+	}
+
+	write := func(clauses []alterClause) {
+		buf.WriteString("ALTER TABLE `")
+		buf.WriteString(table)
+		buf.WriteString("` ")
+		for i, clause := range clauses {
+			if i > 0 {
+				buf.WriteString(", ")
 			}
-			pbuf.WriteTo(&buf)
+			buf.WriteString(clause.sql)
 		}
+		if ctx.onlineDDL {
+			writeOnlineDDLHint(ctx, buf, clauses)
+		}
+		buf.WriteByte(';')
 	}
 
-	return buf.WriteTo(dst)
+	if ctx.coalesce {
+		write(clauses)
+		return
+	}
+
+	for i, clause := range clauses {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		write([]alterClause{clause})
+	}
 }
 
-func dropTableColumns(ctx *alterCtx, dst io.Writer) (int64, error) {
+func dropTableColumns(ctx *alterCtx) ([]alterClause, error) {
 	columnNames := ctx.fromColumns.Difference(ctx.toColumns)
 
-	var buf bytes.Buffer
+	var clauses []alterClause
 	for _, columnName := range columnNames.ToSlice() {
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
+		if _, ok := ctx.renamedColumns[columnName.(string)]; ok {
+			continue
 		}
-		buf.WriteString("ALTER TABLE `")
-		buf.WriteString(ctx.from.Name())
-		buf.WriteString("` DROP COLUMN `")
 		col, ok := ctx.from.LookupColumn(columnName.(string))
 		if !ok {
-			return 0, errors.Errorf(`failed to lookup column %s`, columnName)
+			return nil, errors.Errorf(`failed to lookup column %s`, columnName)
 		}
 
-		buf.WriteString(col.Name())
-		buf.WriteString("`;")
+		clauses = append(clauses, alterClause{kind: DropColumn, name: col.Name(), before: definition(col), sql: "DROP COLUMN `" + col.Name() + "`"})
 	}
 
-	return buf.WriteTo(dst)
+	return clauses, nil
 }
 
-func addTableColumns(ctx *alterCtx, dst io.Writer) (int64, error) {
-	var buf bytes.Buffer
+func addTableColumns(ctx *alterCtx) ([]alterClause, error) {
+	var clauses []alterClause
 
 	beforeToNext := make(map[string]string) // lookup next column
 	nextToBefore := make(map[string]string) // lookup before column
 
+	renamed := mapset.NewSet()
+	for _, newColumnName := range ctx.renamedColumns {
+		renamed.Add(newColumnName)
+	}
+
 	// In order to do this correctly, we need to create a graph so that
 	// we always start adding with a column that has a either no before
 	// columns, or one that already exists in the database
 	var firstColumn model.TableColumn
-	for _, v := range ctx.toColumns.Difference(ctx.fromColumns).ToSlice() {
+	for _, v := range ctx.toColumns.Difference(ctx.fromColumns).Difference(renamed).ToSlice() {
 		columnName := v.(string)
 		// find the before-column for each.
 		col, ok := ctx.to.LookupColumn(columnName)
 		if !ok {
-			return 0, errors.Errorf(`failed to lookup column %s`, columnName)
+			return nil, errors.Errorf(`failed to lookup column %s`, columnName)
 		}
 
 		beforeCol, hasBeforeCol := ctx.to.LookupColumnBefore(col.ID())
@@ -331,13 +1033,18 @@ func addTableColumns(ctx *alterCtx, dst io.Writer) (int64, error) {
 
 	// First column is always safe to add
 	if firstColumn != nil {
-		writeAddColumn(ctx, &buf, firstColumn.ID())
+		c, err := addColumnClauses(ctx, firstColumn.ID())
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, c...)
 	}
 
 	var columnNames []string
 	// Find columns that have before columns which existed in both
-	// from and to tables
-	for _, v := range ctx.toColumns.Intersect(ctx.fromColumns).ToSlice() {
+	// from and to tables (renamed columns exist by the time columns
+	// are added, so they count too)
+	for _, v := range ctx.toColumns.Intersect(ctx.fromColumns).Union(renamed).ToSlice() {
 		columnName := v.(string)
 		if nextColumnName, ok := beforeToNext[columnName]; ok {
 			delete(beforeToNext, columnName)
@@ -348,7 +1055,11 @@ func addTableColumns(ctx *alterCtx, dst io.Writer) (int64, error) {
 
 	if len(columnNames) > 0 {
 		sort.Strings(columnNames)
-		writeAddColumn(ctx, &buf, columnNames...)
+		c, err := addColumnClauses(ctx, columnNames...)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, c...)
 	}
 
 	// Finally, we process the remaining columns.
@@ -366,165 +1077,289 @@ func addTableColumns(ctx *alterCtx, dst io.Writer) (int64, error) {
 			jcol, _ := ctx.to.LookupColumnOrder(columnNames[j])
 			return icol < jcol
 		})
-		writeAddColumn(ctx, &buf, columnNames...)
+		c, err := addColumnClauses(ctx, columnNames...)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, c...)
 	}
-	return buf.WriteTo(dst)
+	return clauses, nil
 }
 
-func writeAddColumn(ctx *alterCtx, buf *bytes.Buffer, columnNames ...string) error {
+func addColumnClauses(ctx *alterCtx, columnNames ...string) ([]alterClause, error) {
+	var clauses []alterClause
 	for _, columnName := range columnNames {
 		stmt, ok := ctx.to.LookupColumn(columnName)
 		if !ok {
-			return errors.Errorf(`failed to lookup column %s`, columnName)
+			return nil, errors.Errorf(`failed to lookup column %s`, columnName)
 		}
 
 		beforeCol, hasBeforeCol := ctx.to.LookupColumnBefore(stmt.ID())
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
-		}
-		buf.WriteString("ALTER TABLE `")
-		buf.WriteString(ctx.from.Name())
-		buf.WriteString("` ADD COLUMN ")
-		if err := format.SQL(buf, stmt); err != nil {
-			return err
+
+		var buf bytes.Buffer
+		buf.WriteString("ADD COLUMN ")
+		if err := format.SQL(&buf, stmt); err != nil {
+			return nil, err
 		}
-		if hasBeforeCol {
+		switch {
+		case ctx.ignoreOrder:
+			// let MySQL append the column
+		case hasBeforeCol:
 			buf.WriteString(" AFTER `")
 			buf.WriteString(beforeCol.Name())
 			buf.WriteString("`")
-		} else {
+		default:
 			buf.WriteString(" FIRST")
 		}
-
-		buf.WriteByte(';')
+		clause := alterClause{kind: AddColumn, name: stmt.Name(), after: definition(stmt), sql: buf.String(), appended: ctx.ignoreOrder || isAppended(ctx, stmt), detail: addColumnDetail(stmt)}
+		if reason := addColumnDataLoss(stmt); reason != "" {
+			clause.warning = "column `" + ctx.to.Name() + "`.`" + stmt.Name() + "`: " + reason
+		}
+		clauses = append(clauses, clause)
 	}
-	return nil
+	return clauses, nil
 }
 
-func alterTableColumns(ctx *alterCtx, dst io.Writer) (int64, error) {
-	var buf bytes.Buffer
-	columnNames := ctx.toColumns.Intersect(ctx.fromColumns)
-	for _, columnName := range columnNames.ToSlice() {
-		beforeColumnStmt, ok := ctx.from.LookupColumn(columnName.(string))
-		if !ok {
-			return 0, errors.Errorf(`column %s not found in old schema`, columnName)
+// isAppended reports whether the column comes after all of the columns
+// that already exist in the table, which MySQL 8.0.12 and later can add
+// instantly
+func isAppended(ctx *alterCtx, col model.TableColumn) bool {
+	existing := ctx.fromColumns.Clone()
+	for _, newColumnName := range ctx.renamedColumns {
+		existing.Add(newColumnName)
+	}
+
+	var after bool
+	for c := range ctx.to.Columns() {
+		switch {
+		case c.ID() == col.ID():
+			after = true
+		case after && existing.Contains(c.ID()):
+			return false
 		}
+	}
+	return after
+}
 
-		afterColumnStmt, ok := ctx.to.LookupColumn(columnName.(string))
+func alterTableColumns(ctx *alterCtx) ([]alterClause, error) {
+	var clauses []alterClause
+	// columns are changed in the order they appear in the new table
+	for afterColumnStmt := range ctx.to.Columns() {
+		if !ctx.fromColumns.Contains(afterColumnStmt.ID()) {
+			continue
+		}
+		beforeColumnStmt, ok := ctx.from.LookupColumn(afterColumnStmt.ID())
 		if !ok {
-			return 0, errors.Errorf(`column %s not found in new schema`, columnName)
+			return nil, errors.Errorf(`column %s not found in old schema`, afterColumnStmt.ID())
 		}
 
-		if reflect.DeepEqual(beforeColumnStmt, afterColumnStmt) {
+		// moved columns are changed when they are moved
+		if ctx.movedColumns.Contains(afterColumnStmt.ID()) || columnsEqual(ctx, beforeColumnStmt, afterColumnStmt) {
 			continue
 		}
 
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
+		if defaultChangedOnly(ctx, beforeColumnStmt, afterColumnStmt) {
+			clauses = append(clauses, alterColumnDefaultClause(beforeColumnStmt, afterColumnStmt))
+			continue
 		}
-		buf.WriteString("ALTER TABLE `")
-		buf.WriteString(ctx.from.Name())
-		buf.WriteString("` CHANGE COLUMN `")
+
+		var buf bytes.Buffer
+		buf.WriteString("CHANGE COLUMN `")
 		buf.WriteString(afterColumnStmt.Name())
 		buf.WriteString("` ")
 		if err := format.SQL(&buf, afterColumnStmt); err != nil {
-			return 0, err
+			return nil, err
 		}
-		buf.WriteByte(';')
+		clause := alterClause{kind: ChangeColumn, name: afterColumnStmt.Name(), before: definition(beforeColumnStmt), after: definition(afterColumnStmt), sql: buf.String(), detail: changeColumnDetail(beforeColumnStmt, afterColumnStmt)}
+		if reason := columnDataLoss(beforeColumnStmt, afterColumnStmt); reason != "" {
+			clause.warning = "column `" + ctx.to.Name() + "`.`" + afterColumnStmt.Name() + "`: " + reason
+		}
+		clauses = append(clauses, clause)
 	}
 
-	return buf.WriteTo(dst)
+	return clauses, nil
 }
 
-func dropTableIndexes(ctx *alterCtx, dst io.Writer) (int64, error) {
+// alterColumnDefaultClause changes the default value of a column
+// with ALTER COLUMN, which only changes the metadata of the table
+func alterColumnDefaultClause(before, after model.TableColumn) alterClause {
 	var buf bytes.Buffer
-	indexes := ctx.fromIndexes.Difference(ctx.toIndexes)
-	// drop index after drop constraint.
-	// because cannot drop index if needed in a foreign key constraint
-	lazy := make([]model.Index, 0, indexes.Cardinality())
-	for _, index := range indexes.ToSlice() {
-		indexStmt, ok := ctx.from.LookupIndex(index.(string))
-		if !ok {
-			return 0, errors.Errorf(`index '%s' not found in old schema (drop index)`, index)
+	buf.WriteString("ALTER COLUMN `")
+	buf.WriteString(after.Name())
+	buf.WriteString("` ")
+	switch {
+	case !after.HasDefault():
+		buf.WriteString("DROP DEFAULT")
+	case after.IsQuotedDefault():
+		buf.WriteString("SET DEFAULT '")
+		buf.WriteString(after.Default())
+		buf.WriteByte('\'')
+	default:
+		buf.WriteString("SET DEFAULT ")
+		buf.WriteString(after.Default())
+	}
+	return alterClause{kind: ChangeColumnDefault, name: after.Name(), before: definition(before), after: definition(after), sql: buf.String(), detail: defaultDetail(after)}
+}
+
+func lookupTableOption(table model.Table, key string) (model.TableOption, bool) {
+	for opt := range table.Options() {
+		if strings.EqualFold(opt.Key(), key) {
+			return opt, true
 		}
+	}
+	return nil, false
+}
 
-		if indexStmt.IsPrimaryKey() {
-			if buf.Len() > 0 {
-				buf.WriteByte('\n')
-			}
-			buf.WriteString("ALTER TABLE `")
-			buf.WriteString(ctx.from.Name())
-			buf.WriteString("` DROP PRIMARY KEY;")
-			continue
+func alterTableOptions(ctx *alterCtx) ([]alterClause, error) {
+	var clauses []alterClause
+
+	if ctx.convertCharset {
+		clause := "CONVERT TO CHARACTER SET " + ctx.toCharset
+		if ctx.toCollation != "" {
+			clause += " COLLATE " + ctx.toCollation
 		}
+		clauses = append(clauses, alterClause{kind: ConvertCharset, before: ctx.fromCharset, after: ctx.toCharset, sql: clause})
+	}
 
-		if !indexStmt.HasName() && !indexStmt.HasSymbol() {
-			return 0, errors.Errorf("can not drop index without name: %s", indexStmt.ID())
+	skip := func(opt model.TableOption) bool {
+		switch strings.ToUpper(opt.Key()) {
+		case "DEFAULT CHARACTER SET", "DEFAULT COLLATE":
+			// taken care of by CONVERT TO
+			return ctx.convertCharset
+		case "COMMENT":
+			return ctx.ignoreComments
+		}
+		return matchTableOption(opt, ctx.ignoreOptions)
+	}
+
+	for opt := range ctx.to.Options() {
+		if skip(opt) {
+			continue
+		}
+		before, ok := lookupTableOption(ctx.from, opt.Key())
+		if ok && tableOptionsEqual(before, opt) {
+			continue
 		}
-		if !indexStmt.IsForeignKey() {
-			lazy = append(lazy, indexStmt)
+		if ok && isCharsetOption(opt) && charsetsEqual(ctx, before.Value(), opt.Value()) {
 			continue
 		}
 
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
+		var buf bytes.Buffer
+		if err := format.SQL(&buf, opt); err != nil {
+			return nil, err
 		}
-		buf.WriteString("ALTER TABLE `")
-		buf.WriteString(ctx.from.Name())
-		buf.WriteString("` DROP FOREIGN KEY `")
-		if indexStmt.HasSymbol() {
-			buf.WriteString(indexStmt.Symbol())
-		} else {
-			buf.WriteString(indexStmt.Name())
+		clause := alterClause{kind: tableOptionKind(opt), name: opt.Key(), after: buf.String(), sql: buf.String()}
+		if ok {
+			clause.before = definition(before)
 		}
-		buf.WriteString("`;")
+		clauses = append(clauses, clause)
 	}
-	// drop index after drop CONSTRAINT
-	for _, indexStmt := range lazy {
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
+
+	// Options that are removed need to be reset explicitly, but only
+	// some of them can be
+	for opt := range ctx.from.Options() {
+		if skip(opt) {
+			continue
 		}
-		buf.WriteString("ALTER TABLE `")
-		buf.WriteString(ctx.from.Name())
-		buf.WriteString("` DROP KEY `")
-		if !indexStmt.HasName() {
-			buf.WriteString(indexStmt.Symbol())
-		} else {
-			buf.WriteString(indexStmt.Name())
+		if _, ok := lookupTableOption(ctx.to, opt.Key()); ok {
+			continue
 		}
 
-		buf.WriteString("`;")
+		switch strings.ToUpper(opt.Key()) {
+		case "COMMENT":
+			clauses = append(clauses, alterClause{kind: ChangeTableOption, name: opt.Key(), before: definition(opt), sql: "COMMENT = ''"})
+		case "ROW_FORMAT":
+			clauses = append(clauses, alterClause{kind: ChangeTableOption, name: opt.Key(), before: definition(opt), sql: "ROW_FORMAT = DEFAULT"})
+		}
 	}
+	return clauses, nil
+}
 
-	return buf.WriteTo(dst)
+// dropTableForeignKeys drops the foreign keys first, as the indexes
+// they rely on cannot be dropped while they exist
+func dropTableForeignKeys(ctx *alterCtx) ([]alterClause, error) {
+	var clauses []alterClause
+	for idx := range ctx.from.Indexes() {
+		if !idx.IsForeignKey() || !ctx.fromIndexes.Contains(idx.ID()) || ctx.toIndexes.Contains(idx.ID()) {
+			continue
+		}
+		if !idx.HasName() && !idx.HasSymbol() {
+			return nil, errors.Errorf("can not drop index without name: %s", idx.ID())
+		}
+		clauses = append(clauses, dropForeignKeyClause(idx))
+	}
+	return clauses, nil
 }
 
-func addTableIndexes(ctx *alterCtx, dst io.Writer) (int64, error) {
-	var buf bytes.Buffer
-	indexes := ctx.toIndexes.Difference(ctx.fromIndexes)
-	// add index before add foreign key.
-	// because cannot add index if create implicitly index by foreign key.
-	lazy := make([]model.Index, 0, indexes.Cardinality())
-	for _, index := range indexes.ToSlice() {
-		indexStmt, ok := ctx.to.LookupIndex(index.(string))
-		if !ok {
-			return 0, errors.Errorf(`index '%s' not found in old schema (add index)`, index)
+// dropTableIndexes drops the indexes other than foreign keys. An index
+// that a remaining foreign key relies on is dropped after the new
+// indexes are added (see dropReplacedIndexes), so that MySQL can move
+// the foreign key onto one of them.
+func dropTableIndexes(ctx *alterCtx) ([]alterClause, error) {
+	var clauses []alterClause
+	for indexStmt := range ctx.from.Indexes() {
+		reordered := ctx.reorderedIndexes.Contains(indexStmt.ID())
+		if indexStmt.IsForeignKey() || (ctx.toIndexes.Contains(indexStmt.ID()) && !reordered) {
+			continue
 		}
-		if indexStmt.IsForeignKey() {
-			lazy = append(lazy, indexStmt)
+
+		if indexStmt.IsPrimaryKey() {
+			clauses = append(clauses, alterClause{kind: DropPrimaryKey, name: indexName(indexStmt), before: definition(indexStmt), sql: "DROP PRIMARY KEY"})
 			continue
 		}
-		if buf.Len() > 0 {
-			buf.WriteByte('\n')
+
+		if !indexStmt.HasName() && !indexStmt.HasSymbol() {
+			return nil, errors.Errorf("can not drop index without name: %s", indexStmt.ID())
 		}
-		buf.WriteString("ALTER TABLE `")
-		buf.WriteString(ctx.from.Name())
-		buf.WriteString("` ADD ")
+		if !reordered && indexNeededByForeignKey(ctx, indexStmt) {
+			ctx.replacedIndexes = append(ctx.replacedIndexes, indexStmt)
+			continue
+		}
+		clauses = append(clauses, dropIndexClause(indexStmt))
+	}
+	return clauses, nil
+}
+
+func addTableIndexes(ctx *alterCtx) ([]alterClause, error) {
+	// foreign keys are added by addTableForeignKeys, after the indexes
+	// they rely on
+	return addIndexClauses(ctx, false)
+}
+
+func dropReplacedIndexes(ctx *alterCtx) ([]alterClause, error) {
+	var clauses []alterClause
+	for _, indexStmt := range ctx.replacedIndexes {
+		clauses = append(clauses, dropIndexClause(indexStmt))
+	}
+	return clauses, nil
+}
+
+func addTableForeignKeys(ctx *alterCtx) ([]alterClause, error) {
+	return addIndexClauses(ctx, true)
+}
+
+// addIndexClauses adds the new indexes, either the foreign keys or
+// everything else, in the order they appear in the new table
+func addIndexClauses(ctx *alterCtx, foreignKeys bool) ([]alterClause, error) {
+	var clauses []alterClause
+	for indexStmt := range ctx.to.Indexes() {
+		if indexStmt.IsForeignKey() != foreignKeys || (ctx.fromIndexes.Contains(indexStmt.ID()) && !ctx.reorderedIndexes.Contains(indexStmt.ID())) {
+			continue
+		}
+
+		var buf bytes.Buffer
+		buf.WriteString("ADD ")
 		if err := format.SQL(&buf, indexStmt); err != nil {
-			return 0, err
+			return nil, err
 		}
-		buf.WriteByte(';')
+		clauses = append(clauses, alterClause{kind: addIndexKind(indexStmt), name: indexName(indexStmt), after: definition(indexStmt), sql: buf.String()})
 	}
+	return clauses, nil
+}
 
-	return buf.WriteTo(dst)
+func dropIndexClause(idx model.Index) alterClause {
+	if !idx.HasName() {
+		return alterClause{kind: DropIndex, name: indexName(idx), before: definition(idx), sql: "DROP KEY `" + idx.Symbol() + "`"}
+	}
+	return alterClause{kind: DropIndex, name: indexName(idx), before: definition(idx), sql: "DROP KEY `" + idx.Name() + "`"}
 }
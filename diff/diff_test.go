@@ -2,9 +2,17 @@ package diff_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
 	"testing"
 
+	"github.com/pkg/errors"
+	"github.com/schemalex/schemalex"
 	"github.com/schemalex/schemalex/diff"
+	"github.com/schemalex/schemalex/model"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -208,3 +216,1138 @@ CREATE TABLE foo (
 		}
 	}
 }
+
+func TestDiffWithOptions(t *testing.T) {
+	type Spec struct {
+		Name    string
+		Before  string
+		After   string
+		Options []diff.Option
+		Expect  string
+	}
+
+	specs := []Spec{
+		{
+			Name:   "create referenced tables first",
+			Before: "",
+			After:  "CREATE TABLE `b` ( `id` INTEGER NOT NULL, `a_id` INTEGER NOT NULL, CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) ); CREATE TABLE `a` ( `id` INTEGER NOT NULL );",
+			Expect: "CREATE TABLE `a` (\n`id` INT (11) NOT NULL\n);\nCREATE TABLE `b` (\n`id` INT (11) NOT NULL,\n`a_id` INT (11) NOT NULL,\nCONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) ON DELETE RESTRICT ON UPDATE RESTRICT\n);",
+		},
+		{
+			Name:   "create tables with circular references",
+			Before: "",
+			After:  "CREATE TABLE `a` ( `id` INTEGER NOT NULL, `b_id` INTEGER NOT NULL, CONSTRAINT `fk_b` FOREIGN KEY (`b_id`) REFERENCES `b` (`id`) ); CREATE TABLE `b` ( `id` INTEGER NOT NULL, `a_id` INTEGER NOT NULL, CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) );",
+			Expect: "CREATE TABLE `a` (\n`id` INT (11) NOT NULL,\n`b_id` INT (11) NOT NULL\n);\nCREATE TABLE `b` (\n`id` INT (11) NOT NULL,\n`a_id` INT (11) NOT NULL,\nCONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) ON DELETE RESTRICT ON UPDATE RESTRICT\n);\nALTER TABLE `a` ADD CONSTRAINT `fk_b` FOREIGN KEY (`b_id`) REFERENCES `b` (`id`) ON DELETE RESTRICT ON UPDATE RESTRICT;",
+		},
+		{
+			Name:   "create and alter tables in order of names",
+			Before: "CREATE TABLE `d` ( `id` INTEGER NOT NULL ); CREATE TABLE `c` ( `id` INTEGER NOT NULL );",
+			After:  "CREATE TABLE `d` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL ); CREATE TABLE `c` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL ); CREATE TABLE `b` ( `id` INTEGER NOT NULL ); CREATE TABLE `a` ( `id` INTEGER NOT NULL );",
+			Expect: "CREATE TABLE `a` (\n`id` INT (11) NOT NULL\n);\nCREATE TABLE `b` (\n`id` INT (11) NOT NULL\n);\n\nALTER TABLE `c` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;\nALTER TABLE `d` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;",
+		},
+		{
+			Name:   "drop referencing tables first",
+			Before: "CREATE TABLE `a` ( `id` INTEGER NOT NULL ); CREATE TABLE `b` ( `id` INTEGER NOT NULL, `a_id` INTEGER NOT NULL, CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) );",
+			After:  "",
+			Expect: "DROP TABLE `b`;\nDROP TABLE `a`;",
+		},
+		{
+			Name:   "drop independent tables by name",
+			Before: "CREATE TABLE `z` ( `id` INTEGER NOT NULL ); CREATE TABLE `x` ( `id` INTEGER NOT NULL ); CREATE TABLE `y` ( `id` INTEGER NOT NULL ); CREATE TABLE `a` ( `id` INTEGER NOT NULL ); CREATE TABLE `b` ( `id` INTEGER NOT NULL, `a_id` INTEGER NOT NULL, CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) );",
+			After:  "",
+			Expect: "DROP TABLE `b`;\nDROP TABLE `a`;\nDROP TABLE `x`;\nDROP TABLE `y`;\nDROP TABLE `z`;",
+		},
+		{
+			Name:   "drop tables with circular references",
+			Before: "CREATE TABLE `a` ( `id` INTEGER NOT NULL, `b_id` INTEGER NOT NULL, CONSTRAINT `fk_b` FOREIGN KEY (`b_id`) REFERENCES `b` (`id`) ); CREATE TABLE `b` ( `id` INTEGER NOT NULL, `a_id` INTEGER NOT NULL, CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) );",
+			After:  "",
+			Expect: "ALTER TABLE `b` DROP FOREIGN KEY `fk_a`;\nDROP TABLE `a`;\nDROP TABLE `b`;",
+		},
+		{
+			Name:   "drop foreign key before dropping referenced table",
+			Before: "CREATE TABLE `a` ( `id` INTEGER NOT NULL ); CREATE TABLE `b` ( `id` INTEGER NOT NULL, `a_id` INTEGER NOT NULL, CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) );",
+			After:  "CREATE TABLE `b` ( `id` INTEGER NOT NULL, `a_id` INTEGER NOT NULL );",
+			Expect: "ALTER TABLE `b` DROP FOREIGN KEY `fk_a`;\nDROP TABLE `a`;",
+		},
+		{
+			Name:   "add index before foreign key",
+			Before: "CREATE TABLE `b` ( `id` INTEGER NOT NULL, `a_id` INTEGER NOT NULL );",
+			After:  "CREATE TABLE `b` ( `id` INTEGER NOT NULL, `a_id` INTEGER NOT NULL, KEY `k_a` (`a_id`), CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) );",
+			Expect: "ALTER TABLE `b` ADD KEY `k_a` (`a_id`);\nALTER TABLE `b` ADD CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) ON DELETE RESTRICT ON UPDATE RESTRICT;",
+		},
+		{
+			Name:   "drop foreign key before index",
+			Before: "CREATE TABLE `b` ( `id` INTEGER NOT NULL, `a_id` INTEGER NOT NULL, KEY `k_a` (`a_id`), CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) );",
+			After:  "CREATE TABLE `b` ( `id` INTEGER NOT NULL, `a_id` INTEGER NOT NULL );",
+			Expect: "ALTER TABLE `b` DROP FOREIGN KEY `fk_a`;\nALTER TABLE `b` DROP KEY `k_a`;",
+		},
+		{
+			Name:   "replace index needed by foreign key",
+			Before: "CREATE TABLE `b` ( `id` INTEGER NOT NULL, `a_id` INTEGER NOT NULL, KEY `k_a` (`a_id`), CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) );",
+			After:  "CREATE TABLE `b` ( `id` INTEGER NOT NULL, `a_id` INTEGER NOT NULL, KEY `k_a_id` (`a_id`, `id`), CONSTRAINT `fk_a` FOREIGN KEY (`a_id`) REFERENCES `a` (`id`) );",
+			Expect: "ALTER TABLE `b` ADD KEY `k_a_id` (`a_id`, `id`);\nALTER TABLE `b` DROP KEY `k_a`;",
+		},
+		{
+			Name:   "create view",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ); CREATE VIEW `v` AS SELECT id FROM fuga;",
+			Expect: "CREATE VIEW `v` AS SELECT id FROM fuga;",
+		},
+		{
+			Name:   "drop view",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ); CREATE VIEW `v` AS SELECT id FROM fuga;",
+			After:  "",
+			Expect: "DROP VIEW `v`;\n\nDROP TABLE `fuga`;",
+		},
+		{
+			Name:    "additive only",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, KEY `k_a` (`a`), KEY `k_id` (`id`) ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL, KEY `k_id` (`id`, `b`) );",
+			Options: []diff.Option{diff.WithAdditiveOnly(true)},
+			Expect:  "ALTER TABLE `fuga` DROP KEY `k_id`;\nALTER TABLE `fuga` ADD COLUMN `b` INT (11) NOT NULL AFTER `id`;\nALTER TABLE `fuga` ADD KEY `k_id` (`id`, `b`);",
+		},
+		{
+			Name:    "additive only with drops as comments",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL );",
+			Options: []diff.Option{diff.WithAdditiveOnly(true), diff.WithDropComments(true), diff.WithCoalesce(true)},
+			Expect:  "-- skipped (additive only): DROP TABLE `hoge`;\n\n-- skipped (additive only): ALTER TABLE `fuga` DROP COLUMN `a`;\nALTER TABLE `fuga` ADD COLUMN `b` INT (11) NOT NULL AFTER `id`;",
+		},
+		{
+			Name:   "view with formatting changes only",
+			Before: "CREATE VIEW `v` AS SELECT id, COUNT(*) FROM fuga WHERE name = 'Foo  Bar';",
+			After:  "CREATE VIEW v AS\n  select `id`,\n    count( * )\n  from `fuga`\n  where name='Foo  Bar';",
+			Expect: "",
+		},
+		{
+			Name:   "view read from a server",
+			Before: "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`localhost` SQL SECURITY DEFINER VIEW `v` AS SELECT id FROM fuga;",
+			After:  "CREATE VIEW `v` AS SELECT id FROM fuga;",
+			Expect: "",
+		},
+		{
+			Name:   "view with a different algorithm",
+			Before: "CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`localhost` SQL SECURITY DEFINER VIEW `v` AS SELECT id FROM fuga;",
+			After:  "CREATE ALGORITHM=MERGE DEFINER=CURRENT_USER VIEW `v` AS SELECT id FROM fuga;",
+			Expect: "CREATE OR REPLACE ALGORITHM = MERGE DEFINER = CURRENT_USER VIEW `v` AS SELECT id FROM fuga;",
+		},
+		{
+			Name:   "replace view",
+			Before: "CREATE VIEW `v` AS SELECT id FROM fuga WHERE name = 'foo';",
+			After:  "CREATE VIEW `v` AS SELECT id FROM fuga WHERE name = 'Foo';",
+			Expect: "CREATE OR REPLACE VIEW `v` AS SELECT id FROM fuga WHERE name = 'Foo';",
+		},
+		{
+			Name:   "create trigger",
+			Before: "",
+			After:  "CREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW SET NEW.id = 1;",
+			Expect: "CREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW SET NEW.id = 1;",
+		},
+		{
+			Name:   "create trigger with block",
+			Before: "",
+			After:  "CREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW BEGIN SET NEW.id = 1; END;",
+			Expect: "DELIMITER ;;\nCREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW BEGIN SET NEW.id = 1; END;;\nDELIMITER ;",
+		},
+		{
+			Name:   "drop trigger",
+			Before: "CREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW SET NEW.id = 1;",
+			After:  "",
+			Expect: "DROP TRIGGER `t`;",
+		},
+		{
+			Name:   "trigger with whitespace changes only",
+			Before: "CREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW BEGIN SET NEW.id = 1; END;",
+			After:  "CREATE TRIGGER t BEFORE INSERT ON fuga FOR EACH ROW\nBEGIN\n  SET NEW.id=1;\nEND;",
+			Expect: "",
+		},
+		{
+			Name:   "trigger read from a server",
+			Before: "CREATE DEFINER=`root`@`localhost` TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW SET NEW.id = 1;",
+			After:  "CREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW SET NEW.id = 1;",
+			Expect: "",
+		},
+		{
+			Name:   "change trigger",
+			Before: "CREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW SET NEW.id = 1;",
+			After:  "CREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW SET NEW.id = 2;",
+			Expect: "DROP TRIGGER `t`;\n\nCREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW SET NEW.id = 2;",
+		},
+		{
+			Name:   "change table options",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = MyISAM, COMMENT = 'foo', ROW_FORMAT = COMPACT;",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = InnoDB, AUTO_INCREMENT = 10;",
+			Expect: "ALTER TABLE `fuga` ENGINE = InnoDB;\nALTER TABLE `fuga` AUTO_INCREMENT = 10;\nALTER TABLE `fuga` COMMENT = '';\nALTER TABLE `fuga` ROW_FORMAT = DEFAULT;",
+		},
+		{
+			Name:   "defaults written differently",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` DECIMAL (10, 2) NOT NULL DEFAULT 0, `b` VARCHAR (20) NOT NULL DEFAULT 0, `c` VARCHAR (20) NOT NULL, `d` TEXT, `e` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` DECIMAL (10, 2) NOT NULL DEFAULT '0.00', `b` VARCHAR (20) NOT NULL DEFAULT '0', `c` VARCHAR (20) NOT NULL DEFAULT '', `d` TEXT DEFAULT NULL, `e` DATETIME NOT NULL DEFAULT now() );",
+			Expect: "",
+		},
+		{
+			Name:   "defaults differing in value",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` VARCHAR (20) NOT NULL DEFAULT '0' );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` VARCHAR (20) NOT NULL DEFAULT '0.0' );",
+			Expect: "ALTER TABLE `fuga` ALTER COLUMN `b` SET DEFAULT '0.0';",
+		},
+		{
+			Name:   "change column default only",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` VARCHAR (10) NOT NULL DEFAULT 'x' );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` VARCHAR (10) NOT NULL DEFAULT 'y' );",
+			Expect: "ALTER TABLE `fuga` ALTER COLUMN `a` SET DEFAULT 'y';",
+		},
+		{
+			Name:    "drop column default",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL DEFAULT 1 );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			Options: []diff.Option{diff.WithOnlineDDL(true), diff.WithMySQLVersion("8.0")},
+			Expect:  "ALTER TABLE `fuga` ALTER COLUMN `a` DROP DEFAULT, ALGORITHM=INSTANT;",
+		},
+		{
+			Name:   "change column default along with type",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL DEFAULT 1 );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` BIGINT NOT NULL DEFAULT 2 );",
+			Expect: "ALTER TABLE `fuga` CHANGE COLUMN `a` `a` BIGINT (20) NOT NULL DEFAULT 2;",
+		},
+		{
+			Name:   "rewrite changes",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ); CREATE TABLE `archived_fuga` ( `id` INTEGER NOT NULL );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL, INDEX `idx_a` (`a`) ); CREATE TABLE `archived_fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			Options: []diff.Option{
+				diff.WithCoalesce(true),
+				diff.WithRewriter(func(change diff.Change) (diff.Change, bool) {
+					return change, !strings.HasPrefix(change.Table, "archived_")
+				}),
+				diff.WithRewriter(func(change diff.Change) (diff.Change, bool) {
+					if change.Kind == diff.AddColumn {
+						change.SQL = strings.TrimSuffix(change.SQL, ";") + ", ALGORITHM=INSTANT;"
+						change.Comment = "instant"
+					}
+					return change, true
+				}),
+			},
+			Expect: "-- instant\nALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`, ALGORITHM=INSTANT;\n-- instant\nALTER TABLE `fuga` ADD COLUMN `b` INT (11) NOT NULL AFTER `a`, ALGORITHM=INSTANT;\nALTER TABLE `fuga` ADD KEY `idx_a` (`a`);",
+		},
+		{
+			Name:    "match indexes by columns",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL, INDEX `a` (`a`), UNIQUE INDEX `b` (`b`) );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL, INDEX `idx_a` (`a`), INDEX `idx_b` (`b`) );",
+			Options: []diff.Option{diff.WithMatchIndexesByColumns(true)},
+			Expect:  "ALTER TABLE `fuga` DROP KEY `b`;\nALTER TABLE `fuga` ADD KEY `idx_b` (`b`);",
+		},
+		{
+			Name:    "rename indexes matched by columns",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, INDEX `a` (`a`) );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, INDEX `idx_a` (`a`) );",
+			Options: []diff.Option{diff.WithMatchIndexesByColumns(true), diff.WithRenameIndexes(true)},
+			Expect:  "ALTER TABLE `fuga` RENAME INDEX `a` TO `idx_a`;",
+		},
+		{
+			Name:    "rename indexes before MySQL 5.7",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, INDEX `a` (`a`) );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, INDEX `idx_a` (`a`) );",
+			Options: []diff.Option{diff.WithMatchIndexesByColumns(true), diff.WithRenameIndexes(true), diff.WithMySQLVersion("5.6")},
+			Expect:  "",
+		},
+		{
+			Name:   "character set aliases",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) CHARACTER SET utf8 COLLATE utf8_bin NOT NULL ) DEFAULT CHARACTER SET = utf8;",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) CHARACTER SET utf8mb3 COLLATE utf8mb3_bin NOT NULL ) DEFAULT CHARACTER SET = utf8mb3;",
+			Expect: "",
+		},
+		{
+			Name:    "character set aliases with notes",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) CHARACTER SET utf8 COLLATE utf8_bin NOT NULL ) DEFAULT CHARACTER SET = utf8;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) CHARACTER SET utf8mb3 COLLATE utf8mb3_bin NOT NULL ) DEFAULT CHARACTER SET = utf8mb3;",
+			Options: []diff.Option{diff.WithCharsetAliasNotes(true)},
+			Expect:  "-- table `fuga`: utf8 is now written as utf8mb3\n-- column `fuga`.`name`: utf8 is now written as utf8mb3\n-- column `fuga`.`name`: utf8_bin is now written as utf8mb3_bin",
+		},
+		{
+			Name:   "character set aliases without collations",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (3) CHARACTER SET utf8 NOT NULL );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (3) CHARACTER SET utf8mb3 NOT NULL );",
+			Expect: "",
+		},
+		{
+			Name:    "lenient comparison of character set aliases without collations",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (3) CHARACTER SET utf8 NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (3) CHARACTER SET utf8mb3 NOT NULL );",
+			Options: []diff.Option{diff.WithStrictness(diff.StrictnessLenient)},
+			Expect:  "",
+		},
+		{
+			Name:    "strict comparison of character set aliases",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) CHARACTER SET utf8 NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) CHARACTER SET utf8mb3 NOT NULL );",
+			Options: []diff.Option{diff.WithStrictness(diff.StrictnessStrict)},
+			Expect:  "ALTER TABLE `fuga` CHANGE COLUMN `name` `name` VARCHAR (20) CHARACTER SET `utf8mb3` COLLATE `utf8mb3_general_ci` NOT NULL;",
+		},
+		{
+			Name:   "booleans",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` BOOLEAN NOT NULL DEFAULT TRUE, `b` BOOL DEFAULT FALSE );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` TINYINT (1) NOT NULL DEFAULT 1, `b` TINYINT (1) DEFAULT '0' );",
+			Expect: "",
+		},
+		{
+			Name:    "booleans ignoring integer display width",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` BOOLEAN NOT NULL, `b` TINYINT (3) NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` TINYINT NOT NULL, `b` TINYINT NOT NULL );",
+			Options: []diff.Option{diff.WithIgnoreIntDisplayWidth(true)},
+			Expect:  "ALTER TABLE `fuga` CHANGE COLUMN `a` `a` TINYINT (4) NOT NULL;",
+		},
+		{
+			Name:    "lenient comparison",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER (10) NOT NULL, `name` VARCHAR (20) CHARACTER SET utf8 COLLATE utf8_bin NOT NULL, `a` INTEGER NOT NULL DEFAULT 1 );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER (11) NOT NULL, `name` VARCHAR (20) CHARACTER SET utf8mb3 COLLATE utf8mb3_bin NOT NULL, `a` INTEGER NOT NULL DEFAULT '1' );",
+			Options: []diff.Option{diff.WithStrictness(diff.StrictnessLenient)},
+			Expect:  "",
+		},
+		{
+			Name:    "lenient comparison with integer display width",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER (10) NOT NULL, `a` INTEGER NOT NULL DEFAULT 1 );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER (11) NOT NULL, `a` INTEGER NOT NULL DEFAULT '1' );",
+			Options: []diff.Option{diff.WithIgnoreIntDisplayWidth(false), diff.WithStrictness(diff.StrictnessLenient)},
+			Expect:  "ALTER TABLE `fuga` CHANGE COLUMN `id` `id` INT (11) NOT NULL;",
+		},
+		{
+			Name:    "strict comparison of index order",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL, INDEX `a` (`a`), INDEX `b` (`b`) );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL, INDEX `b` (`b`), INDEX `a` (`a`) );",
+			Options: []diff.Option{diff.WithStrictness(diff.StrictnessStrict), diff.WithCoalesce(true)},
+			Expect:  "ALTER TABLE `fuga` DROP KEY `a`, ADD KEY `a` (`a`);",
+		},
+		{
+			Name:   "table options differing in case only",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = innodb;",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = InnoDB;",
+			Expect: "",
+		},
+		{
+			Name:   "convert table character set",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL ) DEFAULT CHARACTER SET = latin1;",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL ) DEFAULT CHARACTER SET = utf8mb4, DEFAULT COLLATE = utf8mb4_bin;",
+			Expect: "ALTER TABLE `fuga` CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_bin;",
+		},
+		{
+			Name:   "convert table collation",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL ) DEFAULT CHARACTER SET = utf8mb4, DEFAULT COLLATE = utf8mb4_general_ci;",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL ) DEFAULT CHARACTER SET = utf8mb4, DEFAULT COLLATE = utf8mb4_bin;",
+			Expect: "ALTER TABLE `fuga` CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_bin;",
+		},
+		{
+			Name:    "convert table character set per column",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL ) DEFAULT CHARACTER SET = latin1;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL ) DEFAULT CHARACTER SET = utf8mb4, DEFAULT COLLATE = utf8mb4_bin;",
+			Options: []diff.Option{diff.WithPerColumnCharset(true)},
+			Expect:  "ALTER TABLE `fuga` DEFAULT CHARACTER SET = utf8mb4;\nALTER TABLE `fuga` DEFAULT COLLATE = utf8mb4_bin;\nALTER TABLE `fuga` CHANGE COLUMN `name` `name` VARCHAR (20) CHARACTER SET `utf8mb4` COLLATE `utf8mb4_bin` NOT NULL;",
+		},
+		{
+			Name:    "ignore table comment",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) COMMENT = 'foo';",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) COMMENT = 'bar';",
+			Options: []diff.Option{diff.WithIgnoreComments(true)},
+			Expect:  "",
+		},
+		{
+			Name:    "coalesce",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, INDEX `ia` (`a`) );",
+			After:   "CREATE TABLE `fuga` ( `id` BIGINT NOT NULL, `b` INTEGER NOT NULL, INDEX `ib` (`b`) );",
+			Options: []diff.Option{diff.WithCoalesce(true)},
+			Expect:  "ALTER TABLE `fuga` DROP KEY `ia`, DROP COLUMN `a`, ADD COLUMN `b` INT (11) NOT NULL AFTER `id`, CHANGE COLUMN `id` `id` BIGINT (20) NOT NULL, ADD KEY `ib` (`b`);",
+		},
+		{
+			Name:    "ignore auto increment",
+			Before:  "",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL AUTO_INCREMENT ) AUTO_INCREMENT = 10, ENGINE = InnoDB;",
+			Options: []diff.Option{diff.WithIgnoreAutoIncrement(true)},
+			Expect:  "CREATE TABLE `fuga` (\n`id` INT (11) NOT NULL AUTO_INCREMENT\n) ENGINE = InnoDB;",
+		},
+		{
+			Name:    "rename table ignoring auto increment",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL AUTO_INCREMENT ) AUTO_INCREMENT = 10;",
+			After:   "CREATE TABLE `piyo` ( `id` INTEGER NOT NULL AUTO_INCREMENT ) AUTO_INCREMENT = 20;",
+			Options: []diff.Option{diff.WithIgnoreAutoIncrement(true), diff.WithDetectTableRename(true)},
+			Expect:  "RENAME TABLE `fuga` TO `piyo`;",
+		},
+		{
+			Name:    "ignore table options",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = InnoDB, ROW_FORMAT = COMPRESSED, STATS_PERSISTENT = 1;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ENGINE = MyISAM, ROW_FORMAT = DYNAMIC, STATS_PERSISTENT = 0, STATS_AUTO_RECALC = 1; CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ) ROW_FORMAT = DYNAMIC;",
+			Options: []diff.Option{diff.WithIgnoreTableOptions("row_format", "STATS_*")},
+			Expect:  "CREATE TABLE `hoge` (\n`id` INT (11) NOT NULL\n) ROW_FORMAT = DYNAMIC;\n\nALTER TABLE `fuga` ENGINE = MyISAM;",
+		},
+		{
+			Name:    "rename table ignoring table options",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) ROW_FORMAT = COMPRESSED;",
+			After:   "CREATE TABLE `piyo` ( `id` INTEGER NOT NULL ) ROW_FORMAT = DYNAMIC;",
+			Options: []diff.Option{diff.WithIgnoreTableOptions("ROW_FORMAT"), diff.WithDetectTableRename(true)},
+			Expect:  "RENAME TABLE `fuga` TO `piyo`;",
+		},
+		{
+			Name:    "explicit table and column renames",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `piyo` ( `id` INTEGER NOT NULL, `b` BIGINT NOT NULL, `c` INTEGER NOT NULL ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			Options: []diff.Option{diff.WithTableRenames(map[string]string{"fuga": "piyo"}), diff.WithColumnRenames("piyo", map[string]string{"a": "b"})},
+			Expect:  "RENAME TABLE `fuga` TO `piyo`;\n\nALTER TABLE `piyo` CHANGE COLUMN `a` `b` BIGINT (20) NOT NULL;\nALTER TABLE `piyo` ADD COLUMN `c` INT (11) NOT NULL AFTER `b`;",
+		},
+		{
+			Name:    "rename column on MySQL 8.0",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `x` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL, `y` BIGINT NOT NULL );",
+			Options: []diff.Option{diff.WithColumnRenames("fuga", map[string]string{"a": "b", "x": "y"}), diff.WithMySQLVersion("8.0")},
+			Expect:  "ALTER TABLE `fuga` RENAME COLUMN `a` TO `b`;\nALTER TABLE `fuga` CHANGE COLUMN `x` `y` BIGINT (20) NOT NULL;",
+		},
+		{
+			Name:    "rename indexed column",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, KEY `k_a` (`a`), UNIQUE KEY `u` (`id`, `a`) );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL, KEY `k_a` (`b`), UNIQUE KEY `u` (`id`, `b`) );",
+			Options: []diff.Option{diff.WithColumnRenames("fuga", map[string]string{"a": "b"}), diff.WithMySQLVersion("8.0")},
+			Expect:  "ALTER TABLE `fuga` RENAME COLUMN `a` TO `b`;",
+		},
+		{
+			Name:    "detect rename of indexed column",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, KEY `k_a` (`a`), UNIQUE KEY `u` (`id`, `a`) );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL, KEY `k_a` (`b`), UNIQUE KEY `u` (`id`, `b`) );",
+			Options: []diff.Option{diff.WithDetectColumnRename(true), diff.WithMySQLVersion("8.0")},
+			Expect:  "ALTER TABLE `fuga` RENAME COLUMN `a` TO `b`;",
+		},
+		{
+			Name:    "rename indexed column and change its index",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, KEY `k_a` (`a`) );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL, KEY `k_a` (`b`, `id`) );",
+			Options: []diff.Option{diff.WithColumnRenames("fuga", map[string]string{"a": "b"}), diff.WithMySQLVersion("8.0")},
+			Expect:  "ALTER TABLE `fuga` DROP KEY `k_a`;\nALTER TABLE `fuga` RENAME COLUMN `a` TO `b`;\nALTER TABLE `fuga` ADD KEY `k_a` (`b`, `id`);",
+		},
+		{
+			Name:    "append column instantly on MySQL 8.0",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `a` INTEGER NOT NULL, `id` INTEGER NOT NULL, `b` INTEGER NOT NULL );",
+			Options: []diff.Option{diff.WithOnlineDDL(true), diff.WithMySQLVersion("8.0.12")},
+			Expect:  "ALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL FIRST, ALGORITHM=INPLACE, LOCK=NONE;\nALTER TABLE `fuga` ADD COLUMN `b` INT (11) NOT NULL AFTER `id`, ALGORITHM=INSTANT;",
+		},
+		{
+			Name:   "add and drop check constraints",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `price` INTEGER NOT NULL, CONSTRAINT `chk_old` CHECK (`price` > 0) );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `price` INTEGER NOT NULL, CONSTRAINT `chk_new` CHECK (`price` < 100) NOT ENFORCED );",
+			Expect: "ALTER TABLE `fuga` DROP CHECK `chk_old`;\nALTER TABLE `fuga` ADD CONSTRAINT `chk_new` CHECK (`price` < 100) NOT ENFORCED;",
+		},
+		{
+			Name:   "change check constraint",
+			Before: "CREATE TABLE `fuga` ( `price` INTEGER NOT NULL, CONSTRAINT `chk_price` CHECK (`price` > 0) );",
+			After:  "CREATE TABLE `fuga` ( `price` INTEGER NOT NULL, CONSTRAINT `chk_price` CHECK (`price` >= 0) );",
+			Expect: "ALTER TABLE `fuga` DROP CHECK `chk_price`;\nALTER TABLE `fuga` ADD CONSTRAINT `chk_price` CHECK (`price` >= 0);",
+		},
+		{
+			Name:   "check constraint enforcement",
+			Before: "CREATE TABLE `fuga` ( `price` INTEGER NOT NULL, CONSTRAINT `chk_price` CHECK ((`price` > 0)), CONSTRAINT `chk_max` CHECK (price < 100) NOT ENFORCED );",
+			After:  "CREATE TABLE `fuga` ( `price` INTEGER NOT NULL, CONSTRAINT `chk_price` CHECK (price > 0) NOT ENFORCED, CONSTRAINT `chk_max` CHECK (price < 100) );",
+			Expect: "ALTER TABLE `fuga` ALTER CHECK `chk_price` NOT ENFORCED;\nALTER TABLE `fuga` ALTER CHECK `chk_max` ENFORCED;",
+		},
+		{
+			Name:   "check constraint on new column",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `price` INTEGER NOT NULL, CHECK (`price` > 0) );",
+			Expect: "ALTER TABLE `fuga` ADD COLUMN `price` INT (11) NOT NULL AFTER `id`;\nALTER TABLE `fuga` ADD CHECK (`price` > 0);",
+		},
+		{
+			Name:   "source comments are not compared",
+			Before: "-- old\nCREATE TABLE `fuga` ( `id` INTEGER NOT NULL, -- old\n `name` VARCHAR (20) NOT NULL );",
+			After:  "CREATE TABLE `fuga` (\n -- new\n `id` INTEGER NOT NULL,\n `name` VARCHAR (20) NOT NULL -- new\n);",
+			Expect: "",
+		},
+		{
+			Name:    "ignore comments",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL COMMENT 'old', `name` VARCHAR (20) NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL COMMENT 'new' );",
+			Options: []diff.Option{diff.WithIgnoreComments(true)},
+			Expect:  "",
+		},
+		{
+			Name:    "ignore comments with other changes",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL COMMENT 'old' );",
+			After:   "CREATE TABLE `fuga` ( `id` BIGINT NOT NULL COMMENT 'new' );",
+			Options: []diff.Option{diff.WithIgnoreComments(true)},
+			Expect:  "ALTER TABLE `fuga` CHANGE COLUMN `id` `id` BIGINT (20) NOT NULL COMMENT 'new';",
+		},
+		{
+			Name:    "include tables",
+			Before:  "CREATE TABLE `app_users` ( `id` INTEGER NOT NULL ); CREATE TABLE `tmp` ( `id` INTEGER NOT NULL );",
+			After:   "",
+			Options: []diff.Option{diff.WithIncludeTables("app_*")},
+			Expect:  "DROP TABLE `app_users`;",
+		},
+		{
+			Name:    "exclude tables",
+			Before:  "CREATE TABLE `app_users` ( `id` INTEGER NOT NULL ); CREATE TABLE `users_tmp` ( `id` INTEGER NOT NULL ); CREATE TABLE `_migrations` ( `id` INTEGER NOT NULL );",
+			After:   "",
+			Options: []diff.Option{diff.WithExcludeTables("*_tmp", "/^_mig/")},
+			Expect:  "DROP TABLE `app_users`;",
+		},
+		{
+			Name:    "online ddl",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` BIGINT NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL, INDEX `ia` (`a`) );",
+			Options: []diff.Option{diff.WithOnlineDDL(true)},
+			Expect:  "ALTER TABLE `fuga` ADD COLUMN `b` INT (11) NOT NULL AFTER `a`, ALGORITHM=INPLACE, LOCK=NONE;\nALTER TABLE `fuga` CHANGE COLUMN `id` `id` BIGINT (20) NOT NULL, ALGORITHM=COPY, LOCK=SHARED;\nALTER TABLE `fuga` ADD KEY `ia` (`a`), ALGORITHM=INPLACE, LOCK=NONE;",
+		},
+		{
+			Name:    "online ddl with coalesce and mysql 8.0",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL );",
+			Options: []diff.Option{diff.WithOnlineDDL(true), diff.WithCoalesce(true), diff.WithMySQLVersion("8.0.30")},
+			Expect:  "ALTER TABLE `fuga` DROP COLUMN `a`, ADD COLUMN `b` INT (11) NOT NULL AFTER `id`, ALGORITHM=INSTANT;",
+		},
+		{
+			Name:    "online ddl override",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, INDEX `ia` (`a`) );",
+			Options: []diff.Option{diff.WithOnlineDDL(true), diff.WithOnlineDDLOverride(diff.AddIndex, "INPLACE", "SHARED")},
+			Expect:  "ALTER TABLE `fuga` ADD KEY `ia` (`a`), ALGORITHM=INPLACE, LOCK=SHARED;",
+		},
+		{
+			Name:    "gh-ost",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL COMMENT 'it''s b' );",
+			Options: []diff.Option{diff.WithAlterMode(diff.AlterModeGhost), diff.WithDatabaseName("hoge"), diff.WithToolArgs("--execute")},
+			Expect:  "gh-ost \\\n  --database='hoge' \\\n  --table='fuga' \\\n  --alter='DROP COLUMN `a`, ADD COLUMN `b` INT (11) NOT NULL COMMENT '\\''it'\\''s b'\\'' AFTER `id`' \\\n  --execute",
+		},
+		{
+			Name:    "pt-online-schema-change",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL COMMENT 'it''s b' );",
+			Options: []diff.Option{diff.WithAlterMode(diff.AlterModePTOSC), diff.WithDatabaseName("hoge"), diff.WithToolArgs("--execute")},
+			Expect:  "pt-online-schema-change \\\n  --alter 'DROP COLUMN `a`, ADD COLUMN `b` INT (11) NOT NULL COMMENT '\\''it'\\''s b'\\'' AFTER `id`' \\\n  --execute \\\n  'D=hoge,t=fuga'",
+		},
+		{
+			Name:    "safety comments",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NULL, `b` INTEGER NOT NULL ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL, INDEX `b` (`b`) );",
+			Options: []diff.Option{diff.WithSafetyComments(true)},
+			Expect:  "-- safety: destructive\nDROP TABLE `hoge`;\n\n-- safety: destructive\nALTER TABLE `fuga` CHANGE COLUMN `a` `a` INT (11) NOT NULL;\n-- safety: safe\nALTER TABLE `fuga` ADD KEY `b` (`b`);",
+		},
+		{
+			Name:    "safety comments with coalesce",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` VARCHAR (20) NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` VARCHAR (20) NOT NULL COMMENT 'name', INDEX `a` (`a`) );",
+			Options: []diff.Option{diff.WithSafetyComments(true), diff.WithCoalesce(true)},
+			Expect:  "-- safety: blocking\nALTER TABLE `fuga` CHANGE COLUMN `a` `a` VARCHAR (20) NOT NULL COMMENT 'name', ADD KEY `a` (`a`);",
+		},
+		{
+			Name:    "intent comments",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NULL ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` BIGINT NOT NULL, `deleted_at` DATETIME NULL, INDEX `a` (`a`) );",
+			Options: []diff.Option{diff.WithIntentComments(true), diff.WithSafetyComments(true)},
+			Expect:  "-- drop table hoge: destructive\nDROP TABLE `hoge`;\n\n-- add column fuga.deleted_at (nullable, no default): safe, online\nALTER TABLE `fuga` ADD COLUMN `deleted_at` DATETIME DEFAULT NULL AFTER `a`;\n-- change column fuga.a (INT(11) to BIGINT(20), now NOT NULL): destructive, copies the table\nALTER TABLE `fuga` CHANGE COLUMN `a` `a` BIGINT (20) NOT NULL;\n-- add index fuga.a: safe, online\nALTER TABLE `fuga` ADD KEY `a` (`a`);",
+		},
+		{
+			Name:    "idempotent",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ); CREATE VIEW `v` AS SELECT 1;",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` BIGINT NOT NULL ); CREATE TABLE `piyo` ( `id` INTEGER NOT NULL ); CREATE VIEW `w` AS SELECT 1;",
+			Options: []diff.Option{diff.WithIdempotent(true), diff.WithCoalesce(true)},
+			Expect: "DROP VIEW IF EXISTS `v`;\n\n" +
+				"DROP TABLE IF EXISTS `hoge`;\n\n" +
+				"CREATE TABLE IF NOT EXISTS `piyo` (\n`id` INT (11) NOT NULL\n);\n\n" +
+				"DROP PROCEDURE IF EXISTS `schemalex_migrate`;\n" +
+				"DELIMITER ;;\n" +
+				"CREATE PROCEDURE `schemalex_migrate`()\nBEGIN\n" +
+				"  IF EXISTS (SELECT 1 FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'fuga' AND COLUMN_NAME = 'a') THEN\n" +
+				"    ALTER TABLE `fuga` DROP COLUMN `a`;\n" +
+				"  END IF;\n" +
+				"END;;\n" +
+				"DELIMITER ;\n" +
+				"CALL `schemalex_migrate`();\n" +
+				"DROP PROCEDURE `schemalex_migrate`;\n" +
+				"ALTER TABLE `fuga` CHANGE COLUMN `b` `b` BIGINT (20) NOT NULL;\n\n" +
+				"CREATE OR REPLACE VIEW `w` AS SELECT 1;",
+		},
+		{
+			Name:    "rename table",
+			Before:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ); CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `c` VARCHAR (20) );",
+			After:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ); CREATE TABLE `piyo` ( `id` INTEGER NOT NULL, `c` VARCHAR (20) );",
+			Options: []diff.Option{diff.WithDetectTableRename(true)},
+			Expect:  "RENAME TABLE `fuga` TO `piyo`;",
+		},
+		{
+			Name:    "rename table with indexes",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `c` INTEGER NOT NULL, PRIMARY KEY (`id`), KEY `c` (`c`) );",
+			After:   "CREATE TABLE `piyo` ( `id` INTEGER NOT NULL, `c` INTEGER NOT NULL, PRIMARY KEY (`id`), KEY `c` (`c`) );",
+			Options: []diff.Option{diff.WithDetectTableRename(true)},
+			Expect:  "RENAME TABLE `fuga` TO `piyo`;",
+		},
+		{
+			Name:    "explicit rename of table with indexes",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `c` INTEGER NOT NULL, PRIMARY KEY (`id`), KEY `c` (`c`) );",
+			After:   "CREATE TABLE `piyo` ( `id` INTEGER NOT NULL, `c` INTEGER NOT NULL, `d` INTEGER NOT NULL, PRIMARY KEY (`id`), KEY `c` (`c`) );",
+			Options: []diff.Option{diff.WithTableRenames(map[string]string{"fuga": "piyo"})},
+			Expect:  "RENAME TABLE `fuga` TO `piyo`;\n\nALTER TABLE `piyo` ADD COLUMN `d` INT (11) NOT NULL AFTER `c`;",
+		},
+		{
+			Name:    "rename table with different definition",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `piyo` ( `id` BIGINT NOT NULL );",
+			Options: []diff.Option{diff.WithDetectTableRename(true)},
+			Expect:  "DROP TABLE `fuga`;\n\nCREATE TABLE `piyo` (\n`id` BIGINT (20) NOT NULL\n);",
+		},
+		{
+			Name:    "rename column",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `nickname` VARCHAR (20) NOT NULL );",
+			Options: []diff.Option{diff.WithDetectColumnRename(true)},
+			Expect:  "ALTER TABLE `fuga` CHANGE COLUMN `name` `nickname` VARCHAR (20) NOT NULL;",
+		},
+		{
+			Name:    "rename column with a new column after it",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `nickname` VARCHAR (20) NOT NULL, `age` INTEGER NOT NULL );",
+			Options: []diff.Option{diff.WithDetectColumnRename(true)},
+			Expect:  "ALTER TABLE `fuga` CHANGE COLUMN `name` `nickname` VARCHAR (20) NOT NULL;\nALTER TABLE `fuga` ADD COLUMN `age` INT (11) NOT NULL AFTER `nickname`;",
+		},
+		{
+			Name:    "rename column with different definition",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `nickname` VARCHAR (40) NOT NULL );",
+			Options: []diff.Option{diff.WithDetectColumnRename(true)},
+			Expect:  "ALTER TABLE `fuga` DROP COLUMN `name`;\nALTER TABLE `fuga` ADD COLUMN `nickname` VARCHAR (40) NOT NULL AFTER `id`;",
+		},
+		{
+			Name:    "rename column below threshold",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `title` VARCHAR (20) NOT NULL );",
+			Options: []diff.Option{diff.WithDetectColumnRename(true), diff.WithColumnRenameThreshold(0.8)},
+			Expect:  "ALTER TABLE `fuga` DROP COLUMN `name`;\nALTER TABLE `fuga` ADD COLUMN `title` VARCHAR (20) NOT NULL AFTER `id`;",
+		},
+		{
+			Name:    "reorder columns",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL, `c` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `c` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL );",
+			Options: []diff.Option{diff.WithReorderColumns(true)},
+			Expect:  "ALTER TABLE `fuga` MODIFY COLUMN `c` INT (11) NOT NULL AFTER `id`;",
+		},
+		{
+			Name:    "reorder columns with changes and new columns",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `b` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `b` BIGINT NOT NULL, `x` INTEGER NOT NULL, `a` INTEGER NOT NULL, `id` INTEGER NOT NULL );",
+			Options: []diff.Option{diff.WithReorderColumns(true), diff.WithCoalesce(true)},
+			Expect:  "ALTER TABLE `fuga` MODIFY COLUMN `a` INT (11) NOT NULL AFTER `b`, MODIFY COLUMN `id` INT (11) NOT NULL AFTER `a`, ADD COLUMN `x` INT (11) NOT NULL AFTER `b`, CHANGE COLUMN `b` `b` BIGINT (20) NOT NULL;",
+		},
+		{
+			Name:   "column order is ignored by default",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			After:  "CREATE TABLE `fuga` ( `a` INTEGER NOT NULL, `id` INTEGER NOT NULL );",
+			Expect: "",
+		},
+		{
+			Name:    "ignore column order",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `b` INTEGER NOT NULL, `a` INTEGER NOT NULL, `id` INTEGER NOT NULL );",
+			Options: []diff.Option{diff.WithIgnoreColumnOrder(true), diff.WithReorderColumns(true)},
+			Expect:  "ALTER TABLE `fuga` ADD COLUMN `b` INT (11) NOT NULL;",
+		},
+		{
+			Name:   "add range partition",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) PARTITION BY RANGE (id) (PARTITION p0 VALUES LESS THAN (10));",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) PARTITION BY RANGE (`id`) (PARTITION p0 VALUES LESS THAN (10), PARTITION p1 VALUES LESS THAN (20));",
+			Expect: "ALTER TABLE `fuga` ADD PARTITION (PARTITION `p1` VALUES LESS THAN (20));",
+		},
+		{
+			Name:   "drop range partition",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) PARTITION BY RANGE (id) (PARTITION p0 VALUES LESS THAN (10), PARTITION p1 VALUES LESS THAN (20));",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) PARTITION BY RANGE (id) (PARTITION p1 VALUES LESS THAN (20));",
+			Expect: "ALTER TABLE `fuga` DROP PARTITION `p0`;",
+		},
+		{
+			Name:   "split range partition",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) PARTITION BY RANGE (id) (PARTITION p0 VALUES LESS THAN (10), PARTITION pmax VALUES LESS THAN MAXVALUE);",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) PARTITION BY RANGE (id) (PARTITION p0 VALUES LESS THAN (10), PARTITION p1 VALUES LESS THAN (20), PARTITION pmax VALUES LESS THAN MAXVALUE);",
+			Expect: "ALTER TABLE `fuga` REORGANIZE PARTITION `pmax` INTO (PARTITION `p1` VALUES LESS THAN (20), PARTITION `pmax` VALUES LESS THAN MAXVALUE);",
+		},
+		{
+			Name:   "reorganize range partitions",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) PARTITION BY RANGE (id) (PARTITION p0 VALUES LESS THAN (10), PARTITION p1 VALUES LESS THAN (20));",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) PARTITION BY RANGE (id) (PARTITION p0 VALUES LESS THAN (20));",
+			Expect: "ALTER TABLE `fuga` REORGANIZE PARTITION `p0`, `p1` INTO (PARTITION `p0` VALUES LESS THAN (20));",
+		},
+		{
+			Name:   "add hash partitions",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) PARTITION BY HASH (id) PARTITIONS 4;",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) PARTITION BY HASH (id) PARTITIONS 6;",
+			Expect: "ALTER TABLE `fuga` ADD PARTITION PARTITIONS 2;",
+		},
+		{
+			Name:   "coalesce hash partitions",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) PARTITION BY KEY (id) PARTITIONS 4;",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) PARTITION BY KEY (id) PARTITIONS 3;",
+			Expect: "ALTER TABLE `fuga` COALESCE PARTITION 1;",
+		},
+		{
+			Name:   "repartition",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) PARTITION BY HASH (id) PARTITIONS 4;",
+			Expect: "ALTER TABLE `fuga` PARTITION BY HASH (id) PARTITIONS 4;",
+		},
+		{
+			Name:    "transaction",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			Options: []diff.Option{diff.WithTransaction(true)},
+			Expect:  "\nBEGIN;\n\nSET FOREIGN_KEY_CHECKS = 0;\n\nALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;\n\nSET FOREIGN_KEY_CHECKS = 1;\n\nCOMMIT;",
+		},
+		{
+			Name:    "foreign key checks and sql_mode without transaction",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			Options: []diff.Option{diff.WithForeignKeyChecks(false), diff.WithSQLMode("NO_ENGINE_SUBSTITUTION")},
+			Expect:  "\nSET @OLD_SQL_MODE = @@SESSION.sql_mode;\nSET SESSION sql_mode = 'NO_ENGINE_SUBSTITUTION';\n\nSET FOREIGN_KEY_CHECKS = 0;\n\nALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;\n\nSET FOREIGN_KEY_CHECKS = 1;\n\nSET SESSION sql_mode = @OLD_SQL_MODE;",
+		},
+		{
+			Name:    "transaction with foreign key checks",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			Options: []diff.Option{diff.WithTransaction(true), diff.WithForeignKeyChecks(true)},
+			Expect:  "\nBEGIN;\n\nALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;\n\nCOMMIT;",
+		},
+		{
+			Name:    "custom delimiter",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL ); CREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW BEGIN SET NEW.id = 1; END;",
+			Options: []diff.Option{diff.WithDelimiter("$$")},
+			Expect:  "ALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`$$\n\nCREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW BEGIN SET NEW.id = 1; END$$",
+		},
+		{
+			Name:    "batches",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL ); CREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW BEGIN SET NEW.id = 1; END;",
+			Options: []diff.Option{diff.WithTransaction(true), diff.WithForeignKeyChecks(true), diff.WithBatchSeparator("GO"), diff.WithBatchSize(2)},
+			Expect:  "\nBEGIN;\n\nALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;\nGO\n\nDELIMITER ;;\nCREATE TRIGGER `t` BEFORE INSERT ON `fuga` FOR EACH ROW BEGIN SET NEW.id = 1; END;;\nDELIMITER ;\n\nCOMMIT;\nGO",
+		},
+		{
+			Name:    "single batch with trailing newline",
+			Before:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			Options: []diff.Option{diff.WithSafetyComments(true), diff.WithBatchSeparator("GO"), diff.WithTrailingNewline(true)},
+			Expect:  "-- safety: destructive\nALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;\nGO\n",
+		},
+		{
+			Name:   "remove partitioning",
+			Before: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ) PARTITION BY HASH (id) PARTITIONS 4;",
+			After:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			Expect: "ALTER TABLE `fuga` REMOVE PARTITIONING;",
+		},
+	}
+
+	for _, spec := range specs {
+		t.Run(spec.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if !assert.NoError(t, diff.Strings(&buf, spec.Before, spec.After, spec.Options...), "diff.Strings should succeed") {
+				return
+			}
+			assert.Equal(t, spec.Expect, buf.String(), "result SQL should match")
+		})
+	}
+}
+
+func TestDiffInvalidTableFilter(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Error(t, diff.Strings(&buf, "", "", diff.WithIncludeTables("[")), "invalid glob should result in an error")
+	assert.Error(t, diff.Strings(&buf, "", "", diff.WithExcludeTables("/(/")), "invalid regular expression should result in an error")
+}
+
+func TestDiffInvalidTableOptionPattern(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Error(t, diff.Strings(&buf, "", "", diff.WithIgnoreTableOptions("STATS_[")), "invalid glob should result in an error")
+}
+
+func TestDiffInvalidRenames(t *testing.T) {
+	before := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );"
+	after := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ); CREATE TABLE `piyo` ( `id` INTEGER NOT NULL );"
+
+	var buf bytes.Buffer
+	assert.Error(t, diff.Strings(&buf, before, after, diff.WithTableRenames(map[string]string{"fuga": "piyo"})), "renaming a table that is still in the new schema should result in an error")
+	assert.Error(t, diff.Strings(&buf, before, after, diff.WithTableRenames(map[string]string{"hoge": "piyo"})), "renaming a missing table should result in an error")
+	assert.Error(t, diff.Strings(&buf, before, after, diff.WithColumnRenames("fuga", map[string]string{"id": "uid"})), "renaming to a missing column should result in an error")
+	assert.Error(t, diff.Strings(&buf, before, after, diff.WithColumnRenames("hoge", map[string]string{"id": "uid"})), "renaming columns of a missing table should result in an error")
+}
+
+func TestDiffUnnamedCheck(t *testing.T) {
+	before := "CREATE TABLE `fuga` ( `price` INTEGER NOT NULL, CHECK (`price` > 0) );"
+	after := "CREATE TABLE `fuga` ( `price` INTEGER NOT NULL );"
+
+	var buf bytes.Buffer
+	assert.Error(t, diff.Strings(&buf, before, after), "dropping a check constraint without name should result in an error")
+	assert.NoError(t, diff.Strings(&buf, before, "CREATE TABLE `fuga` ( `price` INTEGER NOT NULL, CHECK (price > 0) );"), "the same check constraint written differently should not be dropped")
+}
+
+func TestDiffInvalidTerminators(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Error(t, diff.Strings(&buf, "", "", diff.WithDelimiter("")), "empty delimiter should result in an error")
+	assert.Error(t, diff.Strings(&buf, "", "", diff.WithBatchSize(-1)), "negative batch size should result in an error")
+}
+
+func TestDiffInvalidMySQLVersion(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Error(t, diff.Strings(&buf, "", "", diff.WithMySQLVersion("eight")), "invalid version should result in an error")
+}
+
+func TestDiffReverse(t *testing.T) {
+	before := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );"
+	after := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) NOT NULL ); CREATE TABLE `piyo` ( `id` INTEGER NOT NULL );"
+
+	var up, down bytes.Buffer
+	if !assert.NoError(t, diff.Strings(&up, before, after, diff.WithTransaction(false), diff.WithReverse(&down)), "diff.Strings should succeed") {
+		return
+	}
+	if !assert.Equal(t, "CREATE TABLE `piyo` (\n`id` INT (11) NOT NULL\n);\n\nALTER TABLE `fuga` ADD COLUMN `name` VARCHAR (20) NOT NULL AFTER `id`;", up.String(), "forward SQL should match") {
+		return
+	}
+	assert.Equal(t, "DROP TABLE `piyo`;\n\nALTER TABLE `fuga` DROP COLUMN `name`;", down.String(), "reverse SQL should match")
+}
+
+func TestCompute(t *testing.T) {
+	p := schemalex.New()
+	before, err := p.ParseString("CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );")
+	if !assert.NoError(t, err, "parsing before should succeed") {
+		return
+	}
+	after, err := p.ParseString("CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL ); CREATE VIEW `v` AS SELECT 1;")
+	if !assert.NoError(t, err, "parsing after should succeed") {
+		return
+	}
+
+	changes, err := diff.Compute(before, after, diff.WithCoalesce(true))
+	if !assert.NoError(t, err, "diff.Compute should succeed") {
+		return
+	}
+	expected := []diff.Change{
+		{Kind: diff.DropTable, Table: "hoge", SQL: "DROP TABLE `hoge`;", Safety: diff.Destructive},
+		{Kind: diff.DropColumn, Table: "fuga", Name: "a", SQL: "ALTER TABLE `fuga` DROP COLUMN `a`;", Safety: diff.Destructive},
+		{Kind: diff.AddColumn, Table: "fuga", Name: "b", SQL: "ALTER TABLE `fuga` ADD COLUMN `b` INT (11) NOT NULL AFTER `id`;", Safety: diff.Destructive},
+		{Kind: diff.CreateView, Name: "v", SQL: "CREATE VIEW `v` AS SELECT 1;", Safety: diff.Safe},
+	}
+	if !assert.Len(t, changes, len(expected), "number of changes should match") {
+		return
+	}
+	for i, change := range changes {
+		assert.Equal(t, expected[i].Kind, change.Kind, "kind should match")
+		assert.Equal(t, expected[i].Table, change.Table, "table should match")
+		assert.Equal(t, expected[i].Name, change.Name, "name should match")
+		assert.Equal(t, expected[i].OldName, change.OldName, "old name should match")
+		assert.Equal(t, expected[i].SQL, change.SQL, "SQL should match")
+		assert.Equal(t, expected[i].Safety, change.Safety, "safety should match")
+	}
+}
+
+func TestComputeProgress(t *testing.T) {
+	before := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );"
+	after := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL ); CREATE TABLE `piyo` ( `id` INTEGER NOT NULL );"
+
+	var progress []diff.Progress
+	_, err := diff.Compute(mustParse(t, before), mustParse(t, after), diff.WithProgress(func(p diff.Progress) {
+		progress = append(progress, p)
+	}))
+	if !assert.NoError(t, err, "diff.Compute should succeed") {
+		return
+	}
+	expected := []diff.Progress{
+		{Table: "hoge", Done: 1, Total: 3},
+		{Table: "piyo", Done: 2, Total: 3},
+		{Table: "fuga", Done: 3, Total: 3},
+	}
+	assert.Equal(t, expected, progress, "every table should be reported once")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = diff.Compute(mustParse(t, before), mustParse(t, after), diff.WithContext(ctx), diff.WithProgress(func(p diff.Progress) {
+		cancel()
+	}))
+	if assert.Error(t, err, "diff.Compute should fail once cancelled") {
+		assert.Equal(t, context.Canceled, errors.Cause(err), "error should be caused by the context")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	before := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, INDEX `a_idx` (`a`) ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );"
+	after := "CREATE TABLE `fuga` ( `id` BIGINT NOT NULL, `b` INTEGER NOT NULL DEFAULT 0, `c` INTEGER, INDEX `b_idx` (`b`) ); CREATE VIEW `v` AS SELECT 1;"
+
+	changes, err := diff.Compute(mustParse(t, before), mustParse(t, after))
+	if !assert.NoError(t, err, "diff.Compute should succeed") {
+		return
+	}
+	expected := diff.Summary{
+		TablesDropped:  1,
+		TablesAltered:  1,
+		ColumnsAdded:   2,
+		ColumnsDropped: 1,
+		ColumnsChanged: 1,
+		IndexesAdded:   1,
+		IndexesDropped: 1,
+		Destructive:    2,
+		Tables: []diff.TableSummary{
+			{Name: "fuga", Additions: 3, Removals: 2, Modifications: 1, Destructive: 1},
+			{Name: "hoge", Removals: 1, Destructive: 1},
+			{Name: "v", Additions: 1},
+		},
+	}
+	assert.Equal(t, expected, diff.Summarize(changes), "summary should match")
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, diff.Strings(&buf, before, after, diff.WithOutputFormat(diff.OutputFormatStat)), "diff.Strings should succeed") {
+		return
+	}
+	expectedStat := " fuga | 6 +++--~ (1 destructive)\n" +
+		" hoge | 1 - (1 destructive)\n" +
+		" v    | 1 +\n" +
+		" 3 tables changed, 4 additions(+), 3 removals(-), 1 modification(~), 2 destructive\n"
+	assert.Equal(t, expectedStat, buf.String(), "stat should match")
+
+	buf.Reset()
+	if !assert.NoError(t, diff.Strings(&buf, before, after, diff.WithOutputFormat(diff.OutputFormatShortStat)), "diff.Strings should succeed") {
+		return
+	}
+	assert.Equal(t, "0 tables created, 1 altered, 1 dropped, 2 destructive changes\n", buf.String(), "shortstat should match")
+	assert.Equal(t, "no changes", diff.Summary{}.ShortStat(), "shortstat without changes should match")
+}
+
+func TestPlan(t *testing.T) {
+	before := "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );\n" +
+		"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `email` VARCHAR (191) NOT NULL, `old` INTEGER NOT NULL );"
+	after := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `email` VARCHAR (255) NOT NULL, `deleted_at` DATETIME, INDEX `idx_email` (`email`) );"
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, diff.Strings(&buf, before, after, diff.WithOutputFormat(diff.OutputFormatPlan)), "diff.Strings should succeed") {
+		return
+	}
+	expected := "- drop table hoge # destructive\n" +
+		"- drop column fuga.old # destructive\n" +
+		"+ add column fuga.deleted_at: nullable, no default\n" +
+		"~ change column fuga.email: VARCHAR(191) to VARCHAR(255)\n" +
+		"+ add index fuga.idx_email\n" +
+		"\nPlan: 2 to add, 1 to change, 2 to remove.\n"
+	assert.Equal(t, expected, buf.String(), "plan should match")
+
+	buf.Reset()
+	if !assert.NoError(t, diff.Strings(&buf, after, after, diff.WithOutputFormat(diff.OutputFormatPlan), diff.WithColor(true)), "diff.Strings should succeed") {
+		return
+	}
+	assert.Equal(t, "No changes.\n", buf.String(), "empty plan should match")
+
+	buf.Reset()
+	if !assert.NoError(t, diff.Strings(&buf, "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );", "", diff.WithOutputFormat(diff.OutputFormatPlan), diff.WithColor(true)), "diff.Strings should succeed") {
+		return
+	}
+	assert.Equal(t, "\x1b[31m- drop table hoge\x1b[0m # destructive\n\nPlan: 0 to add, 0 to change, 1 to remove.\n", buf.String(), "colored plan should match")
+}
+
+func TestDiffColor(t *testing.T) {
+	before := "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );\n" +
+		"CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );"
+	after := "CREATE TABLE `fuga` ( `id` BIGINT NOT NULL, `a` INTEGER NOT NULL );"
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, diff.Strings(&buf, before, after, diff.WithTransaction(false), diff.WithColor(true)), "diff.Strings should succeed") {
+		return
+	}
+	expected := "\x1b[31mDROP TABLE `hoge`;\x1b[0m\n\n" +
+		"\x1b[32mALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;\x1b[0m\n" +
+		"\x1b[33mALTER TABLE `fuga` CHANGE COLUMN `id` `id` BIGINT (20) NOT NULL;\x1b[0m"
+	assert.Equal(t, expected, buf.String(), "statements should be colored after what they do")
+
+	buf.Reset()
+	if !assert.NoError(t, diff.Strings(&buf, before, after, diff.WithTransaction(false), diff.WithColor(true), diff.WithCoalesce(true)), "diff.Strings should succeed") {
+		return
+	}
+	assert.Contains(t, buf.String(), "\x1b[33mALTER TABLE `fuga`", "statements combining changes should be colored as modifications")
+}
+
+type failingSource struct{}
+
+func (failingSource) WriteSchema(io.Writer) error {
+	return errors.New("unreachable")
+}
+
+func TestCheckShards(t *testing.T) {
+	reference := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );"
+	shards := []diff.Shard{
+		{Name: "shard1", Source: schemalex.NewReaderSource(strings.NewReader(reference))},
+		{Name: "shard2", Source: schemalex.NewReaderSource(strings.NewReader("CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );"))},
+		{Name: "shard3", Source: failingSource{}},
+	}
+
+	results, err := diff.CheckShards(schemalex.NewReaderSource(strings.NewReader(reference)), shards)
+	if !assert.NoError(t, err, "diff.CheckShards should succeed") {
+		return
+	}
+	if !assert.Len(t, results, len(shards), "every shard should have a result") {
+		return
+	}
+
+	assert.Equal(t, "shard1", results[0].Name, "results should be in the order of shards")
+	assert.False(t, results[0].Deviates(), "identical shard should not deviate")
+	assert.NoError(t, results[0].Err, "identical shard should be compared")
+
+	assert.True(t, results[1].Deviates(), "different shard should deviate")
+	if assert.Len(t, results[1].Changes, 1, "changes to the shard should be returned") {
+		assert.Equal(t, "ALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;", results[1].Changes[0].SQL, "shard should be brought in line with the reference")
+	}
+
+	assert.Error(t, results[2].Err, "unreachable shard should have an error")
+	assert.False(t, results[2].Deviates(), "unreachable shard should not be reported as deviating")
+}
+
+func TestDiffWarnings(t *testing.T) {
+	before := "CREATE TABLE `fuga` ( `id` BIGINT NOT NULL, `a` VARCHAR (255) NOT NULL, `b` INTEGER NOT NULL, `c` TEXT, `d` ENUM('x', 'y') );"
+	after := "CREATE TABLE `fuga` ( `id` INT NOT NULL, `a` VARCHAR (50) NOT NULL, `b` BIGINT NOT NULL, `c` VARCHAR (100), `d` ENUM('x') );"
+
+	changes, err := diff.Compute(mustParse(t, before), mustParse(t, after))
+	if !assert.NoError(t, err, "diff.Compute should succeed") {
+		return
+	}
+	warnings := make(map[string]string)
+	for _, change := range changes {
+		warnings[change.Name] = change.Warning
+		if change.Warning != "" {
+			assert.Equal(t, diff.Destructive, change.Safety, "changes with warnings should be destructive")
+		}
+	}
+	assert.Equal(t, "column `fuga`.`id`: changing BIGINT(20) to INT(11) may truncate existing values", warnings["id"], "narrowing integers should be warned")
+	assert.Equal(t, "column `fuga`.`a`: changing VARCHAR(255) to VARCHAR(50) may truncate existing values", warnings["a"], "shortening strings should be warned")
+	assert.Equal(t, "", warnings["b"], "widening integers should not be warned")
+	assert.Equal(t, "column `fuga`.`c`: changing TEXT to VARCHAR(100) may truncate existing values", warnings["c"], "TEXT to VARCHAR should be warned")
+	assert.Equal(t, "column `fuga`.`d`: removing values from ENUM invalidates rows that use them", warnings["d"], "removing enum values should be warned")
+
+	added, err := diff.Compute(mustParse(t, "CREATE TABLE `fuga` ( `id` INT NOT NULL );"), mustParse(t, "CREATE TABLE `fuga` ( `id` INT NOT NULL AUTO_INCREMENT, `e` INT NOT NULL, `f` VARCHAR (20) NOT NULL DEFAULT '', `g` INT, `h` INT AS (`id` + 1) NOT NULL, `i` BIGINT NOT NULL AUTO_INCREMENT );"))
+	if !assert.NoError(t, err, "diff.Compute should succeed") {
+		return
+	}
+	warnings = make(map[string]string)
+	safety := make(map[string]diff.Safety)
+	for _, change := range added {
+		warnings[change.Name] = change.Warning
+		safety[change.Name] = change.Safety
+	}
+	assert.Equal(t, "column `fuga`.`e`: adding it NOT NULL without a default value fills existing rows with the implicit default of INT", warnings["e"], "adding NOT NULL columns without a default should be warned")
+	assert.Equal(t, diff.Destructive, safety["e"], "adding NOT NULL columns without a default should need review")
+	for _, name := range []string{"f", "g", "h", "i"} {
+		assert.Equal(t, "", warnings[name], "adding column %s should not be warned", name)
+		assert.Equal(t, diff.Safe, safety[name], "adding column %s should be safe", name)
+	}
+
+	var buf, warn bytes.Buffer
+	if assert.NoError(t, diff.Strings(&buf, before, after, diff.WithWarnings(&warn)), "diff.Strings should succeed") {
+		assert.Len(t, strings.Split(strings.TrimSpace(warn.String()), "\n"), 4, "every warning should be written")
+	}
+	assert.Error(t, diff.Strings(&buf, before, after, diff.WithSafe(true)), "diff.Strings should fail in safe mode")
+	assert.NoError(t, diff.Strings(&buf, "CREATE TABLE `fuga` ( `id` INT NOT NULL );", "CREATE TABLE `fuga` ( `id` BIGINT NOT NULL );", diff.WithSafe(true)), "widening should be allowed in safe mode")
+}
+
+func TestDiffLogger(t *testing.T) {
+	before := "CREATE TABLE `hoge` ( `id` INT NOT NULL ); CREATE TABLE `fuga` ( `id` INT (11) NOT NULL ); CREATE TABLE `piyo` ( `id` INT NOT NULL );"
+	after := "CREATE TABLE `hoge` ( `id` INT NOT NULL, `a` INT NOT NULL ); CREATE TABLE `fuga` ( `id` INT (10) NOT NULL ); CREATE TABLE `bar` ( `id` INT NOT NULL );"
+
+	for _, debug := range []bool{false, true} {
+		var buf, trace bytes.Buffer
+		logger := log.New(&trace, "", 0)
+		options := []diff.Option{
+			diff.WithLogger(logger),
+			diff.WithDebug(debug),
+			diff.WithStrictness(diff.StrictnessLenient),
+			diff.WithExcludeTables("piyo"),
+		}
+		if !assert.NoError(t, diff.Strings(&buf, before, after, options...), "diff.Strings should succeed") {
+			return
+		}
+		assert.Contains(t, trace.String(), "parsed \"from\" schema in ", "parsing should be traced")
+		assert.Contains(t, trace.String(), "table bar: created\n", "created tables should be traced")
+		assert.Contains(t, trace.String(), "table hoge: altered with 1 clauses\n", "altered tables should be traced")
+		assert.Contains(t, trace.String(), "compared 3 tables in ", "comparison should be traced")
+		if debug {
+			assert.Contains(t, trace.String(), "table piyo: left out by the table filters\n", "filtered tables should be traced in debug mode")
+			assert.Contains(t, trace.String(), "table fuga: definitions differ, but only in ways that are ignored or normalized away\n", "normalized differences should be traced in debug mode")
+		} else {
+			assert.NotContains(t, trace.String(), "piyo", "filtered tables should only be traced in debug mode")
+		}
+	}
+}
+
+func TestDiffFailOnDestructive(t *testing.T) {
+	before := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );"
+	after := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL DEFAULT 0 );"
+
+	var buf bytes.Buffer
+	err := diff.Strings(&buf, before, after, diff.WithFailOnDestructive(true))
+	derr, ok := err.(*diff.DestructiveChangesError)
+	if !assert.True(t, ok, "diff.Strings should fail with DestructiveChangesError") {
+		return
+	}
+	if assert.Len(t, derr.Changes, 2, "every destructive change should be reported") {
+		assert.Equal(t, diff.DropTable, derr.Changes[0].Kind, "dropping a table should be reported")
+		assert.Equal(t, diff.DropColumn, derr.Changes[1].Kind, "dropping a column should be reported")
+	}
+	assert.Empty(t, buf.String(), "nothing should be written")
+
+	assert.NoError(t, diff.Strings(&buf, before, after, diff.WithFailOnDestructive(true), diff.WithAdditiveOnly(true)), "suppressed drops should not fail")
+}
+
+func TestDiffJSON(t *testing.T) {
+	before := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );"
+	after := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` BIGINT NOT NULL );"
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, diff.Strings(&buf, before, after, diff.WithOutputFormat(diff.OutputFormatJSON)), "diff.Strings should succeed") {
+		return
+	}
+
+	var result struct {
+		Changes []map[string]string `json:"changes"`
+	}
+	if !assert.NoError(t, json.Unmarshal(buf.Bytes(), &result), "output should be valid JSON") {
+		return
+	}
+	expected := []map[string]string{
+		{
+			"kind":   "change-column",
+			"table":  "fuga",
+			"name":   "a",
+			"before": "`a` INT (11) NOT NULL",
+			"after":  "`a` BIGINT (20) NOT NULL",
+			"sql":    "ALTER TABLE `fuga` CHANGE COLUMN `a` `a` BIGINT (20) NOT NULL;",
+			"safety": "blocking",
+		},
+	}
+	assert.Equal(t, expected, result.Changes, "changes should match")
+}
+
+func TestNormalize(t *testing.T) {
+	src := "CREATE VIEW `v` AS SELECT 1;\n" +
+		"CREATE TABLE `b` ( `id` INT (10) NOT NULL DEFAULT '0', `price` DECIMAL (10,2) NOT NULL DEFAULT '1.50' COMMENT 'in yen', `at` DATETIME DEFAULT NOW() ) AUTO_INCREMENT = 10 COMMENT 'prices';\n" +
+		"CREATE TABLE `a` ( `name` VARCHAR (20) CHARACTER SET utf8mb3 );\n" +
+		"CREATE TABLE `tmp_a` ( `id` INT );"
+
+	var buf bytes.Buffer
+	err := diff.NormalizeSource(&buf, schemalex.NewReaderSource(strings.NewReader(src)),
+		diff.WithStrictness(diff.StrictnessLenient),
+		diff.WithIgnoreAutoIncrement(true),
+		diff.WithIgnoreComments(true),
+		diff.WithExcludeTables("tmp_*"),
+	)
+	if !assert.NoError(t, err, "diff.NormalizeSource should succeed") {
+		return
+	}
+	expected := "CREATE TABLE `a` (\n" +
+		"`name` VARCHAR (20) CHARACTER SET `utf8` COLLATE `utf8_general_ci` DEFAULT NULL\n" +
+		");\n\n" +
+		"CREATE TABLE `b` (\n" +
+		"`id` INT NOT NULL DEFAULT 0,\n" +
+		"`price` DECIMAL (10,2) NOT NULL DEFAULT 1.5 COMMENT '',\n" +
+		"`at` DATETIME DEFAULT CURRENT_TIMESTAMP\n" +
+		");\n\n" +
+		"CREATE VIEW `v` AS SELECT 1;\n"
+	assert.Equal(t, expected, buf.String(), "tables should be normalized and sorted before views")
+}
+
+func mustParse(t *testing.T, s string) model.Stmts {
+	stmts, err := schemalex.New().ParseString(s)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %s", s, err)
+	}
+	return stmts
+}
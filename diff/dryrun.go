@@ -0,0 +1,164 @@
+package diff
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// DryRunError is returned by DryRun when the schema of the scratch
+// database does not match the new schema after the changes are applied
+type DryRunError struct {
+	// Changes are the changes still needed to reach the new schema
+	Changes []Change
+}
+
+// Error lists the remaining statements, each annotated with its safety
+// as with WithSafetyComments
+func (e *DryRunError) Error() string {
+	var buf bytes.Buffer
+	buf.WriteString("schema does not match after applying changes, remaining:")
+	for _, change := range e.Changes {
+		buf.WriteByte('\n')
+		writeSafetyComment(&buf, "-- ", change.Safety)
+		buf.WriteString(change.SQL)
+	}
+	return buf.String()
+}
+
+// DryRun validates the changes between two schemas against a scratch
+// database. It creates a temporary database on the server given by
+// dsn, creates the old schema in it, applies the changes computed by
+// Compute, and verifies that the resulting schema matches the new one.
+// The temporary database is dropped afterwards, whatever the outcome.
+//
+// Only the options that decide which changes are computed, such as
+// WithMySQLVersion or WithColumnRenames, are passed to Compute and
+// Apply. The others, such as WithAlterMode, WithIdempotent or WithHook,
+// are ignored, so that the changes are applied as plain SQL. A
+// *DryRunError is returned if the schemas do not match, which means the
+// generated statements do not do what they are meant to.
+func DryRun(ctx context.Context, dsn string, from, to model.Stmts, options ...Option) error {
+	options = dryRunOptions(options)
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return errors.Wrap(err, `failed to parse DSN`)
+	}
+
+	server, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return errors.Wrap(err, `failed to open connection to database`)
+	}
+	defer server.Close()
+
+	name, err := scratchDatabaseName()
+	if err != nil {
+		return err
+	}
+	if _, err := server.ExecContext(ctx, "CREATE DATABASE `"+name+"`"); err != nil {
+		return errors.Wrapf(err, `failed to create scratch database %s`, name)
+	}
+	defer server.ExecContext(context.Background(), "DROP DATABASE `"+name+"`")
+
+	cfg.DBName = name
+	scratchDSN := cfg.FormatDSN()
+	db, err := sql.Open("mysql", scratchDSN)
+	if err != nil {
+		return errors.Wrapf(err, `failed to open connection to scratch database %s`, name)
+	}
+	defer db.Close()
+
+	setup, err := Compute(nil, from, options...)
+	if err != nil {
+		return errors.Wrap(err, `failed to compute old schema`)
+	}
+	if err := Apply(ctx, db, setup, options...); err != nil {
+		return errors.Wrap(err, `failed to create old schema`)
+	}
+
+	changes, err := Compute(from, to, options...)
+	if err != nil {
+		return errors.Wrap(err, `failed to compute changes`)
+	}
+	if err := Apply(ctx, db, changes, options...); err != nil {
+		return errors.Wrap(err, `failed to apply changes`)
+	}
+
+	var buf bytes.Buffer
+	if err := schemalex.NewMySQLSource(scratchDSN).WriteSchema(&buf); err != nil {
+		return errors.Wrapf(err, `failed to read schema of scratch database %s`, name)
+	}
+	result, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, `failed to parse schema of scratch database %s`, name)
+	}
+
+	remaining, err := Compute(result, to, options...)
+	if err != nil {
+		return errors.Wrap(err, `failed to compare schemas`)
+	}
+	if len(remaining) > 0 {
+		return &DryRunError{Changes: remaining}
+	}
+	return nil
+}
+
+// dryRunOptionNames are the names of the options used by DryRun
+var dryRunOptionNames = map[string]bool{
+	optkeyParser:                true,
+	optkeyMySQLVersion:          true,
+	optkeyIgnoreComments:        true,
+	optkeyIgnoreTableOptions:    true,
+	optkeyIgnoreAutoIncrement:   true,
+	optkeyIncludeTables:         true,
+	optkeyExcludeTables:         true,
+	optkeyDetectTableRename:     true,
+	optkeyDetectColumnRename:    true,
+	optkeyTableRenames:          true,
+	optkeyColumnRenames:         true,
+	optkeyColumnRenameThreshold: true,
+	optkeyReorderColumns:        true,
+	optkeyIgnoreColumnOrder:     true,
+	optkeyPerColumnCharset:      true,
+	optkeyAdditiveOnly:          true,
+	optkeyRewriter:              true,
+	optkeyMatchIndexesByColumns: true,
+	optkeyRenameIndexes:         true,
+	optkeyStrictness:            true,
+	optkeyIgnoreIntDisplayWidth: true,
+	optkeyIgnoreCharsetAliases:  true,
+	optkeyIgnoreDefaultQuoting:  true,
+	optkeyCompareIndexOrder:     true,
+	optkeyContext:               true,
+	optkeyStatementTimeout:      true,
+}
+
+// dryRunOptions returns the options that decide which changes are
+// computed, leaving out the ones that only decide how they are
+// rendered or applied
+func dryRunOptions(options []Option) []Option {
+	l := make([]Option, 0, len(options))
+	for _, o := range options {
+		if dryRunOptionNames[o.Name()] {
+			l = append(l, o)
+		}
+	}
+	return l
+}
+
+// scratchDatabaseName returns a random name for the temporary database
+// created by DryRun
+func scratchDatabaseName() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, `failed to generate scratch database name`)
+	}
+	return fmt.Sprintf("schemalex_dryrun_%x", b), nil
+}
@@ -0,0 +1,98 @@
+package diff_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/schemalex/schemalex/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRunError(t *testing.T) {
+	for _, spec := range []struct {
+		Name   string
+		Before string
+		After  string
+		Expect string
+	}{
+		{
+			Name:   "non-destructive change",
+			Before: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			After:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ); CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			Expect: "schema does not match after applying changes, remaining:\n" +
+				"-- safety: safe\n" +
+				"CREATE TABLE `fuga` (\n`id` INT (11) NOT NULL\n);",
+		},
+		{
+			Name:   "destructive change",
+			Before: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `name` VARCHAR (20) );",
+			After:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			Expect: "schema does not match after applying changes, remaining:\n" +
+				"-- safety: destructive\n" +
+				"ALTER TABLE `hoge` DROP COLUMN `name`;",
+		},
+		{
+			Name:   "destructive and non-destructive changes",
+			Before: "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ); CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			After:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ); CREATE TABLE `piyo` ( `id` INTEGER NOT NULL );",
+			Expect: "schema does not match after applying changes, remaining:\n" +
+				"-- safety: destructive\n" +
+				"DROP TABLE `fuga`;\n" +
+				"-- safety: safe\n" +
+				"CREATE TABLE `piyo` (\n`id` INT (11) NOT NULL\n);",
+		},
+	} {
+		t.Run(spec.Name, func(t *testing.T) {
+			changes, err := diff.Compute(mustParse(t, spec.Before), mustParse(t, spec.After))
+			if !assert.NoError(t, err, "diff.Compute should succeed") {
+				return
+			}
+			err = &diff.DryRunError{Changes: changes}
+			assert.Equal(t, spec.Expect, err.Error(), "remaining changes should be annotated with their safety")
+		})
+	}
+}
+
+func TestDryRunInvalidDSN(t *testing.T) {
+	err := diff.DryRun(context.Background(), "not a dsn", mustParse(t, ""), mustParse(t, ""))
+	assert.Error(t, err, "invalid DSNs should be rejected")
+}
+
+func TestDryRunOptions(t *testing.T) {
+	before := mustParse(t, "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );")
+	after := mustParse(t, "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL, `b` INTEGER NOT NULL );")
+	renames := []diff.Option{
+		diff.WithMySQLVersion("8.0"),
+		diff.WithColumnRenames("hoge", map[string]string{"a": "b"}),
+	}
+	options := append([]diff.Option{
+		diff.WithAlterMode(diff.AlterModeGhost),
+		diff.WithDatabaseName("app"),
+		diff.WithOnlineDDL(true),
+		diff.WithCoalesce(true),
+		diff.WithIdempotent(true),
+		diff.WithSafetyComments(true),
+	}, renames...)
+
+	rendered, err := diff.Compute(before, after, options...)
+	if !assert.NoError(t, err, "diff.Compute should succeed") {
+		return
+	}
+	assert.Contains(t, rendered[0].SQL, "gh-ost", "changes should be rendered as gh-ost commands")
+
+	expected, err := diff.Compute(before, after, renames...)
+	if !assert.NoError(t, err, "diff.Compute should succeed") {
+		return
+	}
+	changes, err := diff.Compute(before, after, diff.DryRunOptions(options)...)
+	if !assert.NoError(t, err, "diff.Compute should succeed") {
+		return
+	}
+	if !assert.Len(t, changes, len(expected), "the same changes should be computed") {
+		return
+	}
+	for i, change := range changes {
+		assert.Equal(t, expected[i].SQL, change.SQL, "changes should be rendered as plain SQL")
+	}
+	assert.Equal(t, "ALTER TABLE `hoge` RENAME COLUMN `a` TO `b`;", changes[0].SQL, "options deciding the changes should be kept")
+}
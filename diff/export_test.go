@@ -0,0 +1,4 @@
+package diff
+
+// DryRunOptions exposes dryRunOptions to the tests
+var DryRunOptions = dryRunOptions
@@ -0,0 +1,213 @@
+package diff
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/deckarep/golang-set"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// tableFilter describes how to copy a table. Only the indexes and
+// options for which the corresponding functions return true are
+// copied, and columns are replaced with the result of the column
+// function. Nil functions copy everything as is.
+type tableFilter struct {
+	column func(model.TableColumn) model.TableColumn
+	index  func(model.Index) bool
+	option func(model.TableOption) bool
+}
+
+// apply returns a filtered copy of the table
+func (f tableFilter) apply(table model.Table) model.Table {
+	t := model.NewTable(table.Name())
+	t.SetTemporary(table.IsTemporary())
+	t.SetIfNotExists(table.IsIfNotExists())
+	if table.HasLikeTable() {
+		t.SetLikeTable(table.LikeTable())
+	}
+	for col := range table.Columns() {
+		if f.column != nil {
+			col = f.column(col)
+		}
+		t.AddColumn(col)
+	}
+	for idx := range table.Indexes() {
+		if f.index != nil && !f.index(idx) {
+			continue
+		}
+		t.AddIndex(idx)
+	}
+	for check := range table.Checks() {
+		t.AddCheck(check)
+	}
+	for opt := range table.Options() {
+		if f.option != nil && !f.option(opt) {
+			continue
+		}
+		t.AddOption(opt)
+	}
+	t.SetPartitioning(table.Partitioning())
+	return t
+}
+
+// withoutOptions returns a function to be used as tableFilter.option
+// that drops the options with the given keys
+func withoutOptions(keys ...string) func(model.TableOption) bool {
+	return func(opt model.TableOption) bool {
+		for _, key := range keys {
+			if strings.EqualFold(opt.Key(), key) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// matchTableOption reports whether the key of the option matches any
+// of the patterns, ignoring case
+func matchTableOption(opt model.TableOption, patterns []string) bool {
+	key := strings.ToUpper(opt.Key())
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToUpper(pattern), key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutComment returns a copy of the column with an empty comment,
+// so that columns which only differ by their comments compare equal
+func withoutComment(col model.TableColumn) model.TableColumn {
+	return col.Clone().SetComment("")
+}
+
+// withoutSourceComments returns a copy of the column without the
+// comments written around it in the source, nor the line it is on,
+// which are never compared
+func withoutSourceComments(col model.TableColumn) model.TableColumn {
+	if len(col.LeadingComments()) == 0 && col.TrailingComment() == "" && col.Line() == 0 {
+		return col
+	}
+	return col.Clone().SetLeadingComments(nil).SetTrailingComment("").SetLine(0)
+}
+
+// tableWithoutIndexes returns a copy of the table, minus the
+// indexes whose IDs are listed in exclude
+func tableWithoutIndexes(table model.Table, exclude mapset.Set) model.Table {
+	f := tableFilter{
+		index: func(idx model.Index) bool {
+			return !exclude.Contains(idx.ID())
+		},
+	}
+	return f.apply(table)
+}
+
+// withoutTableOptions returns a copy of stmts, where the table options
+// with the given keys are removed from all tables
+func withoutTableOptions(stmts model.Stmts, keys ...string) model.Stmts {
+	f := tableFilter{option: withoutOptions(keys...)}
+
+	filtered := make(model.Stmts, 0, len(stmts))
+	for _, stmt := range stmts {
+		if table, ok := stmt.(model.Table); ok {
+			stmt = f.apply(table)
+		}
+		filtered = append(filtered, stmt)
+	}
+	return filtered
+}
+
+// tableNameFilter selects tables by their names
+type tableNameFilter struct {
+	include []func(string) bool
+	exclude []func(string) bool
+}
+
+func newTableNameFilter(include, exclude []string) (*tableNameFilter, error) {
+	var f tableNameFilter
+	for _, pattern := range include {
+		m, err := compileTableNamePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, m)
+	}
+	for _, pattern := range exclude {
+		m, err := compileTableNamePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, m)
+	}
+	return &f, nil
+}
+
+// compileTableNamePattern compiles a glob pattern, or a regular
+// expression if it is enclosed in slashes, into a matching function
+func compileTableNamePattern(pattern string) (func(string) bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, errors.Wrapf(err, `invalid regular expression %s`, pattern)
+		}
+		return re.MatchString, nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, errors.Wrapf(err, `invalid pattern %s`, pattern)
+	}
+	return func(name string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}, nil
+}
+
+// match reports whether the named table should be compared
+func (f *tableNameFilter) match(name string) bool {
+	for _, m := range f.exclude {
+		if m(name) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, m := range f.include {
+		if m(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// leftOut returns the names of the tables among the statements that
+// should not be compared, once each
+func (f *tableNameFilter) leftOut(stmts ...model.Stmts) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, l := range stmts {
+		for _, stmt := range l {
+			if table, ok := stmt.(model.Table); ok && !f.match(table.Name()) && !seen[table.Name()] {
+				seen[table.Name()] = true
+				names = append(names, table.Name())
+			}
+		}
+	}
+	return names
+}
+
+// apply returns a copy of stmts, minus the tables that should not
+// be compared
+func (f *tableNameFilter) apply(stmts model.Stmts) model.Stmts {
+	filtered := make(model.Stmts, 0, len(stmts))
+	for _, stmt := range stmts {
+		if table, ok := stmt.(model.Table); ok && !f.match(table.Name()) {
+			continue
+		}
+		filtered = append(filtered, stmt)
+	}
+	return filtered
+}
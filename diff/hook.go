@@ -0,0 +1,166 @@
+package diff
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/internal/option"
+)
+
+// HookPoint tells when a hook runs (see WithHook)
+type HookPoint string
+
+const (
+	// BeforeDiff runs before Statements compares the schemas
+	BeforeDiff HookPoint = "before-diff"
+	// AfterDiff runs once Statements has written the statements, or
+	// failed to
+	AfterDiff HookPoint = "after-diff"
+	// BeforeApply runs before Apply executes the first statement
+	BeforeApply HookPoint = "before-apply"
+	// AfterApply runs once Apply is done, whether it succeeded or not
+	AfterApply HookPoint = "after-apply"
+)
+
+// HookEvent describes what a hook is called for
+type HookEvent struct {
+	// Point is when the hook runs
+	Point HookPoint
+	// Changes are the changes that were computed, or that are about to
+	// be or have been applied. It is empty before the diff.
+	Changes []Change
+	// Err is why the diff or the apply failed, for the hooks that run
+	// after them
+	Err error
+}
+
+// Hook is a function called before or after the diff is generated or
+// applied, such as to take a backup, or to send a notification. An
+// error returned by a hook that runs before stops the diff or the
+// apply from happening.
+type Hook func(context.Context, HookEvent) error
+
+type hook struct {
+	point HookPoint
+	fn    Hook
+}
+
+// WithHook specifies a hook to run at the given point. Hooks given for
+// the same point run in the order they are given. The hooks around the
+// diff run in Statements, and thus in Sources, Files and Strings, but
+// not in Compute. The hooks around Apply do not run for DryRun, nor do
+// the others.
+func WithHook(point HookPoint, fn Hook) Option {
+	return option.New(optkeyHook, hook{point: point, fn: fn})
+}
+
+// CommandHook returns a hook running command with "sh -c", writing
+// its output to output. The changes are written to its standard input
+// as JSON, in the same format as OutputFormatJSON, and the following
+// environment variables are set:
+//
+//	SCHEMALEX_HOOK     the point the hook runs at, such as "before-apply"
+//	SCHEMALEX_CHANGES  the number of changes
+//	SCHEMALEX_SUMMARY  a one-line summary of the changes (see ShortStat)
+//	SCHEMALEX_ERROR    why the diff or the apply failed, if it did
+//
+// The hook fails if the command exits with a non-zero status.
+func CommandHook(command string, output io.Writer) Hook {
+	return func(ctx context.Context, ev HookEvent) error {
+		var stdin bytes.Buffer
+		if err := writeJSONChanges(&stdin, ev.Changes); err != nil {
+			return err
+		}
+
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Stdin = &stdin
+		cmd.Stdout = output
+		cmd.Stderr = output
+		cmd.Env = append(os.Environ(),
+			"SCHEMALEX_HOOK="+string(ev.Point),
+			"SCHEMALEX_CHANGES="+strconv.Itoa(len(ev.Changes)),
+			"SCHEMALEX_SUMMARY="+Summarize(ev.Changes).ShortStat(),
+		)
+		if ev.Err != nil {
+			cmd.Env = append(cmd.Env, "SCHEMALEX_ERROR="+ev.Err.Error())
+		}
+		if err := cmd.Run(); err != nil {
+			return errors.Wrapf(err, `failed to run command %s`, command)
+		}
+		return nil
+	}
+}
+
+// hookRunner runs the hooks given by WithHook
+type hookRunner struct {
+	context context.Context
+	hooks   []hook
+}
+
+func newHookRunner(ctx context.Context, options []Option) hookRunner {
+	r := hookRunner{context: ctx}
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyContext:
+			if r.context == nil {
+				r.context = o.Value().(context.Context)
+			}
+		case optkeyHook:
+			r.hooks = append(r.hooks, o.Value().(hook))
+		}
+	}
+	if r.context == nil {
+		r.context = context.Background()
+	}
+	return r
+}
+
+// before runs the hooks for the point, stopping at the first one that
+// fails
+func (r hookRunner) before(point HookPoint, changes []Change) error {
+	for _, h := range r.hooks {
+		if h.point != point {
+			continue
+		}
+		if err := h.fn(r.context, HookEvent{Point: point, Changes: visibleChanges(changes)}); err != nil {
+			return errors.Wrapf(err, `%s hook failed`, point)
+		}
+	}
+	return nil
+}
+
+// after runs the hooks for the point, telling them about err. All of
+// them run, and err is returned if it is not nil, so that it is not
+// hidden by the hooks failing.
+func (r hookRunner) after(point HookPoint, changes []Change, err error) error {
+	var hookErr error
+	for _, h := range r.hooks {
+		if h.point != point {
+			continue
+		}
+		if herr := h.fn(r.context, HookEvent{Point: point, Changes: visibleChanges(changes), Err: err}); herr != nil && hookErr == nil {
+			hookErr = errors.Wrapf(herr, `%s hook failed`, point)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return hookErr
+}
+
+// visibleChanges leaves out the changes that are only written as
+// comments
+func visibleChanges(changes []Change) []Change {
+	var l []Change
+	for _, change := range changes {
+		if !change.suppressed {
+			l = append(l, change)
+		}
+	}
+	return l
+}
@@ -0,0 +1,147 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+)
+
+// name of the temporary procedure used to guard statements
+const guardProcedure = "`schemalex_migrate`"
+
+// ifExists returns the IF EXISTS clause for DROP statements, if the
+// output should be idempotent
+func ifExists(ctx *diffCtx) string {
+	if ctx.idempotent {
+		return "IF EXISTS "
+	}
+	return ""
+}
+
+func sqlString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+func columnExists(table, column string) string {
+	return "EXISTS (SELECT 1 FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = " + sqlString(table) + " AND COLUMN_NAME = " + sqlString(column) + ")"
+}
+
+func indexExists(table, index string) string {
+	return "EXISTS (SELECT 1 FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = " + sqlString(table) + " AND INDEX_NAME = " + sqlString(index) + ")"
+}
+
+func foreignKeyExists(table, constraint string) string {
+	return "EXISTS (SELECT 1 FROM information_schema.TABLE_CONSTRAINTS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = " + sqlString(table) + " AND CONSTRAINT_NAME = " + sqlString(constraint) + " AND CONSTRAINT_TYPE = 'FOREIGN KEY')"
+}
+
+func checkExists(table, constraint string) string {
+	return "EXISTS (SELECT 1 FROM information_schema.TABLE_CONSTRAINTS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = " + sqlString(table) + " AND CONSTRAINT_NAME = " + sqlString(constraint) + " AND CONSTRAINT_TYPE = 'CHECK')"
+}
+
+func partitionExists(table, partition string) string {
+	return "EXISTS (SELECT 1 FROM information_schema.PARTITIONS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = " + sqlString(table) + " AND PARTITION_NAME = " + sqlString(partition) + ")"
+}
+
+func tableExists(table string) string {
+	return "EXISTS (SELECT 1 FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = " + sqlString(table) + ")"
+}
+
+// guardCondition returns the condition under which the change still
+// needs to be applied, if the statement would fail when run twice.
+// Statements that can be run any number of times, such as changing
+// a column, have no condition.
+func guardCondition(change Change) string {
+	switch change.Kind {
+	case RenameTable:
+		return tableExists(change.OldName) + " AND NOT " + tableExists(change.Table)
+	case AddColumn:
+		return "NOT " + columnExists(change.Table, change.Name)
+	case DropColumn:
+		return columnExists(change.Table, change.Name)
+	case RenameColumn:
+		return columnExists(change.Table, change.OldName) + " AND NOT " + columnExists(change.Table, change.Name)
+	case AddIndex, AddFulltextIndex, AddSpatialIndex, AddPrimaryKey:
+		if change.Name == "" {
+			// MySQL makes up the name, so there's no telling
+			return ""
+		}
+		return "NOT " + indexExists(change.Table, change.Name)
+	case DropIndex, DropPrimaryKey:
+		return indexExists(change.Table, change.Name)
+	case RenameIndex:
+		return indexExists(change.Table, change.OldName) + " AND NOT " + indexExists(change.Table, change.Name)
+	case AddForeignKey:
+		if change.Name == "" {
+			return ""
+		}
+		return "NOT " + foreignKeyExists(change.Table, change.Name)
+	case DropForeignKey:
+		return foreignKeyExists(change.Table, change.Name)
+	case AddCheck:
+		if change.Name == "" {
+			return ""
+		}
+		return "NOT " + checkExists(change.Table, change.Name)
+	case DropCheck:
+		return checkExists(change.Table, change.Name)
+	case AddPartition:
+		if change.Name == "" {
+			return ""
+		}
+		return "NOT " + partitionExists(change.Table, change.Name)
+	case DropPartition:
+		return partitionExists(change.Table, change.Name)
+	}
+	return ""
+}
+
+// writeIdempotentChanges renders the changes so that the result can be
+// run again without failing. Statements that would fail when run twice
+// are run from a temporary procedure, which checks information_schema
+// to see if each of them is still needed. Changes to a table are never
+// combined, so that each of them can be checked on its own.
+func writeIdempotentChanges(ctx *diffCtx, buf *bytes.Buffer, changes []Change) {
+	for i := 0; i < len(changes); {
+		if i > 0 {
+			if changes[i].phase != changes[i-1].phase {
+				buf.WriteString("\n\n")
+			} else {
+				buf.WriteByte('\n')
+			}
+		}
+
+		if changes[i].suppressed {
+			writeSuppressedChange(ctx, buf, changes[i])
+			i++
+			continue
+		}
+
+		if guardCondition(changes[i]) == "" {
+			writeAnnotations(ctx, buf, "-- ", changes[i])
+			buf.WriteString(changes[i].SQL)
+			i++
+			continue
+		}
+
+		// guarded changes that follow each other share a procedure
+		phase := changes[i].phase
+		buf.WriteString("DROP PROCEDURE IF EXISTS " + guardProcedure + ";\n")
+		buf.WriteString("DELIMITER ;;\n")
+		buf.WriteString("CREATE PROCEDURE " + guardProcedure + "()\nBEGIN\n")
+		for ; i < len(changes) && changes[i].phase == phase; i++ {
+			guard := guardCondition(changes[i])
+			if guard == "" || changes[i].suppressed {
+				break
+			}
+			writeAnnotations(ctx, buf, "  -- ", changes[i])
+			buf.WriteString("  IF ")
+			buf.WriteString(guard)
+			buf.WriteString(" THEN\n    ")
+			buf.WriteString(changes[i].SQL)
+			buf.WriteString("\n  END IF;\n")
+		}
+		buf.WriteString("END;;\n")
+		buf.WriteString("DELIMITER ;\n")
+		buf.WriteString("CALL " + guardProcedure + "();\n")
+		buf.WriteString("DROP PROCEDURE " + guardProcedure + ";")
+	}
+}
@@ -0,0 +1,186 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/schemalex/schemalex/model"
+)
+
+// writeAnnotations writes the comments that precede the statement of
+// a change: the comment set by a Rewriter, and either its intent or
+// its safety
+func writeAnnotations(ctx *diffCtx, buf *bytes.Buffer, prefix string, change Change) {
+	writeComment(buf, prefix, change.Comment)
+	switch {
+	case ctx.intentComments:
+		writeIntentComment(ctx, buf, prefix, change)
+	case ctx.safetyComments:
+		writeSafetyComment(buf, prefix, change.Safety)
+	}
+}
+
+// writeIntentComment writes a comment describing the change, such as
+// "add column users.deleted_at (nullable, no default): safe, online"
+func writeIntentComment(ctx *diffCtx, buf *bytes.Buffer, prefix string, change Change) {
+	buf.WriteString(prefix)
+	buf.WriteString(describeChange(change))
+	if change.clause.detail != "" {
+		buf.WriteString(" (")
+		buf.WriteString(change.clause.detail)
+		buf.WriteByte(')')
+	}
+	buf.WriteString(": ")
+	buf.WriteString(string(change.Safety))
+	if how := describeOnlineDDL(ctx, change); how != "" {
+		buf.WriteString(", ")
+		buf.WriteString(how)
+	}
+	buf.WriteByte('\n')
+}
+
+// describeChange tells what the change does, in human terms
+func describeChange(change Change) string {
+	table, name := change.Table, change.Name
+	qualified := table + "." + name
+	switch change.Kind {
+	case CreateTable:
+		return "create table " + table
+	case DropTable:
+		return "drop table " + table
+	case RenameTable:
+		return "rename table " + change.OldName + " to " + table
+	case AddColumn:
+		return "add column " + qualified
+	case DropColumn:
+		return "drop column " + qualified
+	case ChangeColumn:
+		return "change column " + qualified
+	case RenameColumn:
+		return "rename column " + table + "." + change.OldName + " to " + name
+	case MoveColumn:
+		return "move column " + qualified
+	case ChangeColumnDefault:
+		return "change default of " + qualified
+	case AddIndex:
+		return "add index " + qualified
+	case AddFulltextIndex:
+		return "add fulltext index " + qualified
+	case AddSpatialIndex:
+		return "add spatial index " + qualified
+	case DropIndex:
+		return "drop index " + qualified
+	case RenameIndex:
+		return "rename index " + table + "." + change.OldName + " to " + name
+	case AddPrimaryKey:
+		return "add primary key to " + table
+	case DropPrimaryKey:
+		return "drop primary key of " + table
+	case AddForeignKey:
+		return "add foreign key " + qualified
+	case DropForeignKey:
+		return "drop foreign key " + qualified
+	case AddCheck:
+		return "add check " + qualified
+	case DropCheck:
+		return "drop check " + qualified
+	case AlterCheck:
+		return "alter check " + qualified
+	case ChangeEngine:
+		return "change engine of " + table
+	case ChangeTableOption:
+		return "change " + strings.ToUpper(name) + " of " + table
+	case ConvertCharset:
+		return "convert " + table + " to " + change.clause.after
+	case NormalizeCharset:
+		return "normalize charset of " + table
+	case AddPartition:
+		return "add partition " + qualified
+	case DropPartition:
+		return "drop partition " + qualified
+	case ReorganizePartition:
+		return "reorganize partitions of " + table
+	case CoalescePartition:
+		return "coalesce partitions of " + table
+	case Repartition:
+		return "repartition " + table
+	case RemovePartitioning:
+		return "remove partitioning of " + table
+	case CreateView:
+		return "create view " + name
+	case ReplaceView:
+		return "replace view " + name
+	case DropView:
+		return "drop view " + name
+	case CreateTrigger:
+		return "create trigger " + name + " on " + table
+	case DropTrigger:
+		return "drop trigger " + name + " on " + table
+	}
+	return string(change.Kind) + " " + qualified
+}
+
+// describeOnlineDDL tells how MySQL applies a change to a table, going
+// by the ALGORITHM and LOCK it takes (see WithOnlineDDL). It returns an
+// empty string for other changes.
+func describeOnlineDDL(ctx *diffCtx, change Change) string {
+	if change.batch == 0 {
+		return ""
+	}
+	hint := clauseOnlineDDLHint(ctx, change.clause)
+	switch {
+	case strings.EqualFold(hint.Algorithm, "INSTANT"):
+		return "instant"
+	case strings.EqualFold(hint.Algorithm, "COPY"):
+		return "copies the table"
+	case strings.EqualFold(hint.Lock, "SHARED"):
+		return "blocks writes"
+	case strings.EqualFold(hint.Lock, "EXCLUSIVE"):
+		return "blocks reads and writes"
+	}
+	return "online"
+}
+
+// addColumnDetail describes a column being added
+func addColumnDetail(col model.TableColumn) string {
+	nullable := "nullable"
+	if col.NullState() == model.NullStateNotNull {
+		nullable = "NOT NULL"
+	}
+	return nullable + ", " + defaultDetail(col)
+}
+
+// changeColumnDetail describes how a column is changed, for the type
+// and nullability of the column
+func changeColumnDetail(before, after model.TableColumn) string {
+	before, _ = before.Normalize()
+	after, _ = after.Normalize()
+
+	var details []string
+	if from, to := typeString(before), typeString(after); from != to {
+		details = append(details, from+" to "+to)
+	}
+	beforeNotNull := before.NullState() == model.NullStateNotNull
+	afterNotNull := after.NullState() == model.NullStateNotNull
+	switch {
+	case !beforeNotNull && afterNotNull:
+		details = append(details, "now NOT NULL")
+	case beforeNotNull && !afterNotNull:
+		details = append(details, "now nullable")
+	}
+	return strings.Join(details, ", ")
+}
+
+// defaultDetail describes the default value of a column. DEFAULT NULL,
+// which is what nullable columns are normalized to, is no default in
+// human terms.
+func defaultDetail(col model.TableColumn) string {
+	switch {
+	case !col.HasDefault(), !col.IsQuotedDefault() && strings.EqualFold(col.Default(), "NULL"):
+		return "no default"
+	case col.IsQuotedDefault():
+		return "default '" + col.Default() + "'"
+	default:
+		return "default " + col.Default()
+	}
+}
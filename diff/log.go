@@ -0,0 +1,46 @@
+package diff
+
+import "time"
+
+// Logger receives messages tracing how schemas are read and compared
+// (see WithLogger). *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// tracer writes messages to the logger given by WithLogger, if any.
+// Debug messages are only written if WithDebug is enabled.
+type tracer struct {
+	logger Logger
+	debug  bool
+}
+
+func newTracer(options []Option) tracer {
+	var t tracer
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyLogger:
+			t.logger = o.Value().(Logger)
+		case optkeyDebug:
+			t.debug = o.Value().(bool)
+		}
+	}
+	return t
+}
+
+func (t tracer) logf(format string, args ...interface{}) {
+	if t.logger != nil {
+		t.logger.Printf(format, args...)
+	}
+}
+
+func (t tracer) debugf(format string, args ...interface{}) {
+	if t.debug {
+		t.logf(format, args...)
+	}
+}
+
+// since returns the time elapsed since start, rounded for messages
+func since(start time.Time) time.Duration {
+	return time.Since(start).Round(time.Microsecond)
+}
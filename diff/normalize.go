@@ -0,0 +1,206 @@
+package diff
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// Normalize returns the statements as Compute sees them when comparing
+// tables, so that two schemas are only told apart by Compute if their
+// normalized forms differ, renames aside. The tables left out by
+// WithIncludeTables and WithExcludeTables are removed, as are the table
+// options ignored by WithIgnoreAutoIncrement and
+// WithIgnoreTableOptions, and comments if WithIgnoreComments is given.
+// Column definitions are written the same way whenever Compute counts
+// them the same under the strictness (see WithStrictness): default
+// values are written in a single way, as are integer display widths
+// and character set aliases when they are ignored. Tables are sorted
+// by name, followed by views and then triggers.
+func Normalize(stmts model.Stmts, options ...Option) (model.Stmts, error) {
+	var ignoreComments bool
+	var ignoreAutoIncrement bool
+	var ignoreTableOptions []string
+	var include, exclude []string
+	var strictness Strictness
+	var knobs strictnessKnobs
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyIgnoreComments:
+			ignoreComments = o.Value().(bool)
+		case optkeyIgnoreAutoIncrement:
+			ignoreAutoIncrement = o.Value().(bool)
+		case optkeyIgnoreTableOptions:
+			ignoreTableOptions = append(ignoreTableOptions, o.Value().([]string)...)
+		case optkeyIncludeTables:
+			include = append(include, o.Value().([]string)...)
+		case optkeyExcludeTables:
+			exclude = append(exclude, o.Value().([]string)...)
+		case optkeyStrictness:
+			strictness = o.Value().(Strictness)
+		case optkeyIgnoreIntDisplayWidth:
+			v := o.Value().(bool)
+			knobs.ignoreIntDisplayWidth = &v
+		case optkeyIgnoreCharsetAliases:
+			v := o.Value().(bool)
+			knobs.ignoreCharsetAliases = &v
+		case optkeyIgnoreDefaultQuoting:
+			v := o.Value().(bool)
+			knobs.ignoreDefaultQuoting = &v
+		}
+	}
+
+	if len(include) > 0 || len(exclude) > 0 {
+		f, err := newTableNameFilter(include, exclude)
+		if err != nil {
+			return nil, errors.Wrap(err, `failed to parse table filters`)
+		}
+		stmts = f.apply(stmts)
+	}
+	for _, pattern := range ignoreTableOptions {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, `invalid table option pattern %s`, pattern)
+		}
+	}
+	if ignoreAutoIncrement {
+		ignoreTableOptions = append(ignoreTableOptions, "AUTO_INCREMENT")
+	}
+	if ignoreComments {
+		ignoreTableOptions = append(ignoreTableOptions, "COMMENT")
+	}
+
+	var ctx diffCtx
+	knobs.apply(&ctx, strictness)
+
+	f := tableFilter{
+		column: func(col model.TableColumn) model.TableColumn {
+			if ignoreComments && col.HasComment() {
+				col = withoutComment(col)
+			}
+			return normalizeColumn(&ctx, col)
+		},
+		option: func(opt model.TableOption) bool {
+			return !matchTableOption(opt, ignoreTableOptions)
+		},
+	}
+
+	normalized := make(model.Stmts, 0, len(stmts))
+	for _, stmt := range stmts {
+		if table, ok := stmt.(model.Table); ok {
+			stmt = f.apply(table)
+		}
+		normalized = append(normalized, stmt)
+	}
+	sort.SliceStable(normalized, func(i, j int) bool {
+		ri, rj := normalizedRank(normalized[i]), normalizedRank(normalized[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return stmtName(normalized[i]) < stmtName(normalized[j])
+	})
+	return normalized, nil
+}
+
+// NormalizeSource writes the normalized form of the schema read from
+// src to dst (see Normalize)
+func NormalizeSource(dst io.Writer, src schemalex.SchemaSource, options ...Option) error {
+	var p *schemalex.Parser
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyParser:
+			p = o.Value().(*schemalex.Parser)
+		}
+	}
+	if p == nil {
+		p = schemalex.New()
+	}
+
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := p.Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+	normalized, err := Normalize(stmts, options...)
+	if err != nil {
+		return err
+	}
+	for i, stmt := range normalized {
+		if i > 0 {
+			io.WriteString(dst, "\n\n")
+		}
+		if err := format.SQL(dst, stmt); err != nil {
+			return errors.Wrapf(err, `failed to format statement %s`, stmt.ID())
+		}
+		io.WriteString(dst, ";")
+	}
+	if len(normalized) > 0 {
+		io.WriteString(dst, "\n")
+	}
+	return nil
+}
+
+// normalizeColumn writes the column the same way as any other column
+// that Compute counts the same
+func normalizeColumn(ctx *diffCtx, col model.TableColumn) model.TableColumn {
+	if ctx.ignoreIntDisplayWidth && integerRank[col.Type()] > 0 && col.HasLength() && !isBoolean(col) {
+		col = col.Clone().SetLength(nil)
+	}
+	if ctx.ignoreCharsetAliases {
+		if col.HasCharacterSet() && canonicalCharset(col.CharacterSet()) != col.CharacterSet() {
+			col = col.Clone().SetCharacterSet(canonicalCharset(col.CharacterSet()))
+		}
+		if col.HasCollation() && canonicalCharset(col.Collation()) != col.Collation() {
+			col = col.Clone().SetCollation(canonicalCharset(col.Collation()))
+		}
+	}
+
+	if !col.HasDefault() {
+		return col
+	}
+	value, quoted := col.Default(), col.IsQuotedDefault()
+	if x, err := strconv.ParseFloat(value, 64); err == nil {
+		// numbers are the same whether they are quoted or not, and
+		// however they are written if the column is numeric
+		if isNumericType(col.Type()) {
+			value = strconv.FormatFloat(x, 'f', -1, 64)
+		}
+		quoted = false
+	} else if !quoted {
+		value = canonicalKeyword(value)
+	} else if ctx.ignoreDefaultQuoting {
+		quoted = false
+	}
+	if value != col.Default() || quoted != col.IsQuotedDefault() {
+		col = col.Clone().SetDefault(value, quoted)
+	}
+	return col
+}
+
+func normalizedRank(stmt model.Stmt) int {
+	switch stmt.(type) {
+	case model.Table:
+		return 0
+	case model.View:
+		return 1
+	case model.Trigger:
+		return 2
+	}
+	return 3
+}
+
+func stmtName(stmt model.Stmt) string {
+	if named, ok := stmt.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return ""
+}
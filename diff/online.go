@@ -0,0 +1,134 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/schemalex/schemalex/internal/version"
+	"github.com/schemalex/schemalex/model"
+)
+
+func addIndexKind(idx model.Index) ChangeKind {
+	switch {
+	case idx.IsPrimaryKey():
+		return AddPrimaryKey
+	case idx.IsForeignKey():
+		return AddForeignKey
+	case idx.IsFullText():
+		return AddFulltextIndex
+	case idx.IsSpatial():
+		return AddSpatialIndex
+	default:
+		return AddIndex
+	}
+}
+
+func tableOptionKind(opt model.TableOption) ChangeKind {
+	if strings.EqualFold(opt.Key(), "ENGINE") {
+		return ChangeEngine
+	}
+	return ChangeTableOption
+}
+
+// OnlineDDL describes the ALGORITHM and LOCK clauses to be appended to
+// an ALTER TABLE statement. Empty values are omitted.
+type OnlineDDL struct {
+	Algorithm string
+	Lock      string
+}
+
+// defaultMySQLVersion is the version assumed when classifying changes,
+// if none is specified
+const defaultMySQLVersion = "5.7"
+
+// classifyOnlineDDL returns the least restrictive ALGORITHM and LOCK
+// that MySQL of the given version supports for the kind of change.
+// See https://dev.mysql.com/doc/refman/8.0/en/innodb-online-ddl-operations.html
+func classifyOnlineDDL(kind ChangeKind, v version.MySQL) OnlineDDL {
+	inplace := OnlineDDL{Algorithm: "INPLACE", Lock: "NONE"}
+	instant := OnlineDDL{Algorithm: "INSTANT"}
+	copying := OnlineDDL{Algorithm: "COPY", Lock: "SHARED"}
+
+	switch kind {
+	case AddColumn, DropColumn:
+		if v.AtLeast(8, 0, 29) {
+			return instant
+		}
+		return inplace
+	case RenameColumn:
+		if v.AtLeast(8, 0, 28) {
+			return instant
+		}
+		return inplace
+	case ChangeColumnDefault, RenameIndex:
+		if v.AtLeast(8, 0, 0) {
+			return instant
+		}
+		return inplace
+	case DropCheck:
+		return instant
+	case AddIndex, DropIndex, AddPrimaryKey, DropForeignKey, ChangeTableOption, MoveColumn, AlterCheck:
+		return inplace
+	case AddFulltextIndex, AddSpatialIndex:
+		return OnlineDDL{Algorithm: "INPLACE", Lock: "SHARED"}
+	case AddPartition, DropPartition, ReorganizePartition, CoalescePartition:
+		// these are done in place, but block writes to the table
+		return OnlineDDL{Algorithm: "INPLACE", Lock: "SHARED"}
+	default:
+		// changing column types, dropping the primary key, adding
+		// foreign keys while foreign key checks are enabled, changing
+		// the storage engine and converting the character set all
+		// require the table to be copied, and so does partitioning
+		// the table from scratch
+		return copying
+	}
+}
+
+// onlineDDLHint returns the ALGORITHM and LOCK to use for the kind of
+// change, taking overrides into account
+func onlineDDLHint(ctx *diffCtx, kind ChangeKind) OnlineDDL {
+	if h, ok := ctx.onlineDDLOverrides[kind]; ok {
+		return h
+	}
+	return classifyOnlineDDL(kind, ctx.mysqlVersion)
+}
+
+// clauseOnlineDDLHint is onlineDDLHint for a single clause, which also
+// knows that appending a column is instant since MySQL 8.0.12
+func clauseOnlineDDLHint(ctx *diffCtx, clause alterClause) OnlineDDL {
+	if _, ok := ctx.onlineDDLOverrides[clause.kind]; !ok && clause.kind == AddColumn && clause.appended && ctx.mysqlVersion.AtLeast(8, 0, 12) {
+		return OnlineDDL{Algorithm: "INSTANT"}
+	}
+	return onlineDDLHint(ctx, clause.kind)
+}
+
+var algorithmRank = map[string]int{"INSTANT": 0, "INPLACE": 1, "COPY": 2}
+var lockRank = map[string]int{"": 0, "NONE": 1, "SHARED": 2, "EXCLUSIVE": 3}
+
+// writeOnlineDDLHint appends the ALGORITHM and LOCK clauses that
+// satisfy all of the given clauses
+func writeOnlineDDLHint(ctx *diffCtx, buf *bytes.Buffer, clauses []alterClause) {
+	var hint OnlineDDL
+	for i, clause := range clauses {
+		h := clauseOnlineDDLHint(ctx, clause)
+		if i == 0 || algorithmRank[strings.ToUpper(h.Algorithm)] > algorithmRank[strings.ToUpper(hint.Algorithm)] {
+			hint.Algorithm = h.Algorithm
+		}
+		if lockRank[strings.ToUpper(h.Lock)] > lockRank[strings.ToUpper(hint.Lock)] {
+			hint.Lock = h.Lock
+		}
+	}
+
+	// INSTANT does not allow LOCK to be specified
+	if strings.EqualFold(hint.Algorithm, "INSTANT") {
+		hint.Lock = ""
+	}
+	if hint.Algorithm != "" {
+		buf.WriteString(", ALGORITHM=")
+		buf.WriteString(hint.Algorithm)
+	}
+	if hint.Lock != "" {
+		buf.WriteString(", LOCK=")
+		buf.WriteString(hint.Lock)
+	}
+}
@@ -1,6 +1,10 @@
 package diff
 
 import (
+	"context"
+	"io"
+	"time"
+
 	"github.com/schemalex/schemalex"
 	"github.com/schemalex/schemalex/internal/option"
 )
@@ -8,8 +12,62 @@ import (
 type Option = schemalex.Option
 
 const (
-	optkeyParser      = "parser"
-	optkeyTransaction = "transaction"
+	optkeyParser                = "parser"
+	optkeyTransaction           = "transaction"
+	optkeyForeignKeyChecks      = "foreign-key-checks"
+	optkeySQLMode               = "sql-mode"
+	optkeyDelimiter             = "delimiter"
+	optkeyBatchSeparator        = "batch-separator"
+	optkeyBatchSize             = "batch-size"
+	optkeyTrailingNewline       = "trailing-newline"
+	optkeyCoalesce              = "coalesce"
+	optkeyIdempotent            = "idempotent"
+	optkeyReverse               = "reverse"
+	optkeyAlterMode             = "alter-mode"
+	optkeyOutputFormat          = "output-format"
+	optkeyColor                 = "color"
+	optkeyDatabaseName          = "database-name"
+	optkeyToolArgs              = "tool-args"
+	optkeyOnlineDDL             = "online-ddl"
+	optkeyOnlineDDLOverride     = "online-ddl-override"
+	optkeyMySQLVersion          = "mysql-version"
+	optkeySafetyComments        = "safety-comments"
+	optkeyIntentComments        = "intent-comments"
+	optkeyWarnings              = "warnings"
+	optkeySafe                  = "safe"
+	optkeyFailOnDestructive     = "fail-on-destructive"
+	optkeyIgnoreAutoIncrement   = "ignore-auto-increment"
+	optkeyIgnoreComments        = "ignore-comments"
+	optkeyIgnoreTableOptions    = "ignore-table-options"
+	optkeyIncludeTables         = "include-tables"
+	optkeyExcludeTables         = "exclude-tables"
+	optkeyDetectTableRename     = "detect-table-rename"
+	optkeyDetectColumnRename    = "detect-column-rename"
+	optkeyTableRenames          = "table-renames"
+	optkeyColumnRenames         = "column-renames"
+	optkeyColumnRenameThreshold = "column-rename-threshold"
+	optkeyReorderColumns        = "reorder-columns"
+	optkeyIgnoreColumnOrder     = "ignore-column-order"
+	optkeyPerColumnCharset      = "per-column-charset"
+	optkeyAdditiveOnly          = "additive-only"
+	optkeyContinueOnError       = "continue-on-error"
+	optkeyStatementTimeout      = "statement-timeout"
+	optkeyApproval              = "approval"
+	optkeyDropComments          = "drop-comments"
+	optkeyRewriter              = "rewriter"
+	optkeyMatchIndexesByColumns = "match-indexes-by-columns"
+	optkeyRenameIndexes         = "rename-indexes"
+	optkeyStrictness            = "strictness"
+	optkeyIgnoreIntDisplayWidth = "ignore-int-display-width"
+	optkeyIgnoreCharsetAliases  = "ignore-charset-aliases"
+	optkeyIgnoreDefaultQuoting  = "ignore-default-quoting"
+	optkeyCompareIndexOrder     = "compare-index-order"
+	optkeyCharsetAliasNotes     = "charset-alias-notes"
+	optkeyContext               = "context"
+	optkeyProgress              = "progress"
+	optkeyLogger                = "logger"
+	optkeyDebug                 = "debug"
+	optkeyHook                  = "hook"
 )
 
 // WithParser specifies the parser instance to use when parsing
@@ -20,7 +78,450 @@ func WithParser(p *schemalex.Parser) Option {
 }
 
 // WithTransaction specifies if statements to control transactions
-// should be included in the diff.
+// should be included in the diff. Foreign key checks are disabled
+// within the transaction, unless WithForeignKeyChecks says otherwise.
+// Note that MySQL commits DDL statements implicitly, so the
+// transaction does not make the changes atomic.
 func WithTransaction(b bool) Option {
 	return option.New(optkeyTransaction, b)
 }
+
+// WithForeignKeyChecks specifies if foreign key checks should be left
+// enabled while the statements are run. If false, the statements are
+// wrapped in SET FOREIGN_KEY_CHECKS = 0 and 1, whether they are in a
+// transaction or not.
+func WithForeignKeyChecks(b bool) Option {
+	return option.New(optkeyForeignKeyChecks, b)
+}
+
+// WithSQLMode specifies the sql_mode of the session to run the
+// statements in, such as "" to allow zero dates in existing defaults.
+// The previous sql_mode is restored afterwards.
+func WithSQLMode(mode string) Option {
+	return option.New(optkeySQLMode, mode)
+}
+
+// WithDelimiter specifies the string that terminates each statement,
+// which is ";" by default. With any other delimiter, the DELIMITER
+// commands around statements containing semicolons, such as triggers,
+// are left out, and the runner is expected to split the script on the
+// given delimiter.
+func WithDelimiter(s string) Option {
+	return option.New(optkeyDelimiter, s)
+}
+
+// WithBatchSeparator specifies a line to write after each batch of
+// statements, such as GO, for runners that send the script to the
+// server one batch at a time. See also WithBatchSize
+func WithBatchSeparator(s string) Option {
+	return option.New(optkeyBatchSeparator, s)
+}
+
+// WithBatchSize specifies the maximum number of statements in a batch
+// separated by WithBatchSeparator. If 0, which is the default, all
+// statements are in a single batch.
+func WithBatchSize(n int) Option {
+	return option.New(optkeyBatchSize, n)
+}
+
+// WithTrailingNewline specifies if the output should end with a
+// newline. By default, there is no newline after the last statement.
+func WithTrailingNewline(b bool) Option {
+	return option.New(optkeyTrailingNewline, b)
+}
+
+// WithReverse specifies a destination to write the reverse migration,
+// that is, statements to migrate from the new schema back to the old one.
+// All other options are applied to the reverse migration as well.
+func WithReverse(dst io.Writer) Option {
+	return option.New(optkeyReverse, dst)
+}
+
+// WithCoalesce specifies if all changes to a single table should be
+// combined into one ALTER TABLE statement, instead of generating one
+// statement per change. This avoids having MySQL rebuild the same
+// table multiple times.
+func WithCoalesce(b bool) Option {
+	return option.New(optkeyCoalesce, b)
+}
+
+// WithIdempotent specifies if the generated statements should be safe
+// to run more than once. Tables, views and triggers are dropped with
+// DROP ... IF EXISTS, tables are created with CREATE TABLE IF NOT
+// EXISTS, and views with CREATE OR REPLACE VIEW. Table alterations
+// that would fail when run twice, such as adding a column, are run
+// from a temporary procedure that checks information_schema first.
+// Changes to a table are never coalesced in this mode (see
+// WithCoalesce), so that each of them can be checked on its own.
+func WithIdempotent(b bool) Option {
+	return option.New(optkeyIdempotent, b)
+}
+
+// WithAlterMode specifies how table alterations are rendered. By default
+// they are rendered as ALTER TABLE statements. When an online schema
+// change tool such as gh-ost or pt-online-schema-change is used, a
+// command line that applies all changes to a table is rendered for each
+// table instead. Other statements, such as CREATE TABLE, are still
+// rendered as SQL.
+func WithAlterMode(m AlterMode) Option {
+	return option.New(optkeyAlterMode, m)
+}
+
+// WithOutputFormat specifies the format that Statements writes the
+// diff in. By default the diff is written as SQL statements.
+func WithOutputFormat(f OutputFormat) Option {
+	return option.New(optkeyOutputFormat, f)
+}
+
+// WithColor specifies if the diff should be colored with ANSI escape
+// sequences, for terminals. Statements and the lines of the plan written
+// for OutputFormatPlan are green if they add something, red if they
+// remove something and yellow if they modify something.
+func WithColor(b bool) Option {
+	return option.New(optkeyColor, b)
+}
+
+// WithDatabaseName specifies the name of the database, which is passed
+// to online schema change tools (see WithAlterMode)
+func WithDatabaseName(s string) Option {
+	return option.New(optkeyDatabaseName, s)
+}
+
+// WithToolArgs specifies extra arguments that are appended as is to the
+// command lines of online schema change tools (see WithAlterMode), such
+// as "--execute". This option may be specified multiple times.
+func WithToolArgs(args ...string) Option {
+	return option.New(optkeyToolArgs, args)
+}
+
+// WithOnlineDDL specifies if ALGORITHM and LOCK clauses should be
+// appended to ALTER TABLE statements. The least restrictive values that
+// the target MySQL version (see WithMySQLVersion) supports for the
+// changes in each statement are used, unless they are overridden by
+// WithOnlineDDLOverride.
+func WithOnlineDDL(b bool) Option {
+	return option.New(optkeyOnlineDDL, b)
+}
+
+type onlineDDLOverride struct {
+	kind ChangeKind
+	hint OnlineDDL
+}
+
+// WithOnlineDDLOverride specifies the ALGORITHM and LOCK values to use
+// for the given kind of change, instead of the ones that schemalex
+// deems appropriate. Empty values are omitted from the output.
+// This option may be specified multiple times.
+func WithOnlineDDLOverride(kind ChangeKind, algorithm, lock string) Option {
+	return option.New(optkeyOnlineDDLOverride, &onlineDDLOverride{
+		kind: kind,
+		hint: OnlineDDL{Algorithm: algorithm, Lock: lock},
+	})
+}
+
+// WithMySQLVersion specifies the version of the MySQL server that the
+// generated statements are run against, such as "5.7" or "8.0.29".
+// It is used to decide which changes can be done online.
+// The default is "5.7".
+func WithMySQLVersion(v string) Option {
+	return option.New(optkeyMySQLVersion, v)
+}
+
+// WithSafetyComments specifies if each statement should be preceded by
+// a comment telling whether it is safe, blocking or destructive
+// (see Safety)
+func WithSafetyComments(b bool) Option {
+	return option.New(optkeySafetyComments, b)
+}
+
+// WithIntentComments specifies if each statement should be preceded by
+// a comment describing the change in human terms, along with its
+// safety and how MySQL applies it, such as
+//
+//	-- add column users.deleted_at (nullable, no default): safe, online
+//
+// It takes the place of the comments enabled by WithSafetyComments.
+func WithIntentComments(b bool) Option {
+	return option.New(optkeyIntentComments, b)
+}
+
+// WithWarnings specifies a destination to write warnings about changes
+// that may lose existing data, such as narrowing the type of a column
+// from BIGINT to INT. Each warning is written on its own line.
+func WithWarnings(dst io.Writer) Option {
+	return option.New(optkeyWarnings, dst)
+}
+
+// WithSafe specifies if generating the diff should fail when any of
+// the changes has a warning about losing existing data, instead of
+// generating statements that may silently truncate it.
+func WithSafe(b bool) Option {
+	return option.New(optkeySafe, b)
+}
+
+// WithFailOnDestructive specifies if Statements should fail with a
+// *DestructiveChangesError, without writing anything, when any of the
+// changes is classified as Destructive. Unlike WithSafe, changes that
+// obviously lose data, such as dropping a table, are included too.
+func WithFailOnDestructive(b bool) Option {
+	return option.New(optkeyFailOnDestructive, b)
+}
+
+// WithIgnoreAutoIncrement specifies if the AUTO_INCREMENT table option
+// should be ignored. The counter values of a live database are bound
+// to differ from those in the schema files, and are hardly ever
+// something you want to migrate.
+func WithIgnoreAutoIncrement(b bool) Option {
+	return option.New(optkeyIgnoreAutoIncrement, b)
+}
+
+// WithIgnoreComments specifies if differences in table and column
+// comments should be ignored. Columns that only differ by their
+// comments are not altered.
+func WithIgnoreComments(b bool) Option {
+	return option.New(optkeyIgnoreComments, b)
+}
+
+// WithIgnoreTableOptions specifies table options whose differences
+// should be ignored, such as ROW_FORMAT or KEY_BLOCK_SIZE. Keys are
+// case insensitive, and may contain glob patterns such as STATS_*.
+// Tables that are created still get all of their options.
+// This option may be specified multiple times.
+func WithIgnoreTableOptions(keys ...string) Option {
+	return option.New(optkeyIgnoreTableOptions, keys)
+}
+
+// WithIncludeTables specifies patterns of table names to compare.
+// If specified, tables whose names do not match any of the patterns
+// are ignored. Patterns are shell globs as understood by path.Match,
+// or regular expressions if enclosed in slashes, such as `/^app_/`.
+// This option may be specified multiple times.
+func WithIncludeTables(patterns ...string) Option {
+	return option.New(optkeyIncludeTables, patterns)
+}
+
+// WithExcludeTables specifies patterns of table names to ignore.
+// Exclusion takes precedence over WithIncludeTables. See
+// WithIncludeTables for the pattern syntax.
+// This option may be specified multiple times.
+func WithExcludeTables(patterns ...string) Option {
+	return option.New(optkeyExcludeTables, patterns)
+}
+
+// WithDetectTableRename specifies if tables that are dropped and
+// created with exactly the same definition should be treated as being
+// renamed. When enabled, a `RENAME TABLE old TO new` statement is
+// generated instead of a DROP TABLE and CREATE TABLE pair.
+func WithDetectTableRename(b bool) Option {
+	return option.New(optkeyDetectTableRename, b)
+}
+
+// WithTableRenames specifies tables that are renamed, mapping their
+// names in the old schema to their names in the new schema. Unlike
+// the renames found by WithDetectTableRename, the tables may also be
+// altered, in which case they are renamed first. Each old table must
+// only exist in the old schema, and each new one only in the new
+// schema. This option may be specified multiple times.
+func WithTableRenames(renames map[string]string) Option {
+	return option.New(optkeyTableRenames, renames)
+}
+
+// WithColumnRenames specifies columns of a table that are renamed,
+// mapping their old names to their new names. The table is given by
+// its name in the new schema. Unlike the renames found by
+// WithDetectColumnRename, the definitions of the columns may differ.
+// This option may be specified multiple times.
+func WithColumnRenames(table string, renames map[string]string) Option {
+	return option.New(optkeyColumnRenames, &columnRenames{table: table, renames: renames})
+}
+
+// WithDetectColumnRename specifies if columns that are dropped and
+// added with exactly the same definition should be treated as being
+// renamed. When enabled, a `CHANGE COLUMN old new ...` statement is
+// generated instead of a DROP COLUMN and ADD COLUMN pair, which would
+// lose the data stored in the column.
+func WithDetectColumnRename(b bool) Option {
+	return option.New(optkeyDetectColumnRename, b)
+}
+
+// WithColumnRenameThreshold specifies the minimum similarity (between
+// 0 and 1) of the old and new column names for the pair to be
+// considered a rename when WithDetectColumnRename is enabled.
+// The default 0 means that the names are not taken into account.
+func WithColumnRenameThreshold(f float64) Option {
+	return option.New(optkeyColumnRenameThreshold, f)
+}
+
+// WithReorderColumns specifies if columns that exist in both schemas
+// but in a different order should be moved, so that the physical
+// order of the columns converges to that of the new schema. Columns
+// are moved with `MODIFY COLUMN ... AFTER` (or FIRST), and as few of
+// them as possible are moved. By default, the order of existing
+// columns is not compared.
+func WithReorderColumns(b bool) Option {
+	return option.New(optkeyReorderColumns, b)
+}
+
+// WithIgnoreColumnOrder specifies if the order of columns should be
+// ignored entirely. New columns are added without AFTER or FIRST,
+// which appends them to the table, and existing columns are never
+// moved. It takes precedence over WithReorderColumns.
+func WithIgnoreColumnOrder(b bool) Option {
+	return option.New(optkeyIgnoreColumnOrder, b)
+}
+
+// WithPerColumnCharset specifies how columns are converted when the
+// default character set or collation of a table is changed. By default
+// the whole table is converted with a single
+// `CONVERT TO CHARACTER SET ... COLLATE ...` clause. When enabled, the
+// default of the table is changed on its own, and each column whose
+// character set or collation differs is changed with its own clause,
+// leaving the other columns untouched.
+func WithPerColumnCharset(b bool) Option {
+	return option.New(optkeyPerColumnCharset, b)
+}
+
+// WithAdditiveOnly specifies if only additive changes should be made.
+// When enabled, tables, columns, indexes and partitions that only
+// exist in the old schema are left in place instead of being dropped.
+// Indexes that are dropped to be recreated with a new definition are
+// still dropped.
+func WithAdditiveOnly(b bool) Option {
+	return option.New(optkeyAdditiveOnly, b)
+}
+
+// WithDropComments specifies if the statements suppressed by
+// WithAdditiveOnly should be written out as comments, so that they
+// can be reviewed and applied by hand
+func WithDropComments(b bool) Option {
+	return option.New(optkeyDropComments, b)
+}
+
+// WithContinueOnError specifies if Apply should go on applying the
+// rest of the changes when one of them fails
+func WithContinueOnError(b bool) Option {
+	return option.New(optkeyContinueOnError, b)
+}
+
+// WithApproval specifies a callback that Apply invokes with each
+// change before applying it, to decide whether to apply it, skip it,
+// or abort. Embedding applications may use it to prompt users, or to
+// consult a policy before destructive changes (see Change.Safety).
+func WithApproval(f func(Change) Decision) Option {
+	return option.New(optkeyApproval, f)
+}
+
+// WithStatementTimeout specifies how long each statement executed by
+// Apply may take. The default 0 means no timeout.
+func WithStatementTimeout(d time.Duration) Option {
+	return option.New(optkeyStatementTimeout, d)
+}
+
+// WithRewriter registers a hook that can drop, modify or annotate
+// each change before it is rendered, such as adding ALGORITHM=INSTANT
+// to ADD COLUMN, or leaving out the changes to archived tables. It may
+// be given more than once, in which case the rewriters are run in the
+// order they were given. Changes to a table whose SQL is rewritten are
+// never combined with other changes, and are written as SQL whatever
+// the alter mode.
+func WithRewriter(r Rewriter) Option {
+	return option.New(optkeyRewriter, r)
+}
+
+// WithMatchIndexesByColumns specifies if indexes should be matched by
+// their kind, columns and options rather than by name, so that indexes
+// that only differ by name, such as ones with generated names, are
+// not dropped and added again. Foreign keys are still matched by name.
+func WithMatchIndexesByColumns(b bool) Option {
+	return option.New(optkeyMatchIndexesByColumns, b)
+}
+
+// WithRenameIndexes specifies if indexes matched by
+// WithMatchIndexesByColumns should be renamed with RENAME INDEX to
+// the name they have in the new schema. Otherwise, their names are
+// left as they are. RENAME INDEX requires MySQL 5.7 or later, and
+// this option is ignored for older versions (see WithMySQLVersion).
+func WithRenameIndexes(b bool) Option {
+	return option.New(optkeyRenameIndexes, b)
+}
+
+// WithStrictness specifies the comparison profile, which decides
+// whether differences in integer display widths, character set
+// aliases, default quoting and index order count as changes. The
+// options for each of them take precedence over the profile,
+// whichever order they are given in.
+func WithStrictness(s Strictness) Option {
+	return option.New(optkeyStrictness, s)
+}
+
+// WithIgnoreIntDisplayWidth specifies if the display widths of integer
+// columns, such as the 11 in INT(11), should be ignored. MySQL 8.0.19
+// and later do not show them anymore, except for TINYINT(1), which
+// stays apart from other TINYINTs as the type of booleans.
+func WithIgnoreIntDisplayWidth(b bool) Option {
+	return option.New(optkeyIgnoreIntDisplayWidth, b)
+}
+
+// WithIgnoreCharsetAliases specifies if character sets and collations
+// that are aliases of each other, such as utf8 and utf8mb3, should be
+// considered the same. They are unless WithStrictness(StrictnessStrict)
+// is given.
+func WithIgnoreCharsetAliases(b bool) Option {
+	return option.New(optkeyIgnoreCharsetAliases, b)
+}
+
+// WithIgnoreDefaultQuoting specifies if default values that only
+// differ by quoting should be considered the same, even if they are
+// keywords or expressions, such as DEFAULT CURRENT_TIMESTAMP and
+// DEFAULT 'CURRENT_TIMESTAMP'. Literals such as DEFAULT 1 and
+// DEFAULT '1' are always compared by value.
+func WithIgnoreDefaultQuoting(b bool) Option {
+	return option.New(optkeyIgnoreDefaultQuoting, b)
+}
+
+// WithCompareIndexOrder specifies if the order of the indexes in a
+// table should be compared. Indexes that are out of order are dropped
+// and added again at the end of the table. Use WithCoalesce along
+// with it, so that indexes that foreign keys rely on are dropped and
+// added in the same statement.
+func WithCompareIndexOrder(b bool) Option {
+	return option.New(optkeyCompareIndexOrder, b)
+}
+
+// WithCharsetAliasNotes specifies if tables and columns whose character
+// set or collation is only written as another alias, such as utf8 and
+// utf8mb3, should be reported as normalization only changes. They are
+// written as comments, and are skipped by Apply.
+func WithCharsetAliasNotes(b bool) Option {
+	return option.New(optkeyCharsetAliasNotes, b)
+}
+
+// WithContext specifies a context to cancel the comparison of large
+// schemas with. Once the context is done, the comparison stops after
+// the table being compared, and the context's error is returned.
+func WithContext(ctx context.Context) Option {
+	return option.New(optkeyContext, ctx)
+}
+
+// WithProgress specifies a function to call each time a table has been
+// compared, telling how many of the tables have been compared so far.
+// Tables that are created, dropped or renamed count as compared as
+// soon as their statements are generated.
+func WithProgress(fn func(Progress)) Option {
+	return option.New(optkeyProgress, fn)
+}
+
+// WithLogger specifies a logger to trace how schemas are read, parsed
+// and compared with: how long each step takes, and which tables are
+// created, dropped or altered. See WithDebug for more details.
+func WithLogger(l Logger) Option {
+	return option.New(optkeyLogger, l)
+}
+
+// WithDebug specifies if the logger given by WithLogger also receives
+// the decisions that make differences disappear, such as tables left
+// out by filters, options that are ignored, the strictness of the
+// comparison, detected renames, and tables that are left unchanged.
+func WithDebug(b bool) Option {
+	return option.New(optkeyDebug, b)
+}
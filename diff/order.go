@@ -0,0 +1,263 @@
+package diff
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/deckarep/golang-set"
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// referencedTableIDs returns the IDs of the tables that the given
+// table refers to via foreign keys, excluding itself
+func referencedTableIDs(table model.Table) []string {
+	var ids []string
+	for idx := range table.Indexes() {
+		id, ok := foreignKeyTableID(table, idx)
+		if !ok {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// foreignKeyTableID returns the ID of the table that the index refers
+// to, if the index is a foreign key that refers to a table other than
+// the one it belongs to
+func foreignKeyTableID(table model.Table, idx model.Index) (string, bool) {
+	if !idx.IsForeignKey() || idx.Reference() == nil {
+		return "", false
+	}
+	id := model.NewTable(idx.Reference().TableName()).ID()
+	if id == table.ID() {
+		return "", false
+	}
+	return id, true
+}
+
+// sortTablesByDependency sorts the given tables so that tables that are
+// referred to by foreign keys come before the tables referring to them.
+// Tables that do not depend on each other are sorted by name. If the
+// references form a cycle, the cycle is broken at the table whose name
+// comes first.
+func sortTablesByDependency(stmts model.Stmts, ids mapset.Set) ([]model.Table, error) {
+	return sortTables(stmts, ids, false)
+}
+
+// sortTablesByDependents sorts the given tables the other way round,
+// so that tables referring to others by foreign keys come before the
+// tables they refer to, which is the order they can be dropped in.
+// Tables that do not depend on each other are still sorted by name.
+func sortTablesByDependents(stmts model.Stmts, ids mapset.Set) ([]model.Table, error) {
+	return sortTables(stmts, ids, true)
+}
+
+// sortTables sorts the tables after the tables they refer to, or
+// before them if reverse is true
+func sortTables(stmts model.Stmts, ids mapset.Set, reverse bool) ([]model.Table, error) {
+	var tables []model.Table
+	for _, stmt := range stmts {
+		table, ok := stmt.(model.Table)
+		if !ok || !ids.Contains(table.ID()) {
+			continue
+		}
+		tables = append(tables, table)
+	}
+	if len(tables) != ids.Cardinality() {
+		return nil, errors.New(`failed to lookup tables to sort`)
+	}
+	sortTablesByName(tables)
+
+	// tables in the set that each table has to come after: the ones
+	// it refers to, or the ones referring to it if reverse is true
+	dependencies := make(map[string]mapset.Set)
+	for _, table := range tables {
+		dependencies[table.ID()] = mapset.NewSet()
+	}
+	for _, table := range tables {
+		for _, id := range referencedTableIDs(table) {
+			if !ids.Contains(id) {
+				continue
+			}
+			if reverse {
+				dependencies[id].Add(table.ID())
+			} else {
+				dependencies[table.ID()].Add(id)
+			}
+		}
+	}
+
+	sorted := make([]model.Table, 0, len(tables))
+	done := mapset.NewSet()
+	for len(sorted) < len(tables) {
+		var next model.Table
+		for _, table := range tables {
+			if done.Contains(table.ID()) {
+				continue
+			}
+			if dependencies[table.ID()].Difference(done).Cardinality() == 0 {
+				next = table
+				break
+			}
+		}
+		if next == nil {
+			// there's a cycle. pick the first one remaining
+			for _, table := range tables {
+				if !done.Contains(table.ID()) {
+					next = table
+					break
+				}
+			}
+		}
+		done.Add(next.ID())
+		sorted = append(sorted, next)
+	}
+	return sorted, nil
+}
+
+// sortTablesByName sorts the tables by name, which is the order that
+// changes to tables independent of each other are made in
+func sortTablesByName(tables []model.Table) {
+	sort.SliceStable(tables, func(i, j int) bool {
+		return tables[i].Name() < tables[j].Name()
+	})
+}
+
+func dropForeignKeyClause(idx model.Index) alterClause {
+	if idx.HasSymbol() {
+		return alterClause{kind: DropForeignKey, name: indexName(idx), before: definition(idx), sql: "DROP FOREIGN KEY `" + idx.Symbol() + "`"}
+	}
+	return alterClause{kind: DropForeignKey, name: indexName(idx), before: definition(idx), sql: "DROP FOREIGN KEY `" + idx.Name() + "`"}
+}
+
+// dropReferencingForeignKeys returns the changes to drop foreign
+// keys that would prevent the given tables from being dropped
+// in the given order. These are foreign keys in tables that are
+// kept, which are going away anyway, and foreign keys that form
+// a cycle amongst the dropped tables. The dropped foreign keys are
+// recorded, so that they are not dropped again when altering tables.
+func dropReferencingForeignKeys(ctx *diffCtx, dropped []model.Table) ([]Change, error) {
+	order := make(map[string]int)
+	for i, table := range dropped {
+		order[table.ID()] = i
+	}
+
+	var changes []Change
+	for _, stmt := range ctx.from {
+		table, ok := stmt.(model.Table)
+		if !ok || !ctx.fromSet.Contains(table.ID()) || !ctx.toSet.Contains(table.ID()) {
+			continue
+		}
+
+		stmt, ok := ctx.to.Lookup(table.ID())
+		if !ok {
+			return nil, errors.Errorf(`failed to lookup table %s`, table.ID())
+		}
+		after := stmt.(model.Table)
+
+		var clauses []alterClause
+		for idx := range table.Indexes() {
+			id, ok := foreignKeyTableID(table, idx)
+			if !ok {
+				continue
+			}
+			if _, ok := order[id]; !ok {
+				continue
+			}
+			if _, ok := after.LookupIndex(idx.ID()); ok {
+				continue
+			}
+			ctx.droppedForeignKeys.Add(idx.ID())
+			clauses = append(clauses, dropForeignKeyClause(idx))
+		}
+		changes = append(changes, alterTableChanges(ctx, table.Name(), clauses)...)
+	}
+
+	for i, table := range dropped {
+		var clauses []alterClause
+		for idx := range table.Indexes() {
+			id, ok := foreignKeyTableID(table, idx)
+			if !ok {
+				continue
+			}
+			// tables are dropped in order. only references to the
+			// tables that are dropped earlier are a problem
+			if j, ok := order[id]; !ok || j > i {
+				continue
+			}
+			clauses = append(clauses, dropForeignKeyClause(idx))
+		}
+		changes = append(changes, alterTableChanges(ctx, table.Name(), clauses)...)
+	}
+	return changes, nil
+}
+
+// addDeferredForeignKeys returns the changes to add foreign keys that
+// could not be created along with their tables
+func addDeferredForeignKeys(ctx *diffCtx, tables []model.Table, deferred map[string][]model.Index) ([]Change, error) {
+	var changes []Change
+	for _, table := range tables {
+		var clauses []alterClause
+		for _, idx := range deferred[table.ID()] {
+			var buf bytes.Buffer
+			buf.WriteString("ADD ")
+			if err := format.SQL(&buf, idx); err != nil {
+				return nil, err
+			}
+			clauses = append(clauses, alterClause{kind: AddForeignKey, name: indexName(idx), after: definition(idx), sql: buf.String()})
+		}
+		changes = append(changes, alterTableChanges(ctx, table.Name(), clauses)...)
+	}
+	return changes, nil
+}
+
+// indexNeededByForeignKey reports whether the index is the only one
+// left that a foreign key kept in the new schema can rely on. MySQL
+// refuses to drop such an index (errno 1553).
+func indexNeededByForeignKey(ctx *alterCtx, idx model.Index) bool {
+	for fk := range ctx.from.Indexes() {
+		if !fk.IsForeignKey() || !ctx.fromIndexes.Contains(fk.ID()) || !ctx.toIndexes.Contains(fk.ID()) {
+			continue
+		}
+		if !indexCoversForeignKey(idx, fk) {
+			continue
+		}
+
+		covered := false
+		for other := range ctx.from.Indexes() {
+			if other.IsForeignKey() || other.ID() == idx.ID() || !ctx.toIndexes.Contains(other.ID()) {
+				continue
+			}
+			if indexCoversForeignKey(other, fk) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return true
+		}
+	}
+	return false
+}
+
+// indexCoversForeignKey reports whether the columns of the foreign key
+// are the leading columns of the index
+func indexCoversForeignKey(idx, fk model.Index) bool {
+	var columns []string
+	for col := range idx.Columns() {
+		columns = append(columns, col.Name())
+	}
+
+	i := 0
+	for col := range fk.Columns() {
+		if i >= len(columns) || !strings.EqualFold(columns[i], col.Name()) {
+			return false
+		}
+		i++
+	}
+	return true
+}
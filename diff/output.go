@@ -0,0 +1,52 @@
+package diff
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// OutputFormat describes the format that Statements writes the diff in
+type OutputFormat int
+
+// List of possible OutputFormat values
+const (
+	// OutputFormatSQL writes the diff as SQL statements
+	OutputFormatSQL OutputFormat = iota
+	// OutputFormatJSON writes the diff as a JSON object holding the
+	// list of changes (see Change), so that it can be processed by
+	// other programs
+	OutputFormatJSON
+	// OutputFormatStat writes a summary of the diff in the style of
+	// `git diff --stat` (see Summary.WriteStat)
+	OutputFormatStat
+	// OutputFormatPlan writes a line describing each change, marked
+	// as adding (+), removing (-) or modifying (~) something, in the
+	// style of `terraform plan` (see WithColor)
+	OutputFormatPlan
+	// OutputFormatShortStat writes the totals of the diff on a single
+	// line (see Summary.ShortStat)
+	OutputFormatShortStat
+)
+
+// writeJSONChanges writes the changes as a JSON object. Changes that
+// are suppressed by WithAdditiveOnly are left out.
+func writeJSONChanges(dst io.Writer, changes []Change) error {
+	l := make([]Change, 0, len(changes))
+	for _, change := range changes {
+		if !change.suppressed {
+			l = append(l, change)
+		}
+	}
+
+	enc := json.NewEncoder(dst)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(struct {
+		Changes []Change `json:"changes"`
+	}{l}); err != nil {
+		return errors.Wrap(err, `failed to encode changes`)
+	}
+	return nil
+}
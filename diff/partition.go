@@ -0,0 +1,250 @@
+package diff
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// alterPartitions changes the partitioning of tables that exist in
+// both schemas. MySQL does not allow partitions to be changed along
+// with other alterations, so each change is a statement on its own.
+// Partitions are added, dropped and reorganized where possible, and
+// the table is partitioned from scratch otherwise.
+func alterPartitions(ctx *diffCtx) ([]Change, error) {
+	var changes []Change
+	for _, pair := range alteredTables(ctx) {
+		stmt, ok := ctx.from.Lookup(pair.oldID)
+		if !ok {
+			return nil, errors.Errorf(`table '%s' not found in old schema (alter partitions)`, pair.oldID)
+		}
+		from := stmt.(model.Table)
+
+		stmt, ok = ctx.to.Lookup(pair.newID)
+		if !ok {
+			return nil, errors.Errorf(`table '%s' not found in new schema (alter partitions)`, pair.newID)
+		}
+		to := stmt.(model.Table)
+
+		c, err := alterTablePartitions(from, to)
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to alter partitions of table %s`, to.Name())
+		}
+		changes = append(changes, c...)
+	}
+	return changes, nil
+}
+
+func alterTablePartitions(from, to model.Table) ([]Change, error) {
+	table := to.Name()
+	switch {
+	case !from.HasPartitioning() && !to.HasPartitioning():
+		return nil, nil
+	case !to.HasPartitioning():
+		return []Change{partitionChange(RemovePartitioning, table, "", "REMOVE PARTITIONING")}, nil
+	case !from.HasPartitioning() || !partitioningMethodsEqual(from.Partitioning(), to.Partitioning()):
+		return repartition(to)
+	}
+
+	fromDefs := partitionDefinitions(from.Partitioning())
+	toDefs := partitionDefinitions(to.Partitioning())
+	typ := strings.ToUpper(to.Partitioning().Type())
+	if strings.HasPrefix(typ, "RANGE") || strings.HasPrefix(typ, "LIST") {
+		return alterPartitionRanges(to, fromDefs, toDefs, strings.HasPrefix(typ, "RANGE"))
+	}
+
+	// HASH and KEY partitions can only be added or coalesced, which
+	// is done at the end of the list. Partitions that are named
+	// explicitly must stay the same otherwise.
+	if len(fromDefs) > 0 || len(toDefs) > 0 {
+		n := len(fromDefs)
+		if len(toDefs) < n {
+			n = len(toDefs)
+		}
+		if n == 0 || !partitionsEqual(fromDefs[:n], toDefs[:n]) {
+			return repartition(to)
+		}
+	}
+
+	fromCount := partitionCount(from.Partitioning(), fromDefs)
+	toCount := partitionCount(to.Partitioning(), toDefs)
+	switch {
+	case toCount > fromCount && len(toDefs) > 0:
+		var changes []Change
+		for _, def := range toDefs[fromCount:] {
+			c, err := addPartitionChange(table, def)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, c)
+		}
+		return changes, nil
+	case toCount > fromCount:
+		return []Change{partitionChange(AddPartition, table, "", "ADD PARTITION PARTITIONS "+strconv.Itoa(toCount-fromCount))}, nil
+	case toCount < fromCount:
+		return []Change{partitionChange(CoalescePartition, table, "", "COALESCE PARTITION "+strconv.Itoa(fromCount-toCount))}, nil
+	}
+	return nil, nil
+}
+
+// alterPartitionRanges changes RANGE and LIST partitions. Partitions
+// with the same name and definition in both schemas are left as is,
+// and the partitions between them are added, dropped or reorganized.
+// New RANGE partitions can only be added at the end, so the ones that
+// are added before an existing partition are split out of it.
+func alterPartitionRanges(to model.Table, fromDefs, toDefs []model.PartitionDefinition, isRange bool) ([]Change, error) {
+	table := to.Name()
+	fromIndex := make(map[string]int)
+	for i, def := range fromDefs {
+		fromIndex[def.Name()] = i
+	}
+
+	// partitions that stay as they are, which must be in the same
+	// order in both schemas
+	var anchors [][2]int
+	for j, def := range toDefs {
+		i, ok := fromIndex[def.Name()]
+		if !ok || !partitionsEqual(fromDefs[i:i+1], toDefs[j:j+1]) {
+			continue
+		}
+		if len(anchors) > 0 && anchors[len(anchors)-1][0] > i {
+			return repartition(to)
+		}
+		anchors = append(anchors, [2]int{i, j})
+	}
+	anchors = append(anchors, [2]int{len(fromDefs), len(toDefs)})
+
+	var changes []Change
+	var i, j int
+	for _, anchor := range anchors {
+		dropped := fromDefs[i:anchor[0]]
+		added := toDefs[j:anchor[1]]
+		last := anchor[0] == len(fromDefs)
+
+		switch {
+		case len(dropped) == 0 && len(added) == 0:
+		case len(dropped) == 0 && (last || !isRange):
+			for _, def := range added {
+				c, err := addPartitionChange(table, def)
+				if err != nil {
+					return nil, err
+				}
+				changes = append(changes, c)
+			}
+		case len(added) == 0:
+			for _, def := range dropped {
+				changes = append(changes, partitionChange(DropPartition, table, def.Name(), "DROP PARTITION `"+def.Name()+"`"))
+			}
+		default:
+			if len(dropped) == 0 {
+				// split the next partition
+				dropped = fromDefs[anchor[0] : anchor[0]+1]
+				added = toDefs[j : anchor[1]+1]
+			}
+			c, err := reorganizePartitionChange(table, dropped, added)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, c)
+		}
+
+		i, j = anchor[0]+1, anchor[1]+1
+	}
+	return changes, nil
+}
+
+func repartition(table model.Table) ([]Change, error) {
+	var buf bytes.Buffer
+	if err := format.SQL(&buf, table.Partitioning()); err != nil {
+		return nil, err
+	}
+	return []Change{partitionChange(Repartition, table.Name(), "", buf.String())}, nil
+}
+
+func addPartitionChange(table string, def model.PartitionDefinition) (Change, error) {
+	var buf bytes.Buffer
+	buf.WriteString("ADD PARTITION (")
+	if err := format.SQL(&buf, def); err != nil {
+		return Change{}, err
+	}
+	buf.WriteByte(')')
+	return partitionChange(AddPartition, table, def.Name(), buf.String()), nil
+}
+
+func reorganizePartitionChange(table string, from, to []model.PartitionDefinition) (Change, error) {
+	names := make([]string, len(from))
+	for i, def := range from {
+		names[i] = def.Name()
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("REORGANIZE PARTITION `")
+	buf.WriteString(strings.Join(names, "`, `"))
+	buf.WriteString("` INTO (")
+	for i, def := range to {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if err := format.SQL(&buf, def); err != nil {
+			return Change{}, err
+		}
+	}
+	buf.WriteByte(')')
+	return partitionChange(ReorganizePartition, table, strings.Join(names, ","), buf.String()), nil
+}
+
+func partitionChange(kind ChangeKind, table, name, clause string) Change {
+	return Change{
+		Kind:  kind,
+		Table: table,
+		Name:  name,
+		SQL:   "ALTER TABLE `" + table + "` " + clause + ";",
+	}
+}
+
+func partitionDefinitions(p model.Partitioning) []model.PartitionDefinition {
+	var l []model.PartitionDefinition
+	for def := range p.Definitions() {
+		l = append(l, def)
+	}
+	return l
+}
+
+// partitionCount returns the number of HASH or KEY partitions, which
+// defaults to 1
+func partitionCount(p model.Partitioning, defs []model.PartitionDefinition) int {
+	switch {
+	case len(defs) > 0:
+		return len(defs)
+	case p.HasCount():
+		return p.Count()
+	}
+	return 1
+}
+
+// partitioningMethodsEqual reports whether the tables are partitioned
+// in the same way, regardless of the partitions themselves
+func partitioningMethodsEqual(a, b model.Partitioning) bool {
+	return normalizeDefinition(a.Type()) == normalizeDefinition(b.Type()) &&
+		normalizeDefinition(a.Expression()) == normalizeDefinition(b.Expression()) &&
+		a.HasSubpartitioning() == b.HasSubpartitioning() &&
+		normalizeDefinition(a.Subpartitioning()) == normalizeDefinition(b.Subpartitioning())
+}
+
+func partitionsEqual(a, b []model.PartitionDefinition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name() != b[i].Name() ||
+			normalizeDefinition(a[i].Values()) != normalizeDefinition(b[i].Values()) ||
+			normalizeDefinition(a[i].Options()) != normalizeDefinition(b[i].Options()) {
+			return false
+		}
+	}
+	return true
+}
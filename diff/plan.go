@@ -0,0 +1,126 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ANSI escape sequences used by writePlan and writeColored
+const (
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+// writePlan writes a line per change, such as
+// "~ change column users.email: VARCHAR(191) to VARCHAR(255)", with a
+// marker telling if it adds (+), removes (-) or modifies (~)
+// something, followed by the number of each. Destructive changes are
+// marked as such. Notes and changes suppressed by WithAdditiveOnly are
+// left out, as they change nothing.
+func writePlan(buf *bytes.Buffer, changes []Change, color bool) {
+	var additions, removals, modifications int
+	for _, change := range changes {
+		if change.suppressed || change.Kind == NormalizeCharset {
+			continue
+		}
+
+		marker := changeDirection(change.Kind)
+		switch marker {
+		case '+':
+			additions++
+		case '-':
+			removals++
+		default:
+			modifications++
+		}
+
+		if color {
+			buf.WriteString(directionColor(marker))
+		}
+		buf.WriteByte(marker)
+		buf.WriteByte(' ')
+		buf.WriteString(describeChange(change))
+		if detail := planDetail(change); detail != "" {
+			buf.WriteString(": ")
+			buf.WriteString(detail)
+		}
+		if color {
+			buf.WriteString(ansiReset)
+		}
+		if change.Safety == Destructive {
+			buf.WriteString(" # destructive")
+		}
+		buf.WriteByte('\n')
+	}
+
+	if additions+removals+modifications == 0 {
+		buf.WriteString("No changes.\n")
+		return
+	}
+	fmt.Fprintf(buf, "\nPlan: %d to add, %d to change, %d to remove.\n", additions, modifications, removals)
+}
+
+// directionColor returns the escape sequence coloring changes that add
+// (+) something green, the ones that remove (-) something red, and the
+// ones that modify (~) something yellow
+func directionColor(marker byte) string {
+	switch marker {
+	case '+':
+		return ansiGreen
+	case '-':
+		return ansiRed
+	}
+	return ansiYellow
+}
+
+// batchDirection returns the direction of changes applied together,
+// which only add or remove something if all of them do
+func batchDirection(changes []Change) byte {
+	marker := changeDirection(changes[0].Kind)
+	for _, change := range changes[1:] {
+		if changeDirection(change.Kind) != marker {
+			return '~'
+		}
+	}
+	return marker
+}
+
+// writeColored writes the statement, colored after the direction of
+// the change if WithColor is given. Each line is colored on its own,
+// so that pagers showing part of the statement still color it.
+func writeColored(ctx *diffCtx, buf *bytes.Buffer, marker byte, s string) {
+	if !ctx.color {
+		buf.WriteString(s)
+		return
+	}
+	escape := directionColor(marker)
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		if line == "" {
+			continue
+		}
+		buf.WriteString(escape)
+		buf.WriteString(line)
+		buf.WriteString(ansiReset)
+	}
+}
+
+// planDetail describes what the change does to the object, beyond its
+// name: the detail used by intent comments if there is one, or the
+// definitions before and after it
+func planDetail(change Change) string {
+	switch {
+	case change.clause.detail != "":
+		return change.clause.detail
+	case change.Kind == ChangeTableOption || change.Kind == AlterCheck:
+		if change.Before != "" && change.After != "" {
+			return change.Before + " -> " + change.After
+		}
+	}
+	return ""
+}
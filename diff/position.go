@@ -0,0 +1,137 @@
+package diff
+
+import (
+	"bytes"
+
+	"github.com/deckarep/golang-set"
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// columnPosition describes a column that exists in both the old and
+// the new table, at its position in the new table
+type columnPosition struct {
+	id       string // ID of the column in the new table
+	oldID    string // ID of the column in the old table
+	oldOrder int    // position of the column in the old table
+}
+
+// commonColumns returns the columns that exist in both tables, renamed
+// ones included, in the order they appear in the new table
+func commonColumns(ctx *alterCtx) []columnPosition {
+	oldIDs := make(map[string]string)
+	for oldID, newID := range ctx.renamedColumns {
+		oldIDs[newID] = oldID
+	}
+
+	var columns []columnPosition
+	for col := range ctx.to.Columns() {
+		oldID := col.ID()
+		if id, ok := oldIDs[col.ID()]; ok {
+			oldID = id
+		} else if !ctx.fromColumns.Contains(col.ID()) {
+			continue
+		}
+		order, _ := ctx.from.LookupColumnOrder(oldID)
+		columns = append(columns, columnPosition{id: col.ID(), oldID: oldID, oldOrder: order})
+	}
+	return columns
+}
+
+// detectColumnMoves finds the columns that need to be moved so that
+// the columns common to both tables end up in the same order as in
+// the new table. The columns that are already in order with respect
+// to each other (the longest increasing subsequence of their old
+// positions) stay where they are, and everything else is moved.
+func detectColumnMoves(ctx *alterCtx) {
+	columns := commonColumns(ctx)
+
+	// length[i] is the length of the longest increasing subsequence
+	// ending at i, and prev[i] is the index before i in it
+	length := make([]int, len(columns))
+	prev := make([]int, len(columns))
+	last := -1
+	for i := range columns {
+		length[i] = 1
+		prev[i] = -1
+		for j := 0; j < i; j++ {
+			if columns[j].oldOrder < columns[i].oldOrder && length[j]+1 > length[i] {
+				length[i] = length[j] + 1
+				prev[i] = j
+			}
+		}
+		if last < 0 || length[i] > length[last] {
+			last = i
+		}
+	}
+
+	stay := mapset.NewSet()
+	for i := last; i >= 0; i = prev[i] {
+		stay.Add(columns[i].id)
+	}
+	for _, col := range columns {
+		if !stay.Contains(col.id) {
+			ctx.movedColumns.Add(col.id)
+		}
+	}
+}
+
+// reorderTableColumns moves the columns found by detectColumnMoves,
+// in the order they appear in the new table, each right after the
+// column that precedes it there. Columns that are also renamed or
+// changed are done so in the same clause. New columns are added
+// afterwards, so only the columns common to both tables are taken
+// into account.
+func reorderTableColumns(ctx *alterCtx) ([]alterClause, error) {
+	if ctx.movedColumns.Cardinality() == 0 {
+		return nil, nil
+	}
+
+	var clauses []alterClause
+	var before model.TableColumn
+	for _, pos := range commonColumns(ctx) {
+		col, ok := ctx.to.LookupColumn(pos.id)
+		if !ok {
+			return nil, errors.Errorf(`column %s not found in new schema`, pos.id)
+		}
+		prev := before
+		before = col
+		if !ctx.movedColumns.Contains(pos.id) {
+			continue
+		}
+
+		oldCol, ok := ctx.from.LookupColumn(pos.oldID)
+		if !ok {
+			return nil, errors.Errorf(`column %s not found in old schema`, pos.oldID)
+		}
+
+		var buf bytes.Buffer
+		clause := alterClause{kind: MoveColumn, name: col.Name(), before: definition(oldCol), after: definition(col)}
+		switch {
+		case pos.oldID != pos.id:
+			clause.kind = RenameColumn
+			clause.oldName = oldCol.Name()
+			buf.WriteString("CHANGE COLUMN `" + oldCol.Name() + "` ")
+		case !columnsEqual(ctx, oldCol, col):
+			clause.kind = ChangeColumn
+			if reason := columnDataLoss(oldCol, col); reason != "" {
+				clause.warning = "column `" + ctx.to.Name() + "`.`" + col.Name() + "`: " + reason
+			}
+			buf.WriteString("CHANGE COLUMN `" + col.Name() + "` ")
+		default:
+			buf.WriteString("MODIFY COLUMN ")
+		}
+		if err := format.SQL(&buf, col); err != nil {
+			return nil, err
+		}
+		if prev != nil {
+			buf.WriteString(" AFTER `" + prev.Name() + "`")
+		} else {
+			buf.WriteString(" FIRST")
+		}
+		clause.sql = buf.String()
+		clauses = append(clauses, clause)
+	}
+	return clauses, nil
+}
@@ -0,0 +1,54 @@
+package diff
+
+import (
+	"context"
+
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// Progress describes how far the comparison of two schemas has gone.
+// It is passed to the callback given by WithProgress each time a table
+// has been compared.
+type Progress struct {
+	Table string // name of the table that was just compared
+	Done  int    // number of tables compared so far
+	Total int    // number of tables to compare
+}
+
+// tableProgress keeps track of the tables compared so far
+type tableProgress struct {
+	context  context.Context
+	callback func(Progress)
+	done     int
+	total    int
+}
+
+// startProgress counts the tables to be compared. Renamed tables are
+// no longer in either set of tables, and are counted once.
+func startProgress(ctx *diffCtx) {
+	ctx.progress.done = 0
+	ctx.progress.total = ctx.fromSet.Union(ctx.toSet).Cardinality() + len(ctx.renamedTables)
+}
+
+// tableCompared reports that the given table has been compared, and
+// returns an error if the comparison has been cancelled
+func tableCompared(ctx *diffCtx, table string) error {
+	if err := checkCancelled(ctx); err != nil {
+		return err
+	}
+	ctx.progress.done++
+	if ctx.progress.callback != nil {
+		ctx.progress.callback(Progress{Table: table, Done: ctx.progress.done, Total: ctx.progress.total})
+	}
+	return nil
+}
+
+func checkCancelled(ctx *diffCtx) error {
+	if ctx.progress.context == nil {
+		return nil
+	}
+	if err := ctx.progress.context.Err(); err != nil {
+		return errors.Wrap(err, `comparison cancelled`)
+	}
+	return nil
+}
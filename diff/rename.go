@@ -0,0 +1,541 @@
+package diff
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/deckarep/golang-set"
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/internal/util"
+	"github.com/schemalex/schemalex/model"
+)
+
+// columnDefinition returns the SQL definition of a column, minus
+// its name. Two columns with the same definition only differ by name
+func columnDefinition(col model.TableColumn, ignoreComments bool) (string, error) {
+	if ignoreComments {
+		col = withoutComment(col)
+	}
+	var buf bytes.Buffer
+	if err := format.SQL(&buf, col); err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(buf.String(), util.Backquote(col.Name())+" "), nil
+}
+
+// tableDefinition returns the CREATE TABLE statement of a table,
+// minus its name and the options matching ignoreOptions.
+func tableDefinition(table model.Table, ignoreComments bool, ignoreOptions []string) (string, error) {
+	if ignoreComments {
+		f := tableFilter{column: withoutComment, option: withoutOptions("COMMENT")}
+		table = f.apply(table)
+	}
+	if len(ignoreOptions) > 0 {
+		f := tableFilter{option: func(opt model.TableOption) bool {
+			return !matchTableOption(opt, ignoreOptions)
+		}}
+		table = f.apply(table)
+	}
+	var buf bytes.Buffer
+	if err := format.SQL(&buf, table); err != nil {
+		return "", err
+	}
+	return strings.Replace(buf.String(), util.Backquote(table.Name()), "", 1), nil
+}
+
+// columnRenames is the value of WithColumnRenames
+type columnRenames struct {
+	table   string
+	renames map[string]string
+}
+
+// applyTableRenames pairs up the tables that are renamed explicitly
+// (see WithTableRenames), which are then left out of the tables to be
+// dropped and created, and of the renames to be detected
+func applyTableRenames(ctx *diffCtx, renames map[string]string) error {
+	oldNames := make([]string, 0, len(renames))
+	for oldName := range renames {
+		oldNames = append(oldNames, oldName)
+	}
+	sort.Strings(oldNames)
+
+	for _, oldName := range oldNames {
+		newName := renames[oldName]
+		oldID := model.NewTable(oldName).ID()
+		newID := model.NewTable(newName).ID()
+		if !ctx.fromSet.Contains(oldID) || ctx.toSet.Contains(oldID) {
+			return errors.Errorf(`table %s is renamed, but is not only in the old schema`, oldName)
+		}
+		if !ctx.toSet.Contains(newID) || ctx.fromSet.Contains(newID) {
+			return errors.Errorf(`table %s is renamed to %s, which is not only in the new schema`, oldName, newName)
+		}
+		ctx.renamedTables[oldID] = newID
+		ctx.fromSet.Remove(oldID)
+		ctx.toSet.Remove(newID)
+	}
+	return nil
+}
+
+// checkColumnRenames makes sure that the tables whose columns are
+// renamed explicitly exist in both schemas
+func checkColumnRenames(ctx *diffCtx) error {
+	renamed := mapset.NewSet()
+	for _, newID := range ctx.renamedTables {
+		renamed.Add(newID)
+	}
+	for name := range ctx.columnRenames {
+		id := model.NewTable(name).ID()
+		if !renamed.Contains(id) && !(ctx.fromSet.Contains(id) && ctx.toSet.Contains(id)) {
+			return errors.Errorf(`columns of table %s are renamed, but the table is not in both schemas`, name)
+		}
+	}
+	return nil
+}
+
+// applyColumnRenames pairs up the columns that are renamed explicitly
+// (see WithColumnRenames). Unlike detected renames, the definitions of
+// the columns may differ.
+func applyColumnRenames(ctx *alterCtx, renames map[string]string) error {
+	for oldName, newName := range renames {
+		oldID := model.NewTableColumn(oldName).ID()
+		newID := model.NewTableColumn(newName).ID()
+		if !ctx.fromColumns.Contains(oldID) || ctx.toColumns.Contains(oldID) {
+			return errors.Errorf(`column %s.%s is renamed, but is not only in the old table`, ctx.to.Name(), oldName)
+		}
+		if !ctx.toColumns.Contains(newID) || ctx.fromColumns.Contains(newID) {
+			return errors.Errorf(`column %s.%s is renamed to %s, which is not only in the new table`, ctx.to.Name(), oldName, newName)
+		}
+		ctx.renamedColumns[oldID] = newID
+	}
+	return nil
+}
+
+// detectTableRenames pairs up tables that are dropped from the old
+// schema with tables that are created in the new schema, if they share
+// exactly the same definition. Tables that are paired up are removed
+// from the list of tables to be dropped and created. If a definition
+// matches more than one table, no rename is assumed.
+func detectTableRenames(ctx *diffCtx) error {
+	dropped := make(map[string][]string) // definition -> table IDs
+	for _, id := range ctx.fromSet.Difference(ctx.toSet).ToSlice() {
+		stmt, ok := ctx.from.Lookup(id.(string))
+		if !ok {
+			return errors.Errorf(`failed to lookup table %s`, id)
+		}
+		def, err := tableDefinition(stmt.(model.Table), ctx.ignoreComments, ctx.ignoreTableOptions)
+		if err != nil {
+			return err
+		}
+		dropped[def] = append(dropped[def], id.(string))
+	}
+
+	created := make(map[string][]string) // definition -> table IDs
+	for _, id := range ctx.toSet.Difference(ctx.fromSet).ToSlice() {
+		stmt, ok := ctx.to.Lookup(id.(string))
+		if !ok {
+			return errors.Errorf(`failed to lookup table %s`, id)
+		}
+		def, err := tableDefinition(stmt.(model.Table), ctx.ignoreComments, ctx.ignoreTableOptions)
+		if err != nil {
+			return err
+		}
+		created[def] = append(created[def], id.(string))
+	}
+
+	for def, oldIDs := range dropped {
+		newIDs := created[def]
+		if len(oldIDs) != 1 || len(newIDs) != 1 {
+			continue
+		}
+		ctx.renamedTables[oldIDs[0]] = newIDs[0]
+		ctx.fromSet.Remove(oldIDs[0])
+		ctx.toSet.Remove(newIDs[0])
+	}
+	return nil
+}
+
+// renamedTable returns a copy of the old table of a renamed pair under
+// its new name, which it is altered with once renamed. Indexes are
+// identified along with the name of their table, and would otherwise
+// all be dropped and added again. Foreign keys that are dropped along
+// with the tables they refer to are left out, so that they are added
+// again.
+func renamedTable(ctx *diffCtx, table model.Table, name string) model.Table {
+	t := model.NewTable(name)
+	t.SetTemporary(table.IsTemporary())
+	t.SetIfNotExists(table.IsIfNotExists())
+	if table.HasLikeTable() {
+		t.SetLikeTable(table.LikeTable())
+	}
+	for col := range table.Columns() {
+		t.AddColumn(col)
+	}
+	for idx := range table.Indexes() {
+		if ctx.droppedForeignKeys.Contains(idx.ID()) {
+			continue
+		}
+		t.AddIndex(idx.Clone().SetTable(t.ID()))
+	}
+	for check := range table.Checks() {
+		t.AddCheck(check)
+	}
+	for opt := range table.Options() {
+		t.AddOption(opt)
+	}
+	t.SetPartitioning(table.Partitioning())
+	return t
+}
+
+func renameTables(ctx *diffCtx) ([]Change, error) {
+	oldIDs := make([]string, 0, len(ctx.renamedTables))
+	for oldID := range ctx.renamedTables {
+		oldIDs = append(oldIDs, oldID)
+	}
+	sort.Strings(oldIDs)
+
+	var changes []Change
+	for _, oldID := range oldIDs {
+		oldStmt, ok := ctx.from.Lookup(oldID)
+		if !ok {
+			return nil, errors.Errorf(`failed to lookup table %s`, oldID)
+		}
+		newStmt, ok := ctx.to.Lookup(ctx.renamedTables[oldID])
+		if !ok {
+			return nil, errors.Errorf(`failed to lookup table %s`, ctx.renamedTables[oldID])
+		}
+
+		oldName := oldStmt.(model.Table).Name()
+		newName := newStmt.(model.Table).Name()
+		changes = append(changes, Change{
+			Kind:    RenameTable,
+			Table:   newName,
+			OldName: oldName,
+			Before:  definition(oldStmt),
+			After:   definition(newStmt),
+			SQL:     "RENAME TABLE `" + oldName + "` TO `" + newName + "`;",
+		})
+	}
+	return changes, nil
+}
+
+// detectColumnRenames pairs up columns that are dropped from the old
+// table with columns that are added to the new table, if they share
+// exactly the same definition. When there are multiple candidates,
+// a column at the same position is preferred, followed by the one
+// with the most similar name. If threshold is greater than 0, pairs
+// whose names are less similar than threshold are never considered
+// to be a rename.
+func detectColumnRenames(ctx *alterCtx, threshold float64) error {
+	// columns that are renamed explicitly are already paired up
+	renamed := mapset.NewSet()
+	for oldID, newID := range ctx.renamedColumns {
+		renamed.Add(oldID)
+		renamed.Add(newID)
+	}
+
+	var dropped []model.TableColumn
+	for _, v := range ctx.fromColumns.Difference(ctx.toColumns).Difference(renamed).ToSlice() {
+		col, ok := ctx.from.LookupColumn(v.(string))
+		if !ok {
+			return errors.Errorf(`failed to lookup column %s`, v)
+		}
+		dropped = append(dropped, col)
+	}
+	sort.Slice(dropped, func(i, j int) bool {
+		iorder, _ := ctx.from.LookupColumnOrder(dropped[i].ID())
+		jorder, _ := ctx.from.LookupColumnOrder(dropped[j].ID())
+		return iorder < jorder
+	})
+
+	added := make(map[string]string) // column ID -> definition
+	for _, v := range ctx.toColumns.Difference(ctx.fromColumns).Difference(renamed).ToSlice() {
+		col, ok := ctx.to.LookupColumn(v.(string))
+		if !ok {
+			return errors.Errorf(`failed to lookup column %s`, v)
+		}
+		def, err := columnDefinition(col, ctx.ignoreComments)
+		if err != nil {
+			return err
+		}
+		added[col.ID()] = def
+	}
+
+	for _, oldCol := range dropped {
+		def, err := columnDefinition(oldCol, ctx.ignoreComments)
+		if err != nil {
+			return err
+		}
+
+		var candidates []model.TableColumn
+		for id, newDef := range added {
+			if newDef != def {
+				continue
+			}
+			newCol, _ := ctx.to.LookupColumn(id)
+			if threshold > 0 && similarity(oldCol.Name(), newCol.Name()) < threshold {
+				continue
+			}
+			candidates = append(candidates, newCol)
+		}
+
+		newCol, ok := pickRenameCandidate(ctx, oldCol, candidates)
+		if !ok {
+			continue
+		}
+		ctx.renamedColumns[oldCol.ID()] = newCol.ID()
+		delete(added, newCol.ID())
+	}
+	return nil
+}
+
+func pickRenameCandidate(ctx *alterCtx, oldCol model.TableColumn, candidates []model.TableColumn) (model.TableColumn, bool) {
+	switch len(candidates) {
+	case 0:
+		return nil, false
+	case 1:
+		return candidates[0], true
+	}
+
+	oldOrder, _ := ctx.from.LookupColumnOrder(oldCol.ID())
+	for _, col := range candidates {
+		if order, _ := ctx.to.LookupColumnOrder(col.ID()); order == oldOrder {
+			return col, true
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return similarity(oldCol.Name(), candidates[i].Name()) > similarity(oldCol.Name(), candidates[j].Name())
+	})
+	if similarity(oldCol.Name(), candidates[0].Name()) == similarity(oldCol.Name(), candidates[1].Name()) {
+		// ambiguous. we'd rather drop and add than guess wrong
+		return nil, false
+	}
+	return candidates[0], true
+}
+
+func renameTableColumns(ctx *alterCtx) ([]alterClause, error) {
+	oldColumnNames := make([]string, 0, len(ctx.renamedColumns))
+	for oldColumnName := range ctx.renamedColumns {
+		oldColumnNames = append(oldColumnNames, oldColumnName)
+	}
+	sort.Strings(oldColumnNames)
+
+	var clauses []alterClause
+	for _, oldColumnName := range oldColumnNames {
+		// moved columns are renamed when they are moved
+		if ctx.movedColumns.Contains(ctx.renamedColumns[oldColumnName]) {
+			continue
+		}
+		oldCol, ok := ctx.from.LookupColumn(oldColumnName)
+		if !ok {
+			return nil, errors.Errorf(`column %s not found in old schema`, oldColumnName)
+		}
+		newCol, ok := ctx.to.LookupColumn(ctx.renamedColumns[oldColumnName])
+		if !ok {
+			return nil, errors.Errorf(`column %s not found in new schema`, ctx.renamedColumns[oldColumnName])
+		}
+
+		var buf bytes.Buffer
+		renameOnly, err := onlyRenamed(ctx, oldCol, newCol)
+		if err != nil {
+			return nil, err
+		}
+		if renameOnly {
+			buf.WriteString("RENAME COLUMN `")
+			buf.WriteString(oldCol.Name())
+			buf.WriteString("` TO `")
+			buf.WriteString(newCol.Name())
+			buf.WriteString("`")
+		} else {
+			buf.WriteString("CHANGE COLUMN `")
+			buf.WriteString(oldCol.Name())
+			buf.WriteString("` ")
+			if err := format.SQL(&buf, newCol); err != nil {
+				return nil, err
+			}
+		}
+		clauses = append(clauses, alterClause{kind: RenameColumn, name: newCol.Name(), oldName: oldCol.Name(), before: definition(oldCol), after: definition(newCol), sql: buf.String()})
+	}
+	return clauses, nil
+}
+
+// onlyRenamed reports whether the column can be renamed with RENAME
+// COLUMN, which leaves its definition as it is
+func onlyRenamed(ctx *alterCtx, oldCol, newCol model.TableColumn) (bool, error) {
+	if !ctx.renameColumns {
+		return false, nil
+	}
+	oldDef, err := columnDefinition(oldCol, ctx.ignoreComments)
+	if err != nil {
+		return false, err
+	}
+	newDef, err := columnDefinition(newCol, ctx.ignoreComments)
+	if err != nil {
+		return false, err
+	}
+	return oldDef == newDef, nil
+}
+
+// similarity returns a value between 0 and 1 describing how similar
+// the two strings are, based on their levenshtein distance
+func similarity(a, b string) float64 {
+	a = strings.ToLower(a)
+	b = strings.ToLower(b)
+	max := len(a)
+	if len(b) > max {
+		max = len(b)
+	}
+	if max == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(a, b))/float64(max)
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j] + 1
+			if v := cur[j-1] + 1; v < cur[j] {
+				cur[j] = v
+			}
+			if v := prev[j-1] + cost; v < cur[j] {
+				cur[j] = v
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// indexSignature identifies an index by its kind, columns and options,
+// regardless of its name
+func indexSignature(idx model.Index) string {
+	return idx.Clone().SetName("").SetSymbol("").ID()
+}
+
+// detectIndexRenames matches the indexes that only exist in one of the
+// tables by their signature, in the order they appear in the old
+// table. Matched indexes are treated as existing in both tables.
+func detectIndexRenames(ctx *alterCtx) {
+	var added []model.Index
+	for idx := range ctx.to.Indexes() {
+		if !idx.IsForeignKey() && !idx.IsPrimaryKey() && !ctx.fromIndexes.Contains(idx.ID()) {
+			added = append(added, idx)
+		}
+	}
+
+	for oldIdx := range ctx.from.Indexes() {
+		if oldIdx.IsForeignKey() || oldIdx.IsPrimaryKey() || !ctx.fromIndexes.Contains(oldIdx.ID()) || ctx.toIndexes.Contains(oldIdx.ID()) {
+			continue
+		}
+		sig := indexSignature(oldIdx)
+		for i, newIdx := range added {
+			if indexSignature(newIdx) != sig {
+				continue
+			}
+			ctx.fromIndexes.Add(newIdx.ID())
+			ctx.toIndexes.Add(oldIdx.ID())
+			ctx.matchedIndexes[oldIdx.ID()] = newIdx.ID()
+			added = append(added[:i], added[i+1:]...)
+			break
+		}
+	}
+}
+
+// matchRenamedColumnIndexes treats the indexes that only differ in the
+// names of renamed columns as existing in both tables, as renaming a
+// column renames it in its indexes as well.
+func matchRenamedColumnIndexes(ctx *alterCtx) error {
+	names := make(map[string]string) // old column name -> new column name
+	for oldID, newID := range ctx.renamedColumns {
+		oldCol, ok := ctx.from.LookupColumn(oldID)
+		if !ok {
+			return errors.Errorf(`failed to lookup column %s`, oldID)
+		}
+		newCol, ok := ctx.to.LookupColumn(newID)
+		if !ok {
+			return errors.Errorf(`failed to lookup column %s`, newID)
+		}
+		names[oldCol.Name()] = newCol.Name()
+	}
+
+	for oldIdx := range ctx.from.Indexes() {
+		if !ctx.fromIndexes.Contains(oldIdx.ID()) || ctx.toIndexes.Contains(oldIdx.ID()) {
+			continue
+		}
+		var columns []model.IndexColumn
+		renamed := false
+		for col := range oldIdx.Columns() {
+			newName, ok := names[col.Name()]
+			if !ok {
+				columns = append(columns, col)
+				continue
+			}
+			newCol := model.NewIndexColumn(newName)
+			if col.HasLength() {
+				newCol.SetLength(col.Length())
+			}
+			if col.IsAscending() {
+				newCol.SetSortDirection(model.SortDirectionAscending)
+			} else if col.IsDescending() {
+				newCol.SetSortDirection(model.SortDirectionDescending)
+			}
+			columns = append(columns, newCol)
+			renamed = true
+		}
+		if !renamed {
+			continue
+		}
+		newID := oldIdx.Clone().SetColumns(columns...).ID()
+		if !ctx.toIndexes.Contains(newID) || ctx.fromIndexes.Contains(newID) {
+			continue
+		}
+		ctx.fromIndexes.Add(newID)
+		ctx.toIndexes.Add(oldIdx.ID())
+	}
+	return nil
+}
+
+// renameTableIndexes renames the indexes matched by their signature
+// to the name they have in the new table, if asked to
+func renameTableIndexes(ctx *alterCtx) ([]alterClause, error) {
+	if !ctx.renameIndexes {
+		return nil, nil
+	}
+
+	var clauses []alterClause
+	for oldIdx := range ctx.from.Indexes() {
+		newID, ok := ctx.matchedIndexes[oldIdx.ID()]
+		if !ok || ctx.reorderedIndexes.Contains(oldIdx.ID()) {
+			continue
+		}
+		newIdx, ok := ctx.to.LookupIndex(newID)
+		if !ok {
+			return nil, errors.Errorf(`index %s not found in new schema`, newID)
+		}
+		if indexName(oldIdx) == indexName(newIdx) {
+			continue
+		}
+		clauses = append(clauses, alterClause{
+			kind:    RenameIndex,
+			name:    indexName(newIdx),
+			oldName: indexName(oldIdx),
+			before:  definition(oldIdx),
+			after:   definition(newIdx),
+			sql:     "RENAME INDEX `" + indexName(oldIdx) + "` TO `" + indexName(newIdx) + "`",
+		})
+	}
+	return clauses, nil
+}
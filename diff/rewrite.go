@@ -0,0 +1,53 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Rewriter is a hook that is given each change before it is rendered.
+// It returns the change, possibly modified, and whether to keep it.
+// Changing Change.Comment annotates the change with a comment written
+// before its statement.
+type Rewriter func(Change) (Change, bool)
+
+// rewriteChanges runs the changes through the rewriters, in the order
+// they were given. Changes to a table whose SQL is modified are taken
+// out of their batch, so that they are rendered as they were
+// rewritten, instead of being combined with the other changes to the
+// table.
+func rewriteChanges(ctx *diffCtx, changes []Change) []Change {
+	result := changes[:0]
+	for _, change := range changes {
+		if change.suppressed {
+			result = append(result, change)
+			continue
+		}
+
+		sql := change.SQL
+		keep := true
+		for _, rewrite := range ctx.rewriters {
+			if change, keep = rewrite(change); !keep {
+				break
+			}
+		}
+		if !keep {
+			continue
+		}
+		if change.batch != 0 && change.SQL != sql {
+			change.batch = 0
+		}
+		result = append(result, change)
+	}
+	return result
+}
+
+// writeComment writes the comment added to a change by a Rewriter
+func writeComment(buf *bytes.Buffer, prefix string, comment string) {
+	if comment == "" {
+		return
+	}
+	buf.WriteString(prefix)
+	buf.WriteString(strings.Replace(comment, "\n", "\n"+prefix, -1))
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,269 @@
+package diff
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/deckarep/golang-set"
+	"github.com/schemalex/schemalex/model"
+)
+
+// Safety describes how risky it is to apply a change
+type Safety string
+
+// List of possible Safety values
+const (
+	// Safe changes can be applied without much concern
+	Safe Safety = "safe"
+	// Blocking changes lock or copy the table while they are applied,
+	// blocking writes to it (see WithMySQLVersion)
+	Blocking Safety = "blocking"
+	// Destructive changes may lose existing data
+	Destructive Safety = "destructive"
+)
+
+var safetyRank = map[Safety]int{Safe: 0, Blocking: 1, Destructive: 2}
+
+// classifySafety tells how risky the change is
+func classifySafety(ctx *diffCtx, change Change) Safety {
+	switch change.Kind {
+	case DropTable, DropColumn, DropPartition:
+		return Destructive
+	case CreateTable, RenameTable, CreateView, ReplaceView, DropView, CreateTrigger, DropTrigger, NormalizeCharset:
+		return Safe
+	}
+
+	if change.Warning != "" {
+		return Destructive
+	}
+
+	hint := onlineDDLHint(ctx, change.Kind)
+	if strings.EqualFold(hint.Algorithm, "COPY") || lockRank[strings.ToUpper(hint.Lock)] > lockRank["NONE"] {
+		return Blocking
+	}
+	return Safe
+}
+
+var integerRank = map[model.ColumnType]int{
+	model.ColumnTypeTinyInt:   1,
+	model.ColumnTypeSmallInt:  2,
+	model.ColumnTypeMediumInt: 3,
+	model.ColumnTypeInt:       4,
+	model.ColumnTypeBigInt:    5,
+}
+
+// maximum lengths of the types that do not take one
+var fixedCapacity = map[model.ColumnType]int64{
+	model.ColumnTypeTinyText:   255,
+	model.ColumnTypeText:       65535,
+	model.ColumnTypeMediumText: 16777215,
+	model.ColumnTypeLongText:   4294967295,
+	model.ColumnTypeTinyBlob:   255,
+	model.ColumnTypeBlob:       65535,
+	model.ColumnTypeMediumBlob: 16777215,
+	model.ColumnTypeLongBlob:   4294967295,
+}
+
+func isTextType(typ model.ColumnType) bool {
+	switch typ {
+	case model.ColumnTypeChar, model.ColumnTypeVarChar,
+		model.ColumnTypeTinyText, model.ColumnTypeText,
+		model.ColumnTypeMediumText, model.ColumnTypeLongText:
+		return true
+	}
+	return false
+}
+
+func isNumericType(typ model.ColumnType) bool {
+	switch typ {
+	case model.ColumnTypeDecimal, model.ColumnTypeNumeric,
+		model.ColumnTypeFloat, model.ColumnTypeDouble, model.ColumnTypeReal:
+		return true
+	}
+	return integerRank[typ] > 0
+}
+
+func isBinaryType(typ model.ColumnType) bool {
+	switch typ {
+	case model.ColumnTypeBinary, model.ColumnTypeVarBinary,
+		model.ColumnTypeTinyBlob, model.ColumnTypeBlob,
+		model.ColumnTypeMediumBlob, model.ColumnTypeLongBlob:
+		return true
+	}
+	return false
+}
+
+// capacity returns the maximum length of a string column
+func capacity(col model.TableColumn) int64 {
+	if n, ok := fixedCapacity[col.Type()]; ok {
+		return n
+	}
+	if !col.HasLength() {
+		// CHAR and BINARY default to a length of 1
+		return 1
+	}
+	n, _ := strconv.ParseInt(col.Length().Length(), 10, 64)
+	return n
+}
+
+// decimalDigits returns the number of digits before and after the
+// decimal point of a DECIMAL column
+func decimalDigits(col model.TableColumn) (int, int) {
+	precision, scale := 10, 0
+	if col.HasLength() {
+		precision, _ = strconv.Atoi(col.Length().Length())
+		if col.Length().HasDecimal() {
+			scale, _ = strconv.Atoi(col.Length().Decimal())
+		} else {
+			scale = 0
+		}
+	}
+	return precision - scale, scale
+}
+
+func typeString(col model.TableColumn) string {
+	s := col.Type().String()
+	if l := lengthString(col); l != "" {
+		s += "(" + l + ")"
+	}
+	if col.IsUnsigned() {
+		s += " UNSIGNED"
+	}
+	return s
+}
+
+func lengthString(col model.TableColumn) string {
+	if !col.HasLength() {
+		return ""
+	}
+	if col.Length().HasDecimal() {
+		return col.Length().Length() + "," + col.Length().Decimal()
+	}
+	return col.Length().Length()
+}
+
+func valueSet(ch chan string) mapset.Set {
+	set := mapset.NewSet()
+	for v := range ch {
+		set.Add(v)
+	}
+	return set
+}
+
+// columnDataLoss tells why changing the column may lose the values
+// stored in it, such as when its type is narrowed from BIGINT to INT,
+// or when it becomes NOT NULL without a default value, in which case
+// existing NULLs are either rejected or replaced. It returns an empty
+// string if the values are preserved.
+func columnDataLoss(before, after model.TableColumn) string {
+	// compare INTEGER and INT (11) as the same type
+	before, _ = before.Normalize()
+	after, _ = after.Normalize()
+
+	if reason := typeDataLoss(before, after); reason != "" {
+		return reason
+	}
+	if before.NullState() != model.NullStateNotNull && after.NullState() == model.NullStateNotNull && !after.HasDefault() {
+		return "making it NOT NULL without a default value rejects or replaces existing NULLs"
+	}
+	return ""
+}
+
+// addColumnDataLoss tells why adding the column may not fill existing
+// rows as expected, which is when it is NOT NULL without a default
+// value: MySQL fills it with the implicit default of its type, such as
+// 0 or an empty string. It returns an empty string otherwise.
+func addColumnDataLoss(col model.TableColumn) string {
+	if col.NullState() != model.NullStateNotNull || col.HasDefault() || col.IsAutoIncrement() || col.HasGeneratedExpr() {
+		return ""
+	}
+	return "adding it NOT NULL without a default value fills existing rows with the implicit default of " + col.Type().String()
+}
+
+func typeDataLoss(before, after model.TableColumn) string {
+	changing := "changing " + typeString(before) + " to " + typeString(after)
+	narrowed := changing + " may truncate existing values"
+	bt, at := before.Type(), after.Type()
+
+	if brank, ok := integerRank[bt]; ok {
+		if arank, ok := integerRank[at]; ok {
+			switch {
+			case arank < brank:
+				return narrowed
+			case !before.IsUnsigned() && after.IsUnsigned():
+				return changing + " loses negative values"
+			case before.IsUnsigned() && !after.IsUnsigned() && arank == brank:
+				return narrowed
+			}
+			return ""
+		}
+	}
+
+	switch {
+	case bt == model.ColumnTypeDecimal && at == model.ColumnTypeDecimal:
+		bint, bscale := decimalDigits(before)
+		aint, ascale := decimalDigits(after)
+		if aint < bint || ascale < bscale || !before.IsUnsigned() && after.IsUnsigned() {
+			return narrowed
+		}
+		return ""
+	case (bt == model.ColumnTypeFloat || bt == model.ColumnTypeDouble) && (at == model.ColumnTypeFloat || at == model.ColumnTypeDouble):
+		if bt == model.ColumnTypeDouble && at == model.ColumnTypeFloat || !before.IsUnsigned() && after.IsUnsigned() {
+			return narrowed
+		}
+		return ""
+	case isTextType(bt) && isTextType(at), isBinaryType(bt) && isBinaryType(at):
+		if capacity(after) < capacity(before) {
+			return narrowed
+		}
+		return ""
+	case bt == at && (bt == model.ColumnTypeEnum || bt == model.ColumnTypeSet):
+		var removed mapset.Set
+		if bt == model.ColumnTypeEnum {
+			removed = valueSet(before.EnumValues()).Difference(valueSet(after.EnumValues()))
+		} else {
+			removed = valueSet(before.SetValues()).Difference(valueSet(after.SetValues()))
+		}
+		if removed.Cardinality() > 0 {
+			return "removing values from " + bt.String() + " invalidates rows that use them"
+		}
+		return ""
+	case bt != at:
+		return changing + " may not preserve existing values"
+	}
+	return ""
+}
+
+// worstSafety returns the most risky safety amongst the changes
+func worstSafety(changes []Change) Safety {
+	safety := Safe
+	for _, change := range changes {
+		if safetyRank[change.Safety] > safetyRank[safety] {
+			safety = change.Safety
+		}
+	}
+	return safety
+}
+
+func writeSafetyComment(buf *bytes.Buffer, prefix string, safety Safety) {
+	buf.WriteString(prefix)
+	buf.WriteString("safety: ")
+	buf.WriteString(string(safety))
+	buf.WriteByte('\n')
+}
+
+// DestructiveChangesError is returned when the diff contains
+// destructive changes and WithFailOnDestructive is enabled
+type DestructiveChangesError struct {
+	// Changes are the destructive changes, in the order they would
+	// have been applied
+	Changes []Change
+}
+
+func (e *DestructiveChangesError) Error() string {
+	if len(e.Changes) == 1 {
+		return "refusing to produce diff with a destructive change: " + e.Changes[0].SQL
+	}
+	return "refusing to produce diff with " + strconv.Itoa(len(e.Changes)) + " destructive changes"
+}
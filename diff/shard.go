@@ -0,0 +1,103 @@
+package diff
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// shardConcurrency is the number of shards read and compared at once
+const shardConcurrency = 8
+
+// Shard is a schema to be checked against a reference schema, such as
+// one of the databases that the same schema is deployed to
+type Shard struct {
+	Name   string
+	Source schemalex.SchemaSource
+}
+
+// ShardResult is the outcome of checking a single shard
+type ShardResult struct {
+	Name string
+	// Changes are the changes needed to bring the shard in line with
+	// the reference schema
+	Changes []Change
+	// Err is set if the shard could not be read or compared
+	Err error
+}
+
+// Deviates reports whether the shard differs from the reference schema
+func (r ShardResult) Deviates() bool {
+	return r.Err == nil && len(r.Changes) > 0
+}
+
+// CheckShards compares each shard against the reference schema, and
+// returns the results in the same order as the shards. The reference
+// schema is only read once, and the shards are read and compared
+// concurrently. A shard that cannot be read or compared does not stop
+// the others from being checked, and has its error in its result.
+//
+// The options are used for every comparison, as they are by Compute,
+// except for WithProgress, which is ignored.
+func CheckShards(reference schemalex.SchemaSource, shards []Shard, options ...Option) ([]ShardResult, error) {
+	var p *schemalex.Parser
+	compareOptions := make([]Option, 0, len(options))
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyParser:
+			p = o.Value().(*schemalex.Parser)
+		case optkeyProgress:
+			continue
+		}
+		compareOptions = append(compareOptions, o)
+	}
+	if p == nil {
+		p = schemalex.New()
+	}
+
+	var buf bytes.Buffer
+	if err := reference.WriteSchema(&buf); err != nil {
+		return nil, errors.Wrapf(err, `failed to retrieve schema from reference source %s`, reference)
+	}
+	// each comparison gets statements of its own, so that nothing is
+	// shared between goroutines
+	src := buf.Bytes()
+	if _, err := p.Parse(src); err != nil {
+		return nil, errors.Wrap(err, `failed to parse reference schema`)
+	}
+
+	results := make([]ShardResult, len(shards))
+	sem := make(chan struct{}, shardConcurrency)
+	var wg sync.WaitGroup
+	for i, shard := range shards {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, shard Shard) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = ShardResult{Name: shard.Name}
+			results[i].Changes, results[i].Err = checkShard(p, src, shard, compareOptions)
+		}(i, shard)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+func checkShard(p *schemalex.Parser, reference []byte, shard Shard, options []Option) ([]Change, error) {
+	var buf bytes.Buffer
+	if err := shard.Source.WriteSchema(&buf); err != nil {
+		return nil, errors.Wrapf(err, `failed to retrieve schema from shard %s`, shard.Name)
+	}
+	from, err := p.Parse(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to parse schema of shard %s`, shard.Name)
+	}
+	to, err := p.Parse(reference)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse reference schema`)
+	}
+	return Compute(from, to, options...)
+}
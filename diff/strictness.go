@@ -0,0 +1,213 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// Strictness describes which differences between two schemas count
+// as changes, when they may or may not matter depending on the use
+type Strictness int
+
+// List of possible Strictness values
+const (
+	// StrictnessDefault counts differences in integer display widths
+	// and default quoting, but not in character set aliases, such as
+	// utf8 and utf8mb3, or in the order of indexes
+	StrictnessDefault Strictness = iota
+	// StrictnessStrict counts all of the differences, the order of
+	// indexes included, so that the tables end up defined exactly
+	// as in the new schema
+	StrictnessStrict
+	// StrictnessLenient counts none of the differences, as none of
+	// them change how the tables behave
+	StrictnessLenient
+)
+
+// strictnessKnobs holds the comparison knobs that a Strictness stands
+// for. Knobs given on their own take precedence over the profile.
+type strictnessKnobs struct {
+	ignoreIntDisplayWidth *bool
+	ignoreCharsetAliases  *bool
+	ignoreDefaultQuoting  *bool
+	compareIndexOrder     *bool
+}
+
+func (k *strictnessKnobs) apply(ctx *diffCtx, s Strictness) {
+	ctx.ignoreIntDisplayWidth = s == StrictnessLenient
+	ctx.ignoreCharsetAliases = s != StrictnessStrict
+	ctx.ignoreDefaultQuoting = s == StrictnessLenient
+	ctx.compareIndexOrder = s == StrictnessStrict
+
+	if k.ignoreIntDisplayWidth != nil {
+		ctx.ignoreIntDisplayWidth = *k.ignoreIntDisplayWidth
+	}
+	if k.ignoreCharsetAliases != nil {
+		ctx.ignoreCharsetAliases = *k.ignoreCharsetAliases
+	}
+	if k.ignoreDefaultQuoting != nil {
+		ctx.ignoreDefaultQuoting = *k.ignoreDefaultQuoting
+	}
+	if k.compareIndexOrder != nil {
+		ctx.compareIndexOrder = *k.compareIndexOrder
+	}
+}
+
+// canonicalCharset returns the name of a character set or a collation
+// that its aliases share, so that utf8 and utf8mb3 compare equal
+func canonicalCharset(s string) string {
+	s = strings.ToLower(s)
+	if s == "utf8mb3" || strings.HasPrefix(s, "utf8mb3_") {
+		return "utf8" + s[len("utf8mb3"):]
+	}
+	return s
+}
+
+// charsetAliasNotes returns a normalization only change for each
+// table or column whose character set or collation is only written
+// as another alias, as MySQL 8.0 reports utf8mb3 where older versions
+// said utf8. The changes are written as comments, and are not applied.
+func charsetAliasNotes(ctx *diffCtx) ([]Change, error) {
+	if !ctx.charsetAliasNotes || !ctx.ignoreCharsetAliases {
+		return nil, nil
+	}
+
+	var tables []model.Table
+	for _, stmt := range ctx.to {
+		if to, ok := stmt.(model.Table); ok && ctx.fromSet.Contains(to.ID()) {
+			tables = append(tables, to)
+		}
+	}
+	sortTablesByName(tables)
+
+	var changes []Change
+	note := func(table, name, before, after string) {
+		if before == after || canonicalCharset(before) != canonicalCharset(after) {
+			return
+		}
+		var target string
+		if name != "" {
+			target = "column `" + table + "`.`" + name + "`"
+		} else {
+			target = "table `" + table + "`"
+		}
+		changes = append(changes, Change{
+			Kind:   NormalizeCharset,
+			Table:  table,
+			Name:   name,
+			Before: before,
+			After:  after,
+			SQL:    "-- " + target + ": " + before + " is now written as " + after,
+		})
+	}
+
+	for _, to := range tables {
+		stmt, ok := ctx.from.Lookup(to.ID())
+		if !ok {
+			return nil, errors.Errorf(`table '%s' not found in old schema (normalize charsets)`, to.ID())
+		}
+		from := stmt.(model.Table)
+
+		for _, key := range []string{"DEFAULT CHARACTER SET", "DEFAULT COLLATE"} {
+			before, ok := lookupTableOption(from, key)
+			if !ok {
+				continue
+			}
+			if after, ok := lookupTableOption(to, key); ok {
+				note(to.Name(), "", before.Value(), after.Value())
+			}
+		}
+		for col := range to.Columns() {
+			oldCol, ok := from.LookupColumn(col.ID())
+			if !ok {
+				continue
+			}
+			note(to.Name(), col.Name(), oldCol.CharacterSet(), col.CharacterSet())
+			note(to.Name(), col.Name(), oldCol.Collation(), col.Collation())
+		}
+	}
+	return changes, nil
+}
+
+// charsetsEqual reports whether two character sets or collations are
+// the same, taking aliases into account if asked to
+func charsetsEqual(ctx *alterCtx, a, b string) bool {
+	if ctx.ignoreCharsetAliases {
+		return canonicalCharset(a) == canonicalCharset(b)
+	}
+	return strings.EqualFold(a, b)
+}
+
+// withoutStrictnessDifferences returns the columns with the
+// differences that are not counted made the same
+func withoutStrictnessDifferences(ctx *alterCtx, a, b model.TableColumn) (model.TableColumn, model.TableColumn) {
+	// TINYINT(1) is how booleans are stored, so it stays apart from
+	// the other TINYINTs, as it does in MySQL 8.0.19 and later
+	if ctx.ignoreIntDisplayWidth && a.Type() == b.Type() && integerRank[a.Type()] > 0 && (a.HasLength() || b.HasLength()) && isBoolean(a) == isBoolean(b) {
+		a = a.Clone().SetLength(nil)
+		b = b.Clone().SetLength(nil)
+	}
+	if ctx.ignoreCharsetAliases {
+		if a.HasCharacterSet() && b.HasCharacterSet() && charsetsEqual(ctx, a.CharacterSet(), b.CharacterSet()) {
+			a = a.Clone().SetCharacterSet(b.CharacterSet())
+		}
+		if a.HasCollation() && b.HasCollation() && charsetsEqual(ctx, a.Collation(), b.Collation()) {
+			a = a.Clone().SetCollation(b.Collation())
+		}
+	}
+	if ctx.ignoreDefaultQuoting && a.HasDefault() && b.HasDefault() && a.Default() == b.Default() && a.IsQuotedDefault() != b.IsQuotedDefault() {
+		a = a.Clone().SetDefault(b.Default(), b.IsQuotedDefault())
+	}
+	return a, b
+}
+
+// isBoolean reports whether the column is a TINYINT(1), which BOOL
+// and BOOLEAN columns are normalized into
+func isBoolean(col model.TableColumn) bool {
+	return col.Type() == model.ColumnTypeTinyInt && col.HasLength() && col.Length().Length() == "1"
+}
+
+// detectIndexReorders finds the indexes that need to be dropped and
+// added again so that the indexes end up in the same order as in the
+// new table. The indexes kept at the beginning of the new table, in
+// the same order as in the old one, stay where they are, and the rest
+// are added again after them. Primary keys and foreign keys are listed
+// apart by MySQL, so their order is ignored.
+func detectIndexReorders(ctx *alterCtx) {
+	order := make(map[string]int) // new ID -> position in the old table
+	oldIDs := make(map[string]string)
+	for idx := range ctx.from.Indexes() {
+		if idx.IsPrimaryKey() || idx.IsForeignKey() || !ctx.fromIndexes.Contains(idx.ID()) || !ctx.toIndexes.Contains(idx.ID()) {
+			continue
+		}
+		id := idx.ID()
+		if newID, ok := ctx.matchedIndexes[id]; ok {
+			id = newID
+		}
+		order[id] = len(order)
+		oldIDs[id] = idx.ID()
+	}
+
+	stay := make(map[string]bool)
+	last := -1
+	for idx := range ctx.to.Indexes() {
+		if idx.IsPrimaryKey() || idx.IsForeignKey() {
+			continue
+		}
+		pos, ok := order[idx.ID()]
+		if !ok || pos < last {
+			break
+		}
+		stay[idx.ID()] = true
+		last = pos
+	}
+
+	for id := range order {
+		if !stay[id] {
+			ctx.reorderedIndexes.Add(id)
+			ctx.reorderedIndexes.Add(oldIDs[id])
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// Summary counts the changes between two schemas, for a quick review
+// of what a migration does without reading all of its statements
+type Summary struct {
+	TablesCreated  int `json:"tables_created"`
+	TablesDropped  int `json:"tables_dropped"`
+	TablesRenamed  int `json:"tables_renamed"`
+	TablesAltered  int `json:"tables_altered"`
+	ColumnsAdded   int `json:"columns_added"`
+	ColumnsDropped int `json:"columns_dropped"`
+	// ColumnsChanged counts columns that are changed, renamed or moved
+	ColumnsChanged int `json:"columns_changed"`
+	// IndexesAdded and IndexesDropped count primary keys and foreign
+	// keys as well
+	IndexesAdded   int `json:"indexes_added"`
+	IndexesDropped int `json:"indexes_dropped"`
+	IndexesRenamed int `json:"indexes_renamed"`
+	Destructive    int `json:"destructive"`
+	// Tables holds the changes to each table, view or trigger's
+	// table, sorted by name
+	Tables []TableSummary `json:"tables"`
+}
+
+// TableSummary counts the changes to a single table. Views are
+// counted as tables of their own.
+type TableSummary struct {
+	Name          string `json:"name"`
+	Additions     int    `json:"additions"`
+	Removals      int    `json:"removals"`
+	Modifications int    `json:"modifications"`
+	Destructive   int    `json:"destructive"`
+}
+
+// Changes returns the number of changes to the table
+func (t TableSummary) Changes() int {
+	return t.Additions + t.Removals + t.Modifications
+}
+
+// Summarize counts the changes returned by Compute. Notes that do not
+// change anything, such as those enabled by WithCharsetAliasNotes,
+// are not counted.
+func Summarize(changes []Change) Summary {
+	var s Summary
+	tables := make(map[string]*TableSummary)
+	altered := make(map[string]struct{})
+	for _, change := range changes {
+		if change.suppressed || change.Kind == NormalizeCharset {
+			continue
+		}
+
+		switch change.Kind {
+		case CreateTable:
+			s.TablesCreated++
+		case DropTable:
+			s.TablesDropped++
+		case RenameTable:
+			s.TablesRenamed++
+		case CreateView, ReplaceView, DropView, CreateTrigger, DropTrigger:
+		default:
+			altered[change.Table] = struct{}{}
+		}
+		switch change.Kind {
+		case AddColumn:
+			s.ColumnsAdded++
+		case DropColumn:
+			s.ColumnsDropped++
+		case ChangeColumn, RenameColumn, MoveColumn, ChangeColumnDefault:
+			s.ColumnsChanged++
+		case AddIndex, AddFulltextIndex, AddSpatialIndex, AddPrimaryKey, AddForeignKey:
+			s.IndexesAdded++
+		case DropIndex, DropPrimaryKey, DropForeignKey:
+			s.IndexesDropped++
+		case RenameIndex:
+			s.IndexesRenamed++
+		}
+
+		name := change.Table
+		if name == "" {
+			name = change.Name
+		}
+		t, ok := tables[name]
+		if !ok {
+			t = &TableSummary{Name: name}
+			tables[name] = t
+		}
+		switch changeDirection(change.Kind) {
+		case '+':
+			t.Additions++
+		case '-':
+			t.Removals++
+		default:
+			t.Modifications++
+		}
+		if change.Safety == Destructive {
+			t.Destructive++
+			s.Destructive++
+		}
+	}
+	s.TablesAltered = len(altered)
+
+	for _, t := range tables {
+		s.Tables = append(s.Tables, *t)
+	}
+	sort.Slice(s.Tables, func(i, j int) bool {
+		return s.Tables[i].Name < s.Tables[j].Name
+	})
+	return s
+}
+
+// changeDirection tells if the change adds ('+') or removes ('-')
+// something, or modifies something that exists in both schemas ('~')
+func changeDirection(kind ChangeKind) byte {
+	switch kind {
+	case CreateTable, AddColumn, AddIndex, AddFulltextIndex, AddSpatialIndex,
+		AddPrimaryKey, AddForeignKey, AddCheck, AddPartition, CreateView, CreateTrigger:
+		return '+'
+	case DropTable, DropColumn, DropIndex, DropPrimaryKey, DropForeignKey,
+		DropCheck, DropPartition, DropView, DropTrigger:
+		return '-'
+	}
+	return '~'
+}
+
+// ShortStat returns the totals of the summary on a single line, in the
+// style of `git diff --shortstat`, such as "2 tables created, 1 altered,
+// 0 dropped, 1 destructive change"
+func (s Summary) ShortStat() string {
+	if len(s.Tables) == 0 {
+		return "no changes"
+	}
+	var l []string
+	l = append(l, plural(s.TablesCreated, "table")+" created")
+	l = append(l, strconv.Itoa(s.TablesAltered)+" altered")
+	l = append(l, strconv.Itoa(s.TablesDropped)+" dropped")
+	if s.TablesRenamed > 0 {
+		l = append(l, strconv.Itoa(s.TablesRenamed)+" renamed")
+	}
+	l = append(l, plural(s.Destructive, "destructive change"))
+	return strings.Join(l, ", ")
+}
+
+// statWidth is the maximum width of the bars written by WriteStat
+const statWidth = 40
+
+// WriteStat writes the summary in the style of `git diff --stat`: a
+// line per table with the number of changes and a bar of additions
+// (+), removals (-) and modifications (~), followed by the totals.
+// Bars are scaled down to fit if any table has too many changes.
+func (s Summary) WriteStat(dst io.Writer) error {
+	var nameWidth, countWidth, most int
+	for _, t := range s.Tables {
+		if len(t.Name) > nameWidth {
+			nameWidth = len(t.Name)
+		}
+		if n := len(strconv.Itoa(t.Changes())); n > countWidth {
+			countWidth = n
+		}
+		if t.Changes() > most {
+			most = t.Changes()
+		}
+	}
+
+	var buf bytes.Buffer
+	var additions, removals, modifications int
+	for _, t := range s.Tables {
+		additions += t.Additions
+		removals += t.Removals
+		modifications += t.Modifications
+		fmt.Fprintf(&buf, " %-*s | %*d %s", nameWidth, t.Name, countWidth, t.Changes(), statBar(t, most))
+		if t.Destructive > 0 {
+			fmt.Fprintf(&buf, " (%d destructive)", t.Destructive)
+		}
+		buf.WriteByte('\n')
+	}
+
+	fmt.Fprintf(&buf, " %s changed, %s(+), %s(-), %s(~)",
+		plural(len(s.Tables), "table"),
+		plural(additions, "addition"),
+		plural(removals, "removal"),
+		plural(modifications, "modification"),
+	)
+	if s.Destructive > 0 {
+		fmt.Fprintf(&buf, ", %d destructive", s.Destructive)
+	}
+	buf.WriteByte('\n')
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write summary`)
+	}
+	return nil
+}
+
+// statBar draws the changes to the table, scaled so that the table
+// with the most changes fills statWidth
+func statBar(t TableSummary, most int) string {
+	scale := func(n int) int {
+		if most <= statWidth || n == 0 {
+			return n
+		}
+		// never scale a non-zero count down to nothing
+		if scaled := n * statWidth / most; scaled > 0 {
+			return scaled
+		}
+		return 1
+	}
+	return strings.Repeat("+", scale(t.Additions)) +
+		strings.Repeat("-", scale(t.Removals)) +
+		strings.Repeat("~", scale(t.Modifications))
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
@@ -0,0 +1,78 @@
+package diff
+
+import (
+	"strings"
+)
+
+// scriptTerminators describes how the statements of a script are
+// terminated and grouped into batches
+type scriptTerminators struct {
+	delimiter       string // terminates each statement
+	batchSeparator  string // line written after each batch, if any
+	batchSize       int    // statements per batch, 0 for a single batch
+	trailingNewline bool
+}
+
+func (t scriptTerminators) isDefault() bool {
+	return t.delimiter == ";" && t.batchSeparator == "" && !t.trailingNewline
+}
+
+// terminateStatements rewrites the script s so that each statement is
+// terminated by the delimiter, and writes the batch separator after
+// every batch of statements. Statements are told apart the same way
+// as the mysql client does, line by line, following the DELIMITER
+// commands. Comments are left as they are.
+func terminateStatements(s string, t scriptTerminators) string {
+	var lines []string
+	var count int
+	var pending bool // a batch ended within a DELIMITER block
+	delimiter := ";"
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(strings.ToUpper(trimmed), "DELIMITER ") {
+			delimiter = strings.TrimSpace(trimmed[len("DELIMITER "):])
+			if t.delimiter != ";" {
+				continue
+			}
+			lines = append(lines, line)
+			if pending && delimiter == ";" {
+				lines = append(lines, t.batchSeparator)
+				pending = false
+			}
+			continue
+		}
+		if isCommentLine(trimmed) || !strings.HasSuffix(trimmed, delimiter) {
+			lines = append(lines, line)
+			continue
+		}
+
+		if t.delimiter != ";" {
+			line = strings.TrimSuffix(strings.TrimRight(line, " \t"), delimiter) + t.delimiter
+		}
+		lines = append(lines, line)
+		count++
+		if t.batchSeparator == "" || t.batchSize == 0 || count%t.batchSize != 0 {
+			continue
+		}
+		// the separator goes after the DELIMITER command that ends
+		// the block, if any
+		if t.delimiter == ";" && delimiter != ";" {
+			pending = true
+			continue
+		}
+		lines = append(lines, t.batchSeparator)
+	}
+	if t.batchSeparator != "" && (pending || count > 0 && (t.batchSize == 0 || count%t.batchSize != 0)) {
+		lines = append(lines, t.batchSeparator)
+	}
+
+	out := strings.Join(lines, "\n")
+	if t.trailingNewline && out != "" && !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	return out
+}
+
+func isCommentLine(s string) bool {
+	return strings.HasPrefix(s, "--") || strings.HasPrefix(s, "#")
+}
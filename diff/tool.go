@@ -0,0 +1,72 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+)
+
+// AlterMode describes how table alterations are rendered
+type AlterMode int
+
+// List of possible AlterMode values
+const (
+	// AlterModeSQL renders table alterations as ALTER TABLE statements
+	AlterModeSQL AlterMode = iota
+	// AlterModeGhost renders table alterations as gh-ost command lines
+	AlterModeGhost
+	// AlterModePTOSC renders table alterations as pt-online-schema-change
+	// command lines
+	AlterModePTOSC
+)
+
+// writeGhostCommand writes a gh-ost command line that applies all of
+// the clauses to the table
+func writeGhostCommand(ctx *diffCtx, buf *bytes.Buffer, table string, clauses []alterClause) {
+	buf.WriteString("gh-ost")
+	if ctx.databaseName != "" {
+		buf.WriteString(" \\\n  --database=")
+		buf.WriteString(shellQuote(ctx.databaseName))
+	}
+	buf.WriteString(" \\\n  --table=")
+	buf.WriteString(shellQuote(table))
+	buf.WriteString(" \\\n  --alter=")
+	buf.WriteString(shellQuote(joinClauses(clauses)))
+	for _, arg := range ctx.toolArgs {
+		buf.WriteString(" \\\n  ")
+		buf.WriteString(arg)
+	}
+}
+
+// writePTOSCCommand writes a pt-online-schema-change command line that
+// applies all of the clauses to the table
+func writePTOSCCommand(ctx *diffCtx, buf *bytes.Buffer, table string, clauses []alterClause) {
+	buf.WriteString("pt-online-schema-change")
+	buf.WriteString(" \\\n  --alter ")
+	buf.WriteString(shellQuote(joinClauses(clauses)))
+	for _, arg := range ctx.toolArgs {
+		buf.WriteString(" \\\n  ")
+		buf.WriteString(arg)
+	}
+
+	// the DSN describing the table comes last
+	dsn := "t=" + table
+	if ctx.databaseName != "" {
+		dsn = "D=" + ctx.databaseName + "," + dsn
+	}
+	buf.WriteString(" \\\n  ")
+	buf.WriteString(shellQuote(dsn))
+}
+
+func joinClauses(clauses []alterClause) string {
+	l := make([]string, len(clauses))
+	for i, clause := range clauses {
+		l[i] = clause.sql
+	}
+	return strings.Join(l, ", ")
+}
+
+// shellQuote quotes the string so that it can be passed to POSIX shells
+// as a single argument
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
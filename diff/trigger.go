@@ -0,0 +1,144 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/model"
+)
+
+func triggers(stmts model.Stmts) []model.Trigger {
+	var l []model.Trigger
+	for _, stmt := range stmts {
+		if t, ok := stmt.(model.Trigger); ok {
+			l = append(l, t)
+		}
+	}
+	return l
+}
+
+// triggerChanged reports whether the trigger in the old schema needs to
+// be dropped, either because it is gone, or because it has changed
+func triggerChanged(ctx *diffCtx, trigger model.Trigger) (bool, error) {
+	stmt, ok := ctx.to.Lookup(trigger.ID())
+	if !ok {
+		return true, nil
+	}
+	equal, err := triggersEqual(trigger, stmt.(model.Trigger))
+	if err != nil {
+		return false, err
+	}
+	return !equal, nil
+}
+
+// dropTriggers drops the triggers that do not exist in the new schema,
+// as well as the ones that have changed. MySQL has no way to alter
+// a trigger, so changed triggers are created again in createTriggers.
+func dropTriggers(ctx *diffCtx) ([]Change, error) {
+	var changes []Change
+	for _, trigger := range triggers(ctx.from) {
+		changed, err := triggerChanged(ctx, trigger)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			continue
+		}
+		changes = append(changes, Change{
+			Kind:   DropTrigger,
+			Table:  trigger.TableName(),
+			Name:   trigger.Name(),
+			Before: definition(trigger),
+			SQL:    "DROP TRIGGER " + ifExists(ctx) + "`" + trigger.Name() + "`;",
+		})
+	}
+	return changes, nil
+}
+
+// createTriggers creates the triggers that are new or changed in the
+// new schema, after all tables have been created and altered
+func createTriggers(ctx *diffCtx) ([]Change, error) {
+	var changes []Change
+	for _, trigger := range triggers(ctx.to) {
+		if stmt, ok := ctx.from.Lookup(trigger.ID()); ok {
+			changed, err := triggerChanged(ctx, stmt.(model.Trigger))
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				continue
+			}
+		}
+
+		var buf bytes.Buffer
+		if ctx.idempotent {
+			// CREATE TRIGGER cannot be run from procedures
+			buf.WriteString("DROP TRIGGER IF EXISTS `" + trigger.Name() + "`;\n")
+		}
+		if err := writeTrigger(&buf, trigger); err != nil {
+			return nil, err
+		}
+		changes = append(changes, Change{
+			Kind:  CreateTrigger,
+			Table: trigger.TableName(),
+			Name:  trigger.Name(),
+			After: definition(trigger),
+			SQL:   buf.String(),
+		})
+	}
+	return changes, nil
+}
+
+// writeTrigger writes the CREATE TRIGGER statement. If the body
+// contains semicolons, the statement is wrapped in DELIMITER commands
+// so that it can be fed to the mysql command line client.
+func writeTrigger(buf *bytes.Buffer, trigger model.Trigger) error {
+	delimited := strings.Contains(trigger.Body(), ";")
+	if delimited {
+		buf.WriteString("DELIMITER ;;\n")
+	}
+	if err := format.SQL(buf, trigger); err != nil {
+		return err
+	}
+	if delimited {
+		buf.WriteString(";;\nDELIMITER ;")
+	} else {
+		buf.WriteByte(';')
+	}
+	return nil
+}
+
+// triggersEqual reports whether two triggers have the same definition,
+// disregarding differences in formatting. As with views, the definer
+// is only compared if both triggers have one other than CURRENT_USER,
+// as MySQL fills it in (see withoutImpliedViewClauses).
+func triggersEqual(a, b model.Trigger) (bool, error) {
+	if !a.HasDefiner() || !b.HasDefiner() || isCurrentUser(a.Definer()) || isCurrentUser(b.Definer()) {
+		a = copyTriggerWithoutDefiner(a)
+		b = copyTriggerWithoutDefiner(b)
+	}
+
+	var abuf, bbuf bytes.Buffer
+	if err := format.SQL(&abuf, a); err != nil {
+		return false, err
+	}
+	if err := format.SQL(&bbuf, b); err != nil {
+		return false, err
+	}
+	return normalizeDefinition(abuf.String()) == normalizeDefinition(bbuf.String()), nil
+}
+
+// copyTriggerWithoutDefiner returns a copy of the trigger, without its
+// DEFINER clause
+func copyTriggerWithoutDefiner(t model.Trigger) model.Trigger {
+	c := model.NewTrigger(t.Name())
+	c.SetTiming(t.Timing())
+	c.SetEvent(t.Event())
+	c.SetTableName(t.TableName())
+	if t.HasOrder() {
+		c.SetOrder(t.Order(), t.OrderTrigger())
+	}
+	c.SetBody(t.Body())
+	return c
+}
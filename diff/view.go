@@ -0,0 +1,139 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/model"
+)
+
+func views(stmts model.Stmts) []model.View {
+	var l []model.View
+	for _, stmt := range stmts {
+		if v, ok := stmt.(model.View); ok {
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+// dropViews drops the views that do not exist in the new schema.
+// Views are dropped in the reverse order of their definitions, as
+// views may refer to views defined before them.
+func dropViews(ctx *diffCtx) ([]Change, error) {
+	var changes []Change
+	l := views(ctx.from)
+	for i := len(l) - 1; i >= 0; i-- {
+		if _, ok := ctx.to.Lookup(l[i].ID()); ok {
+			continue
+		}
+		changes = append(changes, Change{
+			Kind:   DropView,
+			Name:   l[i].Name(),
+			Before: definition(l[i]),
+			SQL:    "DROP VIEW " + ifExists(ctx) + "`" + l[i].Name() + "`;",
+		})
+	}
+	return changes, nil
+}
+
+// createViews creates views that are new in the new schema, and
+// replaces views whose definitions have changed. This is done after
+// all tables have been created and altered, so that the tables that
+// the views refer to are ready.
+func createViews(ctx *diffCtx) ([]Change, error) {
+	var changes []Change
+	for _, view := range views(ctx.to) {
+		kind := CreateView
+		if stmt, ok := ctx.from.Lookup(view.ID()); ok {
+			equal, err := viewsEqual(stmt.(model.View), view)
+			if err != nil {
+				return nil, err
+			}
+			if equal {
+				continue
+			}
+			kind = ReplaceView
+		}
+
+		var vbuf bytes.Buffer
+		if err := format.SQL(&vbuf, view); err != nil {
+			return nil, err
+		}
+
+		after := vbuf.String()
+		var buf bytes.Buffer
+		if (kind == ReplaceView || ctx.idempotent) && !view.IsOrReplace() {
+			buf.WriteString("CREATE OR REPLACE")
+			buf.WriteString(strings.TrimPrefix(vbuf.String(), "CREATE"))
+		} else {
+			vbuf.WriteTo(&buf)
+		}
+		buf.WriteByte(';')
+		change := Change{Kind: kind, Name: view.Name(), After: after, SQL: buf.String()}
+		if stmt, ok := ctx.from.Lookup(view.ID()); ok {
+			change.Before = definition(stmt)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// viewsEqual reports whether two views have the same definition,
+// disregarding differences in formatting, and the clauses that MySQL
+// fills in (see withoutImpliedViewClauses)
+func viewsEqual(a, b model.View) (bool, error) {
+	a, b = withoutImpliedViewClauses(a, b)
+
+	// OR REPLACE is not part of the definition
+	var abuf, bbuf bytes.Buffer
+	if err := format.SQL(&abuf, a); err != nil {
+		return false, err
+	}
+	if err := format.SQL(&bbuf, b); err != nil {
+		return false, err
+	}
+	astr := strings.TrimPrefix(abuf.String(), "CREATE OR REPLACE")
+	astr = strings.TrimPrefix(astr, "CREATE")
+	bstr := strings.TrimPrefix(bbuf.String(), "CREATE OR REPLACE")
+	bstr = strings.TrimPrefix(bstr, "CREATE")
+	return normalizeDefinition(astr) == normalizeDefinition(bstr), nil
+}
+
+// withoutImpliedViewClauses returns copies of the views, leaving out
+// the ALGORITHM, DEFINER and SQL SECURITY clauses unless both of them
+// have it. MySQL fills these in when the view is created, so views
+// read from a server always have them, while views written by hand
+// usually do not. A definer of CURRENT_USER matches any other.
+func withoutImpliedViewClauses(a, b model.View) (model.View, model.View) {
+	algorithm := a.HasAlgorithm() && b.HasAlgorithm()
+	definer := a.HasDefiner() && b.HasDefiner() && !isCurrentUser(a.Definer()) && !isCurrentUser(b.Definer())
+	security := a.HasSQLSecurity() && b.HasSQLSecurity()
+	return copyView(a, algorithm, definer, security), copyView(b, algorithm, definer, security)
+}
+
+// copyView returns a copy of the view, with the clauses that are not
+// asked for left out
+func copyView(v model.View, algorithm, definer, security bool) model.View {
+	c := model.NewView(v.Name())
+	c.SetOrReplace(v.IsOrReplace())
+	c.SetColumns(v.Columns())
+	c.SetDefinition(v.Definition())
+	if algorithm {
+		c.SetAlgorithm(v.Algorithm())
+	}
+	if definer {
+		c.SetDefiner(v.Definer())
+	}
+	if security {
+		c.SetSQLSecurity(v.SQLSecurity())
+	}
+	return c
+}
+
+// isCurrentUser reports whether the definer is CURRENT_USER, which is
+// whoever runs the statement
+func isCurrentUser(definer string) bool {
+	return strings.EqualFold(strings.TrimSuffix(definer, "()"), "CURRENT_USER")
+}
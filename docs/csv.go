@@ -0,0 +1,140 @@
+package docs
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/internal/option"
+	"github.com/schemalex/schemalex/model"
+)
+
+const optkeyComma = "comma"
+
+// WithComma specifies the character that separates the fields written
+// by CSV, which is ',' by default. Use '\t' to write TSV.
+func WithComma(r rune) Option {
+	return option.New(optkeyComma, r)
+}
+
+var csvHeader = []string{"table", "column", "position", "type", "nullable", "default", "extra", "key", "references", "comment"}
+
+// CSV writes a flat data dictionary of the tables among the statements
+// to dst, with a header and then a record for each column, for import
+// into spreadsheets and data catalogs. The key field is what SHOW
+// COLUMNS shows as Key: PRI for columns of the primary key, UNI for
+// columns that are unique on their own, and MUL for the first columns
+// of other indexes. The references field is the column that a foreign
+// key on the column references, as table.column.
+func CSV(dst io.Writer, stmts model.Stmts, options ...Option) error {
+	w := csv.NewWriter(dst)
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyComma:
+			w.Comma = o.Value().(rune)
+		}
+	}
+
+	if err := w.Write(csvHeader); err != nil {
+		return errors.Wrap(err, `failed to write header`)
+	}
+	for _, stmt := range stmts {
+		table, ok := stmt.(model.Table)
+		if !ok {
+			continue
+		}
+		table, _ = table.Normalize()
+		if err := writeRecords(w, table); err != nil {
+			return errors.Wrapf(err, `failed to write table %s`, table.Name())
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return errors.Wrap(err, `failed to write records`)
+	}
+	return nil
+}
+
+// CSVSource writes a flat data dictionary of the schema read from src
+// (see CSV)
+func CSVSource(dst io.Writer, src schemalex.SchemaSource, options ...Option) error {
+	stmts, err := parseSource(src)
+	if err != nil {
+		return err
+	}
+	return CSV(dst, stmts, options...)
+}
+
+func writeRecords(w *csv.Writer, table model.Table) error {
+	keys := make(map[string]string)
+	references := make(map[string]string)
+	for idx := range table.Indexes() {
+		var cols []model.IndexColumn
+		for col := range idx.Columns() {
+			cols = append(cols, col)
+		}
+		if len(cols) == 0 {
+			continue
+		}
+		switch {
+		case idx.IsPrimaryKey():
+			for _, col := range cols {
+				keys[col.Name()] = "PRI"
+			}
+		case idx.IsForeignKey():
+			r := idx.Reference()
+			if r == nil {
+				continue
+			}
+			var refs []string
+			for col := range r.Columns() {
+				refs = append(refs, r.TableName()+"."+col.Name())
+			}
+			for i, col := range cols {
+				if i < len(refs) {
+					references[col.Name()] = refs[i]
+				}
+			}
+		case idx.IsUnique() && len(cols) == 1:
+			if keys[cols[0].Name()] == "" {
+				keys[cols[0].Name()] = "UNI"
+			}
+		default:
+			if keys[cols[0].Name()] == "" {
+				keys[cols[0].Name()] = "MUL"
+			}
+		}
+	}
+
+	n := 0
+	for col := range table.Columns() {
+		n++
+		nullable := "YES"
+		if col.NullState() == model.NullStateNotNull {
+			nullable = "NO"
+		}
+		var def string
+		if col.HasDefault() && !strings.EqualFold(col.Default(), "NULL") {
+			def = col.Default()
+		}
+		record := []string{
+			table.Name(),
+			col.Name(),
+			strconv.Itoa(n),
+			columnType(col),
+			nullable,
+			def,
+			extra(col),
+			keys[col.Name()],
+			references[col.Name()],
+			col.Comment(),
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+// Package docs generates data dictionaries from schemas: Markdown
+// documents describing each table, its columns, indexes and foreign
+// keys, so that they no longer have to be maintained by hand, the same
+// details as JSON, or flat CSV listings of the columns
+package docs
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/internal/option"
+	"github.com/schemalex/schemalex/model"
+)
+
+type Option = schemalex.Option
+
+const optkeyTitle = "title"
+
+// WithTitle specifies the heading of the document, which is "Schema"
+// by default
+func WithTitle(s string) Option {
+	return option.New(optkeyTitle, s)
+}
+
+// Stmts writes a Markdown document describing the tables among the
+// statements to dst. Tables are described in the order they are
+// created, after a list of links to each of them.
+func Stmts(dst io.Writer, stmts model.Stmts, options ...Option) error {
+	title := "Schema"
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyTitle:
+			title = o.Value().(string)
+		}
+	}
+
+	var tables []model.Table
+	for _, stmt := range stmts {
+		if table, ok := stmt.(model.Table); ok {
+			table, _ = table.Normalize()
+			tables = append(tables, table)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# ")
+	buf.WriteString(title)
+	buf.WriteString("\n\n")
+	for _, table := range tables {
+		buf.WriteString("- [")
+		buf.WriteString(table.Name())
+		buf.WriteString("](#")
+		buf.WriteString(anchor(table.Name()))
+		buf.WriteString(")\n")
+	}
+	for _, table := range tables {
+		writeTable(&buf, table)
+	}
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write document`)
+	}
+	return nil
+}
+
+// Source writes a Markdown document describing the schema read from
+// src (see Stmts)
+func Source(dst io.Writer, src schemalex.SchemaSource, options ...Option) error {
+	stmts, err := parseSource(src)
+	if err != nil {
+		return err
+	}
+	return Stmts(dst, stmts, options...)
+}
+
+func parseSource(src schemalex.SchemaSource) (model.Stmts, error) {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return nil, errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+	return stmts, nil
+}
+
+var anchorRx = regexp.MustCompile(`[^a-z0-9_\- ]`)
+
+// anchor returns the anchor that GitHub gives to a heading
+func anchor(heading string) string {
+	s := anchorRx.ReplaceAllString(strings.ToLower(heading), "")
+	return strings.Replace(s, " ", "-", -1)
+}
+
+var cellReplacer = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+
+// cell escapes the text for a cell of a table
+func cell(s string) string {
+	return cellReplacer.Replace(s)
+}
+
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + cell(s) + "`"
+}
+
+func writeRow(buf *bytes.Buffer, cells ...string) {
+	buf.WriteString("|")
+	for _, c := range cells {
+		buf.WriteByte(' ')
+		buf.WriteString(c)
+		buf.WriteString(" |")
+	}
+	buf.WriteByte('\n')
+}
+
+func writeHeader(buf *bytes.Buffer, cells ...string) {
+	writeRow(buf, cells...)
+	buf.WriteString("|")
+	for range cells {
+		buf.WriteString(" --- |")
+	}
+	buf.WriteByte('\n')
+}
+
+func writeTable(buf *bytes.Buffer, table model.Table) {
+	buf.WriteString("\n## ")
+	buf.WriteString(table.Name())
+	buf.WriteString("\n\n")
+	for opt := range table.Options() {
+		if strings.EqualFold(opt.Key(), "COMMENT") {
+			buf.WriteString(opt.Value())
+			buf.WriteString("\n\n")
+		}
+	}
+
+	writeHeader(buf, "Column", "Type", "Nullable", "Default", "Extra", "Comment")
+	for col := range table.Columns() {
+		nullable := "YES"
+		if col.NullState() == model.NullStateNotNull {
+			nullable = "NO"
+		}
+		var def string
+		if col.HasDefault() && !strings.EqualFold(col.Default(), "NULL") {
+			def = col.Default()
+			if col.IsQuotedDefault() {
+				def = "'" + def + "'"
+			}
+		}
+		writeRow(buf, code(col.Name()), code(columnType(col)), nullable, code(def), cell(extra(col)), cell(col.Comment()))
+	}
+
+	var indexes, foreignKeys []model.Index
+	for idx := range table.Indexes() {
+		if idx.IsForeignKey() {
+			foreignKeys = append(foreignKeys, idx)
+		} else {
+			indexes = append(indexes, idx)
+		}
+	}
+
+	if len(indexes) > 0 {
+		buf.WriteString("\n### Indexes\n\n")
+		writeHeader(buf, "Name", "Columns", "Kind")
+		for _, idx := range indexes {
+			writeRow(buf, code(indexName(idx)), cell(indexColumns(idx)), indexKind(idx))
+		}
+	}
+
+	if len(foreignKeys) > 0 {
+		buf.WriteString("\n### Foreign keys\n\n")
+		writeHeader(buf, "Name", "Columns", "References", "On delete", "On update")
+		for _, idx := range foreignKeys {
+			var refs, onDelete, onUpdate string
+			if r := idx.Reference(); r != nil {
+				refs = "[" + r.TableName() + "](#" + anchor(r.TableName()) + ") (" + indexColumns(r) + ")"
+				onDelete = referenceOption(r.OnDelete())
+				onUpdate = referenceOption(r.OnUpdate())
+			}
+			writeRow(buf, code(idx.Symbol()), cell(indexColumns(idx)), refs, onDelete, onUpdate)
+		}
+	}
+}
+
+// columnType returns the type of the column the way SHOW COLUMNS does,
+// such as "int(10) unsigned"
+func columnType(col model.TableColumn) string {
+	var buf bytes.Buffer
+	buf.WriteString(strings.ToLower(col.Type().String()))
+	switch {
+	case col.Type() == model.ColumnTypeEnum || col.Type() == model.ColumnTypeSet:
+		values := col.EnumValues()
+		if col.Type() == model.ColumnTypeSet {
+			values = col.SetValues()
+		}
+		var l []string
+		for v := range values {
+			l = append(l, "'"+v+"'")
+		}
+		buf.WriteString("(" + strings.Join(l, ",") + ")")
+	case col.HasLength():
+		l := col.Length()
+		buf.WriteString("(" + l.Length())
+		if l.HasDecimal() {
+			buf.WriteString("," + l.Decimal())
+		}
+		buf.WriteString(")")
+	}
+	if col.IsUnsigned() {
+		buf.WriteString(" unsigned")
+	}
+	if col.IsZeroFill() {
+		buf.WriteString(" zerofill")
+	}
+	return buf.String()
+}
+
+// extra returns what SHOW COLUMNS shows as Extra, such as
+// auto_increment
+func extra(col model.TableColumn) string {
+	var l []string
+	if col.IsAutoIncrement() {
+		l = append(l, "auto_increment")
+	}
+	if col.HasAutoUpdate() {
+		l = append(l, "on update "+col.AutoUpdate())
+	}
+	if col.HasGeneratedExpr() {
+		if col.StoreOption() == model.StoreOptionStored {
+			l = append(l, "STORED GENERATED AS ("+col.GeneratedExpr()+")")
+		} else {
+			l = append(l, "VIRTUAL GENERATED AS ("+col.GeneratedExpr()+")")
+		}
+	}
+	return strings.Join(l, ", ")
+}
+
+func indexName(idx model.Index) string {
+	switch {
+	case idx.IsPrimaryKey():
+		return "PRIMARY"
+	case idx.HasName():
+		return idx.Name()
+	default:
+		return idx.Symbol()
+	}
+}
+
+func indexKind(idx model.Index) string {
+	switch {
+	case idx.IsPrimaryKey():
+		return "PRIMARY KEY"
+	case idx.IsUnique():
+		return "UNIQUE"
+	case idx.IsFullText():
+		return "FULLTEXT"
+	case idx.IsSpatial():
+		return "SPATIAL"
+	default:
+		return "INDEX"
+	}
+}
+
+func indexColumns(c model.ColumnContainer) string {
+	var cols []string
+	for col := range c.Columns() {
+		s := "`" + col.Name()
+		if col.HasLength() {
+			s += "(" + col.Length() + ")"
+		}
+		s += "`"
+		if col.HasSortDirection() && col.IsDescending() {
+			s += " DESC"
+		}
+		cols = append(cols, s)
+	}
+	return strings.Join(cols, ", ")
+}
+
+func referenceOption(opt model.ReferenceOption) string {
+	switch opt {
+	case model.ReferenceOptionRestrict:
+		return "RESTRICT"
+	case model.ReferenceOptionCascade:
+		return "CASCADE"
+	case model.ReferenceOptionSetNull:
+		return "SET NULL"
+	case model.ReferenceOptionNoAction:
+		return "NO ACTION"
+	}
+	return ""
+}
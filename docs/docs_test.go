@@ -0,0 +1,136 @@
+package docs_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/docs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmts(t *testing.T) {
+	src := "CREATE TABLE `users` ( `id` INTEGER UNSIGNED NOT NULL AUTO_INCREMENT, `name` VARCHAR (20) NOT NULL DEFAULT 'x' COMMENT 'first | last', `bio` TEXT, PRIMARY KEY (`id`), UNIQUE KEY `uniq_name` (`name`) ) COMMENT 'Registered users';\n" +
+		"CREATE TABLE `posts` ( `id` BIGINT NOT NULL, `user_id` INTEGER UNSIGNED NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE );"
+	expect := "# Dictionary\n\n- [users](#users)\n- [posts](#posts)\n" +
+		"\n## users\n\nRegistered users\n\n" +
+		"| Column | Type | Nullable | Default | Extra | Comment |\n| --- | --- | --- | --- | --- | --- |\n" +
+		"| `id` | `int(10) unsigned` | NO |  | auto_increment |  |\n" +
+		"| `name` | `varchar(20)` | NO | `'x'` |  | first \\| last |\n" +
+		"| `bio` | `text` | YES |  |  |  |\n" +
+		"\n### Indexes\n\n| Name | Columns | Kind |\n| --- | --- | --- |\n" +
+		"| `PRIMARY` | `id` | PRIMARY KEY |\n" +
+		"| `uniq_name` | `name` | UNIQUE |\n" +
+		"\n## posts\n\n" +
+		"| Column | Type | Nullable | Default | Extra | Comment |\n| --- | --- | --- | --- | --- | --- |\n" +
+		"| `id` | `bigint(20)` | NO |  |  |  |\n" +
+		"| `user_id` | `int(10) unsigned` | NO |  |  |  |\n" +
+		"\n### Indexes\n\n| Name | Columns | Kind |\n| --- | --- | --- |\n" +
+		"| `PRIMARY` | `id` | PRIMARY KEY |\n" +
+		"\n### Foreign keys\n\n| Name | Columns | References | On delete | On update |\n| --- | --- | --- | --- | --- |\n" +
+		"| `fk_user` | `user_id` | [users](#users) (`id`) | CASCADE | RESTRICT |\n"
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parsing should succeed") {
+		return
+	}
+	var buf bytes.Buffer
+	if !assert.NoError(t, docs.Stmts(&buf, stmts, docs.WithTitle("Dictionary")), "docs.Stmts should succeed") {
+		return
+	}
+	assert.Equal(t, expect, buf.String(), "document should match")
+}
+
+func TestCSV(t *testing.T) {
+	src := "CREATE TABLE `users` ( `id` INTEGER UNSIGNED NOT NULL AUTO_INCREMENT, `name` VARCHAR (20) NOT NULL DEFAULT 'x' COMMENT 'first, last', `team_id` INTEGER, PRIMARY KEY (`id`), UNIQUE KEY `uniq_name` (`name`), INDEX `idx_team` (`team_id`), CONSTRAINT `fk_team` FOREIGN KEY (`team_id`) REFERENCES `teams` (`id`) );"
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parsing should succeed") {
+		return
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		expect := "table,column,position,type,nullable,default,extra,key,references,comment\n" +
+			"users,id,1,int(10) unsigned,NO,,auto_increment,PRI,,\n" +
+			"users,name,2,varchar(20),NO,x,,UNI,,\"first, last\"\n" +
+			"users,team_id,3,int(11),YES,,,MUL,teams.id,\n"
+		var buf bytes.Buffer
+		if !assert.NoError(t, docs.CSV(&buf, stmts), "docs.CSV should succeed") {
+			return
+		}
+		assert.Equal(t, expect, buf.String(), "records should match")
+	})
+	t.Run("TSV", func(t *testing.T) {
+		expect := "table\tcolumn\tposition\ttype\tnullable\tdefault\textra\tkey\treferences\tcomment\n" +
+			"users\tid\t1\tint(10) unsigned\tNO\t\tauto_increment\tPRI\t\t\n" +
+			"users\tname\t2\tvarchar(20)\tNO\tx\t\tUNI\t\tfirst, last\n" +
+			"users\tteam_id\t3\tint(11)\tYES\t\t\tMUL\tteams.id\t\n"
+		var buf bytes.Buffer
+		if !assert.NoError(t, docs.CSV(&buf, stmts, docs.WithComma('\t')), "docs.CSV should succeed") {
+			return
+		}
+		assert.Equal(t, expect, buf.String(), "records should match")
+	})
+}
+
+func TestJSON(t *testing.T) {
+	src := "CREATE TABLE `users` ( `id` INTEGER UNSIGNED NOT NULL AUTO_INCREMENT, `team_id` INTEGER DEFAULT NULL COMMENT 'team', PRIMARY KEY (`id`), CONSTRAINT `fk_team` FOREIGN KEY (`team_id`) REFERENCES `teams` (`id`) ON DELETE CASCADE ) COMMENT 'Registered users';"
+	expect := `{
+  "tables": [
+    {
+      "name": "users",
+      "comment": "Registered users",
+      "columns": [
+        {
+          "name": "id",
+          "type": "int(10) unsigned",
+          "nullable": false,
+          "extra": "auto_increment"
+        },
+        {
+          "name": "team_id",
+          "type": "int(11)",
+          "nullable": true,
+          "comment": "team"
+        }
+      ],
+      "indexes": [
+        {
+          "name": "PRIMARY",
+          "kind": "PRIMARY KEY",
+          "columns": [
+            "id"
+          ]
+        }
+      ],
+      "foreign_keys": [
+        {
+          "name": "fk_team",
+          "columns": [
+            "team_id"
+          ],
+          "references": {
+            "table": "teams",
+            "columns": [
+              "id"
+            ]
+          },
+          "on_delete": "CASCADE",
+          "on_update": "RESTRICT"
+        }
+      ]
+    }
+  ]
+}
+`
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parsing should succeed") {
+		return
+	}
+	var buf bytes.Buffer
+	if !assert.NoError(t, docs.JSON(&buf, stmts), "docs.JSON should succeed") {
+		return
+	}
+	assert.Equal(t, expect, buf.String(), "document should match")
+}
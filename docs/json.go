@@ -0,0 +1,131 @@
+package docs
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+type jsonDictionary struct {
+	Tables []jsonTable `json:"tables"`
+}
+
+type jsonTable struct {
+	Name        string           `json:"name"`
+	Comment     string           `json:"comment,omitempty"`
+	Columns     []jsonColumn     `json:"columns"`
+	Indexes     []jsonIndex      `json:"indexes,omitempty"`
+	ForeignKeys []jsonForeignKey `json:"foreign_keys,omitempty"`
+}
+
+type jsonColumn struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Nullable bool    `json:"nullable"`
+	Default  *string `json:"default,omitempty"`
+	Extra    string  `json:"extra,omitempty"`
+	Comment  string  `json:"comment,omitempty"`
+}
+
+type jsonIndex struct {
+	Name    string   `json:"name,omitempty"`
+	Kind    string   `json:"kind"`
+	Columns []string `json:"columns"`
+}
+
+type jsonForeignKey struct {
+	Name       string        `json:"name,omitempty"`
+	Columns    []string      `json:"columns"`
+	References jsonReference `json:"references"`
+	OnDelete   string        `json:"on_delete,omitempty"`
+	OnUpdate   string        `json:"on_update,omitempty"`
+}
+
+type jsonReference struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+}
+
+// JSON writes a data dictionary of the tables among the statements to
+// dst as a JSON document, holding the same details as the Markdown
+// document written by Stmts: the columns of each table, its indexes
+// and its foreign keys. Defaults are left out for columns without one,
+// or whose default is NULL.
+func JSON(dst io.Writer, stmts model.Stmts, options ...Option) error {
+	dict := jsonDictionary{Tables: []jsonTable{}}
+	for _, stmt := range stmts {
+		if table, ok := stmt.(model.Table); ok {
+			table, _ = table.Normalize()
+			dict.Tables = append(dict.Tables, newJSONTable(table))
+		}
+	}
+
+	enc := json.NewEncoder(dst)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(dict); err != nil {
+		return errors.Wrap(err, `failed to write document`)
+	}
+	return nil
+}
+
+// JSONSource writes a JSON data dictionary of the schema read from src
+// (see JSON)
+func JSONSource(dst io.Writer, src schemalex.SchemaSource, options ...Option) error {
+	stmts, err := parseSource(src)
+	if err != nil {
+		return err
+	}
+	return JSON(dst, stmts, options...)
+}
+
+func newJSONTable(table model.Table) jsonTable {
+	t := jsonTable{Name: table.Name(), Columns: []jsonColumn{}}
+	for opt := range table.Options() {
+		if strings.EqualFold(opt.Key(), "COMMENT") {
+			t.Comment = opt.Value()
+		}
+	}
+
+	for col := range table.Columns() {
+		c := jsonColumn{
+			Name:     col.Name(),
+			Type:     columnType(col),
+			Nullable: col.NullState() != model.NullStateNotNull,
+			Extra:    extra(col),
+			Comment:  col.Comment(),
+		}
+		if col.HasDefault() && !strings.EqualFold(col.Default(), "NULL") {
+			def := col.Default()
+			c.Default = &def
+		}
+		t.Columns = append(t.Columns, c)
+	}
+
+	for idx := range table.Indexes() {
+		if !idx.IsForeignKey() {
+			t.Indexes = append(t.Indexes, jsonIndex{Name: indexName(idx), Kind: indexKind(idx), Columns: columnNames(idx)})
+			continue
+		}
+		fk := jsonForeignKey{Name: idx.Symbol(), Columns: columnNames(idx)}
+		if r := idx.Reference(); r != nil {
+			fk.References = jsonReference{Table: r.TableName(), Columns: columnNames(r)}
+			fk.OnDelete = referenceOption(r.OnDelete())
+			fk.OnUpdate = referenceOption(r.OnUpdate())
+		}
+		t.ForeignKeys = append(t.ForeignKeys, fk)
+	}
+	return t
+}
+
+func columnNames(c model.ColumnContainer) []string {
+	cols := []string{}
+	for col := range c.Columns() {
+		cols = append(cols, col.Name())
+	}
+	return cols
+}
@@ -0,0 +1,106 @@
+// Package fingerprint computes hashes of schemas, so that tools can
+// tell whether anything has changed without computing a full diff
+package fingerprint
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// Fingerprint holds the hash of a schema as a whole, and of each of
+// its tables. Hashes are computed from the statements as they are
+// normalized by the parser and written by the format package, so they
+// do not depend on whitespace, letter case of keywords, the order of
+// statements or implicit defaults. They may change between versions
+// of schemalex, if the way statements are written changes.
+type Fingerprint struct {
+	// Sum is the hash of all tables, views and triggers
+	Sum string `json:"sum"`
+	// Tables maps the name of each table to its hash
+	Tables map[string]string `json:"tables"`
+}
+
+// Equal reports whether the schemas have the same fingerprint
+func (f *Fingerprint) Equal(other *Fingerprint) bool {
+	return f.Sum == other.Sum
+}
+
+// ChangedTables returns the names of the tables that differ between
+// the fingerprints, including the ones that only exist in either of
+// them, sorted by name
+func ChangedTables(a, b *Fingerprint) []string {
+	var names []string
+	for name, sum := range a.Tables {
+		if b.Tables[name] != sum {
+			names = append(names, name)
+		}
+	}
+	for name := range b.Tables {
+		if _, ok := a.Tables[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stmt returns the hash of a single statement
+func Stmt(stmt model.Stmt) (string, error) {
+	var buf bytes.Buffer
+	if err := format.SQL(&buf, stmt); err != nil {
+		return "", errors.Wrapf(err, `failed to format statement %s`, stmt.ID())
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Stmts computes the fingerprint of the statements
+func Stmts(stmts model.Stmts) (*Fingerprint, error) {
+	type entry struct {
+		id  string
+		sum string
+	}
+
+	f := &Fingerprint{Tables: make(map[string]string)}
+	var entries []entry
+	for _, stmt := range stmts {
+		sum, err := Stmt(stmt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{id: stmt.ID(), sum: sum})
+		if table, ok := stmt.(model.Table); ok {
+			f.Tables[table.Name()] = sum
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].id < entries[j].id
+	})
+
+	h := sha256.New()
+	for _, e := range entries {
+		h.Write([]byte(e.id + " " + e.sum + "\n"))
+	}
+	f.Sum = hex.EncodeToString(h.Sum(nil))
+	return f, nil
+}
+
+// Source computes the fingerprint of the schema read from src
+func Source(src schemalex.SchemaSource) (*Fingerprint, error) {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return nil, errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+	return Stmts(stmts)
+}
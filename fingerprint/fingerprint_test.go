@@ -0,0 +1,54 @@
+package fingerprint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/fingerprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	p := schemalex.New()
+	compute := func(src string) *fingerprint.Fingerprint {
+		stmts, err := p.ParseString(src)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %s", src, err)
+		}
+		f, err := fingerprint.Stmts(stmts)
+		if err != nil {
+			t.Fatalf("failed to compute fingerprint of %s: %s", src, err)
+		}
+		return f
+	}
+
+	base := compute("CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );")
+	same := compute("create table hoge (id int(11) not null);\n\ncreate table fuga (\n  id int not null\n);")
+	changed := compute("CREATE TABLE `fuga` ( `id` BIGINT NOT NULL ); CREATE TABLE `piyo` ( `id` INTEGER NOT NULL );")
+
+	assert.Len(t, base.Tables, 2, "every table should have a hash")
+	assert.True(t, base.Equal(same), "the same schema written differently should have the same fingerprint")
+	assert.Equal(t, base.Tables, same.Tables, "the same tables should have the same hashes")
+	assert.Empty(t, fingerprint.ChangedTables(base, same), "no table should be changed")
+
+	assert.False(t, base.Equal(changed), "a different schema should have a different fingerprint")
+	assert.Equal(t, []string{"fuga", "hoge", "piyo"}, fingerprint.ChangedTables(base, changed), "changed, dropped and added tables should be listed")
+}
+
+func TestSource(t *testing.T) {
+	src := "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );"
+	f, err := fingerprint.Source(schemalex.NewReaderSource(strings.NewReader(src)))
+	if !assert.NoError(t, err, "fingerprint.Source should succeed") {
+		return
+	}
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parsing should succeed") {
+		return
+	}
+	expected, err := fingerprint.Stmts(stmts)
+	if !assert.NoError(t, err, "fingerprint.Stmts should succeed") {
+		return
+	}
+	assert.Equal(t, expected, f, "fingerprint of the source should match")
+}
@@ -0,0 +1,70 @@
+package format
+
+import (
+	"io"
+	"sort"
+	"sync"
+)
+
+// Formatter writes `model.*` objects as the SQL of a dialect. It takes
+// the same objects and options as SQL, and should ignore the options
+// it does not know.
+type Formatter interface {
+	Format(dst io.Writer, v interface{}, options ...Option) error
+}
+
+// FormatterFunc is a function that implements Formatter
+type FormatterFunc func(dst io.Writer, v interface{}, options ...Option) error
+
+// Format calls f(dst, v, options...)
+func (f FormatterFunc) Format(dst io.Writer, v interface{}, options ...Option) error {
+	return f(dst, v, options...)
+}
+
+// MySQL is the Formatter of the MySQL dialect, which is registered as
+// "mysql". Dialects that are close to MySQL, such as TiDB, may write
+// what they have to and leave the rest to it.
+var MySQL Formatter = FormatterFunc(formatMySQL)
+
+var dialects = struct {
+	sync.RWMutex
+	formatters map[string]Formatter
+}{
+	formatters: map[string]Formatter{"mysql": MySQL},
+}
+
+// Register makes a dialect available by the given name, so that it can
+// be written with WithDialect. It is meant to be called from the init
+// function of the package that implements the dialect. Register panics
+// if it is called twice with the same name, or if the Formatter is nil.
+func Register(name string, f Formatter) {
+	dialects.Lock()
+	defer dialects.Unlock()
+	if f == nil {
+		panic("format: Register formatter is nil")
+	}
+	if _, dup := dialects.formatters[name]; dup {
+		panic("format: Register called twice for dialect " + name)
+	}
+	dialects.formatters[name] = f
+}
+
+// Lookup returns the Formatter of the dialect registered by the name
+func Lookup(name string) (Formatter, bool) {
+	dialects.RLock()
+	defer dialects.RUnlock()
+	f, ok := dialects.formatters[name]
+	return f, ok
+}
+
+// Dialects returns the names of the registered dialects, sorted
+func Dialects() []string {
+	dialects.RLock()
+	defer dialects.RUnlock()
+	names := make([]string, 0, len(dialects.formatters))
+	for name := range dialects.formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
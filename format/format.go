@@ -3,16 +3,33 @@ package format
 import (
 	"bytes"
 	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/schemalex/schemalex/internal/errors"
-	"github.com/schemalex/schemalex/internal/util"
+	"github.com/schemalex/schemalex/internal/version"
 	"github.com/schemalex/schemalex/model"
 )
 
 type fmtCtx struct {
-	curIndent string
-	dst       io.Writer
-	indent    string
+	align               bool
+	canonical           bool
+	columnOrder         ColumnOrder
+	commaStyle          CommaStyle
+	comments            bool
+	compact             bool
+	curIndent           string
+	dst                 io.Writer
+	indent              string
+	keywordCase         KeywordCase
+	omitIntDisplayWidth bool
+	quoting             Quoting
+	target              *version.MySQL
+	// widths that the names and types of columns are padded to, when
+	// aligning the columns of a table
+	nameWidth int
+	typeWidth int
 }
 
 func newFmtCtx(dst io.Writer) *fmtCtx {
@@ -23,20 +40,191 @@ func newFmtCtx(dst io.Writer) *fmtCtx {
 
 func (ctx *fmtCtx) clone() *fmtCtx {
 	return &fmtCtx{
-		curIndent: ctx.curIndent,
-		dst:       ctx.dst,
-		indent:    ctx.indent,
+		align:               ctx.align,
+		canonical:           ctx.canonical,
+		columnOrder:         ctx.columnOrder,
+		commaStyle:          ctx.commaStyle,
+		comments:            ctx.comments,
+		compact:             ctx.compact,
+		curIndent:           ctx.curIndent,
+		dst:                 ctx.dst,
+		indent:              ctx.indent,
+		keywordCase:         ctx.keywordCase,
+		omitIntDisplayWidth: ctx.omitIntDisplayWidth,
+		quoting:             ctx.quoting,
+		target:              ctx.target,
+		nameWidth:           ctx.nameWidth,
+		typeWidth:           ctx.typeWidth,
 	}
 }
 
+// supports reports whether the target version, if any, is the given
+// one or later
+func (ctx *fmtCtx) supports(major, minor, patch int) bool {
+	return ctx.target == nil || ctx.target.AtLeast(major, minor, patch)
+}
+
+// collation returns the name of the collation of the column as the
+// target version knows it. Only the collations implied by the
+// character set are rewritten, as those written in the source are
+// what the user asked for.
+func (ctx *fmtCtx) collation(col model.TableColumn) string {
+	s := col.Collation()
+	if ctx.target == nil || !col.IsImpliedCollation() {
+		return s
+	}
+	switch {
+	case ctx.supports(8, 0, 0) && strings.EqualFold(s, "utf8mb4_general_ci"):
+		return "utf8mb4_0900_ai_ci"
+	case !ctx.supports(8, 0, 0) && strings.EqualFold(s, "utf8mb4_0900_ai_ci"):
+		return "utf8mb4_general_ci"
+	}
+	return s
+}
+
+// keyword returns the keyword in the case specified by WithKeywordCase
+func (ctx *fmtCtx) keyword(s string) string {
+	if ctx.keywordCase == KeywordCaseLower {
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+// listItem is a field of a table, or a partition definition, along
+// with the comments written around it in the source
+type listItem struct {
+	leadingComments []string
+	trailingComment string
+	// commentedOut is true for fields that are written as a comment,
+	// which are not separated from the others by commas
+	commentedOut bool
+	format       func(*fmtCtx) error
+}
+
+// writeList writes the fields of a table, or the definitions of
+// partitions, between parentheses. Each of them goes on a line of its
+// own, unless the output is compact. The fields are written with ctx,
+// which should be indented one level deeper than the enclosing
+// statement. Comments are not written in compact output, as comments
+// that run to the end of the line would swallow the rest of it.
+func writeList(ctx *fmtCtx, buf *bytes.Buffer, closingIndent string, fields []listItem) error {
+	comments := ctx.comments && !ctx.compact
+
+	last := -1 // the last field that is not commented out
+	for i, field := range fields {
+		if !field.commentedOut {
+			last = i
+		}
+	}
+
+	buf.WriteString(" (")
+	var written bool // whether a field that is not commented out was written
+	for i, field := range fields {
+		if comments {
+			for _, comment := range field.leadingComments {
+				buf.WriteByte('\n')
+				buf.WriteString(ctx.curIndent)
+				buf.WriteString(comment)
+			}
+		}
+
+		separate := written && !field.commentedOut
+		fieldctx := ctx
+		switch {
+		case ctx.compact:
+			fieldctx = ctx.clone()
+			fieldctx.curIndent = ""
+			if separate {
+				buf.WriteString(", ")
+			} else if i > 0 {
+				buf.WriteByte(' ')
+			}
+		case ctx.commaStyle == CommaLeading && separate:
+			fieldctx = ctx.clone()
+			fieldctx.curIndent = ""
+			buf.WriteByte('\n')
+			buf.WriteString(ctx.curIndent)
+			buf.WriteString(", ")
+		default:
+			buf.WriteByte('\n')
+		}
+
+		if err := field.format(fieldctx); err != nil {
+			return err
+		}
+		if !ctx.compact && ctx.commaStyle != CommaLeading && !field.commentedOut && i < last {
+			buf.WriteByte(',')
+		}
+		if !field.commentedOut {
+			written = true
+		}
+		if comments && field.trailingComment != "" {
+			buf.WriteByte(' ')
+			buf.WriteString(field.trailingComment)
+		}
+	}
+	if !ctx.compact {
+		buf.WriteByte('\n')
+		buf.WriteString(closingIndent)
+	}
+	buf.WriteByte(')')
+	return nil
+}
+
 // SQL takes an arbitrary `model.*` object and formats it as SQL,
-// writing its result to `dst`
+// writing its result to `dst`. The SQL is that of MySQL, unless another
+// dialect is specified with WithDialect.
 func SQL(dst io.Writer, v interface{}, options ...Option) error {
+	var dialect string
+	var rest []Option
+	for _, o := range options {
+		if o.Name() == optkeyDialect {
+			dialect = o.Value().(string)
+			continue
+		}
+		rest = append(rest, o)
+	}
+	if dialect == "" {
+		return formatMySQL(dst, v, options...)
+	}
+
+	f, ok := Lookup(dialect)
+	if !ok {
+		return errors.Errorf(`unknown dialect %q`, dialect)
+	}
+	return f.Format(dst, v, rest...)
+}
+
+func formatMySQL(dst io.Writer, v interface{}, options ...Option) error {
 	ctx := newFmtCtx(dst)
 	for _, o := range options {
 		switch o.Name() {
 		case optkeyIndent:
 			ctx.indent = o.Value().(string)
+		case optkeyKeywordCase:
+			ctx.keywordCase = o.Value().(KeywordCase)
+		case optkeyCommaStyle:
+			ctx.commaStyle = o.Value().(CommaStyle)
+		case optkeyCompact:
+			ctx.compact = o.Value().(bool)
+		case optkeyAlign:
+			ctx.align = o.Value().(bool)
+		case optkeyCanonical:
+			ctx.canonical = o.Value().(bool)
+		case optkeyColumnOrder:
+			ctx.columnOrder = o.Value().(ColumnOrder)
+		case optkeyQuoting:
+			ctx.quoting = o.Value().(Quoting)
+		case optkeyIntDisplayWidth:
+			ctx.omitIntDisplayWidth = !o.Value().(bool)
+		case optkeyComments:
+			ctx.comments = o.Value().(bool)
+		case optkeyTargetVersion:
+			v, err := version.ParseMySQL(o.Value().(string))
+			if err != nil {
+				return errors.Wrap(err, `invalid target version`)
+			}
+			ctx.target = &v
 		}
 	}
 
@@ -66,6 +254,16 @@ func format(ctx *fmtCtx, v interface{}) error {
 		return formatIndex(ctx, v.(model.Index))
 	case model.Reference:
 		return formatReference(ctx, v.(model.Reference))
+	case model.Check:
+		return formatCheck(ctx, v.(model.Check))
+	case model.View:
+		return formatView(ctx, v.(model.View))
+	case model.Trigger:
+		return formatTrigger(ctx, v.(model.Trigger))
+	case model.Partitioning:
+		return formatPartitioning(ctx, v.(model.Partitioning))
+	case model.PartitionDefinition:
+		return formatPartitionDefinition(ctx, v.(model.PartitionDefinition))
 	default:
 		return errors.New("unsupported model type")
 	}
@@ -73,12 +271,12 @@ func format(ctx *fmtCtx, v interface{}) error {
 
 func formatDatabase(ctx *fmtCtx, d model.Database) error {
 	var buf bytes.Buffer
-	buf.WriteString("CREATE DATABASE")
+	buf.WriteString(ctx.keyword("CREATE DATABASE"))
 	if d.IsIfNotExists() {
-		buf.WriteString(" IF NOT EXISTS")
+		buf.WriteString(ctx.keyword(" IF NOT EXISTS"))
 	}
 	buf.WriteByte(' ')
-	buf.WriteString(util.Backquote(d.Name()))
+	buf.WriteString(ctx.quote(d.Name()))
 	buf.WriteByte(';')
 
 	if _, err := buf.WriteTo(ctx.dst); err != nil {
@@ -87,9 +285,87 @@ func formatDatabase(ctx *fmtCtx, d model.Database) error {
 	return nil
 }
 
+func formatView(ctx *fmtCtx, view model.View) error {
+	var buf bytes.Buffer
+
+	buf.WriteString(ctx.keyword("CREATE"))
+	if view.IsOrReplace() {
+		buf.WriteString(ctx.keyword(" OR REPLACE"))
+	}
+	if view.HasAlgorithm() {
+		buf.WriteString(ctx.keyword(" ALGORITHM = "))
+		buf.WriteString(ctx.keyword(view.Algorithm()))
+	}
+	if view.HasDefiner() {
+		buf.WriteString(ctx.keyword(" DEFINER = "))
+		buf.WriteString(view.Definer())
+	}
+	if view.HasSQLSecurity() {
+		buf.WriteString(ctx.keyword(" SQL SECURITY "))
+		buf.WriteString(ctx.keyword(view.SQLSecurity()))
+	}
+
+	buf.WriteString(ctx.keyword(" VIEW "))
+	buf.WriteString(ctx.quote(view.Name()))
+
+	if columns := view.Columns(); len(columns) > 0 {
+		buf.WriteString(" (")
+		for i, column := range columns {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(ctx.quote(column))
+		}
+		buf.WriteByte(')')
+	}
+
+	buf.WriteString(ctx.keyword(" AS "))
+	buf.WriteString(view.Definition())
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+func formatTrigger(ctx *fmtCtx, trigger model.Trigger) error {
+	var buf bytes.Buffer
+
+	buf.WriteString(ctx.keyword("CREATE"))
+	if trigger.HasDefiner() {
+		buf.WriteString(ctx.keyword(" DEFINER = "))
+		buf.WriteString(trigger.Definer())
+	}
+
+	buf.WriteString(ctx.keyword(" TRIGGER "))
+	buf.WriteString(ctx.quote(trigger.Name()))
+	buf.WriteByte(' ')
+	buf.WriteString(ctx.keyword(trigger.Timing()))
+	buf.WriteByte(' ')
+	buf.WriteString(ctx.keyword(trigger.Event()))
+	buf.WriteString(ctx.keyword(" ON "))
+	buf.WriteString(ctx.quote(trigger.TableName()))
+	buf.WriteString(ctx.keyword(" FOR EACH ROW"))
+
+	if trigger.HasOrder() {
+		buf.WriteByte(' ')
+		buf.WriteString(ctx.keyword(trigger.Order()))
+		buf.WriteByte(' ')
+		buf.WriteString(ctx.quote(trigger.OrderTrigger()))
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(trigger.Body())
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
 func formatTableOption(ctx *fmtCtx, option model.TableOption) error {
 	var buf bytes.Buffer
-	buf.WriteString(option.Key())
+	buf.WriteString(ctx.keyword(option.Key()))
 	buf.WriteString(" = ")
 	if option.NeedQuotes() {
 		buf.WriteByte('\'')
@@ -108,74 +384,87 @@ func formatTableOption(ctx *fmtCtx, option model.TableOption) error {
 func formatTable(ctx *fmtCtx, table model.Table) error {
 	var buf bytes.Buffer
 
-	buf.WriteString("CREATE")
+	if ctx.comments {
+		for _, comment := range table.LeadingComments() {
+			buf.WriteString(ctx.curIndent)
+			buf.WriteString(comment)
+			buf.WriteByte('\n')
+		}
+	}
+
+	buf.WriteString(ctx.keyword("CREATE"))
 	if table.IsTemporary() {
-		buf.WriteString(" TEMPORARY")
+		buf.WriteString(ctx.keyword(" TEMPORARY"))
 	}
 
-	buf.WriteString(" TABLE")
+	buf.WriteString(ctx.keyword(" TABLE"))
 	if table.IsIfNotExists() {
-		buf.WriteString(" IF NOT EXISTS")
+		buf.WriteString(ctx.keyword(" IF NOT EXISTS"))
 	}
 
 	buf.WriteByte(' ')
-	buf.WriteString(util.Backquote(table.Name()))
+	buf.WriteString(ctx.quote(table.Name()))
 
 	if table.HasLikeTable() {
-		buf.WriteString(" LIKE ")
-		buf.WriteString(util.Backquote(table.LikeTable()))
+		buf.WriteString(ctx.keyword(" LIKE "))
+		buf.WriteString(ctx.quote(table.LikeTable()))
 	} else {
 
 		newctx := ctx.clone()
 		newctx.curIndent = newctx.indent + newctx.curIndent
 		newctx.dst = &buf
-
-		buf.WriteString(" (")
-
-		colch := table.Columns()
-		idxch := table.Indexes()
-		colchmax := len(colch)
-		idxchmax := len(idxch)
-
-		var i int
-		for col := range colch {
-			buf.WriteByte('\n')
-			if err := formatTableColumn(newctx, col); err != nil {
+		if ctx.align && !ctx.compact {
+			if err := alignColumns(newctx, table); err != nil {
 				return err
 			}
-			if i < colchmax-1 || idxchmax > 0 {
-				buf.WriteByte(',')
-			}
-			i++
 		}
 
-		i = 0
-		for idx := range idxch {
-			buf.WriteByte('\n')
-			if err := formatIndex(newctx, idx); err != nil {
-				return err
-			}
-			if i < idxchmax-1 {
-				buf.WriteByte(',')
-			}
-			i++
+		var fields []listItem
+		for _, col := range ctx.columns(table) {
+			col := col
+			fields = append(fields, listItem{
+				leadingComments: col.LeadingComments(),
+				trailingComment: col.TrailingComment(),
+				format:          func(ctx *fmtCtx) error { return formatTableColumn(ctx, col) },
+			})
+		}
+		for _, idx := range ctx.indexes(table) {
+			idx := idx
+			fields = append(fields, listItem{format: func(ctx *fmtCtx) error { return formatIndex(ctx, idx) }})
+		}
+		for _, check := range ctx.checks(table) {
+			check := check
+			fields = append(fields, listItem{
+				commentedOut: !newctx.supports(8, 0, 16),
+				format:       func(ctx *fmtCtx) error { return formatCheck(ctx, check) },
+			})
+		}
+		if err := writeList(newctx, &buf, ctx.curIndent, fields); err != nil {
+			return err
 		}
 
-		buf.WriteString("\n)")
-
-		optch := table.Options()
-		if l := len(optch); l > 0 {
+		if options := ctx.tableOptions(table); len(options) > 0 {
 			buf.WriteByte(' ')
-			var i int
-			for option := range optch {
+			for i, option := range options {
 				if err := formatTableOption(newctx, option); err != nil {
 					return err
 				}
 
-				if i < l-1 {
+				if i < len(options)-1 {
 					buf.WriteString(", ")
 				}
-				i++
+			}
+		}
+
+		if table.HasPartitioning() {
+			if ctx.compact {
+				buf.WriteByte(' ')
+			} else {
+				buf.WriteByte('\n')
+			}
+			newctx.curIndent = ctx.curIndent
+			if err := formatPartitioning(newctx, table.Partitioning()); err != nil {
+				return err
 			}
 		}
 	}
@@ -191,94 +480,115 @@ func formatColumnType(ctx *fmtCtx, col model.ColumnType) error {
 		return errors.New(`invalid column type`)
 	}
 
-	if _, err := io.WriteString(ctx.dst, col.String()); err != nil {
+	if _, err := io.WriteString(ctx.dst, ctx.keyword(col.String())); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func formatTableColumn(ctx *fmtCtx, col model.TableColumn) error {
+func formatPartitioning(ctx *fmtCtx, partitioning model.Partitioning) error {
 	var buf bytes.Buffer
 
 	buf.WriteString(ctx.curIndent)
-	buf.WriteString(util.Backquote(col.Name()))
-	buf.WriteByte(' ')
+	buf.WriteString(ctx.keyword("PARTITION BY "))
+	buf.WriteString(ctx.keyword(partitioning.Type()))
+	buf.WriteString(" (")
+	buf.WriteString(partitioning.Expression())
+	buf.WriteByte(')')
 
-	newctx := ctx.clone()
-	newctx.curIndent = ""
-	newctx.dst = &buf
-	if err := formatColumnType(newctx, col.Type()); err != nil {
-		return err
+	if partitioning.HasCount() {
+		buf.WriteString(ctx.keyword(" PARTITIONS "))
+		buf.WriteString(strconv.Itoa(partitioning.Count()))
+	}
+	if partitioning.HasSubpartitioning() {
+		buf.WriteByte(' ')
+		buf.WriteString(partitioning.Subpartitioning())
 	}
 
-	switch col.Type() {
-	case model.ColumnTypeEnum:
-		buf.WriteString(" (")
-		for enumValue := range col.EnumValues() {
-			buf.WriteByte('\'')
-			buf.WriteString(enumValue)
-			buf.WriteByte('\'')
-			buf.WriteByte(',')
-		}
-		buf.Truncate(buf.Len() - 1)
-		buf.WriteByte(')')
-	case model.ColumnTypeSet:
-		buf.WriteString(" (")
-		for setValue := range col.SetValues() {
-			buf.WriteByte('\'')
-			buf.WriteString(setValue)
-			buf.WriteByte('\'')
-			buf.WriteByte(',')
+	defch := partitioning.Definitions()
+	if len(defch) > 0 {
+		newctx := ctx.clone()
+		newctx.curIndent = newctx.indent + newctx.curIndent
+		newctx.dst = &buf
+
+		var defs []listItem
+		for def := range defch {
+			def := def
+			defs = append(defs, listItem{format: func(ctx *fmtCtx) error { return formatPartitionDefinition(ctx, def) }})
 		}
-		buf.Truncate(buf.Len() - 1)
-		buf.WriteByte(')')
-	default:
-		if col.HasLength() {
-			l := col.Length()
-			buf.WriteString(" (")
-			buf.WriteString(l.Length())
-			if l.HasDecimal() {
-				buf.WriteByte(',')
-				buf.WriteString(l.Decimal())
-			}
-			buf.WriteByte(')')
+		if err := writeList(newctx, &buf, ctx.curIndent, defs); err != nil {
+			return err
 		}
 	}
 
-	if col.IsUnsigned() {
-		buf.WriteString(" UNSIGNED")
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
 	}
+	return nil
+}
 
-	if col.IsZeroFill() {
-		buf.WriteString(" ZEROFILL")
+func formatPartitionDefinition(ctx *fmtCtx, def model.PartitionDefinition) error {
+	var buf bytes.Buffer
+
+	buf.WriteString(ctx.curIndent)
+	buf.WriteString(ctx.keyword("PARTITION "))
+	buf.WriteString(ctx.quote(def.Name()))
+	if values := def.Values(); values != "" {
+		buf.WriteString(ctx.keyword(" VALUES "))
+		buf.WriteString(values)
+	}
+	if options := def.Options(); options != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(options)
+	}
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
 	}
+	return nil
+}
+
+func formatTableColumn(ctx *fmtCtx, col model.TableColumn) error {
+	var buf bytes.Buffer
+
+	buf.WriteString(ctx.curIndent)
+	name := ctx.quote(col.Name())
+	buf.WriteString(name)
+	buf.WriteString(padding(name, ctx.nameWidth))
+	buf.WriteByte(' ')
+
+	typeStart := buf.Len()
+	if err := writeColumnType(ctx, &buf, col); err != nil {
+		return err
+	}
+	typeEnd := buf.Len()
 
 	if col.IsBinary() {
-		buf.WriteString(" BINARY")
+		buf.WriteString(ctx.keyword(" BINARY"))
 	}
 
 	if col.HasCharacterSet() {
-		buf.WriteString(" CHARACTER SET ")
-		buf.WriteString(util.Backquote(col.CharacterSet()))
+		buf.WriteString(ctx.keyword(" CHARACTER SET "))
+		buf.WriteString(ctx.quote(col.CharacterSet()))
 	}
 
 	if col.HasCollation() {
-		buf.WriteString(" COLLATE ")
-		buf.WriteString(util.Backquote(col.Collation()))
+		buf.WriteString(ctx.keyword(" COLLATE "))
+		buf.WriteString(ctx.quote(ctx.collation(col)))
 	}
 
 	if col.HasAutoUpdate() {
-		buf.WriteString(" ON UPDATE ")
+		buf.WriteString(ctx.keyword(" ON UPDATE "))
 		buf.WriteString(col.AutoUpdate())
 	}
 
 	if col.IsGeneratedAlways() {
-		buf.WriteString(" GENERATED ALWAYS")
+		buf.WriteString(ctx.keyword(" GENERATED ALWAYS"))
 	}
 
 	if col.HasGeneratedExpr() {
-		buf.WriteString(" AS (")
+		buf.WriteString(ctx.keyword(" AS ("))
 		buf.WriteString(col.GeneratedExpr())
 		buf.WriteByte(')')
 	}
@@ -287,9 +597,9 @@ func formatTableColumn(ctx *fmtCtx, col model.TableColumn) error {
 		buf.WriteByte(' ')
 		switch col.StoreOption() {
 		case model.StoreOptionVirtual:
-			buf.WriteString("VIRTUAL")
+			buf.WriteString(ctx.keyword("VIRTUAL"))
 		case model.StoreOptionStored:
-			buf.WriteString("STORED")
+			buf.WriteString(ctx.keyword("STORED"))
 		}
 	}
 
@@ -297,43 +607,180 @@ func formatTableColumn(ctx *fmtCtx, col model.TableColumn) error {
 		buf.WriteByte(' ')
 		switch n {
 		case model.NullStateNull:
-			buf.WriteString("NULL")
+			buf.WriteString(ctx.keyword("NULL"))
 		case model.NullStateNotNull:
-			buf.WriteString("NOT NULL")
+			buf.WriteString(ctx.keyword("NOT NULL"))
 		}
 	}
 
 	if col.HasDefault() {
-		buf.WriteString(" DEFAULT ")
+		buf.WriteString(ctx.keyword(" DEFAULT "))
 		if col.IsQuotedDefault() {
 			buf.WriteByte('\'')
 			buf.WriteString(col.Default())
 			buf.WriteByte('\'')
+		} else if strings.EqualFold(col.Default(), "NULL") {
+			buf.WriteString(ctx.keyword("NULL"))
 		} else {
 			buf.WriteString(col.Default())
 		}
 	}
 
 	if col.IsAutoIncrement() {
-		buf.WriteString(" AUTO_INCREMENT")
+		buf.WriteString(ctx.keyword(" AUTO_INCREMENT"))
 	}
 
 	if col.IsUnique() {
-		buf.WriteString(" UNIQUE KEY")
+		buf.WriteString(ctx.keyword(" UNIQUE KEY"))
 	}
 
 	if col.IsPrimary() {
-		buf.WriteString(" PRIMARY KEY")
+		buf.WriteString(ctx.keyword(" PRIMARY KEY"))
 	} else if col.IsKey() {
-		buf.WriteString(" KEY")
+		buf.WriteString(ctx.keyword(" KEY"))
 	}
 
 	if col.HasComment() {
-		buf.WriteString(" COMMENT '")
+		buf.WriteString(ctx.keyword(" COMMENT '"))
 		buf.WriteString(col.Comment())
 		buf.WriteByte('\'')
 	}
 
+	// the attributes line up, and columns without any are not padded
+	if ctx.typeWidth > 0 && buf.Len() > typeEnd {
+		b := buf.Bytes()
+		typ := string(b[typeStart:typeEnd])
+		attrs := string(b[typeEnd:])
+		buf.Truncate(typeEnd)
+		buf.WriteString(padding(typ, ctx.typeWidth))
+		buf.WriteString(attrs)
+	}
+
+	if _, err := buf.WriteTo(ctx.dst); err != nil {
+		return err
+	}
+	return nil
+}
+
+// writeColumnType writes the type of the column, along with its length
+// or values, and UNSIGNED and ZEROFILL
+func writeColumnType(ctx *fmtCtx, buf *bytes.Buffer, col model.TableColumn) error {
+	newctx := ctx.clone()
+	newctx.curIndent = ""
+	newctx.dst = buf
+	if err := formatColumnType(newctx, col.Type()); err != nil {
+		return err
+	}
+
+	switch col.Type() {
+	case model.ColumnTypeEnum:
+		buf.WriteString(" (")
+		for enumValue := range col.EnumValues() {
+			buf.WriteByte('\'')
+			buf.WriteString(enumValue)
+			buf.WriteByte('\'')
+			buf.WriteByte(',')
+		}
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteByte(')')
+	case model.ColumnTypeSet:
+		buf.WriteString(" (")
+		for setValue := range col.SetValues() {
+			buf.WriteByte('\'')
+			buf.WriteString(setValue)
+			buf.WriteByte('\'')
+			buf.WriteByte(',')
+		}
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteByte(')')
+	default:
+		if col.HasLength() && !(ctx.omitIntDisplayWidth && hidesDisplayWidth(col)) {
+			l := col.Length()
+			buf.WriteString(" (")
+			buf.WriteString(l.Length())
+			if l.HasDecimal() {
+				buf.WriteByte(',')
+				buf.WriteString(l.Decimal())
+			}
+			buf.WriteByte(')')
+		}
+	}
+
+	if col.IsUnsigned() {
+		buf.WriteString(ctx.keyword(" UNSIGNED"))
+	}
+
+	if col.IsZeroFill() {
+		buf.WriteString(ctx.keyword(" ZEROFILL"))
+	}
+	return nil
+}
+
+// alignColumns sets the widths that the names and types of the columns
+// of the table are padded to, so that they line up
+func alignColumns(ctx *fmtCtx, table model.Table) error {
+	ctx.nameWidth, ctx.typeWidth = 0, 0
+	for col := range table.Columns() {
+		if n := utf8.RuneCountInString(ctx.quote(col.Name())); n > ctx.nameWidth {
+			ctx.nameWidth = n
+		}
+		var buf bytes.Buffer
+		if err := writeColumnType(ctx, &buf, col); err != nil {
+			return err
+		}
+		if n := utf8.RuneCount(buf.Bytes()); n > ctx.typeWidth {
+			ctx.typeWidth = n
+		}
+	}
+	return nil
+}
+
+// padding returns the spaces that pad s to the width
+func padding(s string, width int) string {
+	if n := width - utf8.RuneCountInString(s); n > 0 {
+		return strings.Repeat(" ", n)
+	}
+	return ""
+}
+
+// hidesDisplayWidth reports whether MySQL 8.0.19 and later leave out
+// the display width of the column, which they do for integer types,
+// except for TINYINT(1) and ZEROFILL columns
+func hidesDisplayWidth(col model.TableColumn) bool {
+	switch col.Type() {
+	case model.ColumnTypeTinyInt:
+		return !col.IsZeroFill() && col.Length().Length() != "1"
+	case model.ColumnTypeSmallInt, model.ColumnTypeMediumInt, model.ColumnTypeInt,
+		model.ColumnTypeInteger, model.ColumnTypeBigInt:
+		return !col.IsZeroFill()
+	}
+	return false
+}
+
+func formatCheck(ctx *fmtCtx, check model.Check) error {
+	var buf bytes.Buffer
+
+	buf.WriteString(ctx.curIndent)
+	// MySQL before 8.0.16 parses CHECK constraints, but ignores them
+	commentedOut := !ctx.supports(8, 0, 16)
+	if commentedOut {
+		buf.WriteString("/* ")
+	}
+	if check.HasName() {
+		buf.WriteString(ctx.keyword("CONSTRAINT "))
+		buf.WriteString(ctx.quote(check.Name()))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(ctx.keyword("CHECK ("))
+	buf.WriteString(check.Expression())
+	buf.WriteByte(')')
+	if !check.IsEnforced() {
+		buf.WriteString(ctx.keyword(" NOT ENFORCED"))
+	}
+	if commentedOut {
+		buf.WriteString(" */")
+	}
+
 	if _, err := buf.WriteTo(ctx.dst); err != nil {
 		return err
 	}
@@ -345,29 +792,29 @@ func formatIndex(ctx *fmtCtx, index model.Index) error {
 
 	buf.WriteString(ctx.curIndent)
 	if index.HasSymbol() {
-		buf.WriteString("CONSTRAINT ")
-		buf.WriteString(util.Backquote(index.Symbol()))
+		buf.WriteString(ctx.keyword("CONSTRAINT "))
+		buf.WriteString(ctx.quote(index.Symbol()))
 		buf.WriteByte(' ')
 	}
 
 	switch {
 	case index.IsPrimaryKey():
-		buf.WriteString("PRIMARY KEY")
+		buf.WriteString(ctx.keyword("PRIMARY KEY"))
 	case index.IsNormal():
-		buf.WriteString("KEY")
+		buf.WriteString(ctx.keyword("KEY"))
 	case index.IsUnique():
-		buf.WriteString("UNIQUE KEY")
+		buf.WriteString(ctx.keyword("UNIQUE KEY"))
 	case index.IsFullText():
-		buf.WriteString("FULLTEXT KEY")
+		buf.WriteString(ctx.keyword("FULLTEXT KEY"))
 	case index.IsSpatial():
-		buf.WriteString("SPATIAL KEY")
+		buf.WriteString(ctx.keyword("SPATIAL KEY"))
 	case index.IsForeignKey():
-		buf.WriteString("FOREIGN KEY")
+		buf.WriteString(ctx.keyword("FOREIGN KEY"))
 	}
 
 	if index.HasName() {
 		buf.WriteByte(' ')
-		buf.WriteString(util.Backquote(index.Name()))
+		buf.WriteString(ctx.quote(index.Name()))
 	}
 
 	buf.WriteString(" (")
@@ -379,17 +826,18 @@ func formatIndex(ctx *fmtCtx, index model.Index) error {
 
 	var i int
 	for col := range ch {
-		buf.WriteString(util.Backquote(col.Name()))
+		buf.WriteString(ctx.quote(col.Name()))
 		if col.HasLength() {
 			buf.WriteByte('(')
 			buf.WriteString(col.Length())
 			buf.WriteByte(')')
 		}
-		if col.HasSortDirection() {
+		// MySQL 5.7 parses DESC, but ignores it
+		if col.HasSortDirection() && (col.IsAscending() || ctx.supports(8, 0, 0)) {
 			if col.IsAscending() {
-				buf.WriteString(" ASC")
+				buf.WriteString(ctx.keyword(" ASC"))
 			} else {
-				buf.WriteString(" DESC")
+				buf.WriteString(ctx.keyword(" DESC"))
 			}
 		}
 
@@ -403,14 +851,14 @@ func formatIndex(ctx *fmtCtx, index model.Index) error {
 	if !index.IsForeignKey() {
 		switch {
 		case index.IsBtree():
-			buf.WriteString(" USING BTREE")
+			buf.WriteString(ctx.keyword(" USING BTREE"))
 		case index.IsHash():
-			buf.WriteString(" USING HASH")
+			buf.WriteString(ctx.keyword(" USING HASH"))
 		}
 	}
 
 	if index.HasParser() {
-		buf.WriteString(" WITH PARSER ")
+		buf.WriteString(ctx.keyword(" WITH PARSER "))
 		buf.WriteString(index.Parser())
 	}
 
@@ -434,15 +882,15 @@ func formatReference(ctx *fmtCtx, r model.Reference) error {
 	var buf bytes.Buffer
 
 	buf.WriteString(ctx.curIndent)
-	buf.WriteString("REFERENCES ")
-	buf.WriteString(util.Backquote(r.TableName()))
+	buf.WriteString(ctx.keyword("REFERENCES "))
+	buf.WriteString(ctx.quote(r.TableName()))
 	buf.WriteString(" (")
 
 	ch := r.Columns()
 	lch := len(ch)
 	var i int
 	for col := range ch {
-		buf.WriteString(util.Backquote(col.Name()))
+		buf.WriteString(ctx.quote(col.Name()))
 		if col.HasLength() {
 			buf.WriteByte('(')
 			buf.WriteString(col.Length())
@@ -457,16 +905,16 @@ func formatReference(ctx *fmtCtx, r model.Reference) error {
 
 	switch {
 	case r.MatchFull():
-		buf.WriteString(" MATCH FULL")
+		buf.WriteString(ctx.keyword(" MATCH FULL"))
 	case r.MatchPartial():
-		buf.WriteString(" MATCH PARTIAL")
+		buf.WriteString(ctx.keyword(" MATCH PARTIAL"))
 	case r.MatchSimple():
-		buf.WriteString(" MATCH SIMPLE")
+		buf.WriteString(ctx.keyword(" MATCH SIMPLE"))
 	}
 
 	// we should really check for errors...
-	writeReferenceOption(&buf, "ON DELETE", r.OnDelete())
-	writeReferenceOption(&buf, "ON UPDATE", r.OnUpdate())
+	writeReferenceOption(ctx, &buf, "ON DELETE", r.OnDelete())
+	writeReferenceOption(ctx, &buf, "ON UPDATE", r.OnUpdate())
 
 	if _, err := buf.WriteTo(ctx.dst); err != nil {
 		return err
@@ -474,19 +922,19 @@ func formatReference(ctx *fmtCtx, r model.Reference) error {
 	return nil
 }
 
-func writeReferenceOption(buf *bytes.Buffer, prefix string, opt model.ReferenceOption) error {
+func writeReferenceOption(ctx *fmtCtx, buf *bytes.Buffer, prefix string, opt model.ReferenceOption) error {
 	if opt != model.ReferenceOptionNone {
 		buf.WriteByte(' ')
-		buf.WriteString(prefix)
+		buf.WriteString(ctx.keyword(prefix))
 		switch opt {
 		case model.ReferenceOptionRestrict:
-			buf.WriteString(" RESTRICT")
+			buf.WriteString(ctx.keyword(" RESTRICT"))
 		case model.ReferenceOptionCascade:
-			buf.WriteString(" CASCADE")
+			buf.WriteString(ctx.keyword(" CASCADE"))
 		case model.ReferenceOptionSetNull:
-			buf.WriteString(" SET NULL")
+			buf.WriteString(ctx.keyword(" SET NULL"))
 		case model.ReferenceOptionNoAction:
-			buf.WriteString(" NO ACTION")
+			buf.WriteString(ctx.keyword(" NO ACTION"))
 		default:
 			return errors.New("unknown reference option")
 		}
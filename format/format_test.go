@@ -2,8 +2,10 @@ package format_test
 
 import (
 	"bytes"
+	"io"
 	"testing"
 
+	"github.com/schemalex/schemalex"
 	"github.com/schemalex/schemalex/format"
 	"github.com/schemalex/schemalex/model"
 	"github.com/stretchr/testify/assert"
@@ -33,3 +35,238 @@ func TestFormat(t *testing.T) {
 
 	t.Logf("%s", dst.String())
 }
+
+func TestFormatStyle(t *testing.T) {
+	table := model.NewTable("hoge")
+	for _, name := range []string{"id", "name"} {
+		col := model.NewTableColumn(name)
+		col.SetType(model.ColumnTypeInt)
+		col.SetNullState(model.NullStateNotNull)
+		table.AddColumn(col)
+	}
+	index := model.NewIndex(model.IndexKindPrimaryKey, table.ID())
+	index.AddColumns(model.NewIndexColumn("id"))
+	table.AddIndex(index)
+	table.AddOption(model.NewTableOption("ENGINE", "InnoDB", false))
+
+	type Spec struct {
+		Name    string
+		Options []format.Option
+		Expect  string
+	}
+
+	specs := []Spec{
+		{
+			Name:   "default",
+			Expect: "CREATE TABLE `hoge` (\n`id` INT NOT NULL,\n`name` INT NOT NULL,\nPRIMARY KEY (`id`)\n) ENGINE = InnoDB",
+		},
+		{
+			Name:    "lower case keywords",
+			Options: []format.Option{format.WithKeywordCase(format.KeywordCaseLower), format.WithIndent(" ", 4)},
+			Expect:  "create table `hoge` (\n    `id` int not null,\n    `name` int not null,\n    primary key (`id`)\n) engine = InnoDB",
+		},
+		{
+			Name:    "leading commas",
+			Options: []format.Option{format.WithCommaStyle(format.CommaLeading), format.WithIndent(" ", 2)},
+			Expect:  "CREATE TABLE `hoge` (\n  `id` INT NOT NULL\n  , `name` INT NOT NULL\n  , PRIMARY KEY (`id`)\n) ENGINE = InnoDB",
+		},
+		{
+			Name:    "compact",
+			Options: []format.Option{format.WithCompact(true), format.WithIndent(" ", 2)},
+			Expect:  "CREATE TABLE `hoge` (`id` INT NOT NULL, `name` INT NOT NULL, PRIMARY KEY (`id`)) ENGINE = InnoDB",
+		},
+	}
+
+	for _, spec := range specs {
+		t.Run(spec.Name, func(t *testing.T) {
+			var dst bytes.Buffer
+			if !assert.NoError(t, format.SQL(&dst, table, spec.Options...), "format.SQL should succeed") {
+				return
+			}
+			assert.Equal(t, spec.Expect, dst.String(), "formatted SQL should match")
+		})
+	}
+}
+
+func TestFormatQuoting(t *testing.T) {
+	table := model.NewTable("hoge")
+	for _, name := range []string{"id", "order", "2021", "user name", "$x1"} {
+		col := model.NewTableColumn(name)
+		col.SetType(model.ColumnTypeInt)
+		table.AddColumn(col)
+	}
+
+	type Spec struct {
+		Quoting format.Quoting
+		Expect  string
+	}
+
+	specs := []Spec{
+		{
+			Quoting: format.QuoteAlways,
+			Expect:  "CREATE TABLE `hoge` (`id` INT, `order` INT, `2021` INT, `user name` INT, `$x1` INT)",
+		},
+		{
+			Quoting: format.QuoteWhenNeeded,
+			Expect:  "CREATE TABLE hoge (id INT, `order` INT, `2021` INT, `user name` INT, $x1 INT)",
+		},
+		{
+			Quoting: format.QuoteNever,
+			Expect:  "CREATE TABLE hoge (id INT, order INT, 2021 INT, user name INT, $x1 INT)",
+		},
+	}
+
+	for _, spec := range specs {
+		var dst bytes.Buffer
+		if !assert.NoError(t, format.SQL(&dst, table, format.WithCompact(true), format.WithQuoting(spec.Quoting)), "format.SQL should succeed") {
+			return
+		}
+		assert.Equal(t, spec.Expect, dst.String(), "formatted SQL should match")
+	}
+}
+
+func TestFormatIntDisplayWidth(t *testing.T) {
+	table := model.NewTable("hoge")
+	add := func(name string, typ model.ColumnType, length string, zerofill bool) {
+		col := model.NewTableColumn(name)
+		col.SetType(typ)
+		col.SetLength(model.NewLength(length))
+		col.SetZeroFill(zerofill)
+		table.AddColumn(col)
+	}
+	add("a", model.ColumnTypeInt, "11", false)
+	add("b", model.ColumnTypeTinyInt, "1", false)
+	add("c", model.ColumnTypeInt, "5", true)
+	add("d", model.ColumnTypeVarChar, "20", false)
+
+	var dst bytes.Buffer
+	if !assert.NoError(t, format.SQL(&dst, table, format.WithCompact(true), format.WithIntDisplayWidth(false)), "format.SQL should succeed") {
+		return
+	}
+	assert.Equal(t, "CREATE TABLE `hoge` (`a` INT, `b` TINYINT (1), `c` INT (5) ZEROFILL, `d` VARCHAR (20))", dst.String(), "display widths should be left out like MySQL 8.0 does")
+}
+
+func TestFormatTargetVersion(t *testing.T) {
+	table := model.NewTable("hoge")
+	col := model.NewTableColumn("name")
+	col.SetType(model.ColumnTypeVarChar)
+	col.SetLength(model.NewLength("20"))
+	col.SetCollation("utf8mb4_general_ci").SetImpliedCollation(true)
+	table.AddColumn(col)
+	idxCol := model.NewIndexColumn("name")
+	idxCol.SetSortDirection(model.SortDirectionDescending)
+	idx := model.NewIndex(model.IndexKindNormal, "hoge")
+	idx.SetName("name")
+	idx.AddColumns(idxCol)
+	table.AddIndex(idx)
+	table.AddCheck(model.NewCheck("`name` <> ''"))
+
+	for _, spec := range []struct {
+		Version  string
+		Expected string
+	}{
+		{
+			Version:  "5.7",
+			Expected: "CREATE TABLE `hoge` (`name` VARCHAR (20) COLLATE `utf8mb4_general_ci`, KEY `name` (`name`) /* CHECK (`name` <> '') */)",
+		},
+		{
+			Version:  "8.0.15",
+			Expected: "CREATE TABLE `hoge` (`name` VARCHAR (20) COLLATE `utf8mb4_0900_ai_ci`, KEY `name` (`name` DESC) /* CHECK (`name` <> '') */)",
+		},
+		{
+			Version:  "8.0.16",
+			Expected: "CREATE TABLE `hoge` (`name` VARCHAR (20) COLLATE `utf8mb4_0900_ai_ci`, KEY `name` (`name` DESC), CHECK (`name` <> ''))",
+		},
+	} {
+		var dst bytes.Buffer
+		if !assert.NoError(t, format.SQL(&dst, table, format.WithCompact(true), format.WithTargetVersion(spec.Version)), "format.SQL should succeed") {
+			return
+		}
+		assert.Equal(t, spec.Expected, dst.String(), "statement should be written for MySQL %s", spec.Version)
+	}
+
+	var dst bytes.Buffer
+	assert.Error(t, format.SQL(&dst, table, format.WithTargetVersion("latest")), "invalid versions should be rejected")
+}
+
+func TestFormatTargetVersionExplicitCollation(t *testing.T) {
+	stmts, err := schemalex.New().ParseString("CREATE TABLE `hoge` ( `a` VARCHAR (20) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci, `b` VARCHAR (20) CHARACTER SET utf8mb4 );")
+	if !assert.NoError(t, err, "Parse should succeed") {
+		return
+	}
+	table, _ := stmts[0].(model.Table).Normalize()
+
+	var dst bytes.Buffer
+	if !assert.NoError(t, format.SQL(&dst, table, format.WithCompact(true), format.WithTargetVersion("8.0")), "format.SQL should succeed") {
+		return
+	}
+	assert.Equal(t, "CREATE TABLE `hoge` (`a` VARCHAR (20) CHARACTER SET `utf8mb4` COLLATE `utf8mb4_general_ci` DEFAULT NULL, `b` VARCHAR (20) CHARACTER SET `utf8mb4` COLLATE `utf8mb4_0900_ai_ci` DEFAULT NULL)", dst.String(), "only the implied collation should be rewritten")
+}
+
+func TestDialect(t *testing.T) {
+	stmts, err := schemalex.New().ParseString("CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );")
+	if !assert.NoError(t, err, "Parse should succeed") {
+		return
+	}
+
+	format.Register("test", format.FormatterFunc(func(dst io.Writer, v interface{}, options ...format.Option) error {
+		if _, err := io.WriteString(dst, "-- test\n"); err != nil {
+			return err
+		}
+		return format.MySQL.Format(dst, v, options...)
+	}))
+	assert.Contains(t, format.Dialects(), "test", "dialect should be registered")
+	assert.Panics(t, func() { format.Register("test", format.MySQL) }, "registering twice should panic")
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, format.SQL(&buf, stmts, format.WithDialect("test"), format.WithKeywordCase(format.KeywordCaseLower)), "format.SQL should succeed") {
+		return
+	}
+	assert.Equal(t, "-- test\ncreate table `hoge` (\n`id` int (11) not null\n)", buf.String(), "dialect should be used")
+
+	assert.Error(t, format.SQL(&buf, stmts, format.WithDialect("unknown")), "unknown dialects should fail")
+}
+
+func TestFormatAlign(t *testing.T) {
+	stmts, err := schemalex.New().ParseString("CREATE TABLE `users` ( `id` INTEGER UNSIGNED NOT NULL AUTO_INCREMENT, `name` VARCHAR (255) NOT NULL COMMENT 'full name', `bio` TEXT, `created_at` DATETIME NOT NULL, PRIMARY KEY (`id`) );")
+	if !assert.NoError(t, err, "Parse should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, format.SQL(&buf, stmts[0], format.WithAlign(true), format.WithIndent(" ", 2)), "format.SQL should succeed") {
+		return
+	}
+	expect := "CREATE TABLE `users` (\n" +
+		"  `id`         INT (10) UNSIGNED NOT NULL AUTO_INCREMENT,\n" +
+		"  `name`       VARCHAR (255)     NOT NULL COMMENT 'full name',\n" +
+		"  `bio`        TEXT,\n" +
+		"  `created_at` DATETIME          NOT NULL,\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		")"
+	assert.Equal(t, expect, buf.String(), "columns should be aligned")
+}
+
+func TestFormatCanonical(t *testing.T) {
+	stmts, err := schemalex.New().ParseString("CREATE TABLE `hoge` ( `name` VARCHAR (20) NOT NULL, `id` INTEGER NOT NULL, `fuga_id` INTEGER NOT NULL, CONSTRAINT `fk_fuga` FOREIGN KEY (`fuga_id`) REFERENCES `fuga` (`id`), INDEX `idx_name` (`name`), UNIQUE KEY `uniq_fuga` (`fuga_id`), PRIMARY KEY (`id`) ) ENGINE = InnoDB, AUTO_INCREMENT = 10, COMMENT = 'hoges';")
+	if !assert.NoError(t, err, "Parse should succeed") {
+		return
+	}
+
+	t.Run("declared column order", func(t *testing.T) {
+		var buf bytes.Buffer
+		if !assert.NoError(t, format.SQL(&buf, stmts[0], format.WithCanonical(true), format.WithCompact(true)), "format.SQL should succeed") {
+			return
+		}
+		expect := "CREATE TABLE `hoge` (`name` VARCHAR (20) NOT NULL, `id` INT (11) NOT NULL, `fuga_id` INT (11) NOT NULL, PRIMARY KEY (`id`), UNIQUE KEY `uniq_fuga` (`fuga_id`), KEY `idx_name` (`name`), CONSTRAINT `fk_fuga` FOREIGN KEY (`fuga_id`) REFERENCES `fuga` (`id`) ON DELETE RESTRICT ON UPDATE RESTRICT) AUTO_INCREMENT = 10, COMMENT = 'hoges', ENGINE = InnoDB"
+		assert.Equal(t, expect, buf.String(), "indexes and options should be sorted")
+	})
+	t.Run("alphabetical column order", func(t *testing.T) {
+		var buf bytes.Buffer
+		if !assert.NoError(t, format.SQL(&buf, stmts[0], format.WithCanonical(true), format.WithColumnOrder(format.ColumnOrderAlphabetical), format.WithCompact(true)), "format.SQL should succeed") {
+			return
+		}
+		expect := "CREATE TABLE `hoge` (`fuga_id` INT (11) NOT NULL, `id` INT (11) NOT NULL, `name` VARCHAR (20) NOT NULL, PRIMARY KEY (`id`), UNIQUE KEY `uniq_fuga` (`fuga_id`), KEY `idx_name` (`name`), CONSTRAINT `fk_fuga` FOREIGN KEY (`fuga_id`) REFERENCES `fuga` (`id`) ON DELETE RESTRICT ON UPDATE RESTRICT) AUTO_INCREMENT = 10, COMMENT = 'hoges', ENGINE = InnoDB"
+		assert.Equal(t, expect, buf.String(), "columns should be sorted")
+	})
+}
@@ -23,3 +23,163 @@ func WithIndent(s string, n int) Option {
 	}
 	return option.New(optkeyIndent, strings.Repeat(s, n))
 }
+
+const (
+	optkeyKeywordCase = "keyword-case"
+	optkeyCommaStyle  = "comma-style"
+	optkeyCompact     = "compact"
+	optkeyAlign       = "align"
+	optkeyCanonical   = "canonical"
+	optkeyColumnOrder = "column-order"
+	optkeyQuoting     = "quoting"
+
+	optkeyIntDisplayWidth = "int-display-width"
+	optkeyComments        = "comments"
+	optkeyTargetVersion   = "target-version"
+	optkeyDialect         = "dialect"
+)
+
+// KeywordCase is the letter case that keywords are written in
+type KeywordCase int
+
+// List of possible KeywordCase values
+const (
+	KeywordCaseUpper KeywordCase = iota
+	KeywordCaseLower
+)
+
+// CommaStyle tells where the commas between the fields of a table go
+type CommaStyle int
+
+// List of possible CommaStyle values
+const (
+	// CommaTrailing puts commas at the end of each line but the last
+	CommaTrailing CommaStyle = iota
+	// CommaLeading puts commas at the beginning of each line but the first
+	CommaLeading
+)
+
+// WithKeywordCase specifies the letter case of keywords and data types.
+// Identifiers, values and expressions, such as the definition of a
+// view, are written as they are. Keywords are upper case by default.
+func WithKeywordCase(c KeywordCase) Option {
+	return option.New(optkeyKeywordCase, c)
+}
+
+// WithCommaStyle specifies where the commas between the fields of a
+// table, and between partitions, go when each of them is written on a
+// line of its own. Commas are trailing by default.
+func WithCommaStyle(s CommaStyle) Option {
+	return option.New(optkeyCommaStyle, s)
+}
+
+// WithIntDisplayWidth specifies whether the display widths of integer
+// types, such as the 11 of INT(11), are written. If disabled, columns
+// are written the way MySQL 8.0.19 and later show them, which keep the
+// display width of TINYINT(1) and ZEROFILL columns only. Display widths
+// are written by default.
+func WithIntDisplayWidth(b bool) Option {
+	return option.New(optkeyIntDisplayWidth, b)
+}
+
+// WithComments specifies whether the comments written around tables and
+// columns in the source, which the parser keeps, are written as well.
+// Comments are not written by default.
+func WithComments(b bool) Option {
+	return option.New(optkeyComments, b)
+}
+
+// WithCompact specifies whether the fields of a table are written on
+// the same line as the table, instead of one field per line
+func WithCompact(b bool) Option {
+	return option.New(optkeyCompact, b)
+}
+
+// WithAlign specifies whether the names, types and attributes of the
+// columns of a table are padded so that they line up vertically, as in
+//
+//	CREATE TABLE `users` (
+//	  `id`   INT (10) UNSIGNED NOT NULL AUTO_INCREMENT,
+//	  `name` VARCHAR (255)     NOT NULL,
+//	  ...
+//
+// It has no effect on compact output.
+func WithAlign(b bool) Option {
+	return option.New(optkeyAlign, b)
+}
+
+// WithCanonical specifies whether the indexes, CHECK constraints and
+// options of a table are written in a fixed order, whatever order they
+// are declared in, so that equivalent tables are written the same:
+// the primary key comes first, followed by unique, plain, fulltext and
+// spatial indexes and foreign keys, each sorted by name; constraints
+// are sorted by name, and options by key. See also WithColumnOrder
+func WithCanonical(b bool) Option {
+	return option.New(optkeyCanonical, b)
+}
+
+// ColumnOrder is the order that the columns of a table are written in
+type ColumnOrder int
+
+// List of possible ColumnOrder values
+const (
+	// ColumnOrderDeclared keeps the columns in the order they are
+	// declared in, which is the order MySQL stores them in
+	ColumnOrderDeclared ColumnOrder = iota
+	// ColumnOrderAlphabetical sorts the columns by name. Note that the
+	// resulting table is not the same as the original one to MySQL.
+	ColumnOrderAlphabetical
+)
+
+// WithColumnOrder specifies the order that the columns of a table are
+// written in, which is the declared order by default
+func WithColumnOrder(o ColumnOrder) Option {
+	return option.New(optkeyColumnOrder, o)
+}
+
+// Quoting tells when identifiers are surrounded by backquotes
+type Quoting int
+
+// List of possible Quoting values
+const (
+	// QuoteAlways quotes all identifiers
+	QuoteAlways Quoting = iota
+	// QuoteWhenNeeded only quotes identifiers that would not be valid
+	// otherwise, such as reserved words
+	QuoteWhenNeeded
+	// QuoteNever writes identifiers as they are, which may result in
+	// invalid SQL, but is useful when translating to other dialects
+	QuoteNever
+)
+
+// WithQuoting specifies when identifiers, such as the names of tables
+// and columns, are quoted. They are always quoted by default.
+func WithQuoting(q Quoting) Option {
+	return option.New(optkeyQuoting, q)
+}
+
+// WithTargetVersion specifies the version of MySQL, such as "5.7" or
+// "8.0.21", that the statements are written for. Syntax that the
+// version does not understand is left out or rewritten:
+//
+//   - the default collation of utf8mb4 is written as utf8mb4_0900_ai_ci
+//     for MySQL 8.0 and later, and as utf8mb4_general_ci before that,
+//     when it is implied by the character set of a column. Collations
+//     written in the source are left as they are
+//   - DESC is left out of index columns before MySQL 8.0, which would
+//     ignore it anyway
+//   - CHECK constraints within a table are commented out before MySQL
+//     8.0.16, which would ignore them anyway
+//
+// By default, statements are written as they are.
+func WithTargetVersion(v string) Option {
+	return option.New(optkeyTargetVersion, v)
+}
+
+// WithDialect specifies the name of the dialect of SQL to write, which
+// is "mysql" by default. Other dialects have to be registered with
+// Register first. The rest of the options are passed on to the
+// Formatter of the dialect, which may ignore those it does not know.
+func WithDialect(name string) Option {
+	return option.New(optkeyDialect, name)
+}
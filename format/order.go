@@ -0,0 +1,99 @@
+package format
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/schemalex/schemalex/model"
+)
+
+// columns returns the columns of the table in the order specified by
+// WithColumnOrder
+func (ctx *fmtCtx) columns(table model.Table) []model.TableColumn {
+	var columns []model.TableColumn
+	for col := range table.Columns() {
+		columns = append(columns, col)
+	}
+	if ctx.columnOrder == ColumnOrderAlphabetical {
+		sort.SliceStable(columns, func(i, j int) bool {
+			return columns[i].Name() < columns[j].Name()
+		})
+	}
+	return columns
+}
+
+// indexRank is the position of each kind of index in canonical order
+func indexRank(idx model.Index) int {
+	switch {
+	case idx.IsPrimaryKey():
+		return 0
+	case idx.IsUnique():
+		return 1
+	case idx.IsFullText():
+		return 3
+	case idx.IsSpatial():
+		return 4
+	case idx.IsForeignKey():
+		return 5
+	default:
+		return 2
+	}
+}
+
+// indexSortName returns the name that the index is sorted by, which
+// for foreign keys is the name of the constraint
+func indexSortName(idx model.Index) string {
+	if idx.IsForeignKey() && idx.HasSymbol() || !idx.HasName() {
+		return idx.Symbol()
+	}
+	return idx.Name()
+}
+
+// indexes returns the indexes of the table, in canonical order if
+// specified by WithCanonical
+func (ctx *fmtCtx) indexes(table model.Table) []model.Index {
+	var indexes []model.Index
+	for idx := range table.Indexes() {
+		indexes = append(indexes, idx)
+	}
+	if ctx.canonical {
+		sort.SliceStable(indexes, func(i, j int) bool {
+			ri, rj := indexRank(indexes[i]), indexRank(indexes[j])
+			if ri != rj {
+				return ri < rj
+			}
+			return indexSortName(indexes[i]) < indexSortName(indexes[j])
+		})
+	}
+	return indexes
+}
+
+// checks returns the CHECK constraints of the table, sorted by name if
+// specified by WithCanonical
+func (ctx *fmtCtx) checks(table model.Table) []model.Check {
+	var checks []model.Check
+	for check := range table.Checks() {
+		checks = append(checks, check)
+	}
+	if ctx.canonical {
+		sort.SliceStable(checks, func(i, j int) bool {
+			return checks[i].Name() < checks[j].Name()
+		})
+	}
+	return checks
+}
+
+// tableOptions returns the options of the table, sorted by key if
+// specified by WithCanonical
+func (ctx *fmtCtx) tableOptions(table model.Table) []model.TableOption {
+	var options []model.TableOption
+	for opt := range table.Options() {
+		options = append(options, opt)
+	}
+	if ctx.canonical {
+		sort.SliceStable(options, func(i, j int) bool {
+			return strings.ToUpper(options[i].Key()) < strings.ToUpper(options[j].Key())
+		})
+	}
+	return options
+}
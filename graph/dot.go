@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// DOT writes the tables among the statements to dst as a Graphviz
+// graph, such that `dot -Tsvg` draws an ER diagram. Each table is a
+// record listing its columns, and each foreign key is an edge to the
+// table it references, between the columns themselves if it has only
+// one column.
+func DOT(dst io.Writer, stmts model.Stmts) error {
+	entities, relationships := diagram(stmts)
+
+	var buf bytes.Buffer
+	buf.WriteString("digraph schema {\n")
+	buf.WriteString("  rankdir=LR;\n")
+	buf.WriteString("  node [shape=record];\n")
+	for _, e := range entities {
+		fields := []string{escapeRecord(e.name)}
+		for _, a := range e.attributes {
+			field := "<" + escapeRecord(a.name) + "> " + escapeRecord(a.name+": "+a.typ)
+			var keys []string
+			if a.primary {
+				keys = append(keys, "PK")
+			}
+			if a.foreign {
+				keys = append(keys, "FK")
+			}
+			if len(keys) > 0 {
+				field += " " + strings.Join(keys, ", ")
+			}
+			fields = append(fields, field+`\l`)
+		}
+		buf.WriteString("  ")
+		buf.WriteString(quoteDOT(e.name))
+		buf.WriteString(" [label=")
+		buf.WriteString(quoteDOT("{" + strings.Join(fields, "|") + "}"))
+		buf.WriteString("];\n")
+	}
+
+	for _, r := range relationships {
+		from, to := quoteDOT(r.from), quoteDOT(r.to)
+		if len(r.fromColumns) == 1 && len(r.toColumns) == 1 {
+			from += ":" + quoteDOT(r.fromColumns[0])
+			to += ":" + quoteDOT(r.toColumns[0])
+		}
+		buf.WriteString("  ")
+		buf.WriteString(from)
+		buf.WriteString(" -> ")
+		buf.WriteString(to)
+		if r.name != "" {
+			buf.WriteString(" [label=")
+			buf.WriteString(quoteDOT(r.name))
+			buf.WriteString("]")
+		}
+		buf.WriteString(";\n")
+	}
+	buf.WriteString("}\n")
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write graph`)
+	}
+	return nil
+}
+
+// quoteDOT quotes an ID in DOT. Backslashes are left as they are, as
+// they start escape sequences in labels.
+func quoteDOT(s string) string {
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
+
+var recordReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	"{", `\{`,
+	"}", `\}`,
+	"|", `\|`,
+	"<", `\<`,
+	">", `\>`,
+)
+
+// escapeRecord escapes the characters that have a meaning in record
+// labels
+func escapeRecord(s string) string {
+	return recordReplacer.Replace(s)
+}
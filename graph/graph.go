@@ -0,0 +1,180 @@
+// Package graph draws schemas as entity-relationship diagrams, with
+// tables as entities and foreign keys as relationships between them
+package graph
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/schemalex/schemalex/model"
+)
+
+// entity is a table, along with the columns to draw
+type entity struct {
+	name       string
+	attributes []attribute
+}
+
+type attribute struct {
+	name     string
+	typ      string
+	primary  bool
+	foreign  bool
+	unique   bool
+	nullable bool
+}
+
+// relationship is a foreign key from the columns of one table to
+// the columns of another
+type relationship struct {
+	name        string
+	from        string
+	fromColumns []string
+	to          string
+	toColumns   []string
+	// optional is true if any of the referencing columns is nullable,
+	// in which case rows do not have to reference anything
+	optional bool
+	// unique is true if the referencing columns are unique, in which
+	// case the relationship is one to one
+	unique bool
+	// identifying is true if the referencing columns are part of the
+	// primary key, so that rows cannot exist without what they reference
+	identifying bool
+}
+
+// diagram collects the tables and foreign keys among the statements.
+// Foreign keys to tables that are not among them are left out.
+func diagram(stmts model.Stmts) ([]entity, []relationship) {
+	var tables []model.Table
+	names := make(map[string]struct{})
+	for _, stmt := range stmts {
+		if table, ok := stmt.(model.Table); ok {
+			table, _ = table.Normalize()
+			tables = append(tables, table)
+			names[table.Name()] = struct{}{}
+		}
+	}
+
+	var entities []entity
+	var relationships []relationship
+	for _, table := range tables {
+		primary := make(map[string]bool)
+		foreign := make(map[string]bool)
+		var uniques [][]string
+		for idx := range table.Indexes() {
+			cols := indexColumns(idx)
+			switch {
+			case idx.IsPrimaryKey():
+				for _, col := range cols {
+					primary[col] = true
+				}
+				uniques = append(uniques, cols)
+			case idx.IsUnique():
+				uniques = append(uniques, cols)
+			case idx.IsForeignKey():
+				for _, col := range cols {
+					foreign[col] = true
+				}
+			}
+		}
+
+		e := entity{name: table.Name()}
+		nullable := make(map[string]bool)
+		for col := range table.Columns() {
+			nullable[col.Name()] = col.NullState() != model.NullStateNotNull
+			e.attributes = append(e.attributes, attribute{
+				name:     col.Name(),
+				typ:      columnType(col),
+				primary:  primary[col.Name()],
+				foreign:  foreign[col.Name()],
+				unique:   isUnique(uniques, []string{col.Name()}),
+				nullable: nullable[col.Name()],
+			})
+		}
+		entities = append(entities, e)
+
+		for idx := range table.Indexes() {
+			r := idx.Reference()
+			if !idx.IsForeignKey() || r == nil {
+				continue
+			}
+			if _, ok := names[r.TableName()]; !ok {
+				continue
+			}
+			rel := relationship{
+				name:        idx.Symbol(),
+				from:        table.Name(),
+				fromColumns: indexColumns(idx),
+				to:          r.TableName(),
+				toColumns:   indexColumns(r),
+			}
+			rel.identifying = true
+			for _, col := range rel.fromColumns {
+				if nullable[col] {
+					rel.optional = true
+				}
+				if !primary[col] {
+					rel.identifying = false
+				}
+			}
+			rel.unique = isUnique(uniques, rel.fromColumns)
+			relationships = append(relationships, rel)
+		}
+	}
+	return entities, relationships
+}
+
+func indexColumns(c model.ColumnContainer) []string {
+	var cols []string
+	for col := range c.Columns() {
+		cols = append(cols, col.Name())
+	}
+	return cols
+}
+
+// isUnique tells if any of the unique indexes covers exactly the columns
+func isUnique(uniques [][]string, cols []string) bool {
+	sorted := append([]string(nil), cols...)
+	sort.Strings(sorted)
+	for _, unique := range uniques {
+		if len(unique) != len(sorted) {
+			continue
+		}
+		u := append([]string(nil), unique...)
+		sort.Strings(u)
+		if strings.Join(u, "\x00") == strings.Join(sorted, "\x00") {
+			return true
+		}
+	}
+	return false
+}
+
+// columnType returns the type of the column in lower case, such as
+// varchar(255). Display widths of integers are left out.
+func columnType(col model.TableColumn) string {
+	typ := strings.ToLower(col.Type().String())
+	switch col.Type().SynonymType() {
+	case model.ColumnTypeTinyInt, model.ColumnTypeSmallInt, model.ColumnTypeMediumInt,
+		model.ColumnTypeInt, model.ColumnTypeBigInt, model.ColumnTypeYear,
+		model.ColumnTypeEnum, model.ColumnTypeSet:
+		if isBoolean(col) {
+			return "bool"
+		}
+		return typ
+	}
+	if col.HasLength() {
+		l := col.Length()
+		if l.HasDecimal() {
+			return typ + "(" + l.Length() + "," + l.Decimal() + ")"
+		}
+		return typ + "(" + l.Length() + ")"
+	}
+	return typ
+}
+
+// isBoolean tells if the column is TINYINT(1), which is what BOOLEAN
+// is in MySQL
+func isBoolean(col model.TableColumn) bool {
+	return col.Type() == model.ColumnTypeTinyInt && col.HasLength() && col.Length().Length() == "1" && !col.IsUnsigned()
+}
@@ -0,0 +1,101 @@
+package graph_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/graph"
+	"github.com/schemalex/schemalex/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const schema = "CREATE TABLE `users` ( `id` INTEGER UNSIGNED NOT NULL AUTO_INCREMENT, `name` VARCHAR (20) NOT NULL, PRIMARY KEY (`id`) );\n" +
+	"CREATE TABLE `profiles` ( `user_id` INTEGER UNSIGNED NOT NULL, `bio` TEXT, PRIMARY KEY (`user_id`), CONSTRAINT `fk_profile_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) );\n" +
+	"CREATE TABLE `posts` ( `id` BIGINT NOT NULL, `user_id` INTEGER UNSIGNED, `ext_id` INTEGER, PRIMARY KEY (`id`), CONSTRAINT `fk_post_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`), CONSTRAINT `fk_ext` FOREIGN KEY (`ext_id`) REFERENCES `external` (`id`) );"
+
+func parse(t *testing.T) model.Stmts {
+	stmts, err := schemalex.New().ParseString(schema)
+	if err != nil {
+		t.Fatalf("failed to parse schema: %s", err)
+	}
+	return stmts
+}
+
+func TestDOT(t *testing.T) {
+	expect := `digraph schema {
+  rankdir=LR;
+  node [shape=record];
+  "users" [label="{users|<id> id: int PK\l|<name> name: varchar(20)\l}"];
+  "profiles" [label="{profiles|<user_id> user_id: int PK, FK\l|<bio> bio: text\l}"];
+  "posts" [label="{posts|<id> id: bigint PK\l|<user_id> user_id: int FK\l|<ext_id> ext_id: int FK\l}"];
+  "profiles":"user_id" -> "users":"id" [label="fk_profile_user"];
+  "posts":"user_id" -> "users":"id" [label="fk_post_user"];
+}
+`
+	var buf bytes.Buffer
+	if !assert.NoError(t, graph.DOT(&buf, parse(t)), "graph.DOT should succeed") {
+		return
+	}
+	assert.Equal(t, expect, buf.String(), "graph should match")
+}
+
+func TestMermaid(t *testing.T) {
+	expect := `erDiagram
+    users {
+        int id PK
+        varchar(20) name
+    }
+    profiles {
+        int user_id PK, FK
+        text bio
+    }
+    posts {
+        bigint id PK
+        int user_id FK
+        int ext_id FK
+    }
+    users ||--o| profiles : "fk_profile_user"
+    users |o..o{ posts : "fk_post_user"
+`
+	var buf bytes.Buffer
+	if !assert.NoError(t, graph.Mermaid(&buf, parse(t)), "graph.Mermaid should succeed") {
+		return
+	}
+	assert.Equal(t, expect, buf.String(), "diagram should match")
+}
+
+func TestPlantUML(t *testing.T) {
+	expect := `@startuml
+hide circle
+skinparam linetype ortho
+
+entity "users" as users {
+  * id : int <<PK>>
+  --
+  * name : varchar(20)
+}
+
+entity "profiles" as profiles {
+  * user_id : int <<PK>> <<FK>>
+  --
+  bio : text
+}
+
+entity "posts" as posts {
+  * id : bigint <<PK>>
+  --
+  user_id : int <<FK>>
+  ext_id : int <<FK>>
+}
+
+users ||--o| profiles : fk_profile_user
+users |o..o{ posts : fk_post_user
+@enduml
+`
+	var buf bytes.Buffer
+	if !assert.NoError(t, graph.PlantUML(&buf, parse(t)), "graph.PlantUML should succeed") {
+		return
+	}
+	assert.Equal(t, expect, buf.String(), "diagram should match")
+}
@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// Mermaid writes the tables among the statements to dst as a Mermaid
+// erDiagram, which GitHub, GitLab and many wikis draw in Markdown.
+// Relationships that are part of the primary key are drawn as solid
+// lines (identifying), and others as dashed ones.
+func Mermaid(dst io.Writer, stmts model.Stmts) error {
+	entities, relationships := diagram(stmts)
+
+	var buf bytes.Buffer
+	buf.WriteString("erDiagram\n")
+	for _, e := range entities {
+		buf.WriteString("    ")
+		buf.WriteString(mermaidName(e.name))
+		buf.WriteString(" {\n")
+		for _, a := range e.attributes {
+			buf.WriteString("        ")
+			buf.WriteString(mermaidType(a.typ))
+			buf.WriteByte(' ')
+			buf.WriteString(mermaidAttribute(a.name))
+			var keys []string
+			if a.primary {
+				keys = append(keys, "PK")
+			}
+			if a.foreign {
+				keys = append(keys, "FK")
+			}
+			if a.unique && !a.primary {
+				keys = append(keys, "UK")
+			}
+			if len(keys) > 0 {
+				buf.WriteByte(' ')
+				buf.WriteString(strings.Join(keys, ", "))
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("    }\n")
+	}
+
+	for _, r := range relationships {
+		// the referenced table is on the left
+		parent := "||"
+		if r.optional {
+			parent = "|o"
+		}
+		child := "o{"
+		if r.unique {
+			child = "o|"
+		}
+		line := ".."
+		if r.identifying {
+			line = "--"
+		}
+		buf.WriteString("    ")
+		buf.WriteString(mermaidName(r.to))
+		buf.WriteByte(' ')
+		buf.WriteString(parent + line + child)
+		buf.WriteByte(' ')
+		buf.WriteString(mermaidName(r.from))
+		buf.WriteString(` : "`)
+		buf.WriteString(strings.Replace(r.name, `"`, "'", -1))
+		buf.WriteString("\"\n")
+	}
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write diagram`)
+	}
+	return nil
+}
+
+var mermaidNameRx = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_\-]*$`)
+
+// mermaidName returns the name of an entity, which is quoted if it has
+// characters other than letters, digits, hyphens and underscores
+func mermaidName(s string) string {
+	if mermaidNameRx.MatchString(s) {
+		return s
+	}
+	return `"` + strings.Replace(s, `"`, "'", -1) + `"`
+}
+
+var mermaidInvalidRx = regexp.MustCompile(`[^A-Za-z0-9_\-]`)
+
+// mermaidAttribute returns the name of an attribute, which cannot be
+// quoted, with the characters Mermaid does not allow replaced
+func mermaidAttribute(s string) string {
+	return mermaidInvalidRx.ReplaceAllString(s, "_")
+}
+
+// mermaidType returns the type of an attribute. Mermaid allows
+// parentheses in types, but not commas, so the precision of types
+// such as decimal(10,2) is left out.
+func mermaidType(s string) string {
+	if strings.Contains(s, ",") {
+		if i := strings.IndexByte(s, '('); i >= 0 {
+			s = s[:i]
+		}
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// PlantUML writes the tables among the statements to dst as a PlantUML
+// entity diagram in the Information Engineering notation. Primary key
+// columns are listed above the line in each entity, and NOT NULL
+// columns are marked with "*". Relationships that are part of the
+// primary key are drawn as solid lines (identifying), and others as
+// dashed ones.
+func PlantUML(dst io.Writer, stmts model.Stmts) error {
+	entities, relationships := diagram(stmts)
+
+	var buf bytes.Buffer
+	buf.WriteString("@startuml\n")
+	buf.WriteString("hide circle\n")
+	buf.WriteString("skinparam linetype ortho\n")
+	for _, e := range entities {
+		buf.WriteString("\nentity \"")
+		buf.WriteString(strings.Replace(e.name, `"`, "'", -1))
+		buf.WriteString("\" as ")
+		buf.WriteString(plantUMLAlias(e.name))
+		buf.WriteString(" {\n")
+		var keys, others []attribute
+		for _, a := range e.attributes {
+			if a.primary {
+				keys = append(keys, a)
+			} else {
+				others = append(others, a)
+			}
+		}
+		for _, a := range keys {
+			writePlantUMLAttribute(&buf, a)
+		}
+		buf.WriteString("  --\n")
+		for _, a := range others {
+			writePlantUMLAttribute(&buf, a)
+		}
+		buf.WriteString("}\n")
+	}
+
+	if len(relationships) > 0 {
+		buf.WriteByte('\n')
+	}
+	for _, r := range relationships {
+		// the referenced table is on the left
+		parent := "||"
+		if r.optional {
+			parent = "|o"
+		}
+		child := "o{"
+		if r.unique {
+			child = "o|"
+		}
+		line := ".."
+		if r.identifying {
+			line = "--"
+		}
+		buf.WriteString(plantUMLAlias(r.to))
+		buf.WriteByte(' ')
+		buf.WriteString(parent + line + child)
+		buf.WriteByte(' ')
+		buf.WriteString(plantUMLAlias(r.from))
+		if r.name != "" {
+			buf.WriteString(" : ")
+			buf.WriteString(r.name)
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("@enduml\n")
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write diagram`)
+	}
+	return nil
+}
+
+func writePlantUMLAttribute(buf *bytes.Buffer, a attribute) {
+	buf.WriteString("  ")
+	if !a.nullable {
+		buf.WriteString("* ")
+	}
+	buf.WriteString(a.name)
+	buf.WriteString(" : ")
+	buf.WriteString(a.typ)
+	if a.primary {
+		buf.WriteString(" <<PK>>")
+	}
+	if a.foreign {
+		buf.WriteString(" <<FK>>")
+	}
+	if a.unique && !a.primary {
+		buf.WriteString(" <<UK>>")
+	}
+	buf.WriteByte('\n')
+}
+
+var plantUMLAliasRx = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// plantUMLAlias returns the name that an entity is referred to by in
+// relationships, as table names may have characters that PlantUML does
+// not allow in names
+func plantUMLAlias(s string) string {
+	return plantUMLAliasRx.ReplaceAllString(s, "_")
+}
@@ -0,0 +1,379 @@
+// Package graphql generates GraphQL type definitions from tables, so
+// that GraphQL servers can bootstrap their schema from the definition
+// of the database
+package graphql
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// Custom scalars for the types of columns that GraphQL has no built-in
+// scalar for. They are only declared if they are used.
+const (
+	scalarBigInt   = "BigInt"
+	scalarDecimal  = "Decimal"
+	scalarDate     = "Date"
+	scalarTime     = "Time"
+	scalarDateTime = "DateTime"
+	scalarJSON     = "JSON"
+)
+
+// Stmts writes GraphQL SDL to dst, with an object type for each of the
+// tables among the statements and a field for each of their columns.
+// NOT NULL columns are non-null fields, and a single column primary
+// key is an ID. ENUM columns get enum types of their own, if their
+// values can be written as GraphQL names.
+//
+// Foreign keys add fields on both ends: the referencing type gets a
+// field for the row it references, named after the column without its
+// _id suffix if there is a single one, and the referenced type gets a
+// list of the rows referencing it, or a single row if the foreign key
+// is unique. Fields whose names are already taken are left out.
+// Comments on tables and columns become descriptions.
+func Stmts(dst io.Writer, stmts model.Stmts) error {
+	var tables []model.Table
+	for _, stmt := range stmts {
+		if table, ok := stmt.(model.Table); ok {
+			table, _ = table.Normalize()
+			tables = append(tables, table)
+		}
+	}
+
+	types := make(map[string]*object)
+	var objects []*object
+	for _, table := range tables {
+		o := newObject(table)
+		types[table.Name()] = o
+		objects = append(objects, o)
+	}
+	for _, table := range tables {
+		addRelations(types, table)
+	}
+
+	scalars := make(map[string]struct{})
+	var body bytes.Buffer
+	for _, o := range objects {
+		for _, e := range o.enums {
+			body.WriteByte('\n')
+			body.WriteString("enum ")
+			body.WriteString(e.name)
+			body.WriteString(" {\n")
+			for _, v := range e.values {
+				body.WriteString("  ")
+				body.WriteString(v)
+				body.WriteByte('\n')
+			}
+			body.WriteString("}\n")
+		}
+
+		body.WriteByte('\n')
+		writeDescription(&body, "", o.description)
+		body.WriteString("type ")
+		body.WriteString(o.name)
+		body.WriteString(" {\n")
+		for _, f := range o.fields {
+			if f.scalar != "" {
+				scalars[f.scalar] = struct{}{}
+			}
+			writeDescription(&body, "  ", f.description)
+			body.WriteString("  ")
+			body.WriteString(f.name)
+			body.WriteString(": ")
+			body.WriteString(f.typ)
+			body.WriteByte('\n')
+		}
+		body.WriteString("}\n")
+	}
+
+	var buf bytes.Buffer
+	var names []string
+	for name := range scalars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		buf.WriteString("scalar ")
+		buf.WriteString(name)
+		buf.WriteByte('\n')
+	}
+	if len(names) == 0 {
+		// no blank line before the first type
+		body.Next(1)
+	}
+	body.WriteTo(&buf)
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write schema`)
+	}
+	return nil
+}
+
+// Source writes GraphQL SDL for the schema read from src (see Stmts)
+func Source(dst io.Writer, src schemalex.SchemaSource) error {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+	return Stmts(dst, stmts)
+}
+
+type object struct {
+	name        string
+	description string
+	fields      []field
+	enums       []enum
+}
+
+type field struct {
+	name        string
+	typ         string
+	description string
+	scalar      string // custom scalar that the field is of, if any
+}
+
+type enum struct {
+	name   string
+	values []string
+}
+
+// add appends the field, unless there already is one with the name
+func (o *object) add(f field) {
+	for _, existing := range o.fields {
+		if existing.name == f.name {
+			return
+		}
+	}
+	o.fields = append(o.fields, f)
+}
+
+func newObject(table model.Table) *object {
+	o := &object{name: typeName(table.Name())}
+	for opt := range table.Options() {
+		if strings.EqualFold(opt.Key(), "COMMENT") {
+			o.description = opt.Value()
+		}
+	}
+
+	var id string
+	for idx := range table.Indexes() {
+		if !idx.IsPrimaryKey() {
+			continue
+		}
+		var cols []string
+		for col := range idx.Columns() {
+			cols = append(cols, col.Name())
+		}
+		if len(cols) == 1 {
+			id = cols[0]
+		}
+	}
+
+	for col := range table.Columns() {
+		f := field{name: fieldName(col.Name()), description: col.Comment()}
+		switch {
+		case col.Name() == id:
+			f.typ = "ID"
+		case col.Type() == model.ColumnTypeEnum:
+			values, ok := enumValues(col)
+			if !ok {
+				f.typ = "String"
+				break
+			}
+			e := enum{name: o.name + typeName(col.Name()), values: values}
+			o.enums = append(o.enums, e)
+			f.typ = e.name
+		default:
+			f.typ = scalarType(col)
+			switch f.typ {
+			case "Int", "Float", "String", "Boolean":
+			default:
+				f.scalar = f.typ
+			}
+		}
+		if col.NullState() == model.NullStateNotNull {
+			f.typ += "!"
+		}
+		o.fields = append(o.fields, f)
+	}
+	return o
+}
+
+// addRelations adds the fields for the foreign keys of the table, on
+// the types of both the table and the table it references
+func addRelations(types map[string]*object, table model.Table) {
+	from := types[table.Name()]
+	notNull := make(map[string]bool)
+	for col := range table.Columns() {
+		notNull[col.Name()] = col.NullState() == model.NullStateNotNull
+	}
+	var uniques []string
+	for idx := range table.Indexes() {
+		if idx.IsPrimaryKey() || idx.IsUnique() {
+			uniques = append(uniques, columnSet(idx))
+		}
+	}
+
+	for idx := range table.Indexes() {
+		r := idx.Reference()
+		if !idx.IsForeignKey() || r == nil {
+			continue
+		}
+		to, ok := types[r.TableName()]
+		if !ok {
+			continue
+		}
+
+		var cols []string
+		required := true
+		for col := range idx.Columns() {
+			cols = append(cols, col.Name())
+			if !notNull[col.Name()] {
+				required = false
+			}
+		}
+		name := fieldName(r.TableName())
+		if len(cols) == 1 && strings.HasSuffix(strings.ToLower(cols[0]), "_id") && len(cols[0]) > 3 {
+			name = fieldName(cols[0][:len(cols[0])-3])
+		}
+		typ := to.name
+		if required {
+			typ += "!"
+		}
+		from.add(field{name: name, typ: typ})
+
+		typ = "[" + from.name + "!]!"
+		set := columnSet(idx)
+		for _, unique := range uniques {
+			if unique == set {
+				typ = from.name
+				break
+			}
+		}
+		to.add(field{name: fieldName(table.Name()), typ: typ})
+	}
+}
+
+// columnSet returns the names of the columns, sorted and joined, so
+// that sets of columns can be compared
+func columnSet(c model.ColumnContainer) string {
+	var cols []string
+	for col := range c.Columns() {
+		cols = append(cols, col.Name())
+	}
+	sort.Strings(cols)
+	return strings.Join(cols, "\x00")
+}
+
+var wordRx = regexp.MustCompile(`[A-Za-z0-9]+`)
+
+// typeName returns the name of the type for a table, such as
+// UserProfiles for user_profiles
+func typeName(table string) string {
+	name := camelCase(table)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "T" + name
+	}
+	return name
+}
+
+// fieldName returns the name of the field for a column, in lower camel
+// case as is the convention of GraphQL, such as createdAt for
+// created_at
+func fieldName(col string) string {
+	name := camelCase(col)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return "_" + name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+func camelCase(s string) string {
+	var buf bytes.Buffer
+	for _, word := range wordRx.FindAllString(s, -1) {
+		buf.WriteString(strings.ToUpper(word[:1]))
+		buf.WriteString(word[1:])
+	}
+	return buf.String()
+}
+
+var enumValueRx = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// enumValues returns the values of an ENUM column as GraphQL enum
+// values, such as IN_PROGRESS for in-progress. It fails if any of them
+// cannot be written as one, or two of them end up the same.
+func enumValues(col model.TableColumn) ([]string, bool) {
+	var values []string
+	seen := make(map[string]struct{})
+	ok := true
+	for v := range col.EnumValues() {
+		name := strings.ToUpper(strings.Join(wordRx.FindAllString(v, -1), "_"))
+		_, dup := seen[name]
+		switch {
+		case dup, !enumValueRx.MatchString(name), name == "TRUE", name == "FALSE", name == "NULL":
+			ok = false
+		}
+		seen[name] = struct{}{}
+		values = append(values, name)
+	}
+	return values, ok && len(values) > 0
+}
+
+// scalarType returns the type of the field for a column. GraphQL Int
+// is a signed 32 bit integer, so that larger integers are BigInt.
+func scalarType(col model.TableColumn) string {
+	switch col.Type().SynonymType() {
+	case model.ColumnTypeTinyInt:
+		if col.Type() != model.ColumnTypeTinyInt || col.HasLength() && col.Length().Length() == "1" && !col.IsUnsigned() {
+			return "Boolean"
+		}
+		return "Int"
+	case model.ColumnTypeSmallInt, model.ColumnTypeMediumInt, model.ColumnTypeYear:
+		return "Int"
+	case model.ColumnTypeInt:
+		if col.IsUnsigned() {
+			return scalarBigInt
+		}
+		return "Int"
+	case model.ColumnTypeBigInt, model.ColumnTypeBit:
+		return scalarBigInt
+	case model.ColumnTypeFloat, model.ColumnTypeDouble:
+		return "Float"
+	case model.ColumnTypeDecimal:
+		return scalarDecimal
+	case model.ColumnTypeDate:
+		return scalarDate
+	case model.ColumnTypeTime:
+		return scalarTime
+	case model.ColumnTypeDateTime, model.ColumnTypeTimestamp:
+		return scalarDateTime
+	case model.ColumnTypeJSON:
+		return scalarJSON
+	default:
+		// texts, binaries, which are base64 encoded, and SET
+		return "String"
+	}
+}
+
+// writeDescription writes the text as a block string, if there is any
+func writeDescription(buf *bytes.Buffer, indent, s string) {
+	if s == "" {
+		return
+	}
+	buf.WriteString(indent)
+	buf.WriteString(`"""`)
+	buf.WriteString(strings.Replace(s, `"""`, `\"""`, -1))
+	buf.WriteString(`"""`)
+	buf.WriteByte('\n')
+}
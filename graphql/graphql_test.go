@@ -0,0 +1,59 @@
+package graphql_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmts(t *testing.T) {
+	src := "CREATE TABLE `users` ( `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT, `name` VARCHAR (20) NOT NULL COMMENT 'the name', `status` ENUM('active','in-progress') NOT NULL, `active` BOOL NOT NULL, `created_at` DATETIME DEFAULT NULL, PRIMARY KEY (`id`) ) COMMENT 'users';\n" +
+		"CREATE TABLE `profiles` ( `user_id` BIGINT UNSIGNED NOT NULL, `bio` TEXT, PRIMARY KEY (`user_id`), CONSTRAINT `fk_profile_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) );\n" +
+		"CREATE TABLE `posts` ( `id` INTEGER NOT NULL, `author_id` BIGINT UNSIGNED, `score` DECIMAL (5,2) NOT NULL, PRIMARY KEY (`id`), CONSTRAINT `fk_post_author` FOREIGN KEY (`author_id`) REFERENCES `users` (`id`) );"
+	expect := `scalar BigInt
+scalar DateTime
+scalar Decimal
+
+enum UsersStatus {
+  ACTIVE
+  IN_PROGRESS
+}
+
+"""users"""
+type Users {
+  id: ID!
+  """the name"""
+  name: String!
+  status: UsersStatus!
+  active: Boolean!
+  createdAt: DateTime
+  profiles: Profiles
+  posts: [Posts!]!
+}
+
+type Profiles {
+  userId: ID!
+  bio: String
+  user: Users!
+}
+
+type Posts {
+  id: ID!
+  authorId: BigInt
+  score: Decimal!
+  author: Users
+}
+`
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parsing should succeed") {
+		return
+	}
+	var buf bytes.Buffer
+	if assert.NoError(t, graphql.Stmts(&buf, stmts), "graphql.Stmts should succeed") {
+		assert.Equal(t, expect, buf.String(), "SDL should match")
+	}
+}
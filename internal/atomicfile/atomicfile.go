@@ -0,0 +1,95 @@
+// Package atomicfile writes files atomically: contents are written to a
+// temporary file next to the destination, which replaces it only once
+// everything is written, so that failures never leave partial files
+package atomicfile
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// File is a file being written atomically
+type File struct {
+	tmp    *os.File
+	gz     *gzip.Writer
+	w      io.Writer
+	path   string
+	perm   os.FileMode
+	closed bool
+}
+
+// Create starts writing the file at path, which is left untouched
+// until Commit is called. If compress is true, the contents are
+// compressed with gzip.
+func Create(path string, perm os.FileMode, compress bool) (*File, error) {
+	// the temporary file is in the same directory, as files can only
+	// be renamed within a file system
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to create temporary file for %s`, path)
+	}
+
+	f := &File{tmp: tmp, w: tmp, path: path, perm: perm}
+	if compress {
+		f.gz = gzip.NewWriter(tmp)
+		f.w = f.gz
+	}
+	return f, nil
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	return f.w.Write(p)
+}
+
+// Commit replaces the file with what was written
+func (f *File) Commit() error {
+	if f.closed {
+		return errors.New(`file already closed`)
+	}
+	f.closed = true
+
+	if f.gz != nil {
+		if err := f.gz.Close(); err != nil {
+			f.discard()
+			return errors.Wrapf(err, `failed to compress %s`, f.path)
+		}
+	}
+	if err := f.tmp.Chmod(f.perm); err != nil {
+		f.discard()
+		return errors.Wrapf(err, `failed to change mode of %s`, f.path)
+	}
+	if err := f.tmp.Sync(); err != nil {
+		f.discard()
+		return errors.Wrapf(err, `failed to write %s`, f.path)
+	}
+	if err := f.tmp.Close(); err != nil {
+		os.Remove(f.tmp.Name())
+		return errors.Wrapf(err, `failed to write %s`, f.path)
+	}
+	if err := os.Rename(f.tmp.Name(), f.path); err != nil {
+		os.Remove(f.tmp.Name())
+		return errors.Wrapf(err, `failed to replace %s`, f.path)
+	}
+	return nil
+}
+
+// Close discards what was written, unless Commit was called, so that
+// it can be deferred right after Create
+func (f *File) Close() error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	f.discard()
+	return nil
+}
+
+func (f *File) discard() {
+	f.tmp.Close()
+	os.Remove(f.tmp.Name())
+}
@@ -0,0 +1,367 @@
+// Package awsauth signs requests to AWS with Signature Version 4, with
+// credentials found the way the AWS SDKs find them
+package awsauth
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// Credentials are the credentials that requests are signed with
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// emptyPayloadHash is the SHA-256 hash of an empty body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// endpoints that credentials are fetched from, which are only changed
+// by tests
+var (
+	stsEndpoint       = "https://sts.amazonaws.com/"
+	containerEndpoint = "http://169.254.170.2"
+	instanceEndpoint  = "http://169.254.169.254/latest"
+)
+
+// metadataClient is the client of the endpoints that EC2 instances and
+// ECS tasks get credentials from, which do not exist elsewhere, and
+// must not make the others wait for long
+var metadataClient = &http.Client{Timeout: 2 * time.Second}
+
+// Sign signs the request, which has no body, with Signature Version 4,
+// signing all of its headers along with the host
+func Sign(req *http.Request, creds Credentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	canonical.WriteString(req.Method + "\n")
+	canonical.WriteString(req.URL.EscapedPath() + "\n")
+	canonical.WriteString(req.URL.Query().Encode() + "\n")
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonical.WriteString("\n" + signedHeaders + "\n" + emptyPayloadHash)
+
+	scope := amzDate[:8] + "/" + region + "/" + service + "/aws4_request"
+	hash := sha256.Sum256([]byte(canonical.String()))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, s := range []string{amzDate[:8], region, service, "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// Region returns the region given by the AWS_REGION or
+// AWS_DEFAULT_REGION environment variables, or by the profile in the
+// shared configuration file, and us-east-1 otherwise
+func Region() string {
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(name); region != "" {
+			return region
+		}
+	}
+
+	path := os.Getenv("AWS_CONFIG_FILE")
+	if path == "" {
+		path = awsFile("config")
+	}
+	section := "default"
+	if profile := awsProfile(); profile != "default" {
+		section = "profile " + profile
+	}
+	if region := readINI(path, section)["region"]; region != "" {
+		return region
+	}
+	return "us-east-1"
+}
+
+// LoadCredentials looks for credentials the way the AWS SDKs do, and
+// tells if there are any. They are looked for in the following order:
+//
+//   - the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+//     environment variables
+//   - the profile given by AWS_PROFILE in the shared credentials file
+//   - a web identity token given by AWS_WEB_IDENTITY_TOKEN_FILE and
+//     AWS_ROLE_ARN, exchanged for credentials by STS with client
+//   - the container credentials of ECS
+//   - the instance metadata of EC2, unless AWS_EC2_METADATA_DISABLED
+//     is true
+//
+// The metadata of ECS and EC2 are read with a short timeout, as they
+// do not exist elsewhere.
+func LoadCredentials(client *http.Client) (Credentials, bool, error) {
+	if creds, ok := envCredentials(); ok {
+		return creds, true, nil
+	}
+	if creds, ok := sharedCredentials(); ok {
+		return creds, true, nil
+	}
+
+	if tokenFile, role := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"), os.Getenv("AWS_ROLE_ARN"); tokenFile != "" && role != "" {
+		creds, err := webIdentityCredentials(client, tokenFile, role)
+		return creds, err == nil, err
+	}
+
+	if uri := containerCredentialsURI(); uri != "" {
+		creds, err := containerCredentials(uri)
+		return creds, err == nil, err
+	}
+
+	if !strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		// not being on EC2 is not an error
+		if creds, err := instanceCredentials(); err == nil {
+			return creds, true, nil
+		}
+	}
+	return Credentials{}, false, nil
+}
+
+// envCredentials returns the credentials given by the environment
+func envCredentials() (Credentials, bool) {
+	id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if id == "" || secret == "" {
+		return Credentials{}, false
+	}
+	return Credentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, true
+}
+
+// sharedCredentials returns the credentials of the profile in the
+// shared credentials file
+func sharedCredentials() (Credentials, bool) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		path = awsFile("credentials")
+	}
+	profile := readINI(path, awsProfile())
+	if profile["aws_access_key_id"] == "" {
+		return Credentials{}, false
+	}
+	return Credentials{
+		AccessKeyID:     profile["aws_access_key_id"],
+		SecretAccessKey: profile["aws_secret_access_key"],
+		SessionToken:    profile["aws_session_token"],
+	}, true
+}
+
+func awsProfile() string {
+	if profile := os.Getenv("AWS_PROFILE"); profile != "" {
+		return profile
+	}
+	return "default"
+}
+
+// awsFile returns the path of a file in ~/.aws
+func awsFile(name string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aws", name)
+}
+
+// readINI returns the keys of a section of the INI file at path, which
+// is empty if there is no such file or section
+func readINI(path, section string) map[string]string {
+	values := make(map[string]string)
+	if path == "" {
+		return values
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer f.Close()
+
+	var current string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+		case line[0] == '[' && line[len(line)-1] == ']':
+			current = strings.TrimSpace(line[1 : len(line)-1])
+		case current == section:
+			if i := strings.IndexByte(line, '='); i > 0 {
+				values[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+			}
+		}
+	}
+	return values
+}
+
+// webIdentityCredentials assumes the role with the web identity token,
+// as on EKS with IAM roles for service accounts
+func webIdentityCredentials(client *http.Client, tokenFile, role string) (Credentials, error) {
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return Credentials{}, errors.Wrap(err, `failed to read web identity token`)
+	}
+	session := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if session == "" {
+		session = "schemalex"
+	}
+
+	q := url.Values{}
+	q.Set("Action", "AssumeRoleWithWebIdentity")
+	q.Set("Version", "2011-06-15")
+	q.Set("RoleArn", role)
+	q.Set("RoleSessionName", session)
+	q.Set("WebIdentityToken", strings.TrimSpace(string(token)))
+	req, err := http.NewRequest(http.MethodGet, stsEndpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return Credentials{}, errors.Wrap(err, `failed to create request for web identity credentials`)
+	}
+	body, err := fetch(client, req)
+	if err != nil {
+		return Credentials{}, errors.Wrap(err, `failed to assume role with web identity`)
+	}
+
+	var result struct {
+		Credentials struct {
+			AccessKeyId     string
+			SecretAccessKey string
+			SessionToken    string
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.Unmarshal([]byte(body), &result); err != nil {
+		return Credentials{}, errors.Wrap(err, `failed to decode credentials of web identity`)
+	}
+	c := result.Credentials
+	return Credentials{AccessKeyID: c.AccessKeyId, SecretAccessKey: c.SecretAccessKey, SessionToken: c.SessionToken}, nil
+}
+
+func containerCredentialsURI() string {
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		return containerEndpoint + uri
+	}
+	return os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+}
+
+// containerCredentials gets the credentials of the task, on ECS
+func containerCredentials(uri string) (Credentials, error) {
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return Credentials{}, errors.Wrap(err, `failed to create request for container credentials`)
+	}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return fetchCredentials(req)
+}
+
+// instanceCredentials gets the credentials of the role of the
+// instance, on EC2, with IMDSv2
+func instanceCredentials() (Credentials, error) {
+	req, err := http.NewRequest(http.MethodPut, instanceEndpoint+"/api/token", nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "60")
+	token, err := fetch(metadataClient, req)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	req, err = http.NewRequest(http.MethodGet, instanceEndpoint+"/meta-data/iam/security-credentials/", nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token", token)
+	role, err := fetch(metadataClient, req)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if i := strings.IndexByte(role, '\n'); i >= 0 {
+		role = role[:i]
+	}
+
+	req, err = http.NewRequest(http.MethodGet, instanceEndpoint+"/meta-data/iam/security-credentials/"+role, nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token", token)
+	return fetchCredentials(req)
+}
+
+// fetch sends the request with the client, and returns the body of
+// the response, which has to be 200 OK
+func fetch(client *http.Client, req *http.Request) (string, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		// the query is left out, as it may hold a web identity token
+		return "", errors.Errorf(`failed to get %s://%s%s: %s`, req.URL.Scheme, req.URL.Host, req.URL.Path, res.Status)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// fetchCredentials gets credentials as JSON, as the metadata of ECS
+// tasks and EC2 instances give them
+func fetchCredentials(req *http.Request) (Credentials, error) {
+	body, err := fetch(metadataClient, req)
+	if err != nil {
+		return Credentials{}, errors.Wrap(err, `failed to get credentials`)
+	}
+	var c struct {
+		AccessKeyId     string
+		SecretAccessKey string
+		Token           string
+	}
+	if err := json.Unmarshal([]byte(body), &c); err != nil {
+		return Credentials{}, errors.Wrap(err, `failed to decode credentials`)
+	}
+	return Credentials{AccessKeyID: c.AccessKeyId, SecretAccessKey: c.SecretAccessKey, SessionToken: c.Token}, nil
+}
@@ -74,10 +74,13 @@ func _main() error {
 		{Ident: "EQUAL", Comment: "="},
 		{Ident: "COMMENT_IDENT", Comment: `// /*   */, --, #`},
 		{Ident: "ACTION"},
+		{Ident: "AFTER"},
+		{Ident: "ALGORITHM"},
 		{Ident: "ALWAYS"},
 		{Ident: "AS"},
 		{Ident: "AUTO_INCREMENT"},
 		{Ident: "AVG_ROW_LENGTH"},
+		{Ident: "BEFORE"},
 		{Ident: "BIGINT"},
 		{Ident: "BINARY"},
 		{Ident: "BIT"},
@@ -105,6 +108,7 @@ func _main() error {
 		{Ident: "DATETIME"},
 		{Ident: "DECIMAL"},
 		{Ident: "DEFAULT"},
+		{Ident: "DEFINER"},
 		{Ident: "DELAY_KEY_WRITE"},
 		{Ident: "DELETE"},
 		{Ident: "DIRECTORY"},
@@ -112,6 +116,7 @@ func _main() error {
 		{Ident: "DOUBLE"},
 		{Ident: "DROP"},
 		{Ident: "DYNAMIC"},
+		{Ident: "EACH"},
 		{Ident: "ENGINE"},
 		{Ident: "ENUM"},
 		{Ident: "EXISTS"},
@@ -119,6 +124,8 @@ func _main() error {
 		{Ident: "FIRST"},
 		{Ident: "FIXED"},
 		{Ident: "FLOAT"},
+		{Ident: "FOLLOWS"},
+		{Ident: "FOR"},
 		{Ident: "FOREIGN"},
 		{Ident: "FULL"},
 		{Ident: "FULLTEXT"},
@@ -126,6 +133,7 @@ func _main() error {
 		{Ident: "HASH"},
 		{Ident: "IF"},
 		{Ident: "INDEX"},
+		{Ident: "INSERT"},
 		{Ident: "INSERT_METHOD"},
 		{Ident: "INT"},
 		{Ident: "INTEGER"},
@@ -148,20 +156,26 @@ func _main() error {
 		{Ident: "NULL"},
 		{Ident: "NUMERIC"},
 		{Ident: "ON"},
+		{Ident: "OR"},
 		{Ident: "PACK_KEYS"},
 		{Ident: "PARSER"},
 		{Ident: "PARTIAL"},
 		{Ident: "PASSWORD"},
+		{Ident: "PRECEDES"},
 		{Ident: "PRIMARY"},
 		{Ident: "REAL"},
 		{Ident: "REDUNDANT"},
 		{Ident: "REFERENCES"},
+		{Ident: "REPLACE"},
 		{Ident: "RESTRICT"},
+		{Ident: "ROW"},
 		{Ident: "ROW_FORMAT"},
+		{Ident: "SECURITY"},
 		{Ident: "SET"},
 		{Ident: "SIMPLE"},
 		{Ident: "SMALLINT"},
 		{Ident: "SPATIAL"},
+		{Ident: "SQL"},
 		{Ident: "STATS_AUTO_RECALC"},
 		{Ident: "STATS_PERSISTENT"},
 		{Ident: "STATS_SAMPLE_PAGES"},
@@ -176,6 +190,7 @@ func _main() error {
 		{Ident: "TINYBLOB"},
 		{Ident: "TINYINT"},
 		{Ident: "TINYTEXT"},
+		{Ident: "TRIGGER"},
 		{Ident: "TRUE"},
 		{Ident: "UNION"},
 		{Ident: "UNIQUE"},
@@ -185,6 +200,7 @@ func _main() error {
 		{Ident: "USING"},
 		{Ident: "VARBINARY"},
 		{Ident: "VARCHAR"},
+		{Ident: "VIEW"},
 		{Ident: "VIRTUAL"},
 		{Ident: "WITH"},
 		{Ident: "YEAR"},
@@ -0,0 +1,52 @@
+// Package hookplugin loads the hooks run around diffs and applies from
+// Go plugins, built with go build -buildmode=plugin
+package hookplugin
+
+import (
+	"context"
+	"plugin"
+
+	"github.com/schemalex/schemalex/diff"
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// symbols are the functions a plugin may export, and the points they
+// run at
+var symbols = []struct {
+	name  string
+	point diff.HookPoint
+}{
+	{"BeforeDiff", diff.BeforeDiff},
+	{"AfterDiff", diff.AfterDiff},
+	{"BeforeApply", diff.BeforeApply},
+	{"AfterApply", diff.AfterApply},
+}
+
+// Load opens the plugin at path, and returns the hooks it exports as
+// options. A plugin exports any of the functions BeforeDiff, AfterDiff,
+// BeforeApply and AfterApply, declared as
+//
+//	func(context.Context, diff.HookEvent) error
+func Load(path string) ([]diff.Option, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to open plugin %s`, path)
+	}
+
+	var options []diff.Option
+	for _, s := range symbols {
+		sym, err := p.Lookup(s.name)
+		if err != nil {
+			continue
+		}
+		fn, ok := sym.(func(context.Context, diff.HookEvent) error)
+		if !ok {
+			return nil, errors.Errorf(`%s of plugin %s is a %T, not a hook`, s.name, path, sym)
+		}
+		options = append(options, diff.WithHook(s.point, fn))
+	}
+	if len(options) == 0 {
+		return nil, errors.Errorf(`plugin %s exports no hooks`, path)
+	}
+	return options, nil
+}
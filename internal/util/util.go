@@ -1,7 +1,49 @@
 package util
 
+import "strings"
+
 // Backquote surrounds the given string in backquotes
 func Backquote(s string) string {
 	// XXX Does this require escaping
 	return "`" + s + "`"
 }
+
+// DoubleQuote surrounds the given string in double quotes, which is how
+// standard SQL quotes identifiers
+func DoubleQuote(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+// SingleQuote surrounds the given string in single quotes, which is how
+// SQL quotes strings
+func SingleQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// DoubleQuoteIdentifiers replaces the backquotes around identifiers in
+// the expression with double quotes, leaving string literals as they
+// are. Nothing else in the expression is translated.
+func DoubleQuoteIdentifiers(expr string) string {
+	var buf strings.Builder
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		case quote != 0 && c == '\\':
+			buf.WriteByte(c)
+			i++
+			if i < len(expr) {
+				buf.WriteByte(expr[i])
+			}
+			continue
+		case quote == c:
+			quote = 0
+		case quote == 0 && c == '`':
+			c = '"'
+		}
+		buf.WriteByte(c)
+	}
+	return buf.String()
+}
@@ -0,0 +1,45 @@
+// Package version handles version numbers of MySQL servers
+package version
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// MySQL is a version of MySQL, such as 8.0.21
+type MySQL [3]int
+
+// ParseMySQL parses versions such as "5.7", "8.0.21" or "8.0.21-log".
+// Missing parts are taken as zero.
+func ParseMySQL(s string) (MySQL, error) {
+	var v MySQL
+	// allow things like "8.0.21-log"
+	if i := strings.IndexFunc(s, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); i >= 0 {
+		s = s[:i]
+	}
+	l := strings.Split(s, ".")
+	if len(l) > 3 {
+		return v, errors.Errorf(`invalid MySQL version %s`, s)
+	}
+	for i, c := range l {
+		n, err := strconv.Atoi(c)
+		if err != nil {
+			return v, errors.Wrapf(err, `invalid MySQL version %s`, s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// AtLeast reports whether the version is the given one or later
+func (v MySQL) AtLeast(major, minor, patch int) bool {
+	if v[0] != major {
+		return v[0] > major
+	}
+	if v[1] != minor {
+		return v[1] > minor
+	}
+	return v[2] >= patch
+}
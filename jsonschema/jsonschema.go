@@ -0,0 +1,224 @@
+// Package jsonschema generates JSON Schema documents describing the rows
+// of tables, so that API validation layers can be kept in sync with the
+// database instead of being maintained by hand. The same schemas can be
+// written as the components of an OpenAPI document.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// Draft is the version of JSON Schema that documents are written in
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Stmts writes a JSON Schema document to dst, with a schema under
+// "$defs" for each of the tables among the statements, named after the
+// table. A row is an object with a property for each column, all of
+// which are required; nullable columns may be null. AUTO_INCREMENT and
+// generated columns are read only.
+func Stmts(dst io.Writer, stmts model.Stmts) error {
+	var defs object
+	for _, stmt := range stmts {
+		if table, ok := stmt.(model.Table); ok {
+			table, _ = table.Normalize()
+			defs = append(defs, member{table.Name(), tableSchema(table)})
+		}
+	}
+
+	doc := object{
+		{"$schema", Draft},
+		{"$defs", defs},
+	}
+	buf, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, `failed to encode schema`)
+	}
+	buf = append(buf, '\n')
+	if _, err := dst.Write(buf); err != nil {
+		return errors.Wrap(err, `failed to write schema`)
+	}
+	return nil
+}
+
+// Source writes a JSON Schema document for the schema read from src
+// (see Stmts)
+func Source(dst io.Writer, src schemalex.SchemaSource) error {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+	return Stmts(dst, stmts)
+}
+
+// object is a JSON object that keeps its members in order, so that
+// properties are listed in the order of the columns
+type object []member
+
+type member struct {
+	key   string
+	value interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(m.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func tableSchema(table model.Table) object {
+	s := object{
+		{"title", table.Name()},
+	}
+	for opt := range table.Options() {
+		if opt.Key() == "COMMENT" {
+			s = append(s, member{"description", opt.Value()})
+		}
+	}
+
+	properties := object{}
+	required := []string{}
+	for col := range table.Columns() {
+		properties = append(properties, member{col.Name(), columnSchema(col)})
+		required = append(required, col.Name())
+	}
+	return append(s,
+		member{"type", "object"},
+		member{"properties", properties},
+		member{"required", required},
+		member{"additionalProperties", false},
+	)
+}
+
+// integerRanges holds the minimum and maximum values of the integer
+// types, signed and unsigned
+var integerRanges = map[model.ColumnType][3]string{
+	model.ColumnTypeTinyInt:   {"-128", "127", "255"},
+	model.ColumnTypeSmallInt:  {"-32768", "32767", "65535"},
+	model.ColumnTypeMediumInt: {"-8388608", "8388607", "16777215"},
+	model.ColumnTypeInt:       {"-2147483648", "2147483647", "4294967295"},
+	model.ColumnTypeBigInt:    {"-9223372036854775808", "9223372036854775807", "18446744073709551615"},
+}
+
+func columnSchema(col model.TableColumn) object {
+	var s object
+	if col.HasComment() {
+		s = append(s, member{"description", col.Comment()})
+	}
+	nullable := col.NullState() != model.NullStateNotNull
+
+	// the database assigns these
+	if col.IsAutoIncrement() || col.HasGeneratedExpr() {
+		s = append(s, member{"readOnly", true})
+	}
+
+	var typ string
+	typ, s = columnType(col, s)
+	switch {
+	case typ == "":
+		// JSON columns may hold any value, including null
+		return s
+	case nullable:
+		s = append(object{{"type", []string{typ, "null"}}}, s...)
+	default:
+		s = append(object{{"type", typ}}, s...)
+	}
+
+	if col.Type() == model.ColumnTypeEnum {
+		var values []interface{}
+		for v := range col.EnumValues() {
+			values = append(values, v)
+		}
+		if nullable {
+			values = append(values, nil)
+		}
+		s = append(s, member{"enum", values})
+	}
+	return s
+}
+
+// columnType returns the JSON type of the values of the column, or ""
+// if they may be of any type, along with the keywords that constrain
+// them further
+func columnType(col model.TableColumn, s object) (string, object) {
+	typ := col.Type().SynonymType()
+	switch typ {
+	case model.ColumnTypeTinyInt, model.ColumnTypeSmallInt, model.ColumnTypeMediumInt,
+		model.ColumnTypeInt, model.ColumnTypeBigInt:
+		if isBoolean(col) {
+			return "boolean", s
+		}
+		r := integerRanges[typ]
+		if col.IsUnsigned() {
+			return "integer", append(s, member{"minimum", 0}, member{"maximum", json.Number(r[2])})
+		}
+		return "integer", append(s, member{"minimum", json.Number(r[0])}, member{"maximum", json.Number(r[1])})
+	case model.ColumnTypeYear, model.ColumnTypeBit:
+		return "integer", s
+	case model.ColumnTypeFloat, model.ColumnTypeDouble, model.ColumnTypeDecimal:
+		if col.IsUnsigned() {
+			s = append(s, member{"minimum", 0})
+		}
+		return "number", s
+	case model.ColumnTypeChar, model.ColumnTypeVarChar:
+		if col.HasLength() {
+			if n, err := strconv.Atoi(col.Length().Length()); err == nil {
+				s = append(s, member{"maxLength", n})
+			}
+		}
+		return "string", s
+	case model.ColumnTypeDate:
+		return "string", append(s, member{"format", "date"})
+	case model.ColumnTypeDateTime, model.ColumnTypeTimestamp:
+		return "string", append(s, member{"format", "date-time"})
+	case model.ColumnTypeTime:
+		return "string", append(s, member{"format", "time"})
+	case model.ColumnTypeBinary, model.ColumnTypeVarBinary,
+		model.ColumnTypeTinyBlob, model.ColumnTypeBlob, model.ColumnTypeMediumBlob, model.ColumnTypeLongBlob:
+		return "string", append(s, member{"contentEncoding", "base64"})
+	case model.ColumnTypeJSON:
+		return "", s
+	default:
+		// texts, ENUM and SET
+		return "string", s
+	}
+}
+
+// isBoolean tells if the column is BOOLEAN, or TINYINT(1), which is
+// what BOOLEAN is in MySQL
+func isBoolean(col model.TableColumn) bool {
+	switch col.Type() {
+	case model.ColumnTypeBool, model.ColumnTypeBoolean:
+		return true
+	case model.ColumnTypeTinyInt:
+		return col.HasLength() && col.Length().Length() == "1" && !col.IsUnsigned()
+	}
+	return false
+}
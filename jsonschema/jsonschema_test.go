@@ -0,0 +1,145 @@
+package jsonschema_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/jsonschema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmts(t *testing.T) {
+	const input = "CREATE TABLE `users` ( `id` INTEGER UNSIGNED NOT NULL AUTO_INCREMENT, `name` VARCHAR (20) NOT NULL COMMENT 'display name', `active` BOOLEAN NOT NULL DEFAULT TRUE, `role` ENUM('admin', 'user'), `score` DECIMAL (5, 2), `avatar` BLOB, `settings` JSON, `birthday` DATE, `created` DATETIME NOT NULL, PRIMARY KEY (`id`) ) COMMENT 'people';"
+	const expect = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$defs": {
+    "users": {
+      "title": "users",
+      "description": "people",
+      "type": "object",
+      "properties": {
+        "id": {
+          "type": "integer",
+          "readOnly": true,
+          "minimum": 0,
+          "maximum": 4294967295
+        },
+        "name": {
+          "type": "string",
+          "description": "display name",
+          "maxLength": 20
+        },
+        "active": {
+          "type": "boolean"
+        },
+        "role": {
+          "type": [
+            "string",
+            "null"
+          ],
+          "enum": [
+            "admin",
+            "user",
+            null
+          ]
+        },
+        "score": {
+          "type": [
+            "number",
+            "null"
+          ]
+        },
+        "avatar": {
+          "type": [
+            "string",
+            "null"
+          ],
+          "contentEncoding": "base64"
+        },
+        "settings": {},
+        "birthday": {
+          "type": [
+            "string",
+            "null"
+          ],
+          "format": "date"
+        },
+        "created": {
+          "type": "string",
+          "format": "date-time"
+        }
+      },
+      "required": [
+        "id",
+        "name",
+        "active",
+        "role",
+        "score",
+        "avatar",
+        "settings",
+        "birthday",
+        "created"
+      ],
+      "additionalProperties": false
+    }
+  }
+}
+`
+	stmts, err := schemalex.New().ParseString(input)
+	if !assert.NoError(t, err, "Parse should succeed") {
+		return
+	}
+	var buf bytes.Buffer
+	if !assert.NoError(t, jsonschema.Stmts(&buf, stmts), "jsonschema.Stmts should succeed") {
+		return
+	}
+	assert.Equal(t, expect, buf.String(), "schema should match")
+}
+
+func TestOpenAPI(t *testing.T) {
+	const input = "CREATE TABLE `tags` ( `id` INTEGER NOT NULL AUTO_INCREMENT, `label` CHAR (8) NOT NULL, PRIMARY KEY (`id`) );"
+	const expect = `{
+  "openapi": "3.1.0",
+  "info": {
+    "title": "Tags API",
+    "version": "2.0.0"
+  },
+  "paths": {},
+  "components": {
+    "schemas": {
+      "tags": {
+        "title": "tags",
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "integer",
+            "readOnly": true,
+            "minimum": -2147483648,
+            "maximum": 2147483647
+          },
+          "label": {
+            "type": "string",
+            "maxLength": 8
+          }
+        },
+        "required": [
+          "id",
+          "label"
+        ],
+        "additionalProperties": false
+      }
+    }
+  }
+}
+`
+	stmts, err := schemalex.New().ParseString(input)
+	if !assert.NoError(t, err, "Parse should succeed") {
+		return
+	}
+	var buf bytes.Buffer
+	if !assert.NoError(t, jsonschema.OpenAPI(&buf, stmts, jsonschema.WithTitle("Tags API"), jsonschema.WithVersion("2.0.0")), "jsonschema.OpenAPI should succeed") {
+		return
+	}
+	assert.Equal(t, expect, buf.String(), "document should match")
+}
@@ -0,0 +1,90 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/internal/option"
+	"github.com/schemalex/schemalex/model"
+)
+
+type Option = schemalex.Option
+
+const (
+	optkeyTitle   = "title"
+	optkeyVersion = "version"
+)
+
+// WithTitle specifies the title of the API, which is "Schema" by default
+func WithTitle(s string) Option {
+	return option.New(optkeyTitle, s)
+}
+
+// WithVersion specifies the version of the API, which is "1.0.0" by
+// default
+func WithVersion(s string) Option {
+	return option.New(optkeyVersion, s)
+}
+
+// OpenAPI writes an OpenAPI 3.1 document to dst, with a component
+// schema for each of the tables among the statements, from which CRUD
+// APIs can be scaffolded. The schemas are the same as those written by
+// Stmts, as OpenAPI 3.1 uses JSON Schema, and the document has no paths.
+func OpenAPI(dst io.Writer, stmts model.Stmts, options ...Option) error {
+	title := "Schema"
+	version := "1.0.0"
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyTitle:
+			title = o.Value().(string)
+		case optkeyVersion:
+			version = o.Value().(string)
+		}
+	}
+
+	schemas := object{}
+	for _, stmt := range stmts {
+		if table, ok := stmt.(model.Table); ok {
+			table, _ = table.Normalize()
+			schemas = append(schemas, member{table.Name(), tableSchema(table)})
+		}
+	}
+
+	doc := object{
+		{"openapi", "3.1.0"},
+		{"info", object{
+			{"title", title},
+			{"version", version},
+		}},
+		{"paths", object{}},
+		{"components", object{
+			{"schemas", schemas},
+		}},
+	}
+	buf, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, `failed to encode document`)
+	}
+	buf = append(buf, '\n')
+	if _, err := dst.Write(buf); err != nil {
+		return errors.Wrap(err, `failed to write document`)
+	}
+	return nil
+}
+
+// OpenAPISource writes an OpenAPI document for the schema read from src
+// (see OpenAPI)
+func OpenAPISource(dst io.Writer, src schemalex.SchemaSource, options ...Option) error {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+	return OpenAPI(dst, stmts, options...)
+}
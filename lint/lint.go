@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"sort"
 
 	"github.com/pkg/errors"
 	"github.com/schemalex/schemalex"
 	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/internal/option"
+	"github.com/schemalex/schemalex/model"
 )
 
 type Linter struct{}
@@ -17,6 +20,56 @@ func WithIndent(s string, n int) Option {
 	return format.WithIndent(s, n)
 }
 
+func WithKeywordCase(c format.KeywordCase) Option {
+	return format.WithKeywordCase(c)
+}
+
+func WithCommaStyle(s format.CommaStyle) Option {
+	return format.WithCommaStyle(s)
+}
+
+func WithCompact(b bool) Option {
+	return format.WithCompact(b)
+}
+
+func WithAlign(b bool) Option {
+	return format.WithAlign(b)
+}
+
+func WithCanonical(b bool) Option {
+	return format.WithCanonical(b)
+}
+
+func WithColumnOrder(o format.ColumnOrder) Option {
+	return format.WithColumnOrder(o)
+}
+
+func WithIntDisplayWidth(b bool) Option {
+	return format.WithIntDisplayWidth(b)
+}
+
+func WithComments(b bool) Option {
+	return format.WithComments(b)
+}
+
+func WithQuoting(q format.Quoting) Option {
+	return format.WithQuoting(q)
+}
+
+func WithTargetVersion(v string) Option {
+	return format.WithTargetVersion(v)
+}
+
+const optkeySort = "sort"
+
+// WithSort specifies whether the statements are sorted, so that the
+// output does not depend on the order they are written in: databases
+// come first, then tables sorted by name, then views and triggers in
+// the order they appear, as they may depend on each other.
+func WithSort(b bool) Option {
+	return option.New(optkeySort, b)
+}
+
 func New(options ...Option) *Linter {
 	return &Linter{}
 }
@@ -33,6 +86,15 @@ func (l *Linter) Run(ctx context.Context, src schemalex.SchemaSource, dst io.Wri
 		return errors.Wrap(err, `failed to parse source`)
 	}
 
+	for _, o := range options {
+		switch o.Name() {
+		case optkeySort:
+			if o.Value().(bool) {
+				sortStmts(stmts)
+			}
+		}
+	}
+
 	for i, stmt := range stmts {
 		if i != 0 {
 			dst.Write([]byte{'\n', '\n'})
@@ -46,3 +108,28 @@ func (l *Linter) Run(ctx context.Context, src schemalex.SchemaSource, dst io.Wri
 
 	return nil
 }
+
+func stmtRank(stmt model.Stmt) int {
+	switch stmt.(type) {
+	case model.Database:
+		return 0
+	case model.Table:
+		return 1
+	case model.View:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func sortStmts(stmts model.Stmts) {
+	sort.SliceStable(stmts, func(i, j int) bool {
+		ri, rj := stmtRank(stmts[i]), stmtRank(stmts[j])
+		if ri != rj {
+			return ri < rj
+		}
+		a, aok := stmts[i].(model.Table)
+		b, bok := stmts[j].(model.Table)
+		return aok && bok && a.Name() < b.Name()
+	})
+}
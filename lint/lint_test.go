@@ -0,0 +1,110 @@
+package lint_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/lint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintSort(t *testing.T) {
+	src := "CREATE VIEW `v` AS SELECT 1; CREATE TABLE `b` ( `id` INT NOT NULL ); CREATE TABLE `a` ( `id` INT NOT NULL );"
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, lint.New().Run(context.Background(), schemalex.NewReaderSource(strings.NewReader(src)), &buf, lint.WithSort(true)), "lint should succeed") {
+		return
+	}
+	assert.Equal(t, "CREATE TABLE `a` (\n`id` INT (11) NOT NULL\n);\n\nCREATE TABLE `b` (\n`id` INT (11) NOT NULL\n);\n\nCREATE VIEW `v` AS SELECT 1;", buf.String(), "tables should be sorted by name and come before views")
+}
+
+func TestCheck(t *testing.T) {
+	src := "CREATE TABLE `logs` (\n" +
+		"  `message` TEXT\n" +
+		") DEFAULT CHARSET=utf8;\n" +
+		"CREATE TABLE `orders` (\n" +
+		"  `id` INT NOT NULL,\n" +
+		"  `user_id` INT NOT NULL,\n" +
+		"  `price` DOUBLE NOT NULL,\n" +
+		"  `note` VARCHAR (20) CHARACTER SET utf8mb3,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  KEY `by_note` (`note`),\n" +
+		"  CONSTRAINT `fk_orders_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)\n" +
+		");"
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parsing should succeed") {
+		return
+	}
+	findings, err := lint.Check(stmts)
+	if !assert.NoError(t, err, "lint.Check should succeed") {
+		return
+	}
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.String())
+	}
+	assert.Equal(t, []string{
+		"1: error: table logs has no primary key (primary-key)",
+		"1: warning: table logs uses utf8, use utf8mb4 instead (utf8mb4)",
+		"8: warning: column orders.note uses utf8mb3, use utf8mb4 instead (utf8mb4)",
+		"7: warning: column orders.price looks like money but is DOUBLE, use DECIMAL instead (money-float)",
+		"10: warning: index orders.by_note should be named idx_* (index-name)",
+		"11: warning: foreign key orders.fk_orders_user has no index on (user_id) (foreign-key-index)",
+	}, got, "findings should match")
+
+	findings, err = lint.Check(stmts, lint.WithSeverity("utf8mb4", lint.SeverityOff), lint.WithSeverity("money-float", lint.SeverityError), lint.WithSeverity("index-name", lint.SeverityOff), lint.WithSeverity("foreign-key-index", lint.SeverityOff))
+	if !assert.NoError(t, err, "lint.Check should succeed") {
+		return
+	}
+	got = nil
+	for _, f := range findings {
+		got = append(got, f.String())
+	}
+	assert.Equal(t, []string{
+		"1: error: table logs has no primary key (primary-key)",
+		"7: error: column orders.price looks like money but is DOUBLE, use DECIMAL instead (money-float)",
+	}, got, "configured findings should match")
+
+	_, err = lint.Check(stmts, lint.WithSeverity("no-such-rule", lint.SeverityError))
+	assert.Error(t, err, "unknown rules should be rejected")
+}
+
+func TestValidate(t *testing.T) {
+	src := "CREATE TABLE `users` (\n" +
+		"  `id` INT NOT NULL,\n" +
+		"  `email` VARCHAR (255) NOT NULL,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  UNIQUE KEY `uniq_email` (`email`),\n" +
+		"  KEY `idx_email` (`email`)\n" +
+		");\n" +
+		"CREATE TABLE `orders` (\n" +
+		"  `id` INT NOT NULL,\n" +
+		"  `user_id` INT NOT NULL,\n" +
+		"  `shop_id` INT NOT NULL,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  CONSTRAINT `fk_orders_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`uid`),\n" +
+		"  CONSTRAINT `fk_orders_shop` FOREIGN KEY (`shop_id`) REFERENCES `shops` (`id`)\n" +
+		");\n" +
+		"CREATE TABLE `logs` (\n" +
+		"  `message` TEXT\n" +
+		");"
+
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parsing should succeed") {
+		return
+	}
+	var got []string
+	for _, f := range lint.Validate(stmts) {
+		got = append(got, f.String())
+	}
+	assert.Equal(t, []string{
+		"6: error: index idx_email on users (email) duplicates uniq_email (duplicate-index)",
+		"13: error: foreign key orders.fk_orders_user refers to column users.uid, which does not exist (foreign-key-target)",
+		"14: error: foreign key orders.fk_orders_shop refers to table shops, which does not exist (foreign-key-target)",
+		"16: error: table logs has no primary key (primary-key)",
+	}, got, "findings should match")
+}
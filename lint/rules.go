@@ -0,0 +1,311 @@
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/option"
+	"github.com/schemalex/schemalex/model"
+)
+
+// Severity tells how serious a finding is
+type Severity int
+
+// List of possible Severity values
+const (
+	// SeverityOff disables a rule
+	SeverityOff Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityOff:
+		return "off"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity parses "off", "warning" or "error"
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "off":
+		return SeverityOff, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "error":
+		return SeverityError, nil
+	}
+	return SeverityOff, errors.Errorf(`invalid severity %s`, s)
+}
+
+// Finding is a problem found in a schema by a rule
+type Finding struct {
+	Rule     string
+	Severity Severity
+	// Line is the line of the source that the problem is on, or 0 if
+	// it is not known
+	Line    int
+	Message string
+}
+
+// String returns the finding as "line: severity: message (rule)"
+func (f Finding) String() string {
+	return fmt.Sprintf("%d: %s: %s (%s)", f.Line, f.Severity, f.Message, f.Rule)
+}
+
+// Rule checks each table of a schema for a kind of problem
+type Rule struct {
+	Name        string
+	Description string
+	// Severity is the severity of the findings of the rule, unless it
+	// is configured otherwise with WithSeverity
+	Severity Severity
+	check    func(model.Table) []Finding
+}
+
+// Rules returns the rules that Check runs, in the order they are run
+func Rules() []Rule {
+	return []Rule{
+		{
+			Name:        "primary-key",
+			Description: "every table needs a primary key",
+			Severity:    SeverityError,
+			check:       checkPrimaryKey,
+		},
+		{
+			Name:        "utf8mb4",
+			Description: "tables and columns should use utf8mb4 instead of utf8 (utf8mb3), which cannot store all of Unicode",
+			Severity:    SeverityWarning,
+			check:       checkUTF8MB4,
+		},
+		{
+			Name:        "money-float",
+			Description: "amounts of money should be DECIMAL, as FLOAT and DOUBLE are not exact",
+			Severity:    SeverityWarning,
+			check:       checkMoneyFloat,
+		},
+		{
+			Name:        "index-name",
+			Description: "indexes should be named idx_*, unique ones uniq_* and foreign keys fk_*",
+			Severity:    SeverityWarning,
+			check:       checkIndexName,
+		},
+		{
+			Name:        "foreign-key-index",
+			Description: "the columns of foreign keys should be the leading columns of an index",
+			Severity:    SeverityWarning,
+			check:       checkForeignKeyIndex,
+		},
+	}
+}
+
+const optkeySeverity = "severity"
+
+type ruleSeverity struct {
+	rule     string
+	severity Severity
+}
+
+// WithSeverity specifies the severity of the findings of a rule,
+// which is disabled by SeverityOff
+func WithSeverity(rule string, s Severity) Option {
+	return option.New(optkeySeverity, ruleSeverity{rule: rule, severity: s})
+}
+
+// Check runs the rules against each table among the statements, and
+// returns what they found, table by table
+func Check(stmts model.Stmts, options ...Option) ([]Finding, error) {
+	rules := Rules()
+	byName := make(map[string]*Rule)
+	for i := range rules {
+		byName[rules[i].Name] = &rules[i]
+	}
+	for _, o := range options {
+		switch o.Name() {
+		case optkeySeverity:
+			v := o.Value().(ruleSeverity)
+			rule, ok := byName[v.rule]
+			if !ok {
+				return nil, errors.Errorf(`unknown rule %s`, v.rule)
+			}
+			rule.Severity = v.severity
+		}
+	}
+
+	var findings []Finding
+	for _, stmt := range stmts {
+		table, ok := stmt.(model.Table)
+		if !ok {
+			continue
+		}
+		table, _ = table.Normalize()
+		for _, rule := range rules {
+			if rule.Severity == SeverityOff {
+				continue
+			}
+			for _, f := range rule.check(table) {
+				f.Rule = rule.Name
+				f.Severity = rule.Severity
+				if f.Line == 0 {
+					f.Line = table.Line()
+				}
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings, nil
+}
+
+// CheckSource runs the rules against the schema read from src (see
+// Check)
+func CheckSource(src schemalex.SchemaSource, options ...Option) ([]Finding, error) {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return nil, errors.Wrap(err, `failed to read from source`)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to parse source`)
+	}
+	return Check(stmts, options...)
+}
+
+func checkPrimaryKey(table model.Table) []Finding {
+	for idx := range table.Indexes() {
+		if idx.IsPrimaryKey() {
+			return nil
+		}
+	}
+	return []Finding{{Message: fmt.Sprintf("table %s has no primary key", table.Name())}}
+}
+
+// isUTF8MB3 tells if the character set or collation is the 3 byte
+// utf8, under any of its names
+func isUTF8MB3(s string) bool {
+	s = strings.ToLower(s)
+	return s == "utf8" || s == "utf8mb3" || strings.HasPrefix(s, "utf8_") || strings.HasPrefix(s, "utf8mb3_")
+}
+
+func checkUTF8MB4(table model.Table) []Finding {
+	var findings []Finding
+	// columns inherit the defaults of the table when it is normalized,
+	// which are only reported once
+	inherited := make(map[string]bool)
+	for opt := range table.Options() {
+		key := strings.ToUpper(opt.Key())
+		if (strings.Contains(key, "CHARSET") || strings.Contains(key, "CHARACTER SET") || strings.Contains(key, "COLLATE")) && isUTF8MB3(opt.Value()) {
+			inherited[strings.ToLower(opt.Value())] = true
+			findings = append(findings, Finding{Message: fmt.Sprintf("table %s uses %s, use utf8mb4 instead", table.Name(), opt.Value())})
+		}
+	}
+	for col := range table.Columns() {
+		var charset string
+		switch {
+		case col.HasCharacterSet() && isUTF8MB3(col.CharacterSet()):
+			charset = col.CharacterSet()
+		case col.HasCollation() && isUTF8MB3(col.Collation()):
+			charset = col.Collation()
+		default:
+			continue
+		}
+		if inherited[strings.ToLower(charset)] {
+			continue
+		}
+		findings = append(findings, Finding{Line: col.Line(), Message: fmt.Sprintf("column %s.%s uses %s, use utf8mb4 instead", table.Name(), col.Name(), charset)})
+	}
+	return findings
+}
+
+var moneyRx = regexp.MustCompile(`(?i)(price|amount|cost|balance|money|fee|total|salary|payment)`)
+
+func checkMoneyFloat(table model.Table) []Finding {
+	var findings []Finding
+	for col := range table.Columns() {
+		switch col.Type().SynonymType() {
+		case model.ColumnTypeFloat, model.ColumnTypeDouble:
+		default:
+			continue
+		}
+		if moneyRx.MatchString(col.Name()) {
+			findings = append(findings, Finding{Line: col.Line(), Message: fmt.Sprintf("column %s.%s looks like money but is %s, use DECIMAL instead", table.Name(), col.Name(), col.Type())})
+		}
+	}
+	return findings
+}
+
+func checkIndexName(table model.Table) []Finding {
+	var findings []Finding
+	for idx := range table.Indexes() {
+		var name, prefix string
+		switch {
+		case idx.IsPrimaryKey():
+			continue
+		case idx.IsForeignKey():
+			name, prefix = idx.Symbol(), "fk_"
+		case idx.IsUnique():
+			name, prefix = idx.Name(), "uniq_"
+		default:
+			name, prefix = idx.Name(), "idx_"
+		}
+		if !idx.HasName() && !idx.HasSymbol() {
+			findings = append(findings, Finding{Line: idx.Line(), Message: fmt.Sprintf("index on %s (%s) has no name", table.Name(), strings.Join(columnNames(idx), ", "))})
+			continue
+		}
+		if !strings.HasPrefix(name, prefix) {
+			findings = append(findings, Finding{Line: idx.Line(), Message: fmt.Sprintf("index %s.%s should be named %s*", table.Name(), name, prefix)})
+		}
+	}
+	return findings
+}
+
+func checkForeignKeyIndex(table model.Table) []Finding {
+	var findings []Finding
+	for idx := range table.Indexes() {
+		if !idx.IsForeignKey() {
+			continue
+		}
+		cols := columnNames(idx)
+		var indexed bool
+		for other := range table.Indexes() {
+			if other.IsForeignKey() || other.IsFullText() || other.IsSpatial() {
+				continue
+			}
+			leading := columnNames(other)
+			if len(leading) < len(cols) {
+				continue
+			}
+			indexed = true
+			for i, col := range cols {
+				if !strings.EqualFold(leading[i], col) {
+					indexed = false
+					break
+				}
+			}
+			if indexed {
+				break
+			}
+		}
+		if !indexed {
+			findings = append(findings, Finding{Line: idx.Line(), Message: fmt.Sprintf("foreign key %s.%s has no index on (%s)", table.Name(), idx.Symbol(), strings.Join(cols, ", "))})
+		}
+	}
+	return findings
+}
+
+func columnNames(c model.ColumnContainer) []string {
+	var cols []string
+	for col := range c.Columns() {
+		cols = append(cols, col.Name())
+	}
+	return cols
+}
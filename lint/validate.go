@@ -0,0 +1,159 @@
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/model"
+)
+
+// Validate checks that the statements make up a sound schema: every
+// table has a primary key, foreign keys refer to tables and columns
+// that exist, and no table has two indexes on the same columns or by
+// the same name. Unlike the rules of Check, these cannot be configured,
+// and what they find are always errors.
+func Validate(stmts model.Stmts) []Finding {
+	tables := make(map[string]model.Table)
+	for _, stmt := range stmts {
+		if table, ok := stmt.(model.Table); ok {
+			tables[table.Name()] = table
+		}
+	}
+
+	var findings []Finding
+	for _, stmt := range stmts {
+		table, ok := stmt.(model.Table)
+		if !ok {
+			continue
+		}
+		for _, check := range []struct {
+			rule  string
+			found []Finding
+		}{
+			{"primary-key", checkPrimaryKey(table)},
+			{"foreign-key-target", checkForeignKeyTarget(table, tables)},
+			{"duplicate-index", checkDuplicateIndex(table)},
+		} {
+			for _, f := range check.found {
+				f.Rule = check.rule
+				f.Severity = SeverityError
+				if f.Line == 0 {
+					f.Line = table.Line()
+				}
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings
+}
+
+// ValidateSource validates the schema read from src (see Validate).
+// Syntax errors are returned as errors, which are ParseErrors holding
+// their position.
+func ValidateSource(src schemalex.SchemaSource) ([]Finding, error) {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return nil, errors.Wrap(err, `failed to read from source`)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return Validate(stmts), nil
+}
+
+func checkForeignKeyTarget(table model.Table, tables map[string]model.Table) []Finding {
+	var findings []Finding
+	for idx := range table.Indexes() {
+		if !idx.IsForeignKey() || idx.Reference() == nil {
+			continue
+		}
+		for _, col := range columnNames(idx) {
+			if _, ok := lookupColumn(table, col); !ok {
+				findings = append(findings, Finding{Line: idx.Line(), Message: fmt.Sprintf("foreign key %s refers to column %s, which %s does not have", foreignKeyName(table, idx), col, table.Name())})
+			}
+		}
+
+		ref := idx.Reference()
+		target, ok := tables[ref.TableName()]
+		if !ok {
+			findings = append(findings, Finding{Line: idx.Line(), Message: fmt.Sprintf("foreign key %s refers to table %s, which does not exist", foreignKeyName(table, idx), ref.TableName())})
+			continue
+		}
+		for col := range ref.Columns() {
+			if _, ok := lookupColumn(target, col.Name()); !ok {
+				findings = append(findings, Finding{Line: idx.Line(), Message: fmt.Sprintf("foreign key %s refers to column %s.%s, which does not exist", foreignKeyName(table, idx), ref.TableName(), col.Name())})
+			}
+		}
+	}
+	return findings
+}
+
+func checkDuplicateIndex(table model.Table) []Finding {
+	var findings []Finding
+	names := make(map[string]bool)
+	seen := make(map[string]string)
+	for idx := range table.Indexes() {
+		if idx.HasName() {
+			name := strings.ToLower(idx.Name())
+			if names[name] {
+				findings = append(findings, Finding{Line: idx.Line(), Message: fmt.Sprintf("table %s has more than one index named %s", table.Name(), idx.Name())})
+			}
+			names[name] = true
+		}
+		if idx.IsForeignKey() {
+			continue
+		}
+
+		// full text and spatial indexes serve other queries than the
+		// others on the same columns
+		var key string
+		switch {
+		case idx.IsFullText():
+			key = "fulltext:"
+		case idx.IsSpatial():
+			key = "spatial:"
+		}
+		var cols []string
+		for col := range idx.Columns() {
+			cols = append(cols, strings.ToLower(col.Name())+"("+col.Length()+")")
+		}
+		key += strings.Join(cols, ",")
+
+		if other, ok := seen[key]; ok {
+			findings = append(findings, Finding{Line: idx.Line(), Message: fmt.Sprintf("index %s on %s (%s) duplicates %s", indexName(idx), table.Name(), strings.Join(columnNames(idx), ", "), other)})
+			continue
+		}
+		seen[key] = indexName(idx)
+	}
+	return findings
+}
+
+func foreignKeyName(table model.Table, idx model.Index) string {
+	if idx.HasSymbol() {
+		return table.Name() + "." + idx.Symbol()
+	}
+	return fmt.Sprintf("on %s (%s)", table.Name(), strings.Join(columnNames(idx), ", "))
+}
+
+func indexName(idx model.Index) string {
+	switch {
+	case idx.IsPrimaryKey():
+		return "PRIMARY"
+	case idx.HasName():
+		return idx.Name()
+	}
+	return "(unnamed)"
+}
+
+func lookupColumn(table model.Table, name string) (model.TableColumn, bool) {
+	for col := range table.Columns() {
+		if strings.EqualFold(col.Name(), name) {
+			return col, true
+		}
+	}
+	return nil, false
+}
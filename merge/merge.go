@@ -0,0 +1,376 @@
+// Package merge contains functions to merge two schemas that were
+// derived from a common ancestor, much like git merges files
+package merge
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// Conflict describes an object that was changed differently in both
+// of the schemas being merged. The version in "ours" is kept in the
+// merged schema.
+type Conflict struct {
+	// Stmt is the name of the conflicting table, view or trigger, or
+	// the table that the conflicting object belongs to
+	Stmt string
+	// Name is the name of the conflicting column, index or table
+	// option. It is empty if the statement conflicts as a whole.
+	Name string
+	// Reason describes the conflict
+	Reason string
+}
+
+func (c Conflict) String() string {
+	if c.Name == "" {
+		return fmt.Sprintf("%s: %s", c.Stmt, c.Reason)
+	}
+	return fmt.Sprintf("%s.%s: %s", c.Stmt, c.Name, c.Reason)
+}
+
+type mergeCtx struct {
+	conflicts []Conflict
+}
+
+func (ctx *mergeCtx) conflict(stmt, name, reason string) {
+	ctx.conflicts = append(ctx.conflicts, Conflict{Stmt: stmt, Name: name, Reason: reason})
+}
+
+// Stmts merges the changes made from base to ours, and from base to
+// theirs. Objects that are changed on only one side take that change,
+// and objects that are changed on both sides are merged if they are
+// tables. The objects that cannot be merged are reported as conflicts.
+func Stmts(base, ours, theirs model.Stmts) (model.Stmts, []Conflict, error) {
+	var ctx mergeCtx
+
+	// statements in ours come first, followed by the ones only in theirs
+	var ids []string
+	seen := make(map[string]struct{})
+	for _, stmts := range []model.Stmts{ours, theirs} {
+		for _, stmt := range stmts {
+			if _, ok := seen[stmt.ID()]; ok {
+				continue
+			}
+			seen[stmt.ID()] = struct{}{}
+			ids = append(ids, stmt.ID())
+		}
+	}
+
+	var merged model.Stmts
+	for _, id := range ids {
+		b, _ := base.Lookup(id)
+		o, _ := ours.Lookup(id)
+		t, _ := theirs.Lookup(id)
+
+		stmt, err := mergeStmt(&ctx, b, o, t)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, `failed to merge %s`, id)
+		}
+		if stmt != nil {
+			merged = append(merged, stmt)
+		}
+	}
+	return merged, ctx.conflicts, nil
+}
+
+// definition returns the SQL used to decide whether two versions of an
+// object are the same. Missing objects have an empty definition.
+func definition(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := format.SQL(&buf, v); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// pick decides which version of an object to take, given the
+// definitions in each schema. It returns true if theirs should be
+// taken instead of ours, and whether the two sides conflict.
+func pick(base, ours, theirs string) (takeTheirs bool, conflict bool) {
+	switch {
+	case ours == theirs:
+		return false, false
+	case ours == base:
+		return true, false
+	case theirs == base:
+		return false, false
+	}
+	return false, true
+}
+
+// reason describes why the two versions conflict
+func reason(ours, theirs string) string {
+	switch {
+	case ours == "":
+		return "deleted in ours and modified in theirs"
+	case theirs == "":
+		return "modified in ours and deleted in theirs"
+	}
+	return "modified differently in ours and theirs"
+}
+
+func mergeStmt(ctx *mergeCtx, b, o, t model.Stmt) (model.Stmt, error) {
+	bdef, err := definition(b)
+	if err != nil {
+		return nil, err
+	}
+	odef, err := definition(o)
+	if err != nil {
+		return nil, err
+	}
+	tdef, err := definition(t)
+	if err != nil {
+		return nil, err
+	}
+
+	takeTheirs, conflict := pick(bdef, odef, tdef)
+	if !conflict {
+		if takeTheirs {
+			return t, nil
+		}
+		return o, nil
+	}
+
+	btable, bok := b.(model.Table)
+	otable, ook := o.(model.Table)
+	ttable, tok := t.(model.Table)
+	if bok && ook && tok {
+		return mergeTable(ctx, btable, otable, ttable)
+	}
+
+	named := o
+	if named == nil {
+		named = t
+	}
+	ctx.conflict(stmtName(named), "", reason(odef, tdef))
+	return o, nil
+}
+
+func stmtName(stmt model.Stmt) string {
+	if v, ok := stmt.(interface{ Name() string }); ok {
+		return v.Name()
+	}
+	return stmt.ID()
+}
+
+// mergeTable merges the columns, indexes, options and partitioning of
+// a table that is modified on both sides
+func mergeTable(ctx *mergeCtx, b, o, t model.Table) (model.Table, error) {
+	merged := model.NewTable(o.Name())
+	merged.SetTemporary(o.IsTemporary())
+	merged.SetIfNotExists(o.IsIfNotExists())
+	if o.HasLikeTable() {
+		merged.SetLikeTable(o.LikeTable())
+	}
+
+	columns, err := mergeColumns(ctx, b, o, t)
+	if err != nil {
+		return nil, err
+	}
+	for _, col := range columns {
+		merged.AddColumn(col)
+	}
+
+	indexes, err := mergeIndexes(ctx, b, o, t)
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range indexes {
+		merged.AddIndex(idx)
+	}
+
+	options, err := mergeOptions(ctx, b, o, t)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range options {
+		merged.AddOption(opt)
+	}
+
+	partitioning, err := mergeObject(ctx, o.Name(), b.Partitioning(), o.Partitioning(), t.Partitioning())
+	if err != nil {
+		return nil, err
+	}
+	if partitioning != nil {
+		merged.SetPartitioning(partitioning.(model.Partitioning))
+	}
+	return merged, nil
+}
+
+// mergeColumns merges the columns in the order of ours. Columns that
+// are only added in theirs are placed after the same column as in
+// theirs.
+func mergeColumns(ctx *mergeCtx, b, o, t model.Table) ([]model.TableColumn, error) {
+	var ids []string
+	for col := range o.Columns() {
+		ids = append(ids, col.ID())
+	}
+	for col := range t.Columns() {
+		if _, ok := o.LookupColumn(col.ID()); ok {
+			continue
+		}
+		// insert after the column before it in theirs, if any
+		pos := 0
+		if before, ok := t.LookupColumnBefore(col.ID()); ok {
+			for i, id := range ids {
+				if id == before.ID() {
+					pos = i + 1
+					break
+				}
+			}
+		}
+		ids = append(ids, "")
+		copy(ids[pos+1:], ids[pos:])
+		ids[pos] = col.ID()
+	}
+
+	var columns []model.TableColumn
+	for _, id := range ids {
+		var bcol, ocol, tcol interface{}
+		if col, ok := b.LookupColumn(id); ok {
+			bcol = col
+		}
+		if col, ok := o.LookupColumn(id); ok {
+			ocol = col
+		}
+		if col, ok := t.LookupColumn(id); ok {
+			tcol = col
+		}
+
+		col, err := mergeObject(ctx, o.Name(), bcol, ocol, tcol)
+		if err != nil {
+			return nil, err
+		}
+		if col != nil {
+			columns = append(columns, col.(model.TableColumn))
+		}
+	}
+	return columns, nil
+}
+
+// mergeIndexes merges the indexes. Indexes are identified by their
+// definitions, so an index is kept unless either side dropped it.
+// If both sides end up with different indexes of the same name,
+// the one in ours is kept.
+func mergeIndexes(ctx *mergeCtx, b, o, t model.Table) ([]model.Index, error) {
+	var indexes []model.Index
+	names := make(map[string]struct{})
+	for idx := range o.Indexes() {
+		_, inBase := b.LookupIndex(idx.ID())
+		_, inTheirs := t.LookupIndex(idx.ID())
+		if inBase && !inTheirs {
+			continue
+		}
+		indexes = append(indexes, idx)
+		if idx.HasName() {
+			names[idx.Name()] = struct{}{}
+		}
+	}
+	for idx := range t.Indexes() {
+		if _, ok := o.LookupIndex(idx.ID()); ok {
+			continue
+		}
+		if _, ok := b.LookupIndex(idx.ID()); ok {
+			// dropped in ours
+			continue
+		}
+		if idx.HasName() {
+			if _, ok := names[idx.Name()]; ok {
+				ctx.conflict(o.Name(), idx.Name(), "modified differently in ours and theirs")
+				continue
+			}
+		}
+		indexes = append(indexes, idx)
+	}
+	return indexes, nil
+}
+
+func lookupOption(table model.Table, key string) (model.TableOption, bool) {
+	for opt := range table.Options() {
+		if opt.Key() == key {
+			return opt, true
+		}
+	}
+	return nil, false
+}
+
+func mergeOptions(ctx *mergeCtx, b, o, t model.Table) ([]model.TableOption, error) {
+	var keys []string
+	for opt := range o.Options() {
+		keys = append(keys, opt.Key())
+	}
+	for opt := range t.Options() {
+		if _, ok := lookupOption(o, opt.Key()); !ok {
+			keys = append(keys, opt.Key())
+		}
+	}
+
+	var options []model.TableOption
+	for _, key := range keys {
+		var bopt, oopt, topt interface{}
+		if opt, ok := lookupOption(b, key); ok {
+			bopt = opt
+		}
+		if opt, ok := lookupOption(o, key); ok {
+			oopt = opt
+		}
+		if opt, ok := lookupOption(t, key); ok {
+			topt = opt
+		}
+
+		opt, err := mergeObject(ctx, o.Name(), bopt, oopt, topt)
+		if err != nil {
+			return nil, err
+		}
+		if opt != nil {
+			options = append(options, opt.(model.TableOption))
+		}
+	}
+	return options, nil
+}
+
+// mergeObject decides which version of a column, a table option or
+// the partitioning of a table to take. Missing versions are nil.
+func mergeObject(ctx *mergeCtx, table string, b, o, t interface{}) (interface{}, error) {
+	bdef, err := definition(b)
+	if err != nil {
+		return nil, err
+	}
+	odef, err := definition(o)
+	if err != nil {
+		return nil, err
+	}
+	tdef, err := definition(t)
+	if err != nil {
+		return nil, err
+	}
+
+	takeTheirs, conflict := pick(bdef, odef, tdef)
+	if conflict {
+		named := o
+		if named == nil {
+			named = t
+		}
+		var name string
+		switch v := named.(type) {
+		case model.TableColumn:
+			name = v.Name()
+		case model.TableOption:
+			name = v.Key()
+		case model.Partitioning:
+			name = "PARTITION BY"
+		}
+		ctx.conflict(table, name, reason(odef, tdef))
+	}
+	if takeTheirs {
+		return t, nil
+	}
+	return o, nil
+}
@@ -0,0 +1,90 @@
+package merge_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/merge"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	type Spec struct {
+		Name      string
+		Base      string
+		Ours      string
+		Theirs    string
+		Expect    string
+		Conflicts []string
+	}
+
+	specs := []Spec{
+		{
+			Name:   "changes on one side",
+			Base:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			Ours:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL ); CREATE TABLE `hoge` ( `id` INTEGER NOT NULL );",
+			Theirs: "CREATE TABLE `fuga` ( `id` BIGINT NOT NULL ); CREATE TABLE `piyo` ( `id` INTEGER NOT NULL );",
+			Expect: "CREATE TABLE `fuga` (\n`id` BIGINT (20) NOT NULL\n)CREATE TABLE `piyo` (\n`id` INT (11) NOT NULL\n)",
+		},
+		{
+			Name:   "columns added on both sides",
+			Base:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `z` INTEGER NOT NULL );",
+			Ours:   "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL, `z` INTEGER NOT NULL, INDEX `a` (`a`) );",
+			Theirs: "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `z` INTEGER NOT NULL, `b` INTEGER NOT NULL ) ENGINE = InnoDB;",
+			Expect: "CREATE TABLE `fuga` (\n`id` INT (11) NOT NULL,\n`a` INT (11) NOT NULL,\n`z` INT (11) NOT NULL,\n`b` INT (11) NOT NULL,\nKEY `a` (`a`)\n) ENGINE = InnoDB",
+		},
+		{
+			Name:      "column modified differently",
+			Base:      "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			Ours:      "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` BIGINT NOT NULL );",
+			Theirs:    "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` VARCHAR (20) NOT NULL, `b` INTEGER NOT NULL );",
+			Expect:    "CREATE TABLE `fuga` (\n`id` INT (11) NOT NULL,\n`a` BIGINT (20) NOT NULL,\n`b` INT (11) NOT NULL\n)",
+			Conflicts: []string{"fuga.a: modified differently in ours and theirs"},
+		},
+		{
+			Name:      "table modified and deleted",
+			Base:      "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );",
+			Ours:      "",
+			Theirs:    "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );",
+			Expect:    "",
+			Conflicts: []string{"fuga: deleted in ours and modified in theirs"},
+		},
+	}
+
+	p := schemalex.New()
+	for _, spec := range specs {
+		t.Run(spec.Name, func(t *testing.T) {
+			base, err := p.ParseString(spec.Base)
+			if !assert.NoError(t, err, "parsing base should succeed") {
+				return
+			}
+			ours, err := p.ParseString(spec.Ours)
+			if !assert.NoError(t, err, "parsing ours should succeed") {
+				return
+			}
+			theirs, err := p.ParseString(spec.Theirs)
+			if !assert.NoError(t, err, "parsing theirs should succeed") {
+				return
+			}
+
+			merged, conflicts, err := merge.Stmts(base, ours, theirs)
+			if !assert.NoError(t, err, "merge.Stmts should succeed") {
+				return
+			}
+
+			var buf bytes.Buffer
+			if !assert.NoError(t, format.SQL(&buf, merged), "format.SQL should succeed") {
+				return
+			}
+			assert.Equal(t, spec.Expect, buf.String(), "merged schema should match")
+
+			var l []string
+			for _, c := range conflicts {
+				l = append(l, c.String())
+			}
+			assert.Equal(t, spec.Conflicts, l, "conflicts should match")
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package migration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// flywayVersionRx matches the versioned migrations of Flyway, such as
+// V2__add_users.sql or V1.1__fix.sql, capturing the major version
+var flywayVersionRx = regexp.MustCompile(`^V(\d+)(?:[._]\d+)*__`)
+
+// NextFlywayVersion returns the version of a new Flyway migration in
+// dir. For NumberingSequence it is one more than the largest major
+// version of the versioned migrations found in dir, or 1 if there are
+// none. Flyway does not need versions to be padded, so they are not.
+// For NumberingTimestamp it is the given time in UTC.
+func NextFlywayVersion(dir string, numbering Numbering, now time.Time) (string, error) {
+	if numbering == NumberingTimestamp {
+		return now.UTC().Format(timestampFormat), nil
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", errors.Wrapf(err, `failed to read directory %s`, dir)
+	}
+
+	var last int64
+	for _, fi := range files {
+		m := flywayVersionRx.FindStringSubmatch(fi.Name())
+		if m == nil {
+			continue
+		}
+		v, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if v > last {
+			last = v
+		}
+	}
+	return strconv.FormatInt(last+1, 10), nil
+}
+
+// WriteFlyway writes the migration as a Flyway versioned migration
+// named V<version>__<description>.sql in dir. If undo is true, the
+// down migration is written as the undo migration
+// U<version>__<description>.sql as well. It returns the paths of the
+// files written.
+func WriteFlyway(dir, version, desc string, undo bool, m *Migration) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, `failed to create directory %s`, dir)
+	}
+
+	name := version + "__" + description(desc) + ".sql"
+	paths := []string{filepath.Join(dir, "V"+name)}
+	if err := writeFile(paths[0], strings.TrimSpace(m.Up)); err != nil {
+		return nil, err
+	}
+	if undo {
+		paths = append(paths, filepath.Join(dir, "U"+name))
+		if err := writeFile(paths[1], strings.TrimSpace(m.Down)); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
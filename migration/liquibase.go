@@ -0,0 +1,119 @@
+package migration
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// LiquibaseFormat is the format of a Liquibase changelog
+type LiquibaseFormat int
+
+// List of possible LiquibaseFormat values
+const (
+	LiquibaseYAML LiquibaseFormat = iota
+	LiquibaseXML
+)
+
+// Extension returns the file extension of changelogs in the format,
+// including the leading dot
+func (f LiquibaseFormat) Extension() string {
+	if f == LiquibaseXML {
+		return ".xml"
+	}
+	return ".yaml"
+}
+
+// WriteLiquibase writes the migration as a Liquibase changelog holding
+// a single changeSet with the given id and author. The up migration is
+// the sql change of the changeSet, and the down migration its rollback.
+func WriteLiquibase(dst io.Writer, format LiquibaseFormat, id, author string, m *Migration) error {
+	var buf bytes.Buffer
+	up := strings.TrimSpace(m.Up)
+	down := strings.TrimSpace(m.Down)
+	switch format {
+	case LiquibaseYAML:
+		buf.WriteString("databaseChangeLog:\n")
+		buf.WriteString("  - changeSet:\n")
+		buf.WriteString("      id: " + strconv.Quote(id) + "\n")
+		buf.WriteString("      author: " + strconv.Quote(author) + "\n")
+		buf.WriteString("      changes:\n")
+		writeLiquibaseYAMLSQL(&buf, up)
+		if down != "" {
+			buf.WriteString("      rollback:\n")
+			writeLiquibaseYAMLSQL(&buf, down)
+		}
+	case LiquibaseXML:
+		buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+		buf.WriteString(`<databaseChangeLog` + "\n")
+		buf.WriteString(`    xmlns="http://www.liquibase.org/xml/ns/dbchangelog"` + "\n")
+		buf.WriteString(`    xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"` + "\n")
+		buf.WriteString(`    xsi:schemaLocation="http://www.liquibase.org/xml/ns/dbchangelog http://www.liquibase.org/xml/ns/dbchangelog/dbchangelog-latest.xsd">` + "\n")
+		buf.WriteString(`  <changeSet id="` + xmlAttr(id) + `" author="` + xmlAttr(author) + `">` + "\n")
+		buf.WriteString("    <sql>" + cdata(up) + "</sql>\n")
+		if down != "" {
+			buf.WriteString("    <rollback>\n")
+			buf.WriteString("      <sql>" + cdata(down) + "</sql>\n")
+			buf.WriteString("    </rollback>\n")
+		}
+		buf.WriteString("  </changeSet>\n")
+		buf.WriteString("</databaseChangeLog>\n")
+	default:
+		return errors.Errorf(`unknown Liquibase format %d`, format)
+	}
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write migration`)
+	}
+	return nil
+}
+
+// writeLiquibaseYAMLSQL writes a sql change, with the statements as a
+// literal block scalar
+func writeLiquibaseYAMLSQL(buf *bytes.Buffer, sql string) {
+	buf.WriteString("        - sql:\n")
+	buf.WriteString("            sql: |-\n")
+	for _, line := range strings.Split(sql, "\n") {
+		if line != "" {
+			buf.WriteString("              ")
+			buf.WriteString(line)
+		}
+		buf.WriteByte('\n')
+	}
+}
+
+var xmlAttrReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func xmlAttr(s string) string {
+	return xmlAttrReplacer.Replace(s)
+}
+
+// cdata encloses the text in a CDATA section, splitting it where the
+// text itself contains "]]>"
+func cdata(s string) string {
+	return "<![CDATA[" + strings.Replace(s, "]]>", "]]]]><![CDATA[>", -1) + "]]>"
+}
+
+// WriteLiquibaseFile writes the migration as a Liquibase changelog
+// named VERSION_DESCRIPTION.yaml or VERSION_DESCRIPTION.xml in dir,
+// whose changeSet is identified by the name of the file without the
+// extension. It returns the path of the file written.
+func WriteLiquibaseFile(dir, version, desc string, format LiquibaseFormat, author string, m *Migration) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errors.Wrapf(err, `failed to create directory %s`, dir)
+	}
+	id := version + "_" + description(desc)
+	var buf bytes.Buffer
+	if err := WriteLiquibase(&buf, format, id, author, m); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, id+format.Extension())
+	if err := writeFile(path, buf.String()); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,250 @@
+// Package migration writes diffs as migration files for the various
+// migration tools, each of which has its own conventions for naming
+// the files and telling the forward migration from the reverse one
+package migration
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/diff"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/model"
+)
+
+// Migration holds the statements to migrate from one schema to
+// another, and back
+type Migration struct {
+	// Up migrates from the old schema to the new one
+	Up string
+	// Down migrates from the new schema back to the old one
+	Down string
+	// Changes are the changes made by Up
+	Changes []diff.Change
+}
+
+// Empty reports whether there is nothing to migrate
+func (m *Migration) Empty() bool {
+	return len(m.Changes) == 0
+}
+
+// Stmts computes the migration between two schemas. The options are
+// passed to diff.Statements, except for diff.WithReverse, which is
+// used to compute the reverse migration.
+func Stmts(from, to model.Stmts, options ...diff.Option) (*Migration, error) {
+	changes, err := diff.Compute(from, to, options...)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to compute migration`)
+	}
+
+	var up, down bytes.Buffer
+	options = append(options, diff.WithReverse(&down))
+	if err := diff.Statements(&up, from, to, options...); err != nil {
+		return nil, errors.Wrap(err, `failed to compute migration`)
+	}
+	return &Migration{Up: up.String(), Down: down.String(), Changes: changes}, nil
+}
+
+// Sources computes the migration between the schemas from two sources
+// (see Stmts)
+func Sources(from, to schemalex.SchemaSource, options ...diff.Option) (*Migration, error) {
+	fromStmts, err := parseSource(from)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to parse "from" source %s`, from)
+	}
+	toStmts, err := parseSource(to)
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to parse "to" source %s`, to)
+	}
+	return Stmts(fromStmts, toStmts, options...)
+}
+
+func parseSource(src schemalex.SchemaSource) (model.Stmts, error) {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return nil, err
+	}
+	return schemalex.New().Parse(buf.Bytes())
+}
+
+// Numbering describes how migration files are versioned
+type Numbering int
+
+// List of possible Numbering values
+const (
+	// NumberingSequence numbers the files sequentially, following the
+	// files that already exist in the directory
+	NumberingSequence Numbering = iota
+	// NumberingTimestamp numbers the files with the time they are
+	// created, formatted as 20060102150405
+	NumberingTimestamp
+)
+
+const (
+	timestampFormat = "20060102150405"
+	sequenceDigits  = 6
+)
+
+var versionRx = regexp.MustCompile(`^(\d+)_`)
+
+// NextVersion returns the version of a new migration file in dir. For
+// NumberingSequence it is one more than the largest version found in
+// dir, padded with zeros to the width of the existing versions (or 6
+// digits if there are none). For NumberingTimestamp it is the given
+// time in UTC.
+func NextVersion(dir string, numbering Numbering, now time.Time) (string, error) {
+	if numbering == NumberingTimestamp {
+		return now.UTC().Format(timestampFormat), nil
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", errors.Wrapf(err, `failed to read directory %s`, dir)
+	}
+
+	var last int64
+	digits := sequenceDigits
+	for _, fi := range files {
+		m := versionRx.FindStringSubmatch(fi.Name())
+		if m == nil {
+			continue
+		}
+		v, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if v >= last {
+			last = v
+			digits = len(m[1])
+		}
+	}
+
+	s := strconv.FormatInt(last+1, 10)
+	if len(s) < digits {
+		s = strings.Repeat("0", digits-len(s)) + s
+	}
+	return s, nil
+}
+
+var descriptionRx = regexp.MustCompile(`[^a-z0-9]+`)
+
+// description turns free text into something usable in a file name,
+// such as "add_users_table"
+func description(s string) string {
+	s = strings.Trim(descriptionRx.ReplaceAllString(strings.ToLower(s), "_"), "_")
+	if s == "" {
+		return "migration"
+	}
+	return s
+}
+
+// writeFile writes the file, failing if it already exists so that no
+// migration is ever overwritten
+func writeFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrapf(err, `failed to create file %s`, path)
+	}
+	defer f.Close()
+
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if _, err := f.WriteString(content); err != nil {
+		return errors.Wrapf(err, `failed to write file %s`, path)
+	}
+	return nil
+}
+
+// WriteGolangMigrate writes the migration as a pair of files that
+// golang-migrate understands, VERSION_DESCRIPTION.up.sql and
+// VERSION_DESCRIPTION.down.sql, in dir. It returns the paths of the
+// files written.
+func WriteGolangMigrate(dir, version, desc string, m *Migration) ([]string, error) {
+	base := filepath.Join(dir, version+"_"+description(desc))
+	paths := []string{base + ".up.sql", base + ".down.sql"}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, `failed to create directory %s`, dir)
+	}
+	if err := writeFile(paths[0], strings.TrimSpace(m.Up)); err != nil {
+		return nil, err
+	}
+	if err := writeFile(paths[1], strings.TrimSpace(m.Down)); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// writeAnnotated writes the up and down migrations in a single file,
+// each preceded by the annotation the tool looks for
+func writeAnnotated(dst io.Writer, upAnnotation, downAnnotation string, m *Migration) error {
+	var buf bytes.Buffer
+	buf.WriteString(upAnnotation)
+	buf.WriteString("\n")
+	if up := strings.TrimSpace(m.Up); up != "" {
+		buf.WriteString(up)
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n")
+	buf.WriteString(downAnnotation)
+	buf.WriteString("\n")
+	if down := strings.TrimSpace(m.Down); down != "" {
+		buf.WriteString(down)
+		buf.WriteString("\n")
+	}
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write migration`)
+	}
+	return nil
+}
+
+// writeAnnotatedFile writes the file named VERSION_DESCRIPTION.sql in
+// dir with writeAnnotated, and returns its path
+func writeAnnotatedFile(dir, version, desc, upAnnotation, downAnnotation string, m *Migration) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errors.Wrapf(err, `failed to create directory %s`, dir)
+	}
+	var buf bytes.Buffer
+	if err := writeAnnotated(&buf, upAnnotation, downAnnotation, m); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, version+"_"+description(desc)+".sql")
+	if err := writeFile(path, buf.String()); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// WriteGoose writes the migration as a goose SQL migration, with the
+// up and down migrations in the "-- +goose Up" and "-- +goose Down"
+// sections
+func WriteGoose(dst io.Writer, m *Migration) error {
+	return writeAnnotated(dst, "-- +goose Up", "-- +goose Down", m)
+}
+
+// WriteGooseFile writes the migration as a goose SQL migration named
+// VERSION_DESCRIPTION.sql in dir, and returns its path
+func WriteGooseFile(dir, version, desc string, m *Migration) (string, error) {
+	return writeAnnotatedFile(dir, version, desc, "-- +goose Up", "-- +goose Down", m)
+}
+
+// WriteSQLMigrate writes the migration as a sql-migrate migration, with
+// the up and down migrations in the "-- +migrate Up" and
+// "-- +migrate Down" sections
+func WriteSQLMigrate(dst io.Writer, m *Migration) error {
+	return writeAnnotated(dst, "-- +migrate Up", "-- +migrate Down", m)
+}
+
+// WriteSQLMigrateFile writes the migration as a sql-migrate migration
+// named VERSION_DESCRIPTION.sql in dir, and returns its path
+func WriteSQLMigrateFile(dir, version, desc string, m *Migration) (string, error) {
+	return writeAnnotatedFile(dir, version, desc, "-- +migrate Up", "-- +migrate Down", m)
+}
@@ -0,0 +1,172 @@
+package migration_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/migration"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schemalex-migration")
+	if !assert.NoError(t, err, "creating temporary directory should succeed") {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	v, err := migration.NextVersion(dir, migration.NumberingSequence, time.Time{})
+	if assert.NoError(t, err, "NextVersion should succeed") {
+		assert.Equal(t, "000001", v, "versions should start from 1")
+	}
+
+	for _, name := range []string{"0001_init.up.sql", "0009_users.up.sql", "0009_users.down.sql", "README.md"} {
+		if !assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), nil, 0644), "writing file should succeed") {
+			return
+		}
+	}
+	v, err = migration.NextVersion(dir, migration.NumberingSequence, time.Time{})
+	if assert.NoError(t, err, "NextVersion should succeed") {
+		assert.Equal(t, "0010", v, "versions should follow existing ones")
+	}
+
+	v, err = migration.NextVersion(dir, migration.NumberingTimestamp, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	if assert.NoError(t, err, "NextVersion should succeed") {
+		assert.Equal(t, "20200102030405", v, "timestamps should be formatted")
+	}
+}
+
+func TestWriteGolangMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schemalex-migration")
+	if !assert.NoError(t, err, "creating temporary directory should succeed") {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	p := schemalex.New()
+	from, err := p.ParseString("CREATE TABLE `fuga` ( `id` INTEGER NOT NULL );")
+	if !assert.NoError(t, err, "parsing from should succeed") {
+		return
+	}
+	to, err := p.ParseString("CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `a` INTEGER NOT NULL );")
+	if !assert.NoError(t, err, "parsing to should succeed") {
+		return
+	}
+
+	m, err := migration.Stmts(from, to)
+	if !assert.NoError(t, err, "migration.Stmts should succeed") {
+		return
+	}
+	paths, err := migration.WriteGolangMigrate(dir, "000001", "Add column a", m)
+	if !assert.NoError(t, err, "WriteGolangMigrate should succeed") {
+		return
+	}
+	assert.Equal(t, []string{filepath.Join(dir, "000001_add_column_a.up.sql"), filepath.Join(dir, "000001_add_column_a.down.sql")}, paths, "paths should match")
+
+	up, _ := ioutil.ReadFile(paths[0])
+	assert.Equal(t, "ALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;\n", string(up), "up migration should match")
+	down, _ := ioutil.ReadFile(paths[1])
+	assert.Equal(t, "ALTER TABLE `fuga` DROP COLUMN `a`;\n", string(down), "down migration should match")
+
+	_, err = migration.WriteGolangMigrate(dir, "000001", "Add column a", m)
+	assert.Error(t, err, "existing migrations should not be overwritten")
+}
+
+func TestWriteGoose(t *testing.T) {
+	m := &migration.Migration{
+		Up:   "ALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;",
+		Down: "ALTER TABLE `fuga` DROP COLUMN `a`;",
+	}
+
+	var buf bytes.Buffer
+	if assert.NoError(t, migration.WriteGoose(&buf, m), "WriteGoose should succeed") {
+		assert.Equal(t, "-- +goose Up\nALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;\n\n-- +goose Down\nALTER TABLE `fuga` DROP COLUMN `a`;\n", buf.String(), "goose migration should match")
+	}
+}
+
+func TestWriteSQLMigrate(t *testing.T) {
+	m := &migration.Migration{
+		Up:   "CREATE TABLE `fuga` (\n`id` INT (11) NOT NULL\n);",
+		Down: "DROP TABLE `fuga`;",
+	}
+
+	var buf bytes.Buffer
+	if assert.NoError(t, migration.WriteSQLMigrate(&buf, m), "WriteSQLMigrate should succeed") {
+		assert.Equal(t, "-- +migrate Up\nCREATE TABLE `fuga` (\n`id` INT (11) NOT NULL\n);\n\n-- +migrate Down\nDROP TABLE `fuga`;\n", buf.String(), "sql-migrate migration should match")
+	}
+}
+
+func TestWriteLiquibase(t *testing.T) {
+	m := &migration.Migration{
+		Up:   "CREATE TABLE `fuga` (\n`id` INT (11) NOT NULL\n);",
+		Down: "DROP TABLE `fuga`;",
+	}
+
+	var buf bytes.Buffer
+	if assert.NoError(t, migration.WriteLiquibase(&buf, migration.LiquibaseYAML, "000001_create_fuga", "schemalex", m), "WriteLiquibase should succeed") {
+		assert.Equal(t, `databaseChangeLog:
+  - changeSet:
+      id: "000001_create_fuga"
+      author: "schemalex"
+      changes:
+        - sql:
+            sql: |-
+              CREATE TABLE `+"`fuga`"+` (
+              `+"`id`"+` INT (11) NOT NULL
+              );
+      rollback:
+        - sql:
+            sql: |-
+              DROP TABLE `+"`fuga`"+`;
+`, buf.String(), "YAML changelog should match")
+	}
+
+	buf.Reset()
+	if assert.NoError(t, migration.WriteLiquibase(&buf, migration.LiquibaseXML, "000001_create_fuga", "schemalex", m), "WriteLiquibase should succeed") {
+		assert.Contains(t, buf.String(), `<changeSet id="000001_create_fuga" author="schemalex">`, "XML changelog should have the changeSet")
+		assert.Contains(t, buf.String(), "<sql><![CDATA[CREATE TABLE `fuga` (\n`id` INT (11) NOT NULL\n);]]></sql>", "XML changelog should have the sql change")
+		assert.Contains(t, buf.String(), "<rollback>\n      <sql><![CDATA[DROP TABLE `fuga`;]]></sql>\n    </rollback>", "XML changelog should have the rollback")
+	}
+}
+
+func TestWriteFlyway(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schemalex-migration")
+	if !assert.NoError(t, err, "creating temporary directory should succeed") {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	v, err := migration.NextFlywayVersion(dir, migration.NumberingSequence, time.Time{})
+	if assert.NoError(t, err, "NextFlywayVersion should succeed") {
+		assert.Equal(t, "1", v, "versions should start from 1")
+	}
+
+	for _, name := range []string{"V1__init.sql", "V2.1__users.sql", "U2.1__users.sql", "R__views.sql", "V10_not_flyway.sql"} {
+		if !assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), nil, 0644), "writing file should succeed") {
+			return
+		}
+	}
+	v, err = migration.NextFlywayVersion(dir, migration.NumberingSequence, time.Time{})
+	if !assert.NoError(t, err, "NextFlywayVersion should succeed") {
+		return
+	}
+	assert.Equal(t, "3", v, "versions should follow existing ones")
+
+	m := &migration.Migration{
+		Up:   "ALTER TABLE `fuga` ADD COLUMN `a` INT (11) NOT NULL AFTER `id`;",
+		Down: "ALTER TABLE `fuga` DROP COLUMN `a`;",
+	}
+	paths, err := migration.WriteFlyway(dir, v, "Add column a", true, m)
+	if !assert.NoError(t, err, "WriteFlyway should succeed") {
+		return
+	}
+	assert.Equal(t, []string{filepath.Join(dir, "V3__add_column_a.sql"), filepath.Join(dir, "U3__add_column_a.sql")}, paths, "paths should match")
+
+	undo, _ := ioutil.ReadFile(paths[1])
+	assert.Equal(t, "ALTER TABLE `fuga` DROP COLUMN `a`;\n", string(undo), "undo migration should match")
+}
@@ -0,0 +1,46 @@
+package model
+
+// NewCheck creates a new CHECK constraint with the given condition.
+// The constraint is enforced, unless told otherwise.
+func NewCheck(expression string) Check {
+	return &check{
+		expression: expression,
+		enforced:   true,
+	}
+}
+
+// ID returns the name of the constraint, or its condition if it has
+// no name
+func (c *check) ID() string {
+	if c.name.Valid {
+		return "check#" + c.name.Value
+	}
+	return "check#(" + c.expression + ")"
+}
+
+func (c *check) HasName() bool {
+	return c.name.Valid
+}
+
+func (c *check) Name() string {
+	return c.name.Value
+}
+
+func (c *check) SetName(s string) Check {
+	c.name.Valid = true
+	c.name.Value = s
+	return c
+}
+
+func (c *check) Expression() string {
+	return c.expression
+}
+
+func (c *check) IsEnforced() bool {
+	return c.enforced
+}
+
+func (c *check) SetEnforced(b bool) Check {
+	c.enforced = b
+	return c
+}
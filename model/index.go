@@ -154,6 +154,25 @@ func (stmt *index) IsForeignKey() bool {
 	return stmt.kind == IndexKindForeignKey
 }
 
+func (stmt *index) Line() int {
+	return stmt.line
+}
+
+func (stmt *index) SetTable(table string) Index {
+	stmt.table = table
+	return stmt
+}
+
+func (stmt *index) SetColumns(l ...IndexColumn) Index {
+	stmt.columns = append([]IndexColumn(nil), l...)
+	return stmt
+}
+
+func (stmt *index) SetLine(line int) Index {
+	stmt.line = line
+	return stmt
+}
+
 func (stmt *index) Normalize() (Index, bool) {
 	return stmt, false
 }
@@ -65,6 +65,11 @@ type Index interface {
 	SetType(IndexType) Index
 	SetName(string) Index
 	SetParser(string) Index
+	// SetTable sets the table that the index belongs to, as given to
+	// NewIndex, which is part of its ID
+	SetTable(string) Index
+	// SetColumns replaces the columns of the index
+	SetColumns(...IndexColumn) Index
 	Symbol() string
 	IsBtree() bool
 	IsHash() bool
@@ -75,6 +80,12 @@ type Index interface {
 	IsSpatial() bool
 	IsForeignKey() bool
 
+	// Line returns the line of the source that the index is defined
+	// on, starting from 1, or 0 if it is not known, as for indexes
+	// defined along with a column
+	Line() int
+	SetLine(int) Index
+
 	// Normalize returns normalized index. If a normalization was performed
 	// and the index is modified, returns a new instance of the Table object
 	// along with a true value as the second return value.
@@ -128,6 +139,7 @@ type index struct {
 	// TODO Options.
 	reference Reference
 	parser maybeString
+	line   int
 }
 
 // Reference describes a possible reference from one table to another
@@ -200,6 +212,22 @@ type Table interface {
 	Indexes() chan Index
 	AddOption(TableOption) Table
 	Options() chan TableOption
+	AddCheck(Check) Table
+	Checks() chan Check
+
+	HasPartitioning() bool
+	Partitioning() Partitioning
+	SetPartitioning(Partitioning) Table
+
+	// LeadingComments returns the comments written right before the
+	// table in the source, as they were written, such as "-- users"
+	LeadingComments() []string
+	SetLeadingComments([]string) Table
+
+	// Line returns the line of the source that the table is created
+	// on, starting from 1, or 0 if it is not known
+	Line() int
+	SetLine(int) Table
 
 	LookupColumn(string) (TableColumn, bool)
 	LookupColumnOrder(string) (int, bool)
@@ -226,6 +254,72 @@ type TableOption interface {
 	NeedQuotes() bool
 }
 
+// Partitioning describes the PARTITION BY clause of a table
+type Partitioning interface {
+	// Type returns the partitioning type, such as RANGE, LIST COLUMNS
+	// or LINEAR HASH
+	Type() string
+
+	// Expression returns the expression, or the list of columns, that
+	// the table is partitioned by, as it was written in the source
+	// without the enclosing parentheses
+	Expression() string
+
+	// HasCount returns true if the number of partitions is given
+	// by a PARTITIONS clause
+	HasCount() bool
+	Count() int
+	SetCount(int) Partitioning
+
+	// Subpartitioning returns the SUBPARTITION BY clause, as it was
+	// written in the source
+	HasSubpartitioning() bool
+	Subpartitioning() string
+	SetSubpartitioning(string) Partitioning
+
+	AddDefinition(PartitionDefinition) Partitioning
+	Definitions() chan PartitionDefinition
+}
+
+// Check describes a CHECK constraint of a table, such as
+// `CONSTRAINT chk_price CHECK (price > 0) NOT ENFORCED`
+type Check interface {
+	Stmt
+
+	// Name returns the symbol of the constraint. Constraints without
+	// a symbol are named by MySQL, such as fuga_chk_1
+	HasName() bool
+	Name() string
+	SetName(string) Check
+
+	// Expression returns the condition as it was written in the source,
+	// without the enclosing parentheses
+	Expression() string
+
+	IsEnforced() bool
+	SetEnforced(bool) Check
+}
+
+// PartitionDefinition describes a single partition of a table, such
+// as `PARTITION p0 VALUES LESS THAN (10)`
+type PartitionDefinition interface {
+	Stmt
+
+	Name() string
+
+	// Values returns the VALUES clause without the VALUES keyword,
+	// such as `LESS THAN (10)` or `IN (1, 2)`. It is empty for
+	// partitions of HASH and KEY partitioned tables.
+	Values() string
+	SetValues(string) PartitionDefinition
+
+	// Options returns the rest of the definition, such as
+	// `ENGINE = InnoDB` or the subpartitions, as it was written in
+	// the source
+	Options() string
+	SetOptions(string) PartitionDefinition
+}
+
 type table struct {
 	mu                sync.RWMutex
 	name              string
@@ -236,6 +330,31 @@ type table struct {
 	columnNameToIndex map[string]int
 	indexes           []Index
 	options           []TableOption
+	checks            []Check
+	partitioning      Partitioning
+	comments          []string
+	line              int
+}
+
+type check struct {
+	name       maybeString
+	expression string
+	enforced   bool
+}
+
+type partitioning struct {
+	typ             string
+	expression      string
+	count           int
+	hasCount        bool
+	subpartitioning maybeString
+	definitions     []PartitionDefinition
+}
+
+type partitionDefinition struct {
+	name    string
+	values  string
+	options string
 }
 
 type tableopt struct {
@@ -306,6 +425,11 @@ type TableColumn interface {
 	HasCollation() bool
 	Collation() string
 	SetCollation(string) TableColumn
+	// IsImpliedCollation reports whether the collation was not written
+	// in the source, but filled in by Normalize as the default of the
+	// character set. SetCollation clears it.
+	IsImpliedCollation() bool
+	SetImpliedCollation(bool) TableColumn
 	HasDefault() bool
 	Default() string
 	IsQuotedDefault() bool
@@ -313,6 +437,17 @@ type TableColumn interface {
 	HasComment() bool
 	Comment() string
 	SetComment(string) TableColumn
+	// LeadingComments and TrailingComment return the comments written
+	// in the source on the lines before the column, and on the same
+	// line after it. They have nothing to do with COMMENT 'text'.
+	LeadingComments() []string
+	SetLeadingComments([]string) TableColumn
+	TrailingComment() string
+	SetTrailingComment(string) TableColumn
+	// Line returns the line of the source that the column is defined
+	// on, starting from 1, or 0 if it is not known
+	Line() int
+	SetLine(int) TableColumn
 	HasAutoUpdate() bool
 	AutoUpdate() string
 	SetAutoUpdate(string) TableColumn
@@ -376,6 +511,7 @@ type tablecol struct {
 	nullstate       NullState
 	charset         maybeString
 	collation       maybeString
+	impliedColl     bool
 	defaultValue    defaultValue
 	comment         maybeString
 	autoUpdate      maybeString
@@ -388,6 +524,9 @@ type tablecol struct {
 	unique          bool
 	unsigned        bool
 	zerofill        bool
+	leadingComments []string
+	trailingComment string
+	line            int
 }
 
 // Database represents a database definition
@@ -411,3 +550,101 @@ type database struct {
 	name        string
 	ifnotexists bool
 }
+
+// View represents a view definition
+type View interface {
+	// This is a dummy method to differentiate between View and
+	// other interfaces. See Database for details.
+	isView() bool
+
+	Stmt
+
+	Name() string
+	IsOrReplace() bool
+	SetOrReplace(bool) View
+
+	HasAlgorithm() bool
+	Algorithm() string
+	SetAlgorithm(string) View
+
+	HasDefiner() bool
+	Definer() string
+	SetDefiner(string) View
+
+	HasSQLSecurity() bool
+	SQLSecurity() string
+	SetSQLSecurity(string) View
+
+	// Columns returns the list of column names specified explicitly
+	// for the view, if any
+	Columns() []string
+	SetColumns([]string) View
+
+	// Definition returns the SELECT statement that defines the view,
+	// as it was written in the source, along with any trailing
+	// WITH CHECK OPTION clause
+	Definition() string
+	SetDefinition(string) View
+}
+
+type view struct {
+	name        string
+	orReplace   bool
+	algorithm   maybeString
+	definer     maybeString
+	sqlSecurity maybeString
+	columns     []string
+	definition  string
+}
+
+// Trigger represents a trigger definition
+type Trigger interface {
+	// This is a dummy method to differentiate between Trigger and
+	// other interfaces. See Database for details.
+	isTrigger() bool
+
+	Stmt
+
+	Name() string
+
+	HasDefiner() bool
+	Definer() string
+	SetDefiner(string) Trigger
+
+	// Timing returns either BEFORE or AFTER
+	Timing() string
+	SetTiming(string) Trigger
+
+	// Event returns either INSERT, UPDATE or DELETE
+	Event() string
+	SetEvent(string) Trigger
+
+	TableName() string
+	SetTableName(string) Trigger
+
+	// HasOrder returns true if the trigger has a FOLLOWS or
+	// PRECEDES clause
+	HasOrder() bool
+	// Order returns either FOLLOWS or PRECEDES
+	Order() string
+	// OrderTrigger returns the name of the trigger named in the
+	// FOLLOWS or PRECEDES clause
+	OrderTrigger() string
+	SetOrder(order string, trigger string) Trigger
+
+	// Body returns the statement executed by the trigger, as it was
+	// written in the source
+	Body() string
+	SetBody(string) Trigger
+}
+
+type trigger struct {
+	name         string
+	definer      maybeString
+	timing       string
+	event        string
+	tableName    string
+	order        maybeString
+	orderTrigger string
+	body         string
+}
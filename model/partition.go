@@ -0,0 +1,94 @@
+package model
+
+// NewPartitioning creates a new partitioning model of the given type,
+// such as RANGE or LINEAR HASH, by the given expression
+func NewPartitioning(typ, expression string) Partitioning {
+	return &partitioning{
+		typ:        typ,
+		expression: expression,
+	}
+}
+
+func (p *partitioning) Type() string {
+	return p.typ
+}
+
+func (p *partitioning) Expression() string {
+	return p.expression
+}
+
+func (p *partitioning) HasCount() bool {
+	return p.hasCount
+}
+
+func (p *partitioning) Count() int {
+	return p.count
+}
+
+func (p *partitioning) SetCount(n int) Partitioning {
+	p.hasCount = true
+	p.count = n
+	return p
+}
+
+func (p *partitioning) HasSubpartitioning() bool {
+	return p.subpartitioning.Valid
+}
+
+func (p *partitioning) Subpartitioning() string {
+	return p.subpartitioning.Value
+}
+
+func (p *partitioning) SetSubpartitioning(s string) Partitioning {
+	p.subpartitioning.Valid = true
+	p.subpartitioning.Value = s
+	return p
+}
+
+func (p *partitioning) AddDefinition(def PartitionDefinition) Partitioning {
+	p.definitions = append(p.definitions, def)
+	return p
+}
+
+func (p *partitioning) Definitions() chan PartitionDefinition {
+	ch := make(chan PartitionDefinition, len(p.definitions))
+	for _, def := range p.definitions {
+		ch <- def
+	}
+	close(ch)
+	return ch
+}
+
+// NewPartitionDefinition creates a new partition definition with
+// the given name
+func NewPartitionDefinition(name string) PartitionDefinition {
+	return &partitionDefinition{
+		name: name,
+	}
+}
+
+func (d *partitionDefinition) ID() string {
+	return "partition#" + d.name
+}
+
+func (d *partitionDefinition) Name() string {
+	return d.name
+}
+
+func (d *partitionDefinition) Values() string {
+	return d.values
+}
+
+func (d *partitionDefinition) SetValues(s string) PartitionDefinition {
+	d.values = s
+	return d
+}
+
+func (d *partitionDefinition) Options() string {
+	return d.options
+}
+
+func (d *partitionDefinition) SetOptions(s string) PartitionDefinition {
+	d.options = s
+	return d
+}
@@ -86,6 +86,24 @@ func (t *table) AddOption(v TableOption) Table {
 	return t
 }
 
+func (t *table) AddCheck(v Check) Table {
+	t.checks = append(t.checks, v)
+	return t
+}
+
+func (t *table) HasPartitioning() bool {
+	return t.partitioning != nil
+}
+
+func (t *table) Partitioning() Partitioning {
+	return t.partitioning
+}
+
+func (t *table) SetPartitioning(p Partitioning) Table {
+	t.partitioning = p
+	return t
+}
+
 func (t *table) Name() string {
 	return t.name
 }
@@ -149,6 +167,33 @@ func (t *table) Options() chan TableOption {
 	return ch
 }
 
+func (t *table) LeadingComments() []string {
+	return t.comments
+}
+
+func (t *table) SetLeadingComments(comments []string) Table {
+	t.comments = comments
+	return t
+}
+
+func (t *table) Line() int {
+	return t.line
+}
+
+func (t *table) SetLine(line int) Table {
+	t.line = line
+	return t
+}
+
+func (t *table) Checks() chan Check {
+	ch := make(chan Check, len(t.checks))
+	for _, c := range t.checks {
+		ch <- c
+	}
+	close(ch)
+	return ch
+}
+
 func (t *table) Normalize() (Table, bool) {
 	var clone bool
 	var additionalIndexes []Index
@@ -215,7 +260,7 @@ func (t *table) Normalize() (Table, bool) {
 					if ncol.CharacterSet() == defaultCharacterSet && defaultCollation != "" {
 						ncol.SetCollation(defaultCollation)
 					} else if collation := getDefaultCollationForCharacterSet(ncol.CharacterSet()); collation != "" {
-						ncol.SetCollation(collation)
+						ncol.SetCollation(collation).SetImpliedCollation(true)
 					}
 				} else if defaultCollation != "" {
 					ncol.SetCollation(defaultCollation)
@@ -257,9 +302,16 @@ func (t *table) Normalize() (Table, bool) {
 		tbl.AddIndex(idx)
 	}
 
+	for c := range t.Checks() {
+		tbl.AddCheck(c)
+	}
+
 	for opt := range t.Options() {
 		tbl.AddOption(opt)
 	}
+	tbl.SetPartitioning(t.Partitioning())
+	tbl.SetLeadingComments(t.LeadingComments())
+	tbl.SetLine(t.Line())
 	return tbl, true
 }
 
@@ -323,6 +375,8 @@ func getDefaultCollationForCharacterSet(characterSet string) string {
 		return "armscii8_general_ci"
 	case "utf8":
 		return "utf8_general_ci"
+	case "utf8mb3":
+		return "utf8mb3_general_ci"
 	case "ucs2":
 		return "ucs2_general_ci"
 	case "cp866":
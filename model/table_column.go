@@ -59,6 +59,16 @@ func (t *tablecol) SetCharacterSet(s string) TableColumn {
 func (t *tablecol) SetCollation(s string) TableColumn {
 	t.collation.Valid = true
 	t.collation.Value = s
+	t.impliedColl = false
+	return t
+}
+
+func (t *tablecol) IsImpliedCollation() bool {
+	return t.impliedColl
+}
+
+func (t *tablecol) SetImpliedCollation(v bool) TableColumn {
+	t.impliedColl = v
 	return t
 }
 
@@ -162,6 +172,33 @@ func (t *tablecol) SetComment(v string) TableColumn {
 	return t
 }
 
+func (t *tablecol) LeadingComments() []string {
+	return t.leadingComments
+}
+
+func (t *tablecol) SetLeadingComments(comments []string) TableColumn {
+	t.leadingComments = comments
+	return t
+}
+
+func (t *tablecol) TrailingComment() string {
+	return t.trailingComment
+}
+
+func (t *tablecol) SetTrailingComment(comment string) TableColumn {
+	t.trailingComment = comment
+	return t
+}
+
+func (t *tablecol) Line() int {
+	return t.line
+}
+
+func (t *tablecol) SetLine(line int) TableColumn {
+	t.line = line
+	return t
+}
+
 func (t *tablecol) SetDefault(v string, quoted bool) TableColumn {
 	t.defaultValue.Valid = true
 	t.defaultValue.Value = v
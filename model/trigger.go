@@ -0,0 +1,89 @@
+package model
+
+// NewTrigger creates a new trigger model with the given name
+func NewTrigger(name string) Trigger {
+	return &trigger{
+		name: name,
+	}
+}
+
+func (t *trigger) isTrigger() bool {
+	return true
+}
+
+func (t *trigger) ID() string {
+	return "trigger#" + t.name
+}
+
+func (t *trigger) Name() string {
+	return t.name
+}
+
+func (t *trigger) HasDefiner() bool {
+	return t.definer.Valid
+}
+
+func (t *trigger) Definer() string {
+	return t.definer.Value
+}
+
+func (t *trigger) SetDefiner(s string) Trigger {
+	t.definer.Valid = true
+	t.definer.Value = s
+	return t
+}
+
+func (t *trigger) Timing() string {
+	return t.timing
+}
+
+func (t *trigger) SetTiming(s string) Trigger {
+	t.timing = s
+	return t
+}
+
+func (t *trigger) Event() string {
+	return t.event
+}
+
+func (t *trigger) SetEvent(s string) Trigger {
+	t.event = s
+	return t
+}
+
+func (t *trigger) TableName() string {
+	return t.tableName
+}
+
+func (t *trigger) SetTableName(s string) Trigger {
+	t.tableName = s
+	return t
+}
+
+func (t *trigger) HasOrder() bool {
+	return t.order.Valid
+}
+
+func (t *trigger) Order() string {
+	return t.order.Value
+}
+
+func (t *trigger) OrderTrigger() string {
+	return t.orderTrigger
+}
+
+func (t *trigger) SetOrder(order, trigger string) Trigger {
+	t.order.Valid = true
+	t.order.Value = order
+	t.orderTrigger = trigger
+	return t
+}
+
+func (t *trigger) Body() string {
+	return t.body
+}
+
+func (t *trigger) SetBody(s string) Trigger {
+	t.body = s
+	return t
+}
@@ -0,0 +1,89 @@
+package model
+
+// NewView creates a new view model with the given name
+func NewView(name string) View {
+	return &view{
+		name: name,
+	}
+}
+
+func (v *view) isView() bool {
+	return true
+}
+
+func (v *view) ID() string {
+	return "view#" + v.name
+}
+
+func (v *view) Name() string {
+	return v.name
+}
+
+func (v *view) IsOrReplace() bool {
+	return v.orReplace
+}
+
+func (v *view) SetOrReplace(b bool) View {
+	v.orReplace = b
+	return v
+}
+
+func (v *view) HasAlgorithm() bool {
+	return v.algorithm.Valid
+}
+
+func (v *view) Algorithm() string {
+	return v.algorithm.Value
+}
+
+func (v *view) SetAlgorithm(s string) View {
+	v.algorithm.Valid = true
+	v.algorithm.Value = s
+	return v
+}
+
+func (v *view) HasDefiner() bool {
+	return v.definer.Valid
+}
+
+func (v *view) Definer() string {
+	return v.definer.Value
+}
+
+func (v *view) SetDefiner(s string) View {
+	v.definer.Valid = true
+	v.definer.Value = s
+	return v
+}
+
+func (v *view) HasSQLSecurity() bool {
+	return v.sqlSecurity.Valid
+}
+
+func (v *view) SQLSecurity() string {
+	return v.sqlSecurity.Value
+}
+
+func (v *view) SetSQLSecurity(s string) View {
+	v.sqlSecurity.Valid = true
+	v.sqlSecurity.Value = s
+	return v
+}
+
+func (v *view) Columns() []string {
+	return v.columns
+}
+
+func (v *view) SetColumns(l []string) View {
+	v.columns = l
+	return v
+}
+
+func (v *view) Definition() string {
+	return v.definition
+}
+
+func (v *view) SetDefinition(s string) View {
+	v.definition = s
+	return v
+}
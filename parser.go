@@ -1,8 +1,10 @@
 package schemalex
 
 import (
+	"bytes"
 	"context"
 	"io/ioutil"
+	"strconv"
 	"strings"
 
 	"github.com/schemalex/schemalex/internal/errors"
@@ -66,6 +68,11 @@ type parseCtx struct {
 	lexsrc     chan *Token
 	peekCount  int
 	peekTokens [3]*Token
+
+	// comments skipped by skipWhiteSpaces that are on lines of their
+	// own, and the last one that follows something on the same line
+	comments        []string
+	trailingComment string
 }
 
 func newParseCtx(ctx context.Context) *parseCtx {
@@ -150,6 +157,8 @@ func (p *Parser) Parse(src []byte) (model.Stmts, error) {
 	cctx, cancel := context.WithCancel(context.TODO())
 	defer cancel()
 
+	src = replaceDelimiters(src)
+
 	ctx := newParseCtx(cctx)
 	ctx.input = src
 	ctx.lexsrc = lex(cctx, src)
@@ -160,6 +169,7 @@ LOOP:
 		ctx.skipWhiteSpaces()
 		switch t := ctx.peek(); t.Type {
 		case CREATE:
+			comments := ctx.takeComments()
 			stmt, err := p.parseCreate(ctx)
 			if err != nil {
 				if errors.IsIgnorable(err) {
@@ -171,6 +181,12 @@ LOOP:
 				}
 				return nil, errors.Wrap(err, `failed to parse create`)
 			}
+			if table, ok := stmt.(model.Table); ok {
+				if len(comments) > 0 {
+					table.SetLeadingComments(comments)
+				}
+				table.SetLine(t.Line)
+			}
 			stmts = append(stmts, stmt)
 		case COMMENT_IDENT:
 			ctx.advance()
@@ -205,6 +221,47 @@ LOOP:
 	return stmts, nil
 }
 
+// replaceDelimiters handles DELIMITER commands understood by the mysql
+// command line client, which are commonly found in dumps that contain
+// triggers. DELIMITER lines are blanked out, and statements that are
+// terminated by a custom delimiter are terminated by a semicolon instead.
+// The length of the input is preserved, so that positions reported in
+// parse errors still point to the right place.
+func replaceDelimiters(src []byte) []byte {
+	keyword := []byte("DELIMITER")
+	if !bytes.Contains(bytes.ToUpper(src), keyword) {
+		return src
+	}
+
+	out := make([]byte, 0, len(src))
+	delim := []byte(";")
+	for _, line := range bytes.SplitAfter(src, []byte("\n")) {
+		if fields := bytes.Fields(line); len(fields) == 2 && bytes.EqualFold(fields[0], keyword) {
+			delim = fields[1]
+			for _, c := range line {
+				if c != '\n' {
+					c = ' '
+				}
+				out = append(out, c)
+			}
+			continue
+		}
+
+		if !bytes.Equal(delim, []byte(";")) {
+			trimmed := bytes.TrimRight(line, " \t\r\n")
+			if bytes.HasSuffix(trimmed, delim) {
+				out = append(out, trimmed[:len(trimmed)-len(delim)]...)
+				out = append(out, ';')
+				out = append(out, bytes.Repeat([]byte(" "), len(delim)-1)...)
+				out = append(out, line[len(trimmed):]...)
+				continue
+			}
+		}
+		out = append(out, line...)
+	}
+	return out
+}
+
 func (p *Parser) parseCreate(ctx *parseCtx) (model.Stmt, error) {
 	if t := ctx.next(); t.Type != CREATE {
 		return nil, errors.New(`expected CREATE`)
@@ -218,9 +275,291 @@ func (p *Parser) parseCreate(ctx *parseCtx) (model.Stmt, error) {
 		return nil, errors.Ignorable(nil)
 	case TABLE:
 		return p.parseCreateTable(ctx)
+	}
+
+	prefix, err := p.parseCreatePrefix(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := ctx.peek(); t.Type {
+	case VIEW:
+		return p.parseCreateView(ctx, prefix)
+	case TRIGGER:
+		if prefix.orReplace || prefix.hasAlgorithm || prefix.hasSQLSecurity {
+			return nil, newParseError(ctx, t, "expected VIEW")
+		}
+		return p.parseCreateTrigger(ctx, prefix)
+	default:
+		return nil, newParseError(ctx, t, "expected DATABASE, TABLE, VIEW or TRIGGER")
+	}
+}
+
+// createPrefix holds the clauses that may appear between CREATE and
+// VIEW or TRIGGER
+type createPrefix struct {
+	orReplace      bool
+	algorithm      string
+	hasAlgorithm   bool
+	definer        string
+	hasDefiner     bool
+	sqlSecurity    string
+	hasSQLSecurity bool
+}
+
+// Start parsing after `CREATE`
+func (p *Parser) parseCreatePrefix(ctx *parseCtx) (*createPrefix, error) {
+	var prefix createPrefix
+	if ctx.peek().Type == OR {
+		ctx.advance()
+		if _, err := p.parseIdents(ctx, REPLACE); err != nil {
+			return nil, err
+		}
+		ctx.skipWhiteSpaces()
+		prefix.orReplace = true
+	}
+
+	if ctx.peek().Type == ALGORITHM {
+		ctx.advance()
+		if _, err := p.parseIdents(ctx, EQUAL); err != nil {
+			return nil, err
+		}
+		ctx.skipWhiteSpaces()
+		switch t := ctx.next(); t.Type {
+		case IDENT:
+			prefix.algorithm = strings.ToUpper(t.Value)
+			prefix.hasAlgorithm = true
+		default:
+			return nil, newParseError(ctx, t, "expected IDENT")
+		}
+		ctx.skipWhiteSpaces()
+	}
+
+	if ctx.peek().Type == DEFINER {
+		ctx.advance()
+		if _, err := p.parseIdents(ctx, EQUAL); err != nil {
+			return nil, err
+		}
+		ctx.skipWhiteSpaces()
+
+		// the definer is something like 'user'@'host', which we do not
+		// tokenize properly. Just take everything as is
+		start := ctx.peek().Pos
+	DEFINER:
+		for {
+			switch t := ctx.peek(); t.Type {
+			case SQL, VIEW, TRIGGER:
+				prefix.definer = strings.TrimSpace(string(ctx.input[start:t.Pos]))
+				break DEFINER
+			case EOF:
+				return nil, newParseError(ctx, t, "expected SQL, VIEW or TRIGGER")
+			default:
+				ctx.advance()
+			}
+		}
+		prefix.hasDefiner = true
+	}
+
+	if ctx.peek().Type == SQL {
+		ctx.advance()
+		if _, err := p.parseIdents(ctx, SECURITY); err != nil {
+			return nil, err
+		}
+		ctx.skipWhiteSpaces()
+		switch t := ctx.next(); t.Type {
+		case DEFINER, IDENT:
+			prefix.sqlSecurity = strings.ToUpper(t.Value)
+			prefix.hasSQLSecurity = true
+		default:
+			return nil, newParseError(ctx, t, "expected DEFINER or INVOKER")
+		}
+		ctx.skipWhiteSpaces()
+	}
+	return &prefix, nil
+}
+
+// https://dev.mysql.com/doc/refman/5.7/en/create-view.html
+// Start parsing after `CREATE [OR REPLACE] ... [SQL SECURITY ...]`
+func (p *Parser) parseCreateView(ctx *parseCtx, prefix *createPrefix) (model.View, error) {
+	if t := ctx.next(); t.Type != VIEW {
+		return nil, newParseError(ctx, t, "expected VIEW")
+	}
+	ctx.skipWhiteSpaces()
+
+	var view model.View
+	switch t := ctx.next(); t.Type {
+	case IDENT, BACKTICK_IDENT:
+		view = model.NewView(t.Value)
+	default:
+		return nil, newParseError(ctx, t, "expected IDENT or BACKTICK_IDENT")
+	}
+	view.SetOrReplace(prefix.orReplace)
+	if prefix.hasAlgorithm {
+		view.SetAlgorithm(prefix.algorithm)
+	}
+	if prefix.hasDefiner {
+		view.SetDefiner(prefix.definer)
+	}
+	if prefix.hasSQLSecurity {
+		view.SetSQLSecurity(prefix.sqlSecurity)
+	}
+
+	ctx.skipWhiteSpaces()
+	if ctx.peek().Type == LPAREN {
+		ctx.advance()
+		var columns []string
+	COLUMNS:
+		for {
+			ctx.skipWhiteSpaces()
+			switch t := ctx.next(); t.Type {
+			case IDENT, BACKTICK_IDENT:
+				columns = append(columns, t.Value)
+			default:
+				return nil, newParseError(ctx, t, "expected IDENT or BACKTICK_IDENT")
+			}
+
+			ctx.skipWhiteSpaces()
+			switch t := ctx.next(); t.Type {
+			case RPAREN:
+				break COLUMNS
+			case COMMA:
+			default:
+				return nil, newParseError(ctx, t, "expected RPAREN or COMMA")
+			}
+		}
+		view.SetColumns(columns)
+		ctx.skipWhiteSpaces()
+	}
+
+	if t := ctx.next(); t.Type != AS {
+		return nil, newParseError(ctx, t, "expected AS")
+	}
+	ctx.skipWhiteSpaces()
+
+	// We don't parse SELECT statements. The definition is everything
+	// up to the end of the statement
+	start := ctx.peek().Pos
+	for {
+		switch t := ctx.peek(); t.Type {
+		case SEMICOLON, EOF:
+			definition := strings.TrimSpace(string(ctx.input[start:t.Pos]))
+			if definition == "" {
+				return nil, newParseError(ctx, t, "expected view definition")
+			}
+			view.SetDefinition(definition)
+			ctx.advance()
+			return view, nil
+		default:
+			ctx.advance()
+		}
+	}
+}
+
+// https://dev.mysql.com/doc/refman/5.7/en/create-trigger.html
+// Start parsing after `CREATE [DEFINER = ...]`
+func (p *Parser) parseCreateTrigger(ctx *parseCtx, prefix *createPrefix) (model.Trigger, error) {
+	if t := ctx.next(); t.Type != TRIGGER {
+		return nil, newParseError(ctx, t, "expected TRIGGER")
+	}
+	ctx.skipWhiteSpaces()
+
+	var trigger model.Trigger
+	switch t := ctx.next(); t.Type {
+	case IDENT, BACKTICK_IDENT:
+		trigger = model.NewTrigger(t.Value)
+	default:
+		return nil, newParseError(ctx, t, "expected IDENT or BACKTICK_IDENT")
+	}
+	if prefix.hasDefiner {
+		trigger.SetDefiner(prefix.definer)
+	}
+
+	ctx.skipWhiteSpaces()
+	switch t := ctx.next(); t.Type {
+	case BEFORE, AFTER:
+		trigger.SetTiming(strings.ToUpper(t.Value))
+	default:
+		return nil, newParseError(ctx, t, "expected BEFORE or AFTER")
+	}
+
+	ctx.skipWhiteSpaces()
+	switch t := ctx.next(); t.Type {
+	case INSERT, UPDATE, DELETE:
+		trigger.SetEvent(strings.ToUpper(t.Value))
 	default:
-		return nil, newParseError(ctx, t, "expected DATABASE or TABLE")
+		return nil, newParseError(ctx, t, "expected INSERT, UPDATE or DELETE")
 	}
+
+	if _, err := p.parseIdents(ctx, ON); err != nil {
+		return nil, err
+	}
+	ctx.skipWhiteSpaces()
+	switch t := ctx.next(); t.Type {
+	case IDENT, BACKTICK_IDENT:
+		trigger.SetTableName(t.Value)
+	default:
+		return nil, newParseError(ctx, t, "expected IDENT or BACKTICK_IDENT")
+	}
+
+	if _, err := p.parseIdents(ctx, FOR, EACH, ROW); err != nil {
+		return nil, err
+	}
+	ctx.skipWhiteSpaces()
+
+	switch t := ctx.peek(); t.Type {
+	case FOLLOWS, PRECEDES:
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+		switch t1 := ctx.next(); t1.Type {
+		case IDENT, BACKTICK_IDENT:
+			trigger.SetOrder(strings.ToUpper(t.Value), t1.Value)
+		default:
+			return nil, newParseError(ctx, t1, "expected IDENT or BACKTICK_IDENT")
+		}
+		ctx.skipWhiteSpaces()
+	}
+
+	// We don't parse the body. It is everything up to the end of the
+	// statement, which is a semicolon outside of BEGIN ... END blocks.
+	// BEGIN, END and friends are not reserved words, so we match them
+	// by their values instead of their token types
+	var depth int
+	start := ctx.peek().Pos
+	for {
+		t := ctx.next()
+		switch {
+		case t.Type == EOF || t.Type == SEMICOLON && depth == 0:
+			body := strings.TrimSpace(string(ctx.input[start:t.Pos]))
+			if body == "" {
+				return nil, newParseError(ctx, t, "expected trigger body")
+			}
+			trigger.SetBody(body)
+			return trigger, nil
+		case isWord(t, "BEGIN"), isWord(t, "CASE"):
+			depth++
+		case isWord(t, "END"):
+			// END IF, END LOOP, etc. close blocks that we don't count
+			ctx.skipWhiteSpaces()
+			switch t1 := ctx.peek(); {
+			case t1.Type == IF, isWord(t1, "LOOP"), isWord(t1, "WHILE"), isWord(t1, "REPEAT"):
+				ctx.advance()
+			case isWord(t1, "CASE"):
+				ctx.advance()
+				depth--
+			default:
+				depth--
+			}
+		}
+	}
+}
+
+// isWord returns true if the token is an identifier or a keyword
+// spelled as the given word
+func isWord(t *Token, word string) bool {
+	if t.Type == EOF || t.Type == BACKTICK_IDENT || t.Type == SINGLE_QUOTE_IDENT || t.Type == DOUBLE_QUOTE_IDENT {
+		return false
+	}
+	return strings.EqualFold(t.Value, word)
 }
 
 // https://dev.mysql.com/doc/refman/5.5/en/create-database.html
@@ -336,6 +675,10 @@ func (p *Parser) parseCreateTable(ctx *parseCtx) (model.Table, error) {
 func (p *Parser) parseCreateTableFields(ctx *parseCtx, stmt model.Table) error {
 	for {
 		ctx.skipWhiteSpaces()
+		comments := ctx.takeComments()
+		var col model.TableColumn
+		line := ctx.peek().Line
+		indexes := len(stmt.Indexes())
 		switch t := ctx.peek(); t.Type {
 		case CONSTRAINT:
 			if err := p.parseTableConstraint(ctx, stmt); err != nil {
@@ -366,19 +709,37 @@ func (p *Parser) parseCreateTableFields(ctx *parseCtx, stmt model.Table) error {
 			if err := p.parseTableForeignKey(ctx, stmt); err != nil {
 				return err
 			}
-		case CHECK: // TODO
-			return newParseError(ctx, t, "unsupported field: CHECK")
+		case CHECK:
+			if err := p.parseTableCheck(ctx, stmt, ""); err != nil {
+				return err
+			}
 		case IDENT, BACKTICK_IDENT:
-			if err := p.parseTableColumn(ctx, stmt); err != nil {
+			var err error
+			if col, err = p.parseTableColumn(ctx, stmt); err != nil {
 				return err
 			}
+			col.SetLeadingComments(comments)
+			col.SetLine(line)
 		default:
 			return newParseError(ctx, t, "unexpected create table field token: %s", t.Type)
 		}
 
+		// indexes added by the field, which is an index itself unless
+		// it is a column
+		var i int
+		for idx := range stmt.Indexes() {
+			if i >= indexes && idx.Line() == 0 {
+				idx.SetLine(line)
+			}
+			i++
+		}
+
 		ctx.skipWhiteSpaces()
 		switch t := ctx.peek(); t.Type {
 		case RPAREN:
+			if col != nil {
+				col.SetTrailingComment(ctx.trailingComment)
+			}
 			ctx.advance()
 			if err := p.parseCreateTableOptions(ctx, stmt); err != nil {
 				return err
@@ -390,6 +751,12 @@ func (p *Parser) parseCreateTableFields(ctx *parseCtx, stmt model.Table) error {
 			return nil
 		case COMMA:
 			ctx.advance()
+			// a comment after the comma on the same line belongs to
+			// the field before it
+			ctx.skipWhiteSpaces()
+			if col != nil {
+				col.SetTrailingComment(ctx.trailingComment)
+			}
 			// Expecting another table field, keep looping
 		default:
 			return newParseError(ctx, t, "expected RPAREN or COMMA")
@@ -415,6 +782,8 @@ func (p *Parser) parseTableConstraint(ctx *parseCtx, table model.Table) error {
 
 	var index model.Index
 	switch t := ctx.peek(); t.Type {
+	case CHECK:
+		return p.parseTableCheck(ctx, table, sym)
 	case PRIMARY:
 		index = model.NewIndex(model.IndexKindPrimaryKey, table.ID())
 		if err := p.parseColumnIndexPrimaryKey(ctx, index); err != nil {
@@ -442,6 +811,39 @@ func (p *Parser) parseTableConstraint(ctx *parseCtx, table model.Table) error {
 	return nil
 }
 
+// parseTableCheck parses `CHECK (expr) [[NOT] ENFORCED]`. The
+// expression is kept as it was written.
+func (p *Parser) parseTableCheck(ctx *parseCtx, table model.Table, sym string) error {
+	if t := ctx.next(); t.Type != CHECK {
+		return newParseError(ctx, t, "expected CHECK")
+	}
+	ctx.skipWhiteSpaces()
+
+	expr, err := p.parseParenthesizedText(ctx)
+	if err != nil {
+		return err
+	}
+	check := model.NewCheck(expr)
+	if len(sym) > 0 {
+		check.SetName(sym)
+	}
+
+	ctx.skipWhiteSpaces()
+	if ctx.peek().Type == NOT {
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+		if t := ctx.next(); !isWord(t, "ENFORCED") {
+			return newParseError(ctx, t, "expected ENFORCED")
+		}
+		check.SetEnforced(false)
+	} else if isWord(ctx.peek(), "ENFORCED") {
+		ctx.advance()
+	}
+
+	table.AddCheck(check)
+	return nil
+}
+
 func (p *Parser) parseTablePrimaryKey(ctx *parseCtx, table model.Table) error {
 	index := model.NewIndex(model.IndexKindPrimaryKey, table.ID())
 	if err := p.parseColumnIndexPrimaryKey(ctx, index); err != nil {
@@ -496,20 +898,43 @@ func (p *Parser) parseTableForeignKey(ctx *parseCtx, table model.Table) error {
 	return nil
 }
 
-func (p *Parser) parseTableColumn(ctx *parseCtx, table model.Table) error {
+func (p *Parser) parseTableColumn(ctx *parseCtx, table model.Table) (model.TableColumn, error) {
 	t := ctx.next()
 	switch t.Type {
 	case IDENT, BACKTICK_IDENT:
 	default:
-		return newParseError(ctx, t, "expcted IDENT or BACKTICK_IDENT")
+		return nil, newParseError(ctx, t, "expcted IDENT or BACKTICK_IDENT")
 	}
 
 	col := model.NewTableColumn(t.Value)
 	if err := p.parseTableColumnSpec(ctx, col); err != nil {
-		return err
+		return nil, err
+	}
+
+	// a CHECK constraint may end the column definition. It is added
+	// to the table, as if it were written after the column.
+	ctx.skipWhiteSpaces()
+	switch ctx.peek().Type {
+	case CONSTRAINT:
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+		var sym string
+		switch t := ctx.peek(); t.Type {
+		case IDENT, BACKTICK_IDENT:
+			sym = t.Value
+			ctx.advance()
+			ctx.skipWhiteSpaces()
+		}
+		if err := p.parseTableCheck(ctx, table, sym); err != nil {
+			return nil, err
+		}
+	case CHECK:
+		if err := p.parseTableCheck(ctx, table, ""); err != nil {
+			return nil, err
+		}
 	}
 	table.AddColumn(col)
-	return nil
+	return col, nil
 }
 
 func (p *Parser) parseTableColumnSpec(ctx *parseCtx, col model.TableColumn) error {
@@ -789,6 +1214,15 @@ func (p *Parser) parseCreateTableOptions(ctx *parseCtx, table model.Table) error
 		case COMMA:
 			// no op, continue to next option
 			continue
+		case IDENT:
+			// PARTITION is not a keyword, so that it can be matched
+			// by its value like the rest of the partitioning clause
+			if !isWord(t, "PARTITION") {
+				return newParseError(ctx, t, "unexpected token in table options: "+t.Type.String())
+			}
+			if err := p.parseCreateTablePartitioning(ctx, table); err != nil {
+				return err
+			}
 		default:
 			return newParseError(ctx, t, "unexpected token in table options: "+t.Type.String())
 		}
@@ -808,6 +1242,204 @@ func (p *Parser) parseCreateTableOptions(ctx *parseCtx, table model.Table) error
 	}
 }
 
+// https://dev.mysql.com/doc/refman/5.7/en/create-table.html#create-table-partitioning
+// Start parsing after `PARTITION`. The expressions and the partition
+// options are not parsed, and kept as they were written in the source.
+func (p *Parser) parseCreateTablePartitioning(ctx *parseCtx, table model.Table) error {
+	ctx.skipWhiteSpaces()
+	if t := ctx.next(); !isWord(t, "BY") {
+		return newParseError(ctx, t, "expected BY")
+	}
+
+	typ, expr, err := p.parsePartitioningMethod(ctx)
+	if err != nil {
+		return err
+	}
+	partitioning := model.NewPartitioning(typ, expr)
+
+	ctx.skipWhiteSpaces()
+	if isWord(ctx.peek(), "PARTITIONS") {
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+		t := ctx.next()
+		if t.Type != NUMBER {
+			return newParseError(ctx, t, "expected NUMBER")
+		}
+		n, err := strconv.Atoi(t.Value)
+		if err != nil {
+			return newParseError(ctx, t, "invalid number of partitions")
+		}
+		partitioning.SetCount(n)
+		ctx.skipWhiteSpaces()
+	}
+
+	if isWord(ctx.peek(), "SUBPARTITION") {
+		start := ctx.peek().Pos
+		ctx.advance()
+		ctx.skipWhiteSpaces()
+		if t := ctx.next(); !isWord(t, "BY") {
+			return newParseError(ctx, t, "expected BY")
+		}
+		if _, _, err := p.parsePartitioningMethod(ctx); err != nil {
+			return err
+		}
+		ctx.skipWhiteSpaces()
+		if isWord(ctx.peek(), "SUBPARTITIONS") {
+			ctx.advance()
+			ctx.skipWhiteSpaces()
+			if t := ctx.next(); t.Type != NUMBER {
+				return newParseError(ctx, t, "expected NUMBER")
+			}
+		}
+		partitioning.SetSubpartitioning(strings.TrimSpace(string(ctx.input[start:ctx.peek().Pos])))
+		ctx.skipWhiteSpaces()
+	}
+
+	if ctx.peek().Type == LPAREN {
+		ctx.advance()
+	DEFINITIONS:
+		for {
+			def, err := p.parsePartitionDefinition(ctx)
+			if err != nil {
+				return err
+			}
+			partitioning.AddDefinition(def)
+
+			ctx.skipWhiteSpaces()
+			switch t := ctx.next(); t.Type {
+			case RPAREN:
+				break DEFINITIONS
+			case COMMA:
+			default:
+				return newParseError(ctx, t, "expected RPAREN or COMMA")
+			}
+		}
+	}
+
+	table.SetPartitioning(partitioning)
+	return nil
+}
+
+// parsePartitioningMethod parses things like `RANGE COLUMNS (a, b)`,
+// and returns the type and the expression in the parentheses
+func (p *Parser) parsePartitioningMethod(ctx *parseCtx) (string, string, error) {
+	var words []string
+	for {
+		ctx.skipWhiteSpaces()
+		switch t := ctx.peek(); t.Type {
+		case LPAREN:
+			if len(words) == 0 {
+				return "", "", newParseError(ctx, t, "expected partitioning type")
+			}
+			expr, err := p.parseParenthesizedText(ctx)
+			if err != nil {
+				return "", "", err
+			}
+			return strings.Join(words, " "), expr, nil
+		case EOF, SEMICOLON:
+			return "", "", newParseError(ctx, t, "expected LPAREN")
+		default:
+			// e.g. LINEAR KEY ALGORITHM = 2
+			ctx.advance()
+			words = append(words, strings.ToUpper(t.Value))
+		}
+	}
+}
+
+// parsePartitionDefinition parses a single definition in the list of
+// partitions, such as `PARTITION p0 VALUES LESS THAN (10) ENGINE = InnoDB`
+func (p *Parser) parsePartitionDefinition(ctx *parseCtx) (model.PartitionDefinition, error) {
+	ctx.skipWhiteSpaces()
+	if t := ctx.next(); !isWord(t, "PARTITION") {
+		return nil, newParseError(ctx, t, "expected PARTITION")
+	}
+	ctx.skipWhiteSpaces()
+
+	var def model.PartitionDefinition
+	switch t := ctx.next(); t.Type {
+	case IDENT, BACKTICK_IDENT:
+		def = model.NewPartitionDefinition(t.Value)
+	default:
+		return nil, newParseError(ctx, t, "expected IDENT or BACKTICK_IDENT")
+	}
+
+	ctx.skipWhiteSpaces()
+	if isWord(ctx.peek(), "VALUES") {
+		ctx.advance()
+		var words []string
+	VALUES:
+		for {
+			ctx.skipWhiteSpaces()
+			switch t := ctx.peek(); {
+			case t.Type == LPAREN:
+				list, err := p.parseParenthesizedText(ctx)
+				if err != nil {
+					return nil, err
+				}
+				words = append(words, "("+list+")")
+				break VALUES
+			case isWord(t, "MAXVALUE"):
+				ctx.advance()
+				words = append(words, "MAXVALUE")
+				break VALUES
+			case isWord(t, "LESS"), isWord(t, "THAN"), isWord(t, "IN"):
+				ctx.advance()
+				words = append(words, strings.ToUpper(t.Value))
+			default:
+				return nil, newParseError(ctx, t, "expected LESS THAN or IN")
+			}
+		}
+		def.SetValues(strings.Join(words, " "))
+		ctx.skipWhiteSpaces()
+	}
+
+	// the rest is everything up to the end of this definition,
+	// including the list of subpartitions
+	var depth int
+	start := ctx.peek().Pos
+	for {
+		switch t := ctx.peek(); t.Type {
+		case LPAREN:
+			depth++
+		case RPAREN, COMMA:
+			if depth == 0 {
+				def.SetOptions(strings.TrimSpace(string(ctx.input[start:t.Pos])))
+				return def, nil
+			}
+			if t.Type == RPAREN {
+				depth--
+			}
+		case EOF, SEMICOLON:
+			return nil, newParseError(ctx, t, "expected RPAREN or COMMA")
+		}
+		ctx.advance()
+	}
+}
+
+// parseParenthesizedText returns the text between a pair of
+// parentheses, as it was written in the source
+func (p *Parser) parseParenthesizedText(ctx *parseCtx) (string, error) {
+	if t := ctx.next(); t.Type != LPAREN {
+		return "", newParseError(ctx, t, "expected LPAREN")
+	}
+
+	var depth int
+	start := ctx.peek().Pos
+	for {
+		switch t := ctx.next(); t.Type {
+		case LPAREN:
+			depth++
+		case RPAREN:
+			if depth == 0 {
+				return strings.TrimSpace(string(ctx.input[start:t.Pos])), nil
+			}
+			depth--
+		case EOF:
+			return "", newParseError(ctx, t, "expected RPAREN")
+		}
+	}
+}
+
 // parse column options
 //
 // Also see: https://github.com/schemalex/schemalex/pull/40
@@ -1043,6 +1675,10 @@ func (p *Parser) parseColumnOption(ctx *parseCtx, col model.TableColumn, f int)
 		case RPAREN:
 			ctx.rewind()
 			return nil
+		case CONSTRAINT, CHECK:
+			// left to parseTableColumn
+			ctx.rewind()
+			return nil
 		default:
 			return newParseError(ctx, t, "unexpected column option %s", t.Type)
 		}
@@ -1489,16 +2125,59 @@ func (p *Parser) parseColumnIndexParser(ctx *parseCtx, index model.Index) error
 // Skips over whitespaces. Once this method returns, you can be
 // certain that next call to ctx.next()/peek() will result in a
 // non-space token
+//
+// Comments are kept until they are taken by takeComments, except for
+// the ones followed by a blank line, which do not belong to anything.
+// Executable comments such as /*!50100 ... */ are not kept.
 func (pctx *parseCtx) skipWhiteSpaces() {
+	var newlines int
 	for {
 		switch t := pctx.peek(); t.Type {
-		case SPACE, COMMENT_IDENT:
-			pctx.advance()
-			continue
+		case SPACE:
+			newlines += strings.Count(t.Value, "\n")
+			if newlines > 1 {
+				pctx.comments = nil
+			}
+		case COMMENT_IDENT:
+			// line comments include the newline that ends them
+			newlines = 0
+			if strings.HasSuffix(t.Value, "\n") {
+				newlines = 1
+			}
+			if text := strings.TrimSpace(t.Value); !strings.HasPrefix(text, "/*!") {
+				if pctx.onOwnLine(t) {
+					pctx.comments = append(pctx.comments, text)
+				} else {
+					pctx.trailingComment = text
+				}
+			}
 		default:
 			return
 		}
+		pctx.advance()
+	}
+}
+
+// onOwnLine reports whether nothing but spaces precede the token on
+// the same line
+func (pctx *parseCtx) onOwnLine(t *Token) bool {
+	if t.Pos > len(pctx.input) {
+		return false
+	}
+	before := pctx.input[:t.Pos]
+	if i := bytes.LastIndexByte(before, '\n'); i >= 0 {
+		before = before[i+1:]
 	}
+	return len(bytes.TrimSpace(before)) == 0
+}
+
+// takeComments returns the comments kept on lines of their own since
+// the last call, and forgets about all comments kept so far
+func (pctx *parseCtx) takeComments() []string {
+	comments := pctx.comments
+	pctx.comments = nil
+	pctx.trailingComment = ""
+	return comments
 }
 
 func (p *Parser) parseIdents(ctx *parseCtx, idents ...TokenType) ([]string, error) {
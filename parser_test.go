@@ -10,6 +10,7 @@ import (
 	"github.com/pmezard/go-difflib/difflib"
 	"github.com/schemalex/schemalex"
 	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/model"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -285,6 +286,73 @@ primary key (id, c)
 		Input:  "CREATE TABLE foo (id INT(10) NOT NULL) ENGINE = InnoDB, DEFAULT CHARACTER SET = utf8mb4 \n/**/ ;",
 		Expect: "CREATE TABLE `foo` (\n`id` INT (10) NOT NULL\n) ENGINE = InnoDB, DEFAULT CHARACTER SET = utf8mb4",
 	})
+	parse("PartitionByRange", &Spec{
+		Input:  "CREATE TABLE foo (id INT(10) NOT NULL) ENGINE = InnoDB PARTITION BY RANGE (id) (PARTITION p0 VALUES LESS THAN (10), PARTITION `p1` VALUES LESS THAN MAXVALUE ENGINE = InnoDB);",
+		Expect: "CREATE TABLE `foo` (\n`id` INT (10) NOT NULL\n) ENGINE = InnoDB\nPARTITION BY RANGE (id) (\nPARTITION `p0` VALUES LESS THAN (10),\nPARTITION `p1` VALUES LESS THAN MAXVALUE ENGINE = InnoDB\n)",
+	})
+	parse("PartitionByLinearKey", &Spec{
+		Input:  "CREATE TABLE foo (id INT(10) NOT NULL) partition by linear key algorithm=2 (id) partitions 4",
+		Expect: "CREATE TABLE `foo` (\n`id` INT (10) NOT NULL\n)\nPARTITION BY LINEAR KEY ALGORITHM = 2 (id) PARTITIONS 4",
+	})
+	parse("PartitionWithSubpartitions", &Spec{
+		Input:  "CREATE TABLE foo (d DATE NOT NULL) PARTITION BY LIST (YEAR(d)) SUBPARTITION BY HASH (TO_DAYS(d)) SUBPARTITIONS 2 (PARTITION p0 VALUES IN (1990, 1991) (SUBPARTITION s0, SUBPARTITION s1))",
+		Expect: "CREATE TABLE `foo` (\n`d` DATE NOT NULL\n)\nPARTITION BY LIST (YEAR(d)) SUBPARTITION BY HASH (TO_DAYS(d)) SUBPARTITIONS 2 (\nPARTITION `p0` VALUES IN (1990, 1991) (SUBPARTITION s0, SUBPARTITION s1)\n)",
+	})
+	parse("PartitionWithoutExpression", &Spec{
+		Input: "CREATE TABLE foo (id INT(10) NOT NULL) PARTITION BY HASH;",
+		Error: true,
+	})
+	parse("CheckConstraints", &Spec{
+		Input:  "CREATE TABLE foo (price INT NOT NULL, CHECK (price > 0), CONSTRAINT chk_price CHECK ((`price` < 100)) /*!80016 NOT ENFORCED */, constraint chk_low check (price > (1)) enforced)",
+		Expect: "CREATE TABLE `foo` (\n`price` INT (11) NOT NULL,\nCHECK (price > 0),\nCONSTRAINT `chk_price` CHECK ((`price` < 100)),\nCONSTRAINT `chk_low` CHECK (price > (1))\n)",
+	})
+	parse("CheckConstraintNotEnforced", &Spec{
+		Input:  "CREATE TABLE foo (price INT NOT NULL, CONSTRAINT chk_price CHECK (price > 0) NOT ENFORCED)",
+		Expect: "CREATE TABLE `foo` (\n`price` INT (11) NOT NULL,\nCONSTRAINT `chk_price` CHECK (price > 0) NOT ENFORCED\n)",
+	})
+	parse("ColumnCheckConstraints", &Spec{
+		Input:  "CREATE TABLE foo (a INT CHECK (a > 0), b INT NOT NULL DEFAULT 1 CONSTRAINT chk_b CHECK (b < a) NOT ENFORCED)",
+		Expect: "CREATE TABLE `foo` (\n`a` INT (11) DEFAULT NULL,\n`b` INT (11) NOT NULL DEFAULT 1,\nCHECK (a > 0),\nCONSTRAINT `chk_b` CHECK (b < a) NOT ENFORCED\n)",
+	})
+	parse("ColumnConstraintWithoutCheck", &Spec{
+		Input: "CREATE TABLE foo (a INT CONSTRAINT chk_a UNIQUE)",
+		Error: true,
+	})
+	parse("CheckConstraintWithoutParenthesis", &Spec{
+		Input: "CREATE TABLE foo (price INT NOT NULL, CHECK price > 0)",
+		Error: true,
+	})
+	parse("CreateView", &Spec{
+		Input:  "CREATE VIEW foo AS SELECT id, name FROM bar WHERE id > 1;",
+		Expect: "CREATE VIEW `foo` AS SELECT id, name FROM bar WHERE id > 1",
+	})
+	parse("CreateViewWithOptions", &Spec{
+		Input:  "CREATE OR REPLACE ALGORITHM=merge DEFINER=`root`@`localhost` SQL SECURITY invoker VIEW `foo` (`a`, b) AS SELECT id, name FROM bar WITH CHECK OPTION",
+		Expect: "CREATE OR REPLACE ALGORITHM = MERGE DEFINER = `root`@`localhost` SQL SECURITY INVOKER VIEW `foo` (`a`, `b`) AS SELECT id, name FROM bar WITH CHECK OPTION",
+	})
+	parse("CreateTrigger", &Spec{
+		Input:  "CREATE TRIGGER ins_sum BEFORE INSERT ON account FOR EACH ROW SET @sum = @sum + NEW.amount;",
+		Expect: "CREATE TRIGGER `ins_sum` BEFORE INSERT ON `account` FOR EACH ROW SET @sum = @sum + NEW.amount",
+	})
+	parse("CreateTriggerWithBlock", &Spec{
+		Input: "CREATE DEFINER=`root`@`%` TRIGGER `upd_check` AFTER UPDATE ON `account` FOR EACH ROW FOLLOWS `ins_sum` BEGIN\n" +
+			"  IF NEW.amount < 0 THEN\n    SET NEW.amount = 0;\n  END IF;\n" +
+			"  SET NEW.kind = CASE WHEN NEW.amount > 100 THEN 'big' ELSE 'small' END;\n" +
+			"END;\nCREATE TABLE foo (id INT(10) NOT NULL);",
+		Expect: "CREATE DEFINER = `root`@`%` TRIGGER `upd_check` AFTER UPDATE ON `account` FOR EACH ROW FOLLOWS `ins_sum` BEGIN\n" +
+			"  IF NEW.amount < 0 THEN\n    SET NEW.amount = 0;\n  END IF;\n" +
+			"  SET NEW.kind = CASE WHEN NEW.amount > 100 THEN 'big' ELSE 'small' END;\n" +
+			"END" +
+			"CREATE TABLE `foo` (\n`id` INT (10) NOT NULL\n)",
+	})
+	parse("CreateTriggerWithDelimiter", &Spec{
+		Input:  "DELIMITER ;;\nCREATE TRIGGER ins_sum BEFORE INSERT ON account FOR EACH ROW BEGIN SET @sum = @sum + NEW.amount; END;;\nDELIMITER ;\n",
+		Expect: "CREATE TRIGGER `ins_sum` BEFORE INSERT ON `account` FOR EACH ROW BEGIN SET @sum = @sum + NEW.amount; END",
+	})
+	parse("CreateViewWithoutDefinition", &Spec{
+		Input: "CREATE VIEW foo AS ;",
+		Error: true,
+	})
 }
 
 func testParse(t *testing.T, spec *Spec) {
@@ -322,6 +390,47 @@ func testParse(t *testing.T, spec *Spec) {
 	}
 }
 
+func TestParseComments(t *testing.T) {
+	src := "-- file header\n\n-- users of the service\nCREATE TABLE users (\n  -- primary key\n  id INT NOT NULL, -- trailing\n  name VARCHAR(10) NOT NULL /*!50100 ignored */ -- last\n);"
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	var buf bytes.Buffer
+	if !assert.NoError(t, format.SQL(&buf, stmts[0], format.WithComments(true)), "format should succeed") {
+		return
+	}
+	assert.Equal(t, "-- users of the service\nCREATE TABLE `users` (\n-- primary key\n`id` INT (11) NOT NULL, -- trailing\n`name` VARCHAR (10) NOT NULL -- last\n)", buf.String(), "comments should be kept")
+
+	buf.Reset()
+	if !assert.NoError(t, format.SQL(&buf, stmts[0]), "format should succeed") {
+		return
+	}
+	assert.Equal(t, "CREATE TABLE `users` (\n`id` INT (11) NOT NULL,\n`name` VARCHAR (10) NOT NULL\n)", buf.String(), "comments should not be written by default")
+}
+
+func TestParseLines(t *testing.T) {
+	src := "-- header\nCREATE TABLE users (\n  id INT NOT NULL PRIMARY KEY,\n  name VARCHAR(10) NOT NULL,\n  KEY idx_name (name)\n);"
+	stmts, err := schemalex.New().ParseString(src)
+	if !assert.NoError(t, err, "parse should succeed") {
+		return
+	}
+
+	table := stmts[0].(model.Table)
+	assert.Equal(t, 2, table.Line(), "table should be on the line of CREATE")
+	for col := range table.Columns() {
+		if col.Name() == "name" {
+			assert.Equal(t, 4, col.Line(), "column should be on its line")
+		}
+	}
+	for idx := range table.Indexes() {
+		if idx.Name() == "idx_name" {
+			assert.Equal(t, 5, idx.Line(), "index should be on its line")
+		}
+	}
+}
+
 func TestFile(t *testing.T) {
 	flag.Parse()
 	if testFile == "" {
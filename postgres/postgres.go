@@ -0,0 +1,500 @@
+// Package postgres translates MySQL schemas to PostgreSQL DDL, for
+// products that run on both databases. Constructs that PostgreSQL has
+// no counterpart for are left out or approximated, and reported as
+// issues, so that they can be taken care of by hand.
+package postgres
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/internal/option"
+	"github.com/schemalex/schemalex/internal/util"
+	"github.com/schemalex/schemalex/model"
+)
+
+type Option = schemalex.Option
+
+const optkeyIdentity = "identity"
+
+// WithIdentity specifies whether AUTO_INCREMENT columns are translated
+// to identity columns (GENERATED BY DEFAULT AS IDENTITY), which
+// PostgreSQL 10 and later support. They are translated to SERIAL and
+// its variants by default.
+func WithIdentity(b bool) Option {
+	return option.New(optkeyIdentity, b)
+}
+
+// Issue is a construct that could not be translated as it is
+type Issue struct {
+	// Table is the name of the table, view or trigger
+	Table string `json:"table"`
+	// Column is the name of the column, if the issue is about one
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.Column != "" {
+		return i.Table + "." + i.Column + ": " + i.Message
+	}
+	return i.Table + ": " + i.Message
+}
+
+type pgCtx struct {
+	identity bool
+	issues   []Issue
+}
+
+func (ctx *pgCtx) report(table, column, format string, args ...interface{}) {
+	ctx.issues = append(ctx.issues, Issue{Table: table, Column: column, Message: fmt.Sprintf(format, args...)})
+}
+
+// Stmts writes the statements to dst as PostgreSQL DDL, and returns
+// the constructs that could not be translated as they are, in the
+// order they were found. Indexes other than primary keys are created
+// by separate statements after their tables, and are named after the
+// table and the index, such as "users_idx_email", as the names of
+// indexes are not scoped to tables in PostgreSQL.
+func Stmts(dst io.Writer, stmts model.Stmts, options ...Option) ([]Issue, error) {
+	var ctx pgCtx
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyIdentity:
+			ctx.identity = o.Value().(bool)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, stmt := range stmts {
+		var err error
+		switch stmt := stmt.(type) {
+		case model.Database:
+			ctx.report(stmt.Name(), "", "CREATE DATABASE is not translated")
+		case model.Table:
+			err = writeTable(&ctx, &buf, stmt)
+		case model.View:
+			writeView(&ctx, &buf, stmt)
+		case model.Trigger:
+			ctx.report(stmt.TableName(), "", "trigger %s is not translated, as PostgreSQL triggers call functions", stmt.Name())
+		default:
+			err = errors.Errorf(`unsupported statement %s`, stmt.ID())
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, `failed to translate statement %s`, stmt.ID())
+		}
+	}
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return nil, errors.Wrap(err, `failed to write statements`)
+	}
+	return ctx.issues, nil
+}
+
+// Source translates the schema read from src (see Stmts)
+func Source(dst io.Writer, src schemalex.SchemaSource, options ...Option) ([]Issue, error) {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return nil, errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+	return Stmts(dst, stmts, options...)
+}
+
+func writeTable(ctx *pgCtx, buf *bytes.Buffer, table model.Table) error {
+	// moves PRIMARY KEY and UNIQUE off the columns, among other things
+	table, _ = table.Normalize()
+	name := table.Name()
+
+	buf.WriteString("CREATE ")
+	if table.IsTemporary() {
+		buf.WriteString("TEMPORARY ")
+	}
+	buf.WriteString("TABLE ")
+	if table.IsIfNotExists() {
+		buf.WriteString("IF NOT EXISTS ")
+	}
+	buf.WriteString(util.DoubleQuote(name))
+
+	if table.HasLikeTable() {
+		buf.WriteString(" (LIKE ")
+		buf.WriteString(util.DoubleQuote(table.LikeTable()))
+		buf.WriteString(" INCLUDING ALL);\n\n")
+		return nil
+	}
+
+	var fields, constraints, comments []string
+	for col := range table.Columns() {
+		def, err := columnDefinition(ctx, name, col)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, def)
+		if col.Type() == model.ColumnTypeEnum {
+			constraints = append(constraints, "CHECK ("+util.DoubleQuote(col.Name())+" IN ("+quoteValues(col.EnumValues())+"))")
+		}
+		if col.HasComment() {
+			comments = append(comments, "COMMENT ON COLUMN "+util.DoubleQuote(name)+"."+util.DoubleQuote(col.Name())+" IS "+util.SingleQuote(col.Comment())+";\n")
+		}
+	}
+
+	var indexes []string
+	for idx := range table.Indexes() {
+		switch {
+		case idx.IsPrimaryKey():
+			fields = append(fields, "PRIMARY KEY ("+indexColumns(ctx, name, idx)+")")
+		case idx.IsForeignKey():
+			constraints = append(constraints, foreignKey(ctx, name, idx))
+		case idx.IsFullText():
+			ctx.report(name, "", "FULLTEXT index %s is not translated, consider a GIN index on a tsvector", indexName(idx))
+		case idx.IsSpatial():
+			ctx.report(name, "", "SPATIAL index %s is not translated", indexName(idx))
+		default:
+			indexes = append(indexes, createIndex(ctx, name, idx))
+		}
+	}
+
+	for check := range table.Checks() {
+		if !check.IsEnforced() {
+			ctx.report(name, "", "CHECK (%s) is not enforced, and is left out", check.Expression())
+			continue
+		}
+		var def string
+		if check.HasName() {
+			def = "CONSTRAINT " + util.DoubleQuote(check.Name()) + " "
+		}
+		constraints = append(constraints, def+"CHECK ("+util.DoubleQuoteIdentifiers(check.Expression())+")")
+	}
+
+	if table.HasPartitioning() {
+		ctx.report(name, "", "partitioning is not translated")
+	}
+	for opt := range table.Options() {
+		if strings.EqualFold(opt.Key(), "COMMENT") {
+			comments = append([]string{"COMMENT ON TABLE " + util.DoubleQuote(name) + " IS " + util.SingleQuote(opt.Value()) + ";\n"}, comments...)
+		}
+	}
+
+	buf.WriteString(" (\n  ")
+	buf.WriteString(strings.Join(append(fields, constraints...), ",\n  "))
+	buf.WriteString("\n);\n")
+	for _, index := range indexes {
+		buf.WriteString(index)
+	}
+	for _, comment := range comments {
+		buf.WriteString(comment)
+	}
+	buf.WriteByte('\n')
+	return nil
+}
+
+func columnDefinition(ctx *pgCtx, table string, col model.TableColumn) (string, error) {
+	typ, err := columnType(ctx, table, col)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(util.DoubleQuote(col.Name()))
+	buf.WriteByte(' ')
+	buf.WriteString(typ)
+
+	if col.HasGeneratedExpr() {
+		if col.StoreOption() != model.StoreOptionStored {
+			ctx.report(table, col.Name(), "virtual generated column is translated to a stored one")
+		}
+		buf.WriteString(" GENERATED ALWAYS AS (")
+		buf.WriteString(util.DoubleQuoteIdentifiers(col.GeneratedExpr()))
+		buf.WriteString(") STORED")
+	}
+
+	if col.NullState() == model.NullStateNotNull {
+		buf.WriteString(" NOT NULL")
+	}
+
+	if col.HasDefault() && !col.IsAutoIncrement() {
+		if def, ok := defaultValue(col); ok {
+			buf.WriteString(" DEFAULT ")
+			buf.WriteString(def)
+		}
+	}
+
+	if col.HasAutoUpdate() {
+		ctx.report(table, col.Name(), "ON UPDATE %s is not translated, as it takes a trigger", col.AutoUpdate())
+	}
+	return buf.String(), nil
+}
+
+// isBoolean tells if the column is TINYINT(1), which is what BOOLEAN
+// is in MySQL
+func isBoolean(col model.TableColumn) bool {
+	return col.Type() == model.ColumnTypeTinyInt && col.HasLength() && col.Length().Length() == "1" && !col.IsUnsigned()
+}
+
+func columnType(ctx *pgCtx, table string, col model.TableColumn) (string, error) {
+	typ := col.Type().SynonymType()
+	if col.Type() == model.ColumnTypeBool || col.Type() == model.ColumnTypeBoolean || isBoolean(col) {
+		return "BOOLEAN", nil
+	}
+
+	switch typ {
+	case model.ColumnTypeTinyInt, model.ColumnTypeSmallInt, model.ColumnTypeMediumInt, model.ColumnTypeInt, model.ColumnTypeBigInt:
+		return integerType(ctx, table, col), nil
+	case model.ColumnTypeFloat:
+		return "REAL", nil
+	case model.ColumnTypeDouble:
+		return "DOUBLE PRECISION", nil
+	case model.ColumnTypeDecimal:
+		return "NUMERIC" + length(col), nil
+	case model.ColumnTypeBit:
+		return "BIT" + length(col), nil
+	case model.ColumnTypeDate:
+		return "DATE", nil
+	case model.ColumnTypeTime:
+		return "TIME" + length(col), nil
+	case model.ColumnTypeDateTime:
+		return "TIMESTAMP" + length(col), nil
+	case model.ColumnTypeTimestamp:
+		// MySQL converts TIMESTAMP values to UTC, and back
+		return "TIMESTAMP" + length(col) + " WITH TIME ZONE", nil
+	case model.ColumnTypeYear:
+		return "SMALLINT", nil
+	case model.ColumnTypeChar:
+		return "CHAR" + length(col), nil
+	case model.ColumnTypeVarChar:
+		return "VARCHAR" + length(col), nil
+	case model.ColumnTypeTinyText, model.ColumnTypeText, model.ColumnTypeMediumText, model.ColumnTypeLongText:
+		return "TEXT", nil
+	case model.ColumnTypeBinary, model.ColumnTypeVarBinary,
+		model.ColumnTypeTinyBlob, model.ColumnTypeBlob, model.ColumnTypeMediumBlob, model.ColumnTypeLongBlob:
+		return "BYTEA", nil
+	case model.ColumnTypeEnum:
+		// the values are checked by a CHECK constraint
+		var n int
+		for v := range col.EnumValues() {
+			if len(v) > n {
+				n = len(v)
+			}
+		}
+		return "VARCHAR(" + strconv.Itoa(n) + ")", nil
+	case model.ColumnTypeSet:
+		ctx.report(table, col.Name(), "SET is translated to TEXT, and its values are not checked")
+		return "TEXT", nil
+	case model.ColumnTypeJSON:
+		return "JSONB", nil
+	}
+	return "", errors.Errorf(`unsupported column type %s`, col.Type())
+}
+
+// integerType returns the smallest PostgreSQL integer type that holds
+// the values of the column, as PostgreSQL has no unsigned types
+func integerType(ctx *pgCtx, table string, col model.TableColumn) string {
+	var rank int
+	switch col.Type() {
+	case model.ColumnTypeTinyInt, model.ColumnTypeSmallInt:
+		rank = 0
+	case model.ColumnTypeMediumInt, model.ColumnTypeInt, model.ColumnTypeInteger:
+		rank = 1
+	default:
+		rank = 2
+	}
+	if col.IsUnsigned() && col.Type() != model.ColumnTypeTinyInt {
+		rank++
+	}
+	if rank > 2 {
+		if col.IsAutoIncrement() {
+			ctx.report(table, col.Name(), "BIGINT UNSIGNED AUTO_INCREMENT is translated to BIGINT, which holds half as many values")
+			rank = 2
+		} else {
+			ctx.report(table, col.Name(), "BIGINT UNSIGNED is translated to NUMERIC(20)")
+			return "NUMERIC(20)"
+		}
+	}
+
+	if col.IsAutoIncrement() {
+		if ctx.identity {
+			return []string{"SMALLINT", "INTEGER", "BIGINT"}[rank] + " GENERATED BY DEFAULT AS IDENTITY"
+		}
+		return []string{"SMALLSERIAL", "SERIAL", "BIGSERIAL"}[rank]
+	}
+	return []string{"SMALLINT", "INTEGER", "BIGINT"}[rank]
+}
+
+func length(col model.TableColumn) string {
+	if !col.HasLength() {
+		return ""
+	}
+	l := col.Length()
+	if l.HasDecimal() {
+		return "(" + l.Length() + ", " + l.Decimal() + ")"
+	}
+	return "(" + l.Length() + ")"
+}
+
+// defaultValue returns the DEFAULT clause of the column, if PostgreSQL
+// needs one
+func defaultValue(col model.TableColumn) (string, bool) {
+	def := col.Default()
+	if col.IsQuotedDefault() {
+		if isBoolean(col) {
+			return booleanValue(def), true
+		}
+		return util.SingleQuote(def), true
+	}
+
+	switch upper := strings.ToUpper(def); {
+	case upper == "NULL":
+		// columns are NULL by default
+		return "", false
+	case strings.HasPrefix(upper, "CURRENT_TIMESTAMP"), strings.HasPrefix(upper, "NOW("):
+		return "CURRENT_TIMESTAMP", true
+	case isBoolean(col) || col.Type() == model.ColumnTypeBool || col.Type() == model.ColumnTypeBoolean:
+		return booleanValue(def), true
+	}
+	return def, true
+}
+
+func booleanValue(s string) string {
+	switch strings.ToUpper(s) {
+	case "0", "FALSE":
+		return "FALSE"
+	}
+	return "TRUE"
+}
+
+func quoteValues(ch chan string) string {
+	var values []string
+	for v := range ch {
+		values = append(values, util.SingleQuote(v))
+	}
+	return strings.Join(values, ", ")
+}
+
+func indexName(idx model.Index) string {
+	if idx.HasName() {
+		return idx.Name()
+	}
+	return idx.Symbol()
+}
+
+func indexColumns(ctx *pgCtx, table string, c model.ColumnContainer) string {
+	var cols []string
+	for col := range c.Columns() {
+		s := util.DoubleQuote(col.Name())
+		if col.HasLength() {
+			ctx.report(table, col.Name(), "index prefix length %s is left out", col.Length())
+		}
+		if col.HasSortDirection() && col.IsDescending() {
+			s += " DESC"
+		}
+		cols = append(cols, s)
+	}
+	return strings.Join(cols, ", ")
+}
+
+func createIndex(ctx *pgCtx, table string, idx model.Index) string {
+	var buf bytes.Buffer
+	buf.WriteString("CREATE ")
+	if idx.IsUnique() {
+		buf.WriteString("UNIQUE ")
+	}
+	buf.WriteString("INDEX ")
+	if name := indexName(idx); name != "" {
+		buf.WriteString(util.DoubleQuote(table + "_" + name))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString("ON ")
+	buf.WriteString(util.DoubleQuote(table))
+	if idx.IsHash() {
+		if idx.IsUnique() {
+			ctx.report(table, "", "USING HASH is left out of unique index %s, as PostgreSQL only supports it for non-unique indexes", indexName(idx))
+		} else {
+			buf.WriteString(" USING HASH")
+		}
+	}
+	buf.WriteString(" (")
+	buf.WriteString(indexColumns(ctx, table, idx))
+	buf.WriteString(");\n")
+	return buf.String()
+}
+
+func foreignKey(ctx *pgCtx, table string, idx model.Index) string {
+	var buf bytes.Buffer
+	if idx.HasSymbol() {
+		buf.WriteString("CONSTRAINT ")
+		buf.WriteString(util.DoubleQuote(idx.Symbol()))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString("FOREIGN KEY (")
+	buf.WriteString(indexColumns(ctx, table, idx))
+	buf.WriteString(")")
+
+	r := idx.Reference()
+	if r == nil {
+		return buf.String()
+	}
+	buf.WriteString(" REFERENCES ")
+	buf.WriteString(util.DoubleQuote(r.TableName()))
+	buf.WriteString(" (")
+	buf.WriteString(indexColumns(ctx, table, r))
+	buf.WriteString(")")
+	switch {
+	case r.MatchFull():
+		buf.WriteString(" MATCH FULL")
+	case r.MatchPartial():
+		ctx.report(table, "", "MATCH PARTIAL of foreign key %s is not supported, and is left out", idx.Symbol())
+	}
+	writeReferenceOption(&buf, "ON DELETE", r.OnDelete())
+	writeReferenceOption(&buf, "ON UPDATE", r.OnUpdate())
+	return buf.String()
+}
+
+func writeReferenceOption(buf *bytes.Buffer, prefix string, opt model.ReferenceOption) {
+	var action string
+	switch opt {
+	case model.ReferenceOptionRestrict:
+		action = "RESTRICT"
+	case model.ReferenceOptionCascade:
+		action = "CASCADE"
+	case model.ReferenceOptionSetNull:
+		action = "SET NULL"
+	case model.ReferenceOptionNoAction:
+		action = "NO ACTION"
+	default:
+		return
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(prefix)
+	buf.WriteByte(' ')
+	buf.WriteString(action)
+}
+
+func writeView(ctx *pgCtx, buf *bytes.Buffer, view model.View) {
+	buf.WriteString("CREATE ")
+	if view.IsOrReplace() {
+		buf.WriteString("OR REPLACE ")
+	}
+	buf.WriteString("VIEW ")
+	buf.WriteString(util.DoubleQuote(view.Name()))
+	if cols := view.Columns(); len(cols) > 0 {
+		quoted := make([]string, len(cols))
+		for i, col := range cols {
+			quoted[i] = util.DoubleQuote(col)
+		}
+		buf.WriteString(" (")
+		buf.WriteString(strings.Join(quoted, ", "))
+		buf.WriteByte(')')
+	}
+	buf.WriteString(" AS ")
+	buf.WriteString(util.DoubleQuoteIdentifiers(view.Definition()))
+	buf.WriteString(";\n\n")
+	ctx.report(view.Name(), "", "the definition of the view is written as it is, and may need to be rewritten")
+}
@@ -0,0 +1,75 @@
+package postgres_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/postgres"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmts(t *testing.T) {
+	type Spec struct {
+		Name    string
+		Input   string
+		Options []postgres.Option
+		Expect  string
+		Issues  []string
+	}
+
+	specs := []Spec{
+		{
+			Name:   "types",
+			Input:  "CREATE TABLE `hoge` ( `id` INTEGER UNSIGNED NOT NULL AUTO_INCREMENT, `flag` BOOLEAN NOT NULL DEFAULT TRUE, `small` TINYINT UNSIGNED, `name` VARCHAR (20) NOT NULL DEFAULT 'it''s', `body` LONGTEXT, `bin` VARBINARY (16), `price` DECIMAL (10, 2), `doc` JSON, `created` DATETIME (3), `updated` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (`id`) );",
+			Expect: "CREATE TABLE \"hoge\" (\n  \"id\" BIGSERIAL NOT NULL,\n  \"flag\" BOOLEAN NOT NULL DEFAULT TRUE,\n  \"small\" SMALLINT,\n  \"name\" VARCHAR(20) NOT NULL DEFAULT 'it''s',\n  \"body\" TEXT,\n  \"bin\" BYTEA,\n  \"price\" NUMERIC(10, 2),\n  \"doc\" JSONB,\n  \"created\" TIMESTAMP(3),\n  \"updated\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n  PRIMARY KEY (\"id\")\n);\n\n",
+		},
+		{
+			Name:    "identity",
+			Input:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL AUTO_INCREMENT, PRIMARY KEY (`id`) );",
+			Options: []postgres.Option{postgres.WithIdentity(true)},
+			Expect:  "CREATE TABLE \"hoge\" (\n  \"id\" INTEGER GENERATED BY DEFAULT AS IDENTITY NOT NULL,\n  PRIMARY KEY (\"id\")\n);\n\n",
+		},
+		{
+			Name:   "indexes, constraints and comments",
+			Input:  "CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `role` ENUM('admin', 'user') NOT NULL, `hoge_id` INTEGER NOT NULL COMMENT 'owner', PRIMARY KEY (`id`), UNIQUE KEY `uniq_role` (`role`), INDEX `idx_hoge` (`hoge_id` DESC), CONSTRAINT `fk_hoge` FOREIGN KEY (`hoge_id`) REFERENCES `hoge` (`id`) ON DELETE CASCADE, CONSTRAINT `chk_id` CHECK (`id` > 0) ) COMMENT 'fugas';",
+			Expect: "CREATE TABLE \"fuga\" (\n  \"id\" INTEGER NOT NULL,\n  \"role\" VARCHAR(5) NOT NULL,\n  \"hoge_id\" INTEGER NOT NULL,\n  PRIMARY KEY (\"id\"),\n  CHECK (\"role\" IN ('admin', 'user')),\n  CONSTRAINT \"fk_hoge\" FOREIGN KEY (\"hoge_id\") REFERENCES \"hoge\" (\"id\") ON DELETE CASCADE ON UPDATE RESTRICT,\n  CONSTRAINT \"chk_id\" CHECK (\"id\" > 0)\n);\nCREATE UNIQUE INDEX \"fuga_uniq_role\" ON \"fuga\" (\"role\");\nCREATE INDEX \"fuga_idx_hoge\" ON \"fuga\" (\"hoge_id\" DESC);\nCOMMENT ON TABLE \"fuga\" IS 'fugas';\nCOMMENT ON COLUMN \"fuga\".\"hoge_id\" IS 'owner';\n\n",
+		},
+		{
+			Name:   "untranslatable constructs",
+			Input:  "CREATE TABLE `hoge` ( `id` BIGINT UNSIGNED NOT NULL, `tags` SET('a', 'b'), `body` TEXT, `updated` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP, INDEX `idx_body` (`body`(10)), FULLTEXT INDEX `ft_body` (`body`) ); CREATE TRIGGER `trg` BEFORE INSERT ON `hoge` FOR EACH ROW SET NEW.id = 1;",
+			Expect: "CREATE TABLE \"hoge\" (\n  \"id\" NUMERIC(20) NOT NULL,\n  \"tags\" TEXT,\n  \"body\" TEXT,\n  \"updated\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP\n);\nCREATE INDEX \"hoge_idx_body\" ON \"hoge\" (\"body\");\n\n",
+			Issues: []string{
+				"hoge.id: BIGINT UNSIGNED is translated to NUMERIC(20)",
+				"hoge.tags: SET is translated to TEXT, and its values are not checked",
+				"hoge.updated: ON UPDATE CURRENT_TIMESTAMP is not translated, as it takes a trigger",
+				"hoge.body: index prefix length 10 is left out",
+				"hoge: FULLTEXT index ft_body is not translated, consider a GIN index on a tsvector",
+				"hoge: trigger trg is not translated, as PostgreSQL triggers call functions",
+			},
+		},
+	}
+
+	p := schemalex.New()
+	for _, spec := range specs {
+		t.Run(spec.Name, func(t *testing.T) {
+			stmts, err := p.ParseString(spec.Input)
+			if !assert.NoError(t, err, "parsing should succeed") {
+				return
+			}
+
+			var buf bytes.Buffer
+			issues, err := postgres.Stmts(&buf, stmts, spec.Options...)
+			if !assert.NoError(t, err, "postgres.Stmts should succeed") {
+				return
+			}
+			assert.Equal(t, spec.Expect, buf.String(), "translated statements should match")
+
+			var messages []string
+			for _, issue := range issues {
+				messages = append(messages, issue.String())
+			}
+			assert.Equal(t, spec.Issues, messages, "issues should match")
+		})
+	}
+}
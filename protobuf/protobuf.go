@@ -0,0 +1,202 @@
+// Package protobuf generates Protocol Buffers message definitions from
+// tables, so that data pipelines exchanging rows keep their .proto
+// files in lockstep with the schema
+package protobuf
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/internal/option"
+	"github.com/schemalex/schemalex/model"
+)
+
+type Option = schemalex.Option
+
+const optkeyPackage = "package"
+
+// WithPackage specifies the package of the messages, which is left out
+// by default
+func WithPackage(name string) Option {
+	return option.New(optkeyPackage, name)
+}
+
+const timestamp = "google.protobuf.Timestamp"
+
+// Stmts writes a proto3 file to dst, with a message for each of the
+// tables among the statements. Fields are numbered in the order of the
+// columns, and nullable columns are optional fields. DATETIME and
+// TIMESTAMP columns are google.protobuf.Timestamp, and DECIMAL columns
+// are strings, so as not to lose precision. Comments on tables and
+// columns are carried over.
+func Stmts(dst io.Writer, stmts model.Stmts, options ...Option) error {
+	var pkg string
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyPackage:
+			pkg = o.Value().(string)
+		}
+	}
+
+	var body bytes.Buffer
+	var imports bool
+	for _, stmt := range stmts {
+		table, ok := stmt.(model.Table)
+		if !ok {
+			continue
+		}
+		table, _ = table.Normalize()
+		if writeMessage(&body, table) {
+			imports = true
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("syntax = \"proto3\";\n")
+	if pkg != "" {
+		buf.WriteString("\npackage ")
+		buf.WriteString(pkg)
+		buf.WriteString(";\n")
+	}
+	if imports {
+		buf.WriteString("\nimport \"google/protobuf/timestamp.proto\";\n")
+	}
+	body.WriteTo(&buf)
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write messages`)
+	}
+	return nil
+}
+
+// Source writes a proto3 file for the schema read from src (see Stmts)
+func Source(dst io.Writer, src schemalex.SchemaSource, options ...Option) error {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+	return Stmts(dst, stmts, options...)
+}
+
+// writeMessage writes the message for the table, and tells if it uses
+// google.protobuf.Timestamp
+func writeMessage(buf *bytes.Buffer, table model.Table) bool {
+	var timestamps bool
+	buf.WriteByte('\n')
+	for opt := range table.Options() {
+		if strings.EqualFold(opt.Key(), "COMMENT") {
+			writeComment(buf, "", opt.Value())
+		}
+	}
+	buf.WriteString("message ")
+	buf.WriteString(messageName(table.Name()))
+	buf.WriteString(" {\n")
+	n := 0
+	for col := range table.Columns() {
+		n++
+		typ := scalarType(col)
+		if typ == timestamp {
+			timestamps = true
+		}
+		if col.HasComment() {
+			writeComment(buf, "  ", col.Comment())
+		}
+		buf.WriteString("  ")
+		// message fields have presence anyway
+		if col.NullState() != model.NullStateNotNull && typ != timestamp {
+			buf.WriteString("optional ")
+		}
+		buf.WriteString(typ)
+		buf.WriteByte(' ')
+		buf.WriteString(fieldName(col.Name()))
+		buf.WriteString(" = ")
+		buf.WriteString(strconv.Itoa(n))
+		buf.WriteString(";\n")
+	}
+	buf.WriteString("}\n")
+	return timestamps
+}
+
+func writeComment(buf *bytes.Buffer, indent, comment string) {
+	for _, line := range strings.Split(comment, "\n") {
+		buf.WriteString(indent)
+		buf.WriteString("// ")
+		buf.WriteString(strings.TrimRight(line, "\r"))
+		buf.WriteByte('\n')
+	}
+}
+
+var wordRx = regexp.MustCompile(`[A-Za-z0-9]+`)
+
+// messageName returns the name of the message for a table, such as
+// UserProfiles for user_profiles
+func messageName(table string) string {
+	var buf bytes.Buffer
+	for _, word := range wordRx.FindAllString(table, -1) {
+		buf.WriteString(strings.ToUpper(word[:1]))
+		buf.WriteString(word[1:])
+	}
+	name := buf.String()
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "T" + name
+	}
+	return name
+}
+
+var invalidRx = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// fieldName returns the name of the field for a column, in lower snake
+// case as the style guide of Protocol Buffers recommends
+func fieldName(col string) string {
+	name := invalidRx.ReplaceAllString(strings.ToLower(col), "_")
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// scalarType returns the type of the field for a column
+func scalarType(col model.TableColumn) string {
+	switch col.Type().SynonymType() {
+	case model.ColumnTypeTinyInt:
+		if col.Type() != model.ColumnTypeTinyInt || col.HasLength() && col.Length().Length() == "1" && !col.IsUnsigned() {
+			return "bool"
+		}
+		fallthrough
+	case model.ColumnTypeSmallInt, model.ColumnTypeMediumInt, model.ColumnTypeInt:
+		if col.IsUnsigned() {
+			return "uint32"
+		}
+		return "int32"
+	case model.ColumnTypeBigInt:
+		if col.IsUnsigned() {
+			return "uint64"
+		}
+		return "int64"
+	case model.ColumnTypeYear:
+		return "int32"
+	case model.ColumnTypeBit:
+		return "uint64"
+	case model.ColumnTypeFloat:
+		return "float"
+	case model.ColumnTypeDouble:
+		return "double"
+	case model.ColumnTypeDateTime, model.ColumnTypeTimestamp:
+		return timestamp
+	case model.ColumnTypeBinary, model.ColumnTypeVarBinary,
+		model.ColumnTypeTinyBlob, model.ColumnTypeBlob, model.ColumnTypeMediumBlob, model.ColumnTypeLongBlob:
+		return "bytes"
+	default:
+		// DECIMAL, DATE, TIME, texts, ENUM, SET and JSON
+		return "string"
+	}
+}
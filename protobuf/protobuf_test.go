@@ -0,0 +1,47 @@
+package protobuf_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/protobuf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmts(t *testing.T) {
+	type Spec struct {
+		Name    string
+		Input   string
+		Options []protobuf.Option
+		Expect  string
+	}
+
+	specs := []Spec{
+		{
+			Name:   "types",
+			Input:  "CREATE TABLE `user_profiles` ( `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT, `age` TINYINT UNSIGNED, `active` BOOLEAN NOT NULL, `name` VARCHAR (20) NOT NULL COMMENT 'display name', `balance` DECIMAL (10, 2) NOT NULL, `ratio` DOUBLE, `avatar` BLOB, `created` DATETIME NOT NULL, `deleted` TIMESTAMP NULL, PRIMARY KEY (`id`) ) COMMENT 'profiles of users';",
+			Expect: "syntax = \"proto3\";\n\nimport \"google/protobuf/timestamp.proto\";\n\n// profiles of users\nmessage UserProfiles {\n  uint64 id = 1;\n  optional uint32 age = 2;\n  bool active = 3;\n  // display name\n  string name = 4;\n  string balance = 5;\n  optional double ratio = 6;\n  optional bytes avatar = 7;\n  google.protobuf.Timestamp created = 8;\n  google.protobuf.Timestamp deleted = 9;\n}\n",
+		},
+		{
+			Name:    "package",
+			Input:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL ); CREATE TABLE `fuga` ( `Hoge-ID` INTEGER NOT NULL );",
+			Options: []protobuf.Option{protobuf.WithPackage("example.v1")},
+			Expect:  "syntax = \"proto3\";\n\npackage example.v1;\n\nmessage Hoge {\n  int32 id = 1;\n}\n\nmessage Fuga {\n  int32 hoge_id = 1;\n}\n",
+		},
+	}
+
+	for _, spec := range specs {
+		t.Run(spec.Name, func(t *testing.T) {
+			stmts, err := schemalex.New().ParseString(spec.Input)
+			if !assert.NoError(t, err, "Parse should succeed") {
+				return
+			}
+			var buf bytes.Buffer
+			if !assert.NoError(t, protobuf.Stmts(&buf, stmts, spec.Options...), "protobuf.Stmts should succeed") {
+				return
+			}
+			assert.Equal(t, spec.Expect, buf.String(), "messages should match")
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package schemalex
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/schemalex/schemalex/internal/awsauth"
+	"github.com/schemalex/schemalex/internal/errors"
+)
+
+// s3Source reads an object of an S3 bucket
+type s3Source struct {
+	bucket string
+	key    string
+	region string
+}
+
+// NewS3Source creates a SchemaSource whose contents are read from the
+// object of an S3 bucket. The region of the bucket is that of the
+// AWS_REGION or AWS_DEFAULT_REGION environment variables, or of the
+// profile in the shared configuration file, if region is empty, and
+// us-east-1 otherwise. AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL give
+// another endpoint, such as that of a MinIO server.
+//
+// Requests are signed with the credentials found the way the AWS SDKs
+// do: from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN environment variables, from the profile given by
+// AWS_PROFILE in the shared credentials file, from a web identity
+// token given by AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN, from the
+// container credentials of ECS, or from the instance metadata of EC2.
+// Objects are requested anonymously if there are none, which works for
+// public buckets.
+//
+// NewSchemaSource creates S3 sources from URIs in the form of
+// s3://bucket/path/to/schema.sql, which may give the region as the
+// "region" parameter.
+func NewS3Source(bucket, key, region string) SchemaSource {
+	return s3Source{bucket: bucket, key: key, region: region}
+}
+
+func (s s3Source) String() string {
+	return "s3://" + s.bucket + "/" + s.key
+}
+
+func (s s3Source) WriteSchema(dst io.Writer) error {
+	region := s.region
+	if region == "" {
+		region = awsauth.Region()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s.url(region), nil)
+	if err != nil {
+		return errors.Wrapf(err, `failed to create request for %s`, s)
+	}
+	creds, ok, err := awsauth.LoadCredentials(httpClient)
+	if err != nil {
+		return errors.Wrap(err, `failed to load AWS credentials`)
+	}
+	if ok {
+		awsauth.Sign(req, creds, region, "s3", time.Now())
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, `failed to get %s`, s)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		var e struct {
+			Code    string
+			Message string
+		}
+		if body, err := ioutil.ReadAll(res.Body); err == nil && xml.Unmarshal(body, &e) == nil && e.Code != "" {
+			return errors.Errorf(`failed to get %s: %s: %s`, s, e.Code, e.Message)
+		}
+		return errors.Errorf(`failed to get %s: %s`, s, res.Status)
+	}
+	if _, err := io.Copy(dst, res.Body); err != nil {
+		return errors.Wrapf(err, `failed to read %s`, s)
+	}
+	return nil
+}
+
+// url returns the URL of the object, in the virtual-hosted style
+// unless the name of the bucket would not be a valid host for the
+// certificate of S3, or another endpoint is given
+func (s s3Source) url(region string) string {
+	path := "/" + s3EscapePath(s.key)
+	for _, name := range []string{"AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"} {
+		if endpoint := os.Getenv(name); endpoint != "" {
+			return strings.TrimSuffix(endpoint, "/") + "/" + s.bucket + path
+		}
+	}
+	if strings.Contains(s.bucket, ".") {
+		return "https://s3." + region + ".amazonaws.com/" + s.bucket + path
+	}
+	return "https://" + s.bucket + ".s3." + region + ".amazonaws.com" + path
+}
+
+// s3EscapePath escapes the key of an object, leaving only the
+// characters that are unreserved by RFC 3986, as well as slashes
+func s3EscapePath(key string) string {
+	var buf strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			buf.WriteByte(c)
+			continue
+		}
+		buf.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+	}
+	return buf.String()
+}
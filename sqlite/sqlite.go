@@ -0,0 +1,367 @@
+// Package sqlite writes MySQL schemas as SQLite DDL, so that test
+// fixtures can be generated from the canonical MySQL schema. Columns
+// are declared with the names of the type affinities of SQLite, and
+// anything SQLite has no use for, such as character sets, comments or
+// FULLTEXT indexes, is left out.
+package sqlite
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/format"
+	"github.com/schemalex/schemalex/internal/errors"
+	"github.com/schemalex/schemalex/internal/option"
+	"github.com/schemalex/schemalex/internal/util"
+	"github.com/schemalex/schemalex/model"
+)
+
+type Option = schemalex.Option
+
+func init() {
+	format.Register("sqlite", format.FormatterFunc(formatSQL))
+}
+
+// formatSQL writes statements as SQLite DDL for format.SQL, when the
+// "sqlite" dialect is specified with format.WithDialect
+func formatSQL(dst io.Writer, v interface{}, options ...Option) error {
+	switch v := v.(type) {
+	case model.Stmts:
+		return Stmts(dst, v, options...)
+	case model.Stmt:
+		return Stmts(dst, model.Stmts{v}, options...)
+	}
+	return errors.New(`unsupported model type`)
+}
+
+const optkeyAutoIncrement = "auto-increment"
+
+// WithAutoIncrement specifies whether AUTO_INCREMENT columns are
+// declared with AUTOINCREMENT, which keeps SQLite from reusing the
+// values of deleted rows, as MySQL does. It is enabled by default.
+//
+// Either way, such a column is only incremented automatically if it
+// is the primary key of its table on its own, in which case it is
+// declared as INTEGER PRIMARY KEY, which SQLite takes as the rowid.
+func WithAutoIncrement(b bool) Option {
+	return option.New(optkeyAutoIncrement, b)
+}
+
+type sqliteCtx struct {
+	autoIncrement bool
+}
+
+// Stmts writes the tables and views among the statements to dst as
+// SQLite DDL. Databases and triggers are left out. Indexes are created
+// by separate statements after their tables, and are named after the
+// table and the index, as the names of indexes are not scoped to tables
+// in SQLite.
+func Stmts(dst io.Writer, stmts model.Stmts, options ...Option) error {
+	ctx := sqliteCtx{autoIncrement: true}
+	for _, o := range options {
+		switch o.Name() {
+		case optkeyAutoIncrement:
+			ctx.autoIncrement = o.Value().(bool)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, stmt := range stmts {
+		var err error
+		switch stmt := stmt.(type) {
+		case model.Database, model.Trigger:
+		case model.Table:
+			err = writeTable(&ctx, &buf, stmt)
+		case model.View:
+			writeView(&buf, stmt)
+		default:
+			err = errors.Errorf(`unsupported statement %s`, stmt.ID())
+		}
+		if err != nil {
+			return errors.Wrapf(err, `failed to write statement %s`, stmt.ID())
+		}
+	}
+
+	if _, err := buf.WriteTo(dst); err != nil {
+		return errors.Wrap(err, `failed to write statements`)
+	}
+	return nil
+}
+
+// Source writes the schema read from src as SQLite DDL (see Stmts)
+func Source(dst io.Writer, src schemalex.SchemaSource, options ...Option) error {
+	var buf bytes.Buffer
+	if err := src.WriteSchema(&buf); err != nil {
+		return errors.Wrapf(err, `failed to retrieve schema from source %s`, src)
+	}
+	stmts, err := schemalex.New().Parse(buf.Bytes())
+	if err != nil {
+		return errors.Wrapf(err, `failed to parse source %s`, src)
+	}
+	return Stmts(dst, stmts, options...)
+}
+
+func writeTable(ctx *sqliteCtx, buf *bytes.Buffer, table model.Table) error {
+	// moves PRIMARY KEY and UNIQUE off the columns, among other things
+	table, _ = table.Normalize()
+	name := table.Name()
+
+	if table.HasLikeTable() {
+		return errors.New(`CREATE TABLE ... LIKE is not supported by SQLite`)
+	}
+
+	// an AUTO_INCREMENT column that is the primary key on its own is
+	// declared as INTEGER PRIMARY KEY, along with the column
+	var rowid string
+	for idx := range table.Indexes() {
+		if !idx.IsPrimaryKey() || len(idx.Columns()) != 1 {
+			continue
+		}
+		pk := (<-idx.Columns()).Name()
+		for col := range table.Columns() {
+			if col.Name() == pk && col.IsAutoIncrement() {
+				rowid = pk
+			}
+		}
+	}
+
+	var fields, constraints []string
+	for col := range table.Columns() {
+		def := util.DoubleQuote(col.Name()) + " " + columnDefinition(ctx, col, col.Name() == rowid)
+		fields = append(fields, def)
+		if col.Type() == model.ColumnTypeEnum {
+			constraints = append(constraints, "CHECK ("+util.DoubleQuote(col.Name())+" IN ("+quoteValues(col.EnumValues())+"))")
+		}
+	}
+
+	var indexes []string
+	for idx := range table.Indexes() {
+		switch {
+		case idx.IsPrimaryKey():
+			if rowid == "" {
+				fields = append(fields, "PRIMARY KEY ("+indexColumns(idx)+")")
+			}
+		case idx.IsForeignKey():
+			constraints = append(constraints, foreignKey(idx))
+		case idx.IsFullText(), idx.IsSpatial():
+		default:
+			indexes = append(indexes, createIndex(name, idx))
+		}
+	}
+
+	for check := range table.Checks() {
+		if !check.IsEnforced() {
+			continue
+		}
+		var def string
+		if check.HasName() {
+			def = "CONSTRAINT " + util.DoubleQuote(check.Name()) + " "
+		}
+		constraints = append(constraints, def+"CHECK ("+util.DoubleQuoteIdentifiers(check.Expression())+")")
+	}
+
+	buf.WriteString("CREATE ")
+	if table.IsTemporary() {
+		buf.WriteString("TEMPORARY ")
+	}
+	buf.WriteString("TABLE ")
+	if table.IsIfNotExists() {
+		buf.WriteString("IF NOT EXISTS ")
+	}
+	buf.WriteString(util.DoubleQuote(name))
+	buf.WriteString(" (\n  ")
+	buf.WriteString(strings.Join(append(fields, constraints...), ",\n  "))
+	buf.WriteString("\n);\n")
+	for _, index := range indexes {
+		buf.WriteString(index)
+	}
+	buf.WriteByte('\n')
+	return nil
+}
+
+func columnDefinition(ctx *sqliteCtx, col model.TableColumn, rowid bool) string {
+	if rowid {
+		if ctx.autoIncrement {
+			return "INTEGER PRIMARY KEY AUTOINCREMENT"
+		}
+		return "INTEGER PRIMARY KEY"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(affinity(col))
+
+	if col.HasGeneratedExpr() {
+		buf.WriteString(" GENERATED ALWAYS AS (")
+		buf.WriteString(util.DoubleQuoteIdentifiers(col.GeneratedExpr()))
+		buf.WriteString(")")
+		if col.StoreOption() == model.StoreOptionStored {
+			buf.WriteString(" STORED")
+		}
+	}
+
+	if col.NullState() == model.NullStateNotNull {
+		buf.WriteString(" NOT NULL")
+	}
+
+	if col.HasDefault() {
+		if def, ok := defaultValue(col); ok {
+			buf.WriteString(" DEFAULT ")
+			buf.WriteString(def)
+		}
+	}
+	return buf.String()
+}
+
+// affinity returns the type affinity that SQLite gives to the column.
+// See https://www.sqlite.org/datatype3.html
+func affinity(col model.TableColumn) string {
+	switch col.Type().SynonymType() {
+	case model.ColumnTypeTinyInt, model.ColumnTypeSmallInt, model.ColumnTypeMediumInt,
+		model.ColumnTypeInt, model.ColumnTypeBigInt, model.ColumnTypeYear:
+		return "INTEGER"
+	case model.ColumnTypeFloat, model.ColumnTypeDouble:
+		return "REAL"
+	case model.ColumnTypeChar, model.ColumnTypeVarChar,
+		model.ColumnTypeTinyText, model.ColumnTypeText, model.ColumnTypeMediumText, model.ColumnTypeLongText,
+		model.ColumnTypeEnum, model.ColumnTypeSet, model.ColumnTypeJSON:
+		return "TEXT"
+	case model.ColumnTypeBinary, model.ColumnTypeVarBinary,
+		model.ColumnTypeTinyBlob, model.ColumnTypeBlob, model.ColumnTypeMediumBlob, model.ColumnTypeLongBlob:
+		return "BLOB"
+	default:
+		// DECIMAL, BIT and dates and times
+		return "NUMERIC"
+	}
+}
+
+// defaultValue returns the DEFAULT clause of the column, if SQLite
+// needs one
+func defaultValue(col model.TableColumn) (string, bool) {
+	def := col.Default()
+	if col.IsQuotedDefault() {
+		return util.SingleQuote(def), true
+	}
+
+	switch upper := strings.ToUpper(def); {
+	case upper == "NULL":
+		// columns are NULL by default
+		return "", false
+	case strings.HasPrefix(upper, "CURRENT_TIMESTAMP"), strings.HasPrefix(upper, "NOW("):
+		return "CURRENT_TIMESTAMP", true
+	case upper == "TRUE":
+		return "1", true
+	case upper == "FALSE":
+		return "0", true
+	case strings.HasPrefix(def, "("):
+		return util.DoubleQuoteIdentifiers(def), true
+	}
+	return def, true
+}
+
+func quoteValues(ch chan string) string {
+	var values []string
+	for v := range ch {
+		values = append(values, util.SingleQuote(v))
+	}
+	return strings.Join(values, ", ")
+}
+
+func indexColumns(c model.ColumnContainer) string {
+	var cols []string
+	for col := range c.Columns() {
+		s := util.DoubleQuote(col.Name())
+		if col.HasSortDirection() && col.IsDescending() {
+			s += " DESC"
+		}
+		cols = append(cols, s)
+	}
+	return strings.Join(cols, ", ")
+}
+
+func createIndex(table string, idx model.Index) string {
+	var buf bytes.Buffer
+	buf.WriteString("CREATE ")
+	if idx.IsUnique() {
+		buf.WriteString("UNIQUE ")
+	}
+	buf.WriteString("INDEX ")
+	name := idx.Symbol()
+	if idx.HasName() {
+		name = idx.Name()
+	}
+	if name != "" {
+		buf.WriteString(util.DoubleQuote(table + "_" + name))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString("ON ")
+	buf.WriteString(util.DoubleQuote(table))
+	buf.WriteString(" (")
+	buf.WriteString(indexColumns(idx))
+	buf.WriteString(");\n")
+	return buf.String()
+}
+
+func foreignKey(idx model.Index) string {
+	var buf bytes.Buffer
+	if idx.HasSymbol() {
+		buf.WriteString("CONSTRAINT ")
+		buf.WriteString(util.DoubleQuote(idx.Symbol()))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString("FOREIGN KEY (")
+	buf.WriteString(indexColumns(idx))
+	buf.WriteString(")")
+
+	r := idx.Reference()
+	if r == nil {
+		return buf.String()
+	}
+	buf.WriteString(" REFERENCES ")
+	buf.WriteString(util.DoubleQuote(r.TableName()))
+	buf.WriteString(" (")
+	buf.WriteString(indexColumns(r))
+	buf.WriteString(")")
+	writeReferenceOption(&buf, "ON DELETE", r.OnDelete())
+	writeReferenceOption(&buf, "ON UPDATE", r.OnUpdate())
+	return buf.String()
+}
+
+func writeReferenceOption(buf *bytes.Buffer, prefix string, opt model.ReferenceOption) {
+	var action string
+	switch opt {
+	case model.ReferenceOptionRestrict:
+		action = "RESTRICT"
+	case model.ReferenceOptionCascade:
+		action = "CASCADE"
+	case model.ReferenceOptionSetNull:
+		action = "SET NULL"
+	case model.ReferenceOptionNoAction:
+		action = "NO ACTION"
+	default:
+		return
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(prefix)
+	buf.WriteByte(' ')
+	buf.WriteString(action)
+}
+
+func writeView(buf *bytes.Buffer, view model.View) {
+	// SQLite has no CREATE OR REPLACE VIEW
+	buf.WriteString("CREATE VIEW ")
+	buf.WriteString(util.DoubleQuote(view.Name()))
+	if cols := view.Columns(); len(cols) > 0 {
+		quoted := make([]string, len(cols))
+		for i, col := range cols {
+			quoted[i] = util.DoubleQuote(col)
+		}
+		buf.WriteString(" (")
+		buf.WriteString(strings.Join(quoted, ", "))
+		buf.WriteByte(')')
+	}
+	buf.WriteString(" AS ")
+	buf.WriteString(util.DoubleQuoteIdentifiers(view.Definition()))
+	buf.WriteString(";\n\n")
+}
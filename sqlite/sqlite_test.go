@@ -0,0 +1,59 @@
+package sqlite_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/schemalex/schemalex"
+	"github.com/schemalex/schemalex/sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmts(t *testing.T) {
+	type Spec struct {
+		Name    string
+		Input   string
+		Options []sqlite.Option
+		Expect  string
+	}
+
+	specs := []Spec{
+		{
+			Name:   "type affinities",
+			Input:  "CREATE TABLE `hoge` ( `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT, `flag` BOOLEAN NOT NULL DEFAULT TRUE, `name` VARCHAR (20) NOT NULL DEFAULT 'it''s' COLLATE utf8mb4_bin, `score` DOUBLE, `price` DECIMAL (10, 2), `body` BLOB, `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP, `doc` JSON, PRIMARY KEY (`id`) ) ENGINE = InnoDB COMMENT 'hoges';",
+			Expect: "CREATE TABLE \"hoge\" (\n  \"id\" INTEGER PRIMARY KEY AUTOINCREMENT,\n  \"flag\" INTEGER NOT NULL DEFAULT 1,\n  \"name\" TEXT NOT NULL DEFAULT 'it''s',\n  \"score\" REAL,\n  \"price\" NUMERIC,\n  \"body\" BLOB,\n  \"created\" NUMERIC NOT NULL DEFAULT CURRENT_TIMESTAMP,\n  \"doc\" TEXT\n);\n\n",
+		},
+		{
+			Name:    "without AUTOINCREMENT",
+			Input:   "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL AUTO_INCREMENT, PRIMARY KEY (`id`) );",
+			Options: []sqlite.Option{sqlite.WithAutoIncrement(false)},
+			Expect:  "CREATE TABLE \"hoge\" (\n  \"id\" INTEGER PRIMARY KEY\n);\n\n",
+		},
+		{
+			Name:   "auto increment in composite primary key",
+			Input:  "CREATE TABLE `hoge` ( `id` INTEGER NOT NULL AUTO_INCREMENT, `shard` INTEGER NOT NULL, PRIMARY KEY (`id`, `shard`) );",
+			Expect: "CREATE TABLE \"hoge\" (\n  \"id\" INTEGER NOT NULL,\n  \"shard\" INTEGER NOT NULL,\n  PRIMARY KEY (\"id\", \"shard\")\n);\n\n",
+		},
+		{
+			Name:   "indexes and constraints",
+			Input:  "CREATE DATABASE `piyo`; CREATE TABLE `fuga` ( `id` INTEGER NOT NULL, `role` ENUM('admin', 'user') NOT NULL, `hoge_id` INTEGER NOT NULL, `body` TEXT, PRIMARY KEY (`id`), UNIQUE KEY `uniq_role` (`role`), INDEX `idx_hoge` (`hoge_id` DESC, `body`(10)), FULLTEXT INDEX `ft_body` (`body`), CONSTRAINT `fk_hoge` FOREIGN KEY (`hoge_id`) REFERENCES `hoge` (`id`) ON DELETE CASCADE, CONSTRAINT `chk_id` CHECK (`id` > 0) ); CREATE VIEW `v` AS SELECT `id` FROM `fuga`;",
+			Expect: "CREATE TABLE \"fuga\" (\n  \"id\" INTEGER NOT NULL,\n  \"role\" TEXT NOT NULL,\n  \"hoge_id\" INTEGER NOT NULL,\n  \"body\" TEXT,\n  PRIMARY KEY (\"id\"),\n  CHECK (\"role\" IN ('admin', 'user')),\n  CONSTRAINT \"fk_hoge\" FOREIGN KEY (\"hoge_id\") REFERENCES \"hoge\" (\"id\") ON DELETE CASCADE ON UPDATE RESTRICT,\n  CONSTRAINT \"chk_id\" CHECK (\"id\" > 0)\n);\nCREATE UNIQUE INDEX \"fuga_uniq_role\" ON \"fuga\" (\"role\");\nCREATE INDEX \"fuga_idx_hoge\" ON \"fuga\" (\"hoge_id\" DESC, \"body\");\n\nCREATE VIEW \"v\" AS SELECT \"id\" FROM \"fuga\";\n\n",
+		},
+	}
+
+	p := schemalex.New()
+	for _, spec := range specs {
+		t.Run(spec.Name, func(t *testing.T) {
+			stmts, err := p.ParseString(spec.Input)
+			if !assert.NoError(t, err, "parsing should succeed") {
+				return
+			}
+
+			var buf bytes.Buffer
+			if !assert.NoError(t, sqlite.Stmts(&buf, stmts, spec.Options...), "sqlite.Stmts should succeed") {
+				return
+			}
+			assert.Equal(t, spec.Expect, buf.String(), "statements should match")
+		})
+	}
+}
@@ -44,10 +44,13 @@ const (
 	EQUAL         // =
 	COMMENT_IDENT // // /*   */, --, #
 	ACTION
+	AFTER
+	ALGORITHM
 	ALWAYS
 	AS
 	AUTO_INCREMENT
 	AVG_ROW_LENGTH
+	BEFORE
 	BIGINT
 	BINARY
 	BIT
@@ -75,6 +78,7 @@ const (
 	DATETIME
 	DECIMAL
 	DEFAULT
+	DEFINER
 	DELAY_KEY_WRITE
 	DELETE
 	DIRECTORY
@@ -82,6 +86,7 @@ const (
 	DOUBLE
 	DROP
 	DYNAMIC
+	EACH
 	ENGINE
 	ENUM
 	EXISTS
@@ -89,6 +94,8 @@ const (
 	FIRST
 	FIXED
 	FLOAT
+	FOLLOWS
+	FOR
 	FOREIGN
 	FULL
 	FULLTEXT
@@ -96,6 +103,7 @@ const (
 	HASH
 	IF
 	INDEX
+	INSERT
 	INSERT_METHOD
 	INT
 	INTEGER
@@ -118,20 +126,26 @@ const (
 	NULL
 	NUMERIC
 	ON
+	OR
 	PACK_KEYS
 	PARSER
 	PARTIAL
 	PASSWORD
+	PRECEDES
 	PRIMARY
 	REAL
 	REDUNDANT
 	REFERENCES
+	REPLACE
 	RESTRICT
+	ROW
 	ROW_FORMAT
+	SECURITY
 	SET
 	SIMPLE
 	SMALLINT
 	SPATIAL
+	SQL
 	STATS_AUTO_RECALC
 	STATS_PERSISTENT
 	STATS_SAMPLE_PAGES
@@ -146,6 +160,7 @@ const (
 	TINYBLOB
 	TINYINT
 	TINYTEXT
+	TRIGGER
 	TRUE
 	UNION
 	UNIQUE
@@ -155,6 +170,7 @@ const (
 	USING
 	VARBINARY
 	VARCHAR
+	VIEW
 	VIRTUAL
 	WITH
 	YEAR
@@ -166,10 +182,13 @@ const (
 
 var keywordIdentMap = map[string]TokenType{
 	"ACTION":             ACTION,
+	"AFTER":              AFTER,
+	"ALGORITHM":          ALGORITHM,
 	"ALWAYS":             ALWAYS,
 	"AS":                 AS,
 	"AUTO_INCREMENT":     AUTO_INCREMENT,
 	"AVG_ROW_LENGTH":     AVG_ROW_LENGTH,
+	"BEFORE":             BEFORE,
 	"BIGINT":             BIGINT,
 	"BINARY":             BINARY,
 	"BIT":                BIT,
@@ -197,6 +216,7 @@ var keywordIdentMap = map[string]TokenType{
 	"DATETIME":           DATETIME,
 	"DECIMAL":            DECIMAL,
 	"DEFAULT":            DEFAULT,
+	"DEFINER":            DEFINER,
 	"DELAY_KEY_WRITE":    DELAY_KEY_WRITE,
 	"DELETE":             DELETE,
 	"DIRECTORY":          DIRECTORY,
@@ -204,6 +224,7 @@ var keywordIdentMap = map[string]TokenType{
 	"DOUBLE":             DOUBLE,
 	"DROP":               DROP,
 	"DYNAMIC":            DYNAMIC,
+	"EACH":               EACH,
 	"ENGINE":             ENGINE,
 	"ENUM":               ENUM,
 	"EXISTS":             EXISTS,
@@ -211,6 +232,8 @@ var keywordIdentMap = map[string]TokenType{
 	"FIRST":              FIRST,
 	"FIXED":              FIXED,
 	"FLOAT":              FLOAT,
+	"FOLLOWS":            FOLLOWS,
+	"FOR":                FOR,
 	"FOREIGN":            FOREIGN,
 	"FULL":               FULL,
 	"FULLTEXT":           FULLTEXT,
@@ -218,6 +241,7 @@ var keywordIdentMap = map[string]TokenType{
 	"HASH":               HASH,
 	"IF":                 IF,
 	"INDEX":              INDEX,
+	"INSERT":             INSERT,
 	"INSERT_METHOD":      INSERT_METHOD,
 	"INT":                INT,
 	"INTEGER":            INTEGER,
@@ -240,20 +264,26 @@ var keywordIdentMap = map[string]TokenType{
 	"NULL":               NULL,
 	"NUMERIC":            NUMERIC,
 	"ON":                 ON,
+	"OR":                 OR,
 	"PACK_KEYS":          PACK_KEYS,
 	"PARSER":             PARSER,
 	"PARTIAL":            PARTIAL,
 	"PASSWORD":           PASSWORD,
+	"PRECEDES":           PRECEDES,
 	"PRIMARY":            PRIMARY,
 	"REAL":               REAL,
 	"REDUNDANT":          REDUNDANT,
 	"REFERENCES":         REFERENCES,
+	"REPLACE":            REPLACE,
 	"RESTRICT":           RESTRICT,
+	"ROW":                ROW,
 	"ROW_FORMAT":         ROW_FORMAT,
+	"SECURITY":           SECURITY,
 	"SET":                SET,
 	"SIMPLE":             SIMPLE,
 	"SMALLINT":           SMALLINT,
 	"SPATIAL":            SPATIAL,
+	"SQL":                SQL,
 	"STATS_AUTO_RECALC":  STATS_AUTO_RECALC,
 	"STATS_PERSISTENT":   STATS_PERSISTENT,
 	"STATS_SAMPLE_PAGES": STATS_SAMPLE_PAGES,
@@ -268,6 +298,7 @@ var keywordIdentMap = map[string]TokenType{
 	"TINYBLOB":           TINYBLOB,
 	"TINYINT":            TINYINT,
 	"TINYTEXT":           TINYTEXT,
+	"TRIGGER":            TRIGGER,
 	"TRUE":               TRUE,
 	"UNION":              UNION,
 	"UNIQUE":             UNIQUE,
@@ -277,6 +308,7 @@ var keywordIdentMap = map[string]TokenType{
 	"USING":              USING,
 	"VARBINARY":          VARBINARY,
 	"VARCHAR":            VARCHAR,
+	"VIEW":               VIEW,
 	"VIRTUAL":            VIRTUAL,
 	"WITH":               WITH,
 	"YEAR":               YEAR,
@@ -332,6 +364,10 @@ func (t TokenType) String() string {
 		return "COMMENT_IDENT"
 	case ACTION:
 		return "ACTION"
+	case AFTER:
+		return "AFTER"
+	case ALGORITHM:
+		return "ALGORITHM"
 	case ALWAYS:
 		return "ALWAYS"
 	case AS:
@@ -340,6 +376,8 @@ func (t TokenType) String() string {
 		return "AUTO_INCREMENT"
 	case AVG_ROW_LENGTH:
 		return "AVG_ROW_LENGTH"
+	case BEFORE:
+		return "BEFORE"
 	case BIGINT:
 		return "BIGINT"
 	case BINARY:
@@ -394,6 +432,8 @@ func (t TokenType) String() string {
 		return "DECIMAL"
 	case DEFAULT:
 		return "DEFAULT"
+	case DEFINER:
+		return "DEFINER"
 	case DELAY_KEY_WRITE:
 		return "DELAY_KEY_WRITE"
 	case DELETE:
@@ -408,6 +448,8 @@ func (t TokenType) String() string {
 		return "DROP"
 	case DYNAMIC:
 		return "DYNAMIC"
+	case EACH:
+		return "EACH"
 	case ENGINE:
 		return "ENGINE"
 	case ENUM:
@@ -422,6 +464,10 @@ func (t TokenType) String() string {
 		return "FIXED"
 	case FLOAT:
 		return "FLOAT"
+	case FOLLOWS:
+		return "FOLLOWS"
+	case FOR:
+		return "FOR"
 	case FOREIGN:
 		return "FOREIGN"
 	case FULL:
@@ -436,6 +482,8 @@ func (t TokenType) String() string {
 		return "IF"
 	case INDEX:
 		return "INDEX"
+	case INSERT:
+		return "INSERT"
 	case INSERT_METHOD:
 		return "INSERT_METHOD"
 	case INT:
@@ -480,6 +528,8 @@ func (t TokenType) String() string {
 		return "NUMERIC"
 	case ON:
 		return "ON"
+	case OR:
+		return "OR"
 	case PACK_KEYS:
 		return "PACK_KEYS"
 	case PARSER:
@@ -488,6 +538,8 @@ func (t TokenType) String() string {
 		return "PARTIAL"
 	case PASSWORD:
 		return "PASSWORD"
+	case PRECEDES:
+		return "PRECEDES"
 	case PRIMARY:
 		return "PRIMARY"
 	case REAL:
@@ -496,10 +548,16 @@ func (t TokenType) String() string {
 		return "REDUNDANT"
 	case REFERENCES:
 		return "REFERENCES"
+	case REPLACE:
+		return "REPLACE"
 	case RESTRICT:
 		return "RESTRICT"
+	case ROW:
+		return "ROW"
 	case ROW_FORMAT:
 		return "ROW_FORMAT"
+	case SECURITY:
+		return "SECURITY"
 	case SET:
 		return "SET"
 	case SIMPLE:
@@ -508,6 +566,8 @@ func (t TokenType) String() string {
 		return "SMALLINT"
 	case SPATIAL:
 		return "SPATIAL"
+	case SQL:
+		return "SQL"
 	case STATS_AUTO_RECALC:
 		return "STATS_AUTO_RECALC"
 	case STATS_PERSISTENT:
@@ -536,6 +596,8 @@ func (t TokenType) String() string {
 		return "TINYINT"
 	case TINYTEXT:
 		return "TINYTEXT"
+	case TRIGGER:
+		return "TRIGGER"
 	case TRUE:
 		return "TRUE"
 	case UNION:
@@ -554,6 +616,8 @@ func (t TokenType) String() string {
 		return "VARBINARY"
 	case VARCHAR:
 		return "VARCHAR"
+	case VIEW:
+		return "VIEW"
 	case VIRTUAL:
 		return "VIRTUAL"
 	case WITH: